@@ -0,0 +1,216 @@
+// Package alert notifies external systems, such as chat channels or incident
+// tooling, of property violations found while monitoring a live trace.
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/traces/fingerprint"
+	"github.com/traces/property"
+	"github.com/traces/report"
+	t "github.com/traces/types"
+)
+
+// Alert describes a newly seen kind of violation.
+type Alert struct {
+	Property    string `json:"property"`
+	Fingerprint string `json:"fingerprint"`
+	Message     string `json:"message"`
+	Events      []int  `json:"events"`
+	ReportURL   string `json:"report_url,omitempty"`
+}
+
+func (a Alert) String() string {
+	s := fmt.Sprintf("[%s] %s (fingerprint %s)", a.Property, a.Message, a.Fingerprint)
+	if a.ReportURL != "" {
+		s += " " + a.ReportURL
+	}
+	return s
+}
+
+// Hook delivers alerts somewhere.
+type Hook interface {
+	Fire(a Alert) error
+}
+
+// Webhook posts every alert as JSON to a URL.
+type Webhook struct {
+	URL string
+}
+
+func (h Webhook) Fire(a Alert) error { return post(h.URL, a) }
+
+// SlackWebhook posts every alert as a message to a Slack incoming webhook.
+type SlackWebhook struct {
+	URL string
+}
+
+func (h SlackWebhook) Fire(a Alert) error {
+	return post(h.URL, map[string]string{"text": "Trace violation: " + a.String()})
+}
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+func post(target string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	// Webhook URLs carry their credentials, so errors name the host only
+	host := "(invalid URL)"
+	if u, err := url.Parse(target); err == nil {
+		host = u.Host
+	}
+	resp, err := client.Post(target, "application/json", bytes.NewReader(data))
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("webhook on %s: %w", host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook on %s: %s", host, resp.Status)
+	}
+	return nil
+}
+
+// ParseHook parses "slack:<url>" as a SlackWebhook and any other URL as a
+// generic Webhook.
+func ParseHook(spec string) (Hook, error) {
+	if url, ok := strings.CutPrefix(spec, "slack:"); ok {
+		return SlackWebhook{URL: url}, nil
+	}
+	if !strings.HasPrefix(spec, "http://") && !strings.HasPrefix(spec, "https://") {
+		return nil, fmt.Errorf("hook %q: expected an http(s) URL, optionally prefixed with slack:", spec)
+	}
+	return Webhook{URL: spec}, nil
+}
+
+// Monitor checks properties on a growing trace and fires the hooks once per
+// violation fingerprint, so a recurring problem alerts only the first time.
+// A fingerprint counts as alerted once every hook accepted its alert; the
+// next check tries again only the hooks that failed to deliver it. It is
+// safe for concurrent use.
+type Monitor struct {
+	Properties []property.Property
+	Hooks      []Hook
+	ReportURL  string // where the latest report is served, linked from alerts
+
+	mu       sync.Mutex
+	seen     map[string]bool
+	inflight map[string]bool // fingerprints being alerted by a check
+	// accepted holds, for fingerprints not yet alerted to every hook, the
+	// indices of the hooks that accepted their alert
+	accepted map[string]map[int]bool
+	latest   *report.Report
+}
+
+// Check checks the trace, keeps its report as the latest and fires the hooks
+// for violations with new fingerprints. It returns the alerts fired and any
+// errors delivering them.
+func (m *Monitor) Check(trace t.Trace) ([]Alert, []error) {
+	violations := property.Check(trace, m.Properties...)
+	grouper := fingerprint.NewGrouper()
+	grouper.Add(trace, violations)
+	rep := report.New("live", len(trace), violations, grouper.Groups())
+	rep.Locate(trace)
+
+	m.mu.Lock()
+	if m.seen == nil {
+		m.seen = make(map[string]bool)
+	}
+	if m.inflight == nil {
+		m.inflight = make(map[string]bool)
+	}
+	if m.accepted == nil {
+		m.accepted = make(map[string]map[int]bool)
+	}
+	m.latest = &rep
+	var alerts []Alert
+	for _, g := range rep.Groups {
+		if m.seen[g.Fingerprint] || m.inflight[g.Fingerprint] {
+			continue
+		}
+		m.inflight[g.Fingerprint] = true
+		alerts = append(alerts, Alert{
+			Property:    g.Property,
+			Fingerprint: g.Fingerprint,
+			Message:     g.Example.Message,
+			Events:      g.Example.Events,
+			ReportURL:   m.ReportURL,
+		})
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, a := range alerts {
+		// Only this check fires the fingerprint, so its entry can be read
+		// and updated without the lock until it is stored back
+		m.mu.Lock()
+		accepted := m.accepted[a.Fingerprint]
+		m.mu.Unlock()
+		if accepted == nil {
+			accepted = make(map[int]bool)
+		}
+		for i, h := range m.Hooks {
+			if accepted[i] {
+				continue
+			}
+			if err := h.Fire(a); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			accepted[i] = true
+		}
+		m.mu.Lock()
+		delete(m.inflight, a.Fingerprint)
+		if len(accepted) == len(m.Hooks) {
+			m.seen[a.Fingerprint] = true
+			delete(m.accepted, a.Fingerprint)
+		} else {
+			m.accepted[a.Fingerprint] = accepted
+		}
+		m.mu.Unlock()
+	}
+	return alerts, errs
+}
+
+// Latest returns the report of the last check, or nil before the first.
+func (m *Monitor) Latest() *report.Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.latest
+}
+
+// Seen returns the fingerprints already alerted on, sorted, for a restarted
+// monitor to pass to Restore.
+func (m *Monitor) Seen() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Sorted(maps.Keys(m.seen))
+}
+
+// Restore marks fingerprints as already alerted on, so a monitor resuming a
+// live trace does not fire again for violations it reported before.
+func (m *Monitor) Restore(seen []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seen == nil {
+		m.seen = make(map[string]bool)
+	}
+	for _, fp := range seen {
+		m.seen[fp] = true
+	}
+}
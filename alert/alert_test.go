@@ -0,0 +1,44 @@
+package alert
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/traces/property"
+	"github.com/traces/script"
+)
+
+// countingHook records the alerts it accepts and fails the first fail calls.
+type countingHook struct {
+	fail  int
+	fired int
+}
+
+func (h *countingHook) Fire(Alert) error {
+	if h.fail > 0 {
+		h.fail--
+		return errors.New("unavailable")
+	}
+	h.fired++
+	return nil
+}
+
+func TestRetryOnlyFailedHooks(t *testing.T) {
+	// Concurrent critical sections of L violate mutual exclusion
+	trace := script.MustCompile("A acquires L; A releases L; B acquires L; B releases L")
+	ok, flaky := &countingHook{}, &countingHook{fail: 1}
+	m := &Monitor{Properties: []property.Property{property.MutualExclusion{}}, Hooks: []Hook{ok, flaky}}
+
+	if alerts, errs := m.Check(trace); len(alerts) != 1 || len(errs) != 1 {
+		t.Fatalf("first check: %d alerts, %d errors; want 1 and 1", len(alerts), len(errs))
+	}
+	if _, errs := m.Check(trace); len(errs) != 0 {
+		t.Fatalf("second check: %v", errs)
+	}
+	if alerts, _ := m.Check(trace); len(alerts) != 0 {
+		t.Errorf("third check fired %d alerts for a delivered fingerprint", len(alerts))
+	}
+	if ok.fired != 1 || flaky.fired != 1 {
+		t.Errorf("hooks accepted %d and %d alerts, want 1 each", ok.fired, flaky.fired)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/traces/script"
+	"github.com/traces/types"
+)
+
+// aliased names the script processes podA, podB and podC for display.
+var aliased = map[string]string{"podA": "alice", "podB": "bob", "podC": "carol"}
+
+// aliasFixture writes trace and the aliases to a temporary directory and
+// returns their paths.
+func aliasFixture(t *testing.T, trace types.Trace) (in, aliases string) {
+	t.Helper()
+	dir := t.TempDir()
+	in, aliases = filepath.Join(dir, "trace.json"), filepath.Join(dir, "aliases.json")
+	if err := saveTrace(in, trace); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(aliased)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(aliases, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return in, aliases
+}
+
+// output runs a command and returns what it printed to standard output.
+func output(t *testing.T, run func([]string) error, args ...string) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+	err = run(args)
+	os.Stdout = stdout
+	w.Close()
+	return <-done, err
+}
+
+// checkAliased runs every command with -aliases and reports output that
+// is missing the display names or still shows a raw one.
+func checkAliased(t *testing.T, trace types.Trace, commands map[string]func(in, aliases string) (string, error)) {
+	t.Helper()
+	in, aliases := aliasFixture(t, trace)
+	for name, run := range commands {
+		out, err := run(in, aliases)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		shown := false
+		for raw, display := range aliased {
+			if strings.Contains(out, raw) {
+				t.Errorf("%s shows raw name %s:\n%s", name, raw, out)
+			}
+			shown = shown || strings.Contains(out, display)
+		}
+		if !shown {
+			t.Errorf("%s shows no display name:\n%s", name, out)
+		}
+	}
+}
+
+// command adapts a run function to checkAliased, passing -in and -aliases
+// after args.
+func command(t *testing.T, run func([]string) error, args ...string) func(string, string) (string, error) {
+	return func(in, aliases string) (string, error) {
+		return output(t, run, append(args, "-in", in, "-aliases", aliases)...)
+	}
+}
+
+func TestAliasesCausalReports(t *testing.T) {
+	trace := script.MustCompile(`podA sends m1 to podB; podC steps
+podB receives m1; podB sends m2 to podC; podA steps
+podC receives m2; podC sends m3 to podA, podB
+podA receives m3; podB receives m3`)
+	checkAliased(t, trace, map[string]func(string, string) (string, error){
+		"critical-path": command(t, runCriticalPath, "-v", "-slack"),
+		"stability":     command(t, runStability, "-v", "-gc"),
+		"lamport":       command(t, runLamport),
+		"dissemination": command(t, runDissemination),
+		"causal-log":    command(t, runCausalLog),
+	})
+}
+
+func TestAliasesLedgers(t *testing.T) {
+	trace := script.MustCompile(`podA acquires L1; podA acquires L2; podA releases L2; podA releases L1
+podB acquires L2; podB acquires L1; podB releases L1; podB releases L2
+podA sends q to podB; podB receives q; podA sends r to podC`)
+	for i, e := range trace {
+		if e.Type == types.EventSend {
+			trace[i].Role, trace[i].CorrelationKey = "request", "k"
+		}
+	}
+	checkAliased(t, trace, map[string]func(string, string) (string, error){
+		"effects": command(t, runEffects),
+		"rpc":     command(t, runRPC),
+		"locks":   command(t, runLocks),
+	})
+}
+
+func TestAliasesPropertyReports(t *testing.T) {
+	trace := script.MustCompile(`podA sends m1 to podC; podA sends m2 to podC
+podC receives m1; podC receives m2
+podA acquires L; podA releases L; podB acquires L; podB releases L`)
+	checkAliased(t, trace, map[string]func(string, string) (string, error){
+		"explore": command(t, runExplore, "-property", "fifo,mutex"),
+		"whatif":  command(t, runWhatIf, "-assume", "e-5->e-6", "-property", "mutex"),
+		"suggest": command(t, runSuggest, "-property", "mutex"),
+		"graph":   command(t, runGraph),
+	})
+}
+
+func TestAliasesFailureReports(t *testing.T) {
+	trace := script.MustCompile(`podA sends m1 to podB; podB receives m1; podB steps`)
+	trace[2].VClock["podA"] = 5
+	trace[0].Labels = map[string]string{"state": "busy"}
+	spec := filepath.Join(t.TempDir(), "machine.json")
+	if err := os.WriteFile(spec, []byte(`{"machines": {"*": {"initial": "idle", "transitions": []}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	in, aliases := aliasFixture(t, trace)
+	for name, run := range map[string]func() (string, error){
+		"validate": func() (string, error) { return output(t, runValidate, "-in", in, "-aliases", aliases) },
+		"replay": func() (string, error) {
+			return output(t, runReplay, "-in", in, "-machine", spec, "-aliases", aliases)
+		},
+	} {
+		out, err := run()
+		if err == nil {
+			t.Errorf("%s: expected the trace to be rejected", name)
+		}
+		for raw, display := range aliased {
+			if strings.Contains(out, raw) {
+				t.Errorf("%s shows raw name %s:\n%s", name, raw, out)
+			}
+			if raw == "podA" && !strings.Contains(out, display) {
+				t.Errorf("%s does not show %s:\n%s", name, display, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package analysis
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/traces/messages"
+	t "github.com/traces/types"
+)
+
+// Criterion is a structural requirement a generated trace must meet to be
+// accepted.
+type Criterion struct {
+	Name   string
+	Accept func(cfg messages.Config, trace t.Trace) bool
+}
+
+// metricCriterion compares one field of Metrics against a bound.
+func metricCriterion(name, op string, bound int) (Criterion, error) {
+	var get func(Metrics) int
+	switch name {
+	case "events":
+		get = func(m Metrics) int { return m.Events }
+	case "processes":
+		get = func(m Metrics) int { return m.Processes }
+	case "messages":
+		get = func(m Metrics) int { return m.Messages }
+	case "edges":
+		get = func(m Metrics) int { return m.Edges }
+	case "critical_path":
+		get = func(m Metrics) int { return m.CriticalPath }
+	case "width":
+		get = func(m Metrics) int { return m.Width }
+	default:
+		return Criterion{}, fmt.Errorf("unknown metric %q", name)
+	}
+
+	var cmp func(v int) bool
+	switch op {
+	case ">=":
+		cmp = func(v int) bool { return v >= bound }
+	case "<=":
+		cmp = func(v int) bool { return v <= bound }
+	case ">":
+		cmp = func(v int) bool { return v > bound }
+	case "<":
+		cmp = func(v int) bool { return v < bound }
+	case "=", "==":
+		cmp = func(v int) bool { return v == bound }
+	default:
+		return Criterion{}, fmt.Errorf("unknown comparison %q", op)
+	}
+
+	return Criterion{
+		Name: name + op + strconv.Itoa(bound),
+		Accept: func(_ messages.Config, trace t.Trace) bool {
+			return cmp(get(Compute(trace)))
+		},
+	}, nil
+}
+
+// EveryChannel accepts traces in which every channel allowed by the
+// configured topology or adjacency delivered at least one message.
+var EveryChannel = Criterion{
+	Name: "channels",
+	Accept: func(cfg messages.Config, trace t.Trace) bool {
+		senders := make(map[int]string)
+		for _, e := range trace {
+			if e.Type == t.EventSend {
+				senders[e.MessageID] = e.Process
+			}
+		}
+		used := make(map[[2]string]bool)
+		for _, e := range trace {
+			if from, ok := senders[e.MessageID]; ok && e.Type == t.EventReceive {
+				used[[2]string{from, e.Process}] = true
+			}
+		}
+		for _, p := range cfg.Processes {
+			for _, q := range cfg.Neighbors(p) {
+				if !used[[2]string{p, q}] {
+					return false
+				}
+			}
+		}
+		return true
+	},
+}
+
+// ParseCriteria parses a comma separated list of criteria such as
+// "width>=3,channels". Metric names match those of Metrics in snake case.
+func ParseCriteria(spec string) ([]Criterion, error) {
+	var criteria []Criterion
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if part == EveryChannel.Name {
+			criteria = append(criteria, EveryChannel)
+			continue
+		}
+
+		i := strings.IndexAny(part, "<>=")
+		if i <= 0 {
+			return nil, fmt.Errorf("criterion %q: want metric<op>value or %q", part, EveryChannel.Name)
+		}
+		j := i
+		for j < len(part) && strings.ContainsRune("<>=", rune(part[j])) {
+			j++
+		}
+		bound, err := strconv.Atoi(strings.TrimSpace(part[j:]))
+		if err != nil {
+			return nil, fmt.Errorf("criterion %q: %w", part, err)
+		}
+		c, err := metricCriterion(strings.TrimSpace(part[:i]), part[i:j], bound)
+		if err != nil {
+			return nil, fmt.Errorf("criterion %q: %w", part, err)
+		}
+		criteria = append(criteria, c)
+	}
+	return criteria, nil
+}
+
+// GenerateAccepted generates traces from r until one meets every criterion,
+// giving up after maxTries attempts. It returns the accepted trace and the
+// number of attempts it took.
+func GenerateAccepted(cfg messages.Config, r *rand.Rand, maxTries int, criteria ...Criterion) (t.Trace, int, error) {
+	rejected := make(map[string]int)
+	for try := 1; try <= maxTries; try++ {
+		trace := messages.Generate(cfg, r)
+		ok := true
+		for _, c := range criteria {
+			if !c.Accept(cfg, trace) {
+				rejected[c.Name]++
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return trace, try, nil
+		}
+	}
+
+	var reasons []string
+	for _, c := range criteria {
+		if n := rejected[c.Name]; n > 0 {
+			reasons = append(reasons, fmt.Sprintf("%s failed %d times", c.Name, n))
+		}
+	}
+	return nil, maxTries, fmt.Errorf("no trace accepted in %d tries (%s)", maxTries, strings.Join(reasons, ", "))
+}
@@ -0,0 +1,89 @@
+package analysis
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	t "github.com/traces/types"
+)
+
+// RollUp is the combined result of analysing every correlation key of a
+// trace independently.
+type RollUp struct {
+	Keys   []string
+	PerKey map[string]Metrics
+
+	TotalEvents     int
+	MaxCriticalPath int
+	MaxWidth        int
+	LongestKey      string // key with the longest critical path
+}
+
+// AnalyzeByKey splits the trace by correlation key and computes the metrics
+// of each sub-trace in parallel using up to workers goroutines.
+func AnalyzeByKey(trace t.Trace, workers int) RollUp {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	parts := t.SplitByKey(trace)
+	keys := trace.Keys()
+
+	perKey := make(map[string]Metrics, len(keys))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range jobs {
+				m := Compute(parts[key])
+				mu.Lock()
+				perKey[key] = m
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, key := range keys {
+		jobs <- key
+	}
+	close(jobs)
+	wg.Wait()
+
+	rollUp := RollUp{Keys: keys, PerKey: perKey}
+	for _, key := range keys {
+		m := perKey[key]
+		rollUp.TotalEvents += m.Events
+		if m.CriticalPath > rollUp.MaxCriticalPath {
+			rollUp.MaxCriticalPath = m.CriticalPath
+			rollUp.LongestKey = key
+		}
+		rollUp.MaxWidth = max(rollUp.MaxWidth, m.Width)
+	}
+	return rollUp
+}
+
+func (r RollUp) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-16s %7s %9s %6s %14s %6s\n",
+		"key", "events", "processes", "edges", "critical_path", "width")
+	for _, key := range r.Keys {
+		m := r.PerKey[key]
+		name := key
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(&b, "%-16s %7d %9d %6d %14d %6d\n",
+			name, m.Events, m.Processes, m.Edges, m.CriticalPath, m.Width)
+	}
+	if len(r.Keys) == 0 {
+		b.WriteString("0 keys, 0 events\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%d keys, %d events, max critical path %d (key %s), max width %d\n",
+		len(r.Keys), r.TotalEvents, r.MaxCriticalPath, r.LongestKey, r.MaxWidth)
+	return b.String()
+}
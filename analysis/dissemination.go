@@ -0,0 +1,70 @@
+package analysis
+
+import (
+	"github.com/traces/dag"
+	t "github.com/traces/types"
+)
+
+// Reach is when information originating at one event first reaches a
+// process: the process's first event that the origin happens before.
+type Reach struct {
+	Process string
+	Event   int // trace index
+	// Hops is the fewest messages on a causal chain from the origin to the
+	// event, and Depth the most direct dependencies on one.
+	Hops, Depth int
+	// Latency is the origin's timeLabel subtracted from that of the
+	// process's first event from Event on to carry one, or 0.
+	Latency float64
+}
+
+// Dissemination returns, for every process the origin event reaches, where
+// and how it was first reached, in order of the processes' first events.
+// Values of timeLabel are parsed like LabelWeights values.
+func Dissemination(trace t.Trace, origin int, timeLabel string) []Reach {
+	preds := dag.DirectPreds(trace)
+	hops := make([]int, len(trace))
+	depth := make([]int, len(trace))
+	reached := make([]bool, len(trace))
+	reached[origin] = true
+	for _, j := range causalOrder(trace) {
+		if j == origin || !trace[origin].VClock.HappensBefore(trace[j].VClock) {
+			continue
+		}
+		reached[j] = true
+		hops[j] = -1
+		for _, i := range preds(j) {
+			if !reached[i] {
+				continue
+			}
+			h := hops[i]
+			if trace[i].Process != trace[j].Process {
+				h++
+			}
+			if hops[j] < 0 || h < hops[j] {
+				hops[j] = h
+			}
+			depth[j] = max(depth[j], depth[i]+1)
+		}
+	}
+
+	var out []Reach
+	seen := make(map[string]bool)
+	for j, e := range trace {
+		if !reached[j] || seen[e.Process] {
+			continue
+		}
+		seen[e.Process] = true
+		r := Reach{Process: e.Process, Event: j, Hops: hops[j], Depth: depth[j]}
+		if from := trace[origin].Labels[timeLabel]; timeLabel != "" && from != "" {
+			for _, k := range trace[j:] {
+				if to := k.Labels[timeLabel]; k.Process == e.Process && to != "" {
+					r.Latency = parseWeight(to) - parseWeight(from)
+					break
+				}
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
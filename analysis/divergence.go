@@ -0,0 +1,204 @@
+package analysis
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	t "github.com/traces/types"
+)
+
+// DivergenceWindow is a stretch of the trace during which the replicas of an
+// object disagreed on the updates they had seen.
+type DivergenceWindow struct {
+	// Start is the event after which the replicas disagreed, End the event
+	// that made them agree again, or len(trace) if they never did.
+	Start, End int
+	// Wall is the wall time between Start and End, zero unless both carry
+	// a time label.
+	Wall time.Duration
+}
+
+// Events is the length of the window in events.
+func (w DivergenceWindow) Events() int { return w.End - w.Start }
+
+// DivergenceStep records, after the event at Cut, how many of the object's
+// updates each replica had seen. A step is recorded whenever that changes.
+type DivergenceStep struct {
+	Cut      int
+	Seen     map[string]int
+	Diverged bool
+}
+
+// ObjectDivergence is the divergence timeline of one replicated object.
+type ObjectDivergence struct {
+	Key      string
+	Replicas []string // processes that read or wrote the object, sorted
+	Updates  int
+	Steps    []DivergenceStep
+	Windows  []DivergenceWindow
+}
+
+// Longest returns the longest divergence window in events, or false if the
+// replicas never diverged.
+func (o ObjectDivergence) Longest() (DivergenceWindow, bool) {
+	if len(o.Windows) == 0 {
+		return DivergenceWindow{}, false
+	}
+	return slices.MaxFunc(o.Windows, func(a, b DivergenceWindow) int { return a.Events() - b.Events() }), true
+}
+
+// Divergence walks the trace's prefixes, each of which is a consistent cut,
+// and tracks for every object which of its updates (labeled writes) each of
+// its replicas has seen: those that happen before the replica's latest event
+// in the cut. The replicas diverge whenever their sets differ. Objects are
+// returned in key order.
+func Divergence(trace t.Trace, labels KVLabels) []ObjectDivergence {
+	writes := make(map[string][]int)
+	replicaSet := make(map[string]map[string]bool)
+	for _, op := range labels.Ops(trace) {
+		if op.Write {
+			writes[op.Key] = append(writes[op.Key], op.Index)
+		}
+		if replicaSet[op.Key] == nil {
+			replicaSet[op.Key] = make(map[string]bool)
+		}
+		replicaSet[op.Key][trace[op.Index].Process] = true
+	}
+
+	objects := make(map[string]*ObjectDivergence)
+	byProcess := make(map[string][]string) // process -> keys it replicates
+	seen := make(map[string]map[string]string)
+	counts := make(map[string]map[string]int)
+	for _, key := range slices.Sorted(maps.Keys(writes)) {
+		o := &ObjectDivergence{Key: key, Replicas: slices.Sorted(maps.Keys(replicaSet[key])), Updates: len(writes[key])}
+		objects[key] = o
+		seen[key] = make(map[string]string)
+		counts[key] = make(map[string]int)
+		for _, p := range o.Replicas {
+			byProcess[p] = append(byProcess[p], key)
+		}
+	}
+
+	timeLabel := defaultLabel(labels.Time, "time")
+	start := make(map[string]int)
+	for i, e := range trace {
+		for _, key := range byProcess[e.Process] {
+			o := objects[key]
+			var set strings.Builder
+			n := 0
+			for _, w := range writes[key] {
+				if w > i {
+					break
+				}
+				if w == i || trace[w].VClock.HappensBefore(e.VClock) {
+					fmt.Fprintf(&set, "%d,", w)
+					n++
+				}
+			}
+			if n == counts[key][e.Process] {
+				continue
+			}
+			seen[key][e.Process] = set.String()
+			counts[key][e.Process] = n
+
+			diverged := false
+			for _, p := range o.Replicas {
+				if seen[key][p] != seen[key][o.Replicas[0]] {
+					diverged = true
+				}
+			}
+			was := len(o.Steps) > 0 && o.Steps[len(o.Steps)-1].Diverged
+			o.Steps = append(o.Steps, DivergenceStep{Cut: i, Seen: maps.Clone(counts[key]), Diverged: diverged})
+			if diverged && !was {
+				start[key] = i
+			} else if !diverged && was {
+				o.Windows = append(o.Windows, window(trace, timeLabel, start[key], i))
+			}
+		}
+	}
+
+	out := make([]ObjectDivergence, 0, len(objects))
+	for _, key := range slices.Sorted(maps.Keys(objects)) {
+		o := objects[key]
+		if len(o.Steps) > 0 && o.Steps[len(o.Steps)-1].Diverged {
+			o.Windows = append(o.Windows, window(trace, timeLabel, start[key], len(trace)))
+		}
+		out = append(out, *o)
+	}
+	return out
+}
+
+func window(trace t.Trace, timeLabel string, start, end int) DivergenceWindow {
+	w := DivergenceWindow{Start: start, End: end}
+	if end < len(trace) {
+		from, to := parseTimeLabel(trace[start].Labels[timeLabel]), parseTimeLabel(trace[end].Labels[timeLabel])
+		if !from.IsZero() && !to.IsZero() {
+			w.Wall = to.Sub(from)
+		}
+	}
+	return w
+}
+
+func (o ObjectDivergence) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d updates on %d replicas (%s), %d divergence windows",
+		t.QuoteName(o.Key), o.Updates, len(o.Replicas), strings.Join(o.Replicas, ", "), len(o.Windows))
+	if w, ok := o.Longest(); ok {
+		fmt.Fprintf(&b, ", longest %d events (e-%d..e-%d)", w.Events(), w.Start, w.End)
+		if w.Wall != 0 {
+			fmt.Fprintf(&b, ", %s", w.Wall)
+		}
+	}
+	return b.String()
+}
+
+// WriteDivergenceCSV writes the timelines as rows of
+// key,cut,replica,seen,updates,diverged, one per replica and step.
+func WriteDivergenceCSV(w io.Writer, objects []ObjectDivergence) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"key", "cut", "replica", "seen", "updates", "diverged"})
+	for _, o := range objects {
+		for _, s := range o.Steps {
+			for _, p := range o.Replicas {
+				cw.Write([]string{o.Key, strconv.Itoa(s.Cut), p, strconv.Itoa(s.Seen[p]),
+					strconv.Itoa(o.Updates), strconv.FormatBool(s.Diverged)})
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteDivergenceSVG draws the timelines as a chart with one row per object
+// and a bar for each divergence window, over an axis of trace events.
+func WriteDivergenceSVG(w io.Writer, objects []ObjectDivergence, events int) error {
+	const labelWidth, plotWidth, rowHeight = 160, 800, 24
+	scale := float64(plotWidth) / float64(max(events, 1))
+	height := rowHeight*len(objects) + 30
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n",
+		labelWidth+plotWidth+10, height)
+	for i, o := range objects {
+		y := i * rowHeight
+		fmt.Fprintf(&b, `<text x="4" y="%d">%s</text>`+"\n", y+16, html.EscapeString(o.Key))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="#eee"/>`+"\n", labelWidth, y+4, plotWidth, rowHeight-8)
+		for _, win := range o.Windows {
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="#d9534f"><title>e-%d..e-%d: %d events</title></rect>`+"\n",
+				labelWidth+float64(win.Start)*scale, y+4, max(float64(win.Events())*scale, 1), rowHeight-8, win.Start, win.End, win.Events())
+		}
+	}
+	axis := rowHeight*len(objects) + 16
+	fmt.Fprintf(&b, `<text x="%d" y="%d">e-0</text>`+"\n", labelWidth, axis)
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">e-%d</text>`+"\n", labelWidth+plotWidth, axis, max(events-1, 0))
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,147 @@
+package analysis
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// EffectOutcome classifies how often a delivered message took effect.
+type EffectOutcome string
+
+const (
+	ExactlyOnce     EffectOutcome = "exactly-once"
+	DuplicateEffect EffectOutcome = "duplicate"
+	LostEffect      EffectOutcome = "lost"      // no effect, and the receiver crashed after delivery
+	NoEffect        EffectOutcome = "no-effect" // no effect, without a crash to blame
+)
+
+// EffectEntry is the ledger entry of one message at one receiver.
+type EffectEntry struct {
+	MessageID  int
+	Receiver   string
+	Send       int   // trace index of the SEND, -1 if it is not in the trace
+	Deliveries []int // RECVs of the message on Receiver
+	// Effects are the events of Receiver labeled as applying the message.
+	Effects []int
+	// Crash is the first CRASH of Receiver after the first delivery, -1 if
+	// there is none; Recover is the RECOVER that followed it, -1 if none.
+	Crash, Recover int
+	Outcome        EffectOutcome
+}
+
+// AcrossCrash reports whether the message was delivered before a crash of
+// its receiver.
+func (e EffectEntry) AcrossCrash() bool { return e.Crash >= 0 }
+
+// Rederived returns the effects that happened after the receiver recovered:
+// effects re-derived from the message, for instance by replaying it.
+func (e EffectEntry) Rederived() []int {
+	if e.Recover < 0 {
+		return nil
+	}
+	k, _ := slices.BinarySearch(e.Effects, e.Recover)
+	return e.Effects[k:]
+}
+
+func (e EffectEntry) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Msg-%d at %s: %s, %d deliveries, %d effects", e.MessageID, t.QuoteName(e.Receiver),
+		e.Outcome, len(e.Deliveries), len(e.Effects))
+	if e.AcrossCrash() {
+		fmt.Fprintf(&b, ", receiver crashed at e-%d", e.Crash)
+		if e.Recover >= 0 {
+			fmt.Fprintf(&b, " and recovered at e-%d with %d effects re-derived", e.Recover, len(e.Rederived()))
+		} else {
+			b.WriteString(" and never recovered")
+		}
+	}
+	return b.String()
+}
+
+// EffectLedger tracks the effects of every delivered message. An event
+// applies a message when its effectLabel holds the message ID; empty
+// defaults to "effect". Entries are sorted by message ID, then receiver.
+func EffectLedger(trace t.Trace, effectLabel string) []EffectEntry {
+	effectLabel = defaultLabel(effectLabel, "effect")
+	type at struct {
+		id       int
+		receiver string
+	}
+	entries := make(map[at]*EffectEntry)
+	entry := func(id int, receiver string) *EffectEntry {
+		k := at{id, receiver}
+		if entries[k] == nil {
+			entries[k] = &EffectEntry{MessageID: id, Receiver: receiver, Send: -1, Crash: -1, Recover: -1}
+		}
+		return entries[k]
+	}
+
+	sends := make(map[int]int)
+	crashes := make(map[string][]int)
+	recovers := make(map[string][]int)
+	for i, e := range trace {
+		switch e.Type {
+		case t.EventSend:
+			sends[e.MessageID] = i
+		case t.EventReceive:
+			en := entry(e.MessageID, e.Process)
+			en.Deliveries = append(en.Deliveries, i)
+		case t.EventCrash:
+			crashes[e.Process] = append(crashes[e.Process], i)
+		case t.EventRecover:
+			recovers[e.Process] = append(recovers[e.Process], i)
+		}
+		if v, ok := e.Labels[effectLabel]; ok {
+			if id, err := strconv.Atoi(v); err == nil {
+				en := entry(id, e.Process)
+				en.Effects = append(en.Effects, i)
+			}
+		}
+	}
+
+	out := make([]EffectEntry, 0, len(entries))
+	for _, en := range entries {
+		if s, ok := sends[en.MessageID]; ok {
+			en.Send = s
+		}
+		if len(en.Deliveries) > 0 {
+			en.Crash = after(crashes[en.Receiver], en.Deliveries[0])
+		}
+		if en.Crash >= 0 {
+			en.Recover = after(recovers[en.Receiver], en.Crash)
+		}
+		switch {
+		case len(en.Effects) > 1:
+			en.Outcome = DuplicateEffect
+		case len(en.Effects) == 1:
+			en.Outcome = ExactlyOnce
+		case en.Crash >= 0:
+			en.Outcome = LostEffect
+		default:
+			en.Outcome = NoEffect
+		}
+		out = append(out, *en)
+	}
+	slices.SortFunc(out, func(a, b EffectEntry) int {
+		return cmp.Or(cmp.Compare(a.MessageID, b.MessageID), strings.Compare(a.Receiver, b.Receiver))
+	})
+	return out
+}
+
+// after returns the first of the sorted indices that is greater than i, or
+// -1.
+func after(indices []int, i int) int {
+	k, found := slices.BinarySearch(indices, i)
+	if found {
+		k++
+	}
+	if k == len(indices) {
+		return -1
+	}
+	return indices[k]
+}
@@ -0,0 +1,79 @@
+package analysis
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	t "github.com/traces/types"
+)
+
+// KVLabels names the labels that describe key-value operations: the session
+// an event belongs to, its operation ("read" or "write"), the key it reads or
+// writes, the value written or returned, optionally the transaction it is
+// part of and the wall time it happened at (RFC 3339 or Unix nanoseconds).
+// Empty fields default to "session", "op", "key", "value", "txn" and "time".
+//
+// A read returned the latest write of its key and value listed before it in
+// the trace, or the initial value if there is none. A session's operations
+// are ordered by their position in the trace, which matches the order the
+// client issued them as long as each one is issued after the previous one
+// returned.
+type KVLabels struct {
+	Session, Op, Key, Value, Txn, Time string
+}
+
+// KVOp is a labeled read or write.
+type KVOp struct {
+	Index   int // trace index
+	Session string
+	Txn     string // empty outside transactions
+	Key     string
+	Write   bool
+	From    int       // write returned by a read, -1 for the initial value
+	At      time.Time // zero when the event has no valid time label
+}
+
+func defaultLabel(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
+
+// KeyLabel returns the name of the key label.
+func (l KVLabels) KeyLabel() string { return defaultLabel(l.Key, "key") }
+
+// Ops returns the labeled reads and writes of the trace in trace order.
+func (l KVLabels) Ops(trace t.Trace) []KVOp {
+	session, op, key := defaultLabel(l.Session, "session"), defaultLabel(l.Op, "op"), l.KeyLabel()
+	value, txn, at := defaultLabel(l.Value, "value"), defaultLabel(l.Txn, "txn"), defaultLabel(l.Time, "time")
+
+	var out []KVOp
+	latest := make(map[[2]string]int) // key, value -> last write
+	for i, e := range trace {
+		kind := strings.ToLower(e.Labels[op])
+		if kind != "read" && kind != "write" {
+			continue
+		}
+		o := KVOp{Index: i, Session: e.Labels[session], Txn: e.Labels[txn], Key: e.Labels[key], Write: kind == "write", From: -1}
+		o.At = parseTimeLabel(e.Labels[at])
+		kv := [2]string{o.Key, e.Labels[value]}
+		if o.Write {
+			latest[kv] = i
+		} else if w, ok := latest[kv]; ok {
+			o.From = w
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+// parseTimeLabel reads an RFC 3339 time or a count of Unix nanoseconds.
+func parseTimeLabel(s string) time.Time {
+	if ns, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(0, ns)
+	}
+	at, _ := time.Parse(time.RFC3339Nano, s)
+	return at
+}
@@ -0,0 +1,77 @@
+package analysis
+
+import (
+	t "github.com/traces/types"
+)
+
+// LamportComparison shows where Lamport order and causality part ways.
+// Lamport clocks never contradict happens-before when they are kept
+// correctly, but they also order most concurrent events, which readers of
+// scalar timestamps easily mistake for causality.
+type LamportComparison struct {
+	Clocks     []t.LamportClock // per event, as recorded or derived from vector clocks
+	Recorded   bool             // whether Clocks came from the trace's Lamport fields
+	Ordered    int              // pairs related by happens-before
+	Concurrent int              // pairs unrelated by happens-before
+	// Misordered counts concurrent pairs with different Lamport clocks,
+	// which Lamport order presents as one before the other.
+	Misordered int
+	// Contradictions lists pairs a happens before b whose clocks are not
+	// increasing, possible only for recorded clocks.
+	Contradictions [][2]int
+	// Examples lists some misordered pairs, earlier Lamport clock first.
+	Examples [][2]int
+}
+
+// CompareLamport compares Lamport order with happens-before over every pair
+// of events, keeping up to examples misordered pairs. It uses the trace's own
+// Lamport clocks when every event has one, and derives them otherwise.
+func CompareLamport(trace t.Trace, examples int) LamportComparison {
+	c := LamportComparison{Recorded: len(trace) > 0}
+	for _, e := range trace {
+		if e.Lamport == 0 {
+			c.Recorded = false
+			break
+		}
+	}
+	if c.Recorded {
+		c.Clocks = make([]t.LamportClock, len(trace))
+		for i, e := range trace {
+			c.Clocks[i] = e.Lamport
+		}
+	} else {
+		c.Clocks = t.LamportClocks(trace)
+	}
+
+	for i := range trace {
+		for j := i + 1; j < len(trace); j++ {
+			a, b := trace[i].VClock, trace[j].VClock
+			switch {
+			case a.HappensBefore(b):
+				c.Ordered++
+				if c.Clocks[i] >= c.Clocks[j] {
+					c.Contradictions = append(c.Contradictions, [2]int{i, j})
+				}
+			case b.HappensBefore(a):
+				c.Ordered++
+				if c.Clocks[j] >= c.Clocks[i] {
+					c.Contradictions = append(c.Contradictions, [2]int{j, i})
+				}
+			default:
+				c.Concurrent++
+				if c.Clocks[i] == c.Clocks[j] {
+					continue
+				}
+				c.Misordered++
+				if len(c.Examples) < examples {
+					if c.Clocks[i] < c.Clocks[j] {
+						c.Examples = append(c.Examples, [2]int{i, j})
+					} else {
+						c.Examples = append(c.Examples, [2]int{j, i})
+					}
+				}
+			}
+		}
+	}
+	return c
+}
@@ -0,0 +1,224 @@
+package analysis
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// LockEdge records that a process acquired To while holding From. Outer and
+// Inner are the trace indices of the two ACQUIRE events.
+type LockEdge struct {
+	From, To     string
+	Process      string
+	Outer, Inner int
+}
+
+// LockGraph is the lock-order graph of a trace: an edge From -> To for every
+// pair of locks some process nested in that order, with the first witness
+// seen in the trace.
+type LockGraph struct {
+	Locks []string // sorted
+	Edges map[[2]string]LockEdge
+}
+
+// LockOrderGraph builds the lock-order graph of the trace from its ACQUIRE
+// and RELEASE events.
+func LockOrderGraph(trace t.Trace) *LockGraph {
+	g := &LockGraph{Edges: make(map[[2]string]LockEdge)}
+	seen := make(map[string]bool)
+	// Locks held per process, with the index of their ACQUIRE
+	held := make(map[string]map[string]int)
+
+	for i, e := range trace {
+		if e.Type != t.EventAcquire && e.Type != t.EventRelease {
+			continue
+		}
+		if !seen[e.Lock] {
+			seen[e.Lock] = true
+			g.Locks = append(g.Locks, e.Lock)
+		}
+		h := held[e.Process]
+		if h == nil {
+			h = make(map[string]int)
+			held[e.Process] = h
+		}
+
+		if e.Type == t.EventRelease {
+			delete(h, e.Lock)
+			continue
+		}
+		for _, outer := range slices.Sorted(maps.Keys(h)) {
+			key := [2]string{outer, e.Lock}
+			if _, ok := g.Edges[key]; !ok && outer != e.Lock {
+				g.Edges[key] = LockEdge{From: outer, To: e.Lock, Process: e.Process, Outer: h[outer], Inner: i}
+			}
+		}
+		h[e.Lock] = i
+	}
+	slices.Sort(g.Locks)
+	return g
+}
+
+func (g *LockGraph) successors(l string) []string {
+	var out []string
+	for key := range g.Edges {
+		if key[0] == l {
+			out = append(out, key[1])
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Inversion is a cycle in the lock-order graph: processes took the locks in
+// conflicting orders, so a different interleaving could deadlock.
+type Inversion struct {
+	Locks []string   // the cycle, starting from its smallest lock
+	Edges []LockEdge // Edges[i] witnesses Locks[i] -> Locks[i+1 mod n]
+	// Concurrent reports whether the outer acquisitions of at least two
+	// witnesses were concurrent in the trace, i.e. nothing else ordered the
+	// conflicting critical sections.
+	Concurrent bool
+}
+
+func (inv Inversion) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "lock-order inversion %s", strings.Join(append(slices.Clone(inv.Locks), inv.Locks[0]), " -> "))
+	if inv.Concurrent {
+		b.WriteString(" (concurrent)")
+	}
+	for _, e := range inv.Edges {
+		fmt.Fprintf(&b, "\n  %s holds %s (e-%d) while acquiring %s (e-%d)", e.Process, e.From, e.Outer, e.To, e.Inner)
+	}
+	return b.String()
+}
+
+// Inversions returns one cycle for every strongly connected group of locks
+// in the lock-order graph whose witnesses involve more than one process.
+func (g *LockGraph) Inversions(trace t.Trace) []Inversion {
+	var out []Inversion
+	for _, scc := range g.components() {
+		if len(scc) < 2 {
+			continue
+		}
+		cycle := g.cycle(scc)
+		inv := Inversion{Locks: cycle}
+		procs := make(map[string]bool)
+		for i, l := range cycle {
+			e := g.Edges[[2]string{l, cycle[(i+1)%len(cycle)]}]
+			inv.Edges = append(inv.Edges, e)
+			procs[e.Process] = true
+		}
+		if len(procs) < 2 {
+			continue // one process nesting inconsistently cannot deadlock itself
+		}
+		for i, a := range inv.Edges {
+			for _, b := range inv.Edges[i+1:] {
+				if a.Process != b.Process && trace[a.Outer].VClock.ConcurrentWith(trace[b.Outer].VClock) {
+					inv.Concurrent = true
+				}
+			}
+		}
+		out = append(out, inv)
+	}
+	return out
+}
+
+// components returns the strongly connected components of the graph
+// (Tarjan's algorithm), each sorted, in order of their smallest lock.
+func (g *LockGraph) components() [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var out [][]string
+
+	var visit func(l string)
+	visit = func(l string) {
+		index[l] = len(index)
+		low[l] = index[l]
+		stack = append(stack, l)
+		onStack[l] = true
+		for _, m := range g.successors(l) {
+			if _, ok := index[m]; !ok {
+				visit(m)
+				low[l] = min(low[l], low[m])
+			} else if onStack[m] {
+				low[l] = min(low[l], index[m])
+			}
+		}
+		if low[l] == index[l] {
+			var scc []string
+			for {
+				m := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[m] = false
+				scc = append(scc, m)
+				if m == l {
+					break
+				}
+			}
+			slices.Sort(scc)
+			out = append(out, scc)
+		}
+	}
+	for _, l := range g.Locks {
+		if _, ok := index[l]; !ok {
+			visit(l)
+		}
+	}
+	slices.SortFunc(out, func(a, b []string) int { return strings.Compare(a[0], b[0]) })
+	return out
+}
+
+// cycle returns a shortest cycle through the smallest lock of a strongly
+// connected component, found by breadth-first search inside it.
+func (g *LockGraph) cycle(scc []string) []string {
+	in := make(map[string]bool, len(scc))
+	for _, l := range scc {
+		in[l] = true
+	}
+	start := scc[0]
+	prev := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		l := queue[0]
+		queue = queue[1:]
+		for _, m := range g.successors(l) {
+			if !in[m] {
+				continue
+			}
+			if m == start {
+				cycle := []string{l}
+				for l != start {
+					l = prev[l]
+					cycle = append(cycle, l)
+				}
+				slices.Reverse(cycle)
+				return cycle
+			}
+			if _, ok := prev[m]; !ok {
+				prev[m] = l
+				queue = append(queue, m)
+			}
+		}
+	}
+	return scc
+}
+
+// String lists the edges of the lock-order graph.
+func (g *LockGraph) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d locks, %d order edges\n", len(g.Locks), len(g.Edges))
+	for _, l := range g.Locks {
+		for _, m := range g.successors(l) {
+			e := g.Edges[[2]string{l, m}]
+			fmt.Fprintf(&b, "  %s -> %s  (%s, e-%d -> e-%d)\n", l, m, e.Process, e.Outer, e.Inner)
+		}
+	}
+	return b.String()
+}
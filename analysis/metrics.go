@@ -0,0 +1,119 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/traces/dag"
+	t "github.com/traces/types"
+)
+
+// Metrics summarises the causal structure of a single trace.
+type Metrics struct {
+	Events       int
+	Processes    int
+	Messages     int
+	Edges        int // edges in the transitively reduced DAG
+	CriticalPath int // number of events on the longest causal chain
+	Width        int // size of the largest set of pairwise concurrent events
+}
+
+// Compute derives the structural metrics of the given trace.
+func Compute(trace t.Trace) Metrics {
+	processes := make(map[string]bool)
+	messages := make(map[int]bool)
+	for _, e := range trace {
+		processes[e.Process] = true
+		if e.Type.IsMessage() {
+			messages[e.MessageID] = true
+		}
+	}
+
+	return Metrics{
+		Events:       len(trace),
+		Processes:    len(processes),
+		Messages:     len(messages),
+		Edges:        len(dag.BuildDAG(trace).Edges),
+		CriticalPath: CriticalPathLength(trace),
+		Width:        Width(trace),
+	}
+}
+
+// causalOrder returns the indices of trace sorted into a linear extension of
+// happens-before. If a -> b then the sum of a's clock is strictly smaller than
+// the sum of b's clock, so sorting by that sum is always a valid order.
+func causalOrder(trace t.Trace) []int {
+	sums := make([]int, len(trace))
+	order := make([]int, len(trace))
+	for i, e := range trace {
+		order[i] = i
+		for _, v := range e.VClock {
+			sums[i] += v
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return sums[order[a]] < sums[order[b]]
+	})
+	return order
+}
+
+// CriticalPathLength returns the number of events on the longest chain of
+// causally ordered events.
+func CriticalPathLength(trace t.Trace) int {
+	order := causalOrder(trace)
+	longest := make([]int, len(trace))
+	best := 0
+
+	for x, i := range order {
+		longest[i] = 1
+		for _, j := range order[:x] {
+			if trace[j].VClock.HappensBefore(trace[i].VClock) && longest[j]+1 > longest[i] {
+				longest[i] = longest[j] + 1
+			}
+		}
+		best = max(best, longest[i])
+	}
+	return best
+}
+
+// Width returns the size of the largest antichain of the happens-before
+// partial order. By Dilworth's theorem this equals the number of events minus
+// a maximum matching in the bipartite "a happens-before b" graph.
+func Width(trace t.Trace) int {
+	n := len(trace)
+	succ := make([][]int, n)
+	for i := range trace {
+		for j := range trace {
+			if i != j && trace[i].VClock.HappensBefore(trace[j].VClock) {
+				succ[i] = append(succ[i], j)
+			}
+		}
+	}
+
+	matchedTo := make([]int, n)
+	for i := range matchedTo {
+		matchedTo[i] = -1
+	}
+
+	var augment func(u int, seen []bool) bool
+	augment = func(u int, seen []bool) bool {
+		for _, v := range succ[u] {
+			if seen[v] {
+				continue
+			}
+			seen[v] = true
+			if matchedTo[v] == -1 || augment(matchedTo[v], seen) {
+				matchedTo[v] = u
+				return true
+			}
+		}
+		return false
+	}
+
+	matching := 0
+	for u := range n {
+		if augment(u, make([]bool, n)) {
+			matching++
+		}
+	}
+	return n - matching
+}
@@ -0,0 +1,193 @@
+package analysis
+
+import (
+	"sort"
+	"time"
+
+	"github.com/traces/dag"
+	t "github.com/traces/types"
+)
+
+// TimeInversion is a direct causal dependency whose timestamps run backwards:
+// Before happens before After, yet After's process recorded an earlier time,
+// a sign of clock skew between the two processes.
+type TimeInversion struct {
+	Before, After int           // trace indices
+	By            time.Duration // how much earlier After was stamped
+}
+
+// TimeInversions returns the direct causal dependencies between timestamped
+// events whose timestamps contradict happens-before, largest first. Only
+// direct dependencies are checked: a later event that inherits the
+// contradiction through program order is not reported again.
+func TimeInversions(trace t.Trace) []TimeInversion {
+	preds := dag.DirectPreds(trace)
+	var out []TimeInversion
+	for j, e := range trace {
+		if e.Timestamp.IsZero() {
+			continue
+		}
+		for _, i := range preds(j) {
+			if !trace[i].Timestamp.IsZero() && e.Timestamp.Before(trace[i].Timestamp) {
+				out = append(out, TimeInversion{Before: i, After: j, By: trace[i].Timestamp.Sub(e.Timestamp)})
+			}
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].By > out[b].By })
+	return out
+}
+
+// SkewBound bounds how far the clock of process To runs ahead of that of
+// From, derived from the messages between them: a message from From to To
+// cannot arrive before it was sent, so To's offset exceeds From's by at most
+// the smallest stamped delay, and by at least minus the smallest delay the
+// other way. A bound without messages in its direction is missing.
+type SkewBound struct {
+	From, To       string
+	Min, Max       time.Duration
+	HasMin, HasMax bool
+	Messages       int // timestamped deliveries between the two, both ways
+}
+
+// SkewBounds returns a bound for every pair of processes that exchanged
+// timestamped messages, ordered by process names.
+func SkewBounds(trace t.Trace) []SkewBound {
+	type pair struct{ from, to string }
+	sends := make(map[int]int)
+	for i, e := range trace {
+		if e.Type == t.EventSend {
+			sends[e.MessageID] = i
+		}
+	}
+	delay := make(map[pair]time.Duration)
+	count := make(map[pair]int)
+	for _, e := range trace {
+		s, ok := sends[e.MessageID]
+		if !ok || e.Type != t.EventReceive || e.Timestamp.IsZero() || trace[s].Timestamp.IsZero() || trace[s].Process == e.Process {
+			continue
+		}
+		k := pair{trace[s].Process, e.Process}
+		d := e.Timestamp.Sub(trace[s].Timestamp)
+		if n, seen := delay[k]; !seen || d < n {
+			delay[k] = d
+		}
+		count[k]++
+	}
+
+	seen := make(map[pair]bool)
+	var out []SkewBound
+	for k := range delay {
+		from, to := k.from, k.to
+		if to < from {
+			from, to = to, from
+		}
+		if seen[pair{from, to}] {
+			continue
+		}
+		seen[pair{from, to}] = true
+		b := SkewBound{From: from, To: to, Messages: count[pair{from, to}] + count[pair{to, from}]}
+		b.Max, b.HasMax = delay[pair{from, to}]
+		if d, ok := delay[pair{to, from}]; ok {
+			b.Min, b.HasMin = -d, true
+		}
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].From != out[j].From {
+			return out[i].From < out[j].From
+		}
+		return out[i].To < out[j].To
+	})
+	return out
+}
+
+// OrderAgreement compares the physical order of timestamped events with
+// their causal order, over every pair of them.
+type OrderAgreement struct {
+	Ordered    int // pairs related by happens-before
+	Agreeing   int // of those, pairs whose timestamps are in causal order
+	Concurrent int // pairs unrelated by happens-before
+	Tied       int // of those, pairs stamped with the same time
+}
+
+// ComparePhysicalOrder counts how often timestamps agree with happens-before.
+// Events without a timestamp are left out.
+func ComparePhysicalOrder(trace t.Trace) OrderAgreement {
+	var a OrderAgreement
+	for i := range trace {
+		if trace[i].Timestamp.IsZero() {
+			continue
+		}
+		for j := i + 1; j < len(trace); j++ {
+			if trace[j].Timestamp.IsZero() {
+				continue
+			}
+			ci, cj := trace[i].VClock, trace[j].VClock
+			ti, tj := trace[i].Timestamp, trace[j].Timestamp
+			switch {
+			case ci.HappensBefore(cj):
+				a.Ordered++
+				if !tj.Before(ti) {
+					a.Agreeing++
+				}
+			case cj.HappensBefore(ci):
+				a.Ordered++
+				if !ti.Before(tj) {
+					a.Agreeing++
+				}
+			default:
+				a.Concurrent++
+				if ti.Equal(tj) {
+					a.Tied++
+				}
+			}
+		}
+	}
+	return a
+}
+
+// HLCComparison summarises the hybrid logical clocks of a trace.
+type HLCComparison struct {
+	Stamped int // events with an HLC
+	Ordered int // pairs of them related by happens-before
+	// Contradictions counts ordered pairs whose HLCs are not increasing,
+	// which correctly kept clocks never show.
+	Contradictions int
+	MaxLogical     int           // largest logical counter
+	MaxDrift       time.Duration // most an HLC's wall time runs ahead of its event's timestamp
+}
+
+// CompareHLC checks the HLCs of a trace against happens-before and measures
+// how far they drift from the events' timestamps.
+func CompareHLC(trace t.Trace) HLCComparison {
+	var c HLCComparison
+	for i, e := range trace {
+		if e.HLC.IsZero() {
+			continue
+		}
+		c.Stamped++
+		c.MaxLogical = max(c.MaxLogical, e.HLC.Logical)
+		if !e.Timestamp.IsZero() {
+			c.MaxDrift = max(c.MaxDrift, time.Duration(e.HLC.Wall-e.Timestamp.UnixNano()))
+		}
+		for j := i + 1; j < len(trace); j++ {
+			f := trace[j]
+			if f.HLC.IsZero() {
+				continue
+			}
+			switch {
+			case e.VClock.HappensBefore(f.VClock):
+				c.Ordered++
+				if !e.HLC.Before(f.HLC) {
+					c.Contradictions++
+				}
+			case f.VClock.HappensBefore(e.VClock):
+				c.Ordered++
+				if !f.HLC.Before(e.HLC) {
+					c.Contradictions++
+				}
+			}
+		}
+	}
+	return c
+}
@@ -0,0 +1,101 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// QuorumSpec describes quorum operations: events sharing a correlation key
+// form one operation, events with the Response role are the replies it waits
+// for and events with the Complete role finish it.
+type QuorumSpec struct {
+	// Size is the number of distinct processes that must have responded.
+	// Zero means a majority of the processes of the trace.
+	Size     int
+	Response string // role of replica responses, "ack" if empty
+	Complete string // role of completion events, "commit" if empty
+}
+
+func (s QuorumSpec) withDefaults(trace t.Trace) QuorumSpec {
+	if s.Response == "" {
+		s.Response = "ack"
+	}
+	if s.Complete == "" {
+		s.Complete = "commit"
+	}
+	if s.Size == 0 {
+		procs := make(map[string]bool)
+		for _, e := range trace {
+			procs[e.Process] = true
+		}
+		s.Size = len(procs)/2 + 1
+	}
+	return s
+}
+
+// QuorumCompletion is one completion event of an operation together with the
+// responses that causally precede it.
+type QuorumCompletion struct {
+	Operation string
+	Event     int            // trace index of the completion
+	Responses map[string]int // responding process -> index of its first response before Event
+	Required  int
+}
+
+// Reached reports whether enough distinct processes responded.
+func (c QuorumCompletion) Reached() bool { return len(c.Responses) >= c.Required }
+
+// Responders returns the processes that responded before the completion,
+// sorted.
+func (c QuorumCompletion) Responders() []string {
+	procs := make([]string, 0, len(c.Responses))
+	for p := range c.Responses {
+		procs = append(procs, p)
+	}
+	slices.Sort(procs)
+	return procs
+}
+
+func (c QuorumCompletion) String() string {
+	status := "quorum"
+	if !c.Reached() {
+		status = "UNDER QUORUM"
+	}
+	return fmt.Sprintf("%s: e-%d completed with %d/%d responses [%s] %s",
+		c.Operation, c.Event, len(c.Responses), c.Required, strings.Join(c.Responders(), ", "), status)
+}
+
+// Quorums returns every completion event of the trace, in trace order, with
+// the responses of its operation that happen before it. A response counts
+// only if the completion causally depends on it; responses that arrive after
+// the operation finished, or concurrently with it, do not.
+func Quorums(trace t.Trace, spec QuorumSpec) []QuorumCompletion {
+	spec = spec.withDefaults(trace)
+	responses := make(map[string][]int)
+	for i, e := range trace {
+		if e.Role == spec.Response {
+			responses[e.CorrelationKey] = append(responses[e.CorrelationKey], i)
+		}
+	}
+
+	var out []QuorumCompletion
+	for i, e := range trace {
+		if e.Role != spec.Complete {
+			continue
+		}
+		c := QuorumCompletion{Operation: e.CorrelationKey, Event: i, Responses: make(map[string]int), Required: spec.Size}
+		for _, r := range responses[e.CorrelationKey] {
+			if _, ok := c.Responses[trace[r].Process]; ok {
+				continue
+			}
+			if trace[r].VClock.HappensBefore(e.VClock) {
+				c.Responses[trace[r].Process] = r
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/traces/messages"
+	t "github.com/traces/types"
+)
+
+// RPCCall is a request to one callee and its response. Indices are -1 for
+// the steps that never happened.
+type RPCCall struct {
+	Key            string // correlation key shared by request and response
+	Caller, Callee string // Callee is empty if nobody received the request
+	Request        int    // SEND of the request
+	Received       int    // its RECV on the callee
+	Response       int    // SEND of the response
+	Answered       int    // its RECV on the caller
+}
+
+// Status says how far the call got: "answered", "request undelivered",
+// "not answered" or "response undelivered".
+func (c RPCCall) Status() string {
+	switch {
+	case c.Answered >= 0:
+		return "answered"
+	case c.Received < 0:
+		return "request undelivered"
+	case c.Response < 0:
+		return "not answered"
+	default:
+		return "response undelivered"
+	}
+}
+
+func (c RPCCall) String() string {
+	callee := t.QuoteName(c.Callee)
+	if c.Callee == "" {
+		callee = "?"
+	}
+	return fmt.Sprintf("%s %s -> %s (e-%d): %s", t.QuoteName(c.Key), t.QuoteName(c.Caller), callee, c.Request, c.Status())
+}
+
+// RPCCalls pairs the requests of a trace, SENDs with the requestRole, with
+// the responses their callees sent back under the same correlation key
+// with the responseRole. Empty roles default to those of the generator's
+// RPC mode. Calls are listed in request order, a broadcast request giving
+// one call per callee.
+func RPCCalls(trace t.Trace, requestRole, responseRole string) []RPCCall {
+	requestRole, responseRole = defaultLabel(requestRole, messages.RoleRequest), defaultLabel(responseRole, messages.RoleResponse)
+
+	recvs := make(map[int][]int) // message ID -> RECVs, in trace order
+	for i, e := range trace {
+		if e.Type == t.EventReceive {
+			recvs[e.MessageID] = append(recvs[e.MessageID], i)
+		}
+	}
+	firstRecv := func(id int, process string) int {
+		for _, r := range recvs[id] {
+			if trace[r].Process == process {
+				return r
+			}
+		}
+		return -1
+	}
+
+	var out []RPCCall
+	for i, e := range trace {
+		if e.Type != t.EventSend || e.Role != requestRole {
+			continue
+		}
+		callees := make(map[string]bool)
+		for _, r := range recvs[e.MessageID] {
+			if callee := trace[r].Process; !callees[callee] {
+				callees[callee] = true
+				out = append(out, answer(trace, RPCCall{Key: e.CorrelationKey, Caller: e.Process, Callee: callee,
+					Request: i, Received: r}, responseRole, firstRecv))
+			}
+		}
+		if len(callees) == 0 {
+			out = append(out, RPCCall{Key: e.CorrelationKey, Caller: e.Process, Request: i, Received: -1, Response: -1, Answered: -1})
+		}
+	}
+	return out
+}
+
+// answer finds the callee's response to a received request and its receipt.
+func answer(trace t.Trace, c RPCCall, responseRole string, firstRecv func(int, string) int) RPCCall {
+	c.Response, c.Answered = -1, -1
+	for j := c.Received + 1; j < len(trace); j++ {
+		e := trace[j]
+		if e.Type == t.EventSend && e.Process == c.Callee && e.Role == responseRole && e.CorrelationKey == c.Key {
+			if r := firstRecv(e.MessageID, c.Caller); r >= 0 || c.Response < 0 {
+				c.Response, c.Answered = j, r
+			}
+			if c.Answered >= 0 {
+				break
+			}
+		}
+	}
+	return c
+}
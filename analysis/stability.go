@@ -0,0 +1,126 @@
+package analysis
+
+import (
+	"sort"
+
+	t "github.com/traces/types"
+)
+
+// Stability describes when an event became causally stable: known to every
+// process, i.e. in the causal past of some event of each of them. Causal
+// broadcast may deliver and a CRDT may garbage-collect metadata about an
+// update only once it is stable.
+type Stability struct {
+	Event  int
+	Stable bool
+	// Frontier maps each process to its first event that has Event in its
+	// causal past (Event itself on its own process). Processes that never
+	// learn of Event are missing.
+	Frontier map[string]int
+	// Last is the process that learned of Event last: its frontier event
+	// has the largest causal past.
+	Last string
+	// Latency is the number of events in the causal past of the frontier
+	// but not in that of Event: how much happened before every process
+	// knew it. It does not depend on how the trace was linearised.
+	Latency int
+}
+
+// CausalStability computes the stability of every event of the trace, in
+// trace order.
+func CausalStability(trace t.Trace) []Stability {
+	byProcess := make(map[string][]int)
+	var processes []string
+	for i, e := range trace {
+		if _, ok := byProcess[e.Process]; !ok {
+			processes = append(processes, e.Process)
+		}
+		byProcess[e.Process] = append(byProcess[e.Process], i)
+	}
+	sort.Strings(processes)
+
+	out := make([]Stability, len(trace))
+	for i, e := range trace {
+		s := Stability{Event: i, Stable: true, Frontier: make(map[string]int, len(processes))}
+		counter := e.VClock[e.Process]
+		frontier := make(t.VectorClock)
+		lastSize := -1
+		for _, p := range processes {
+			// Clocks only grow along a process, so the first event that
+			// knows e can be found by binary search
+			events := byProcess[p]
+			k := sort.Search(len(events), func(k int) bool { return trace[events[k]].VClock[e.Process] >= counter })
+			if k == len(events) {
+				s.Stable = false
+				continue
+			}
+			f := events[k]
+			s.Frontier[p] = f
+			frontier.Merge(trace[f].VClock)
+			if size := clockSize(trace[f].VClock); size > lastSize {
+				s.Last, lastSize = p, size
+			}
+		}
+		if s.Stable {
+			s.Latency = clockSize(frontier) - clockSize(e.VClock)
+		}
+		out[i] = s
+	}
+	return out
+}
+
+// clockSize is the number of events in the causal past a clock describes.
+func clockSize(vc t.VectorClock) int {
+	n := 0
+	for _, v := range vc {
+		n += v
+	}
+	return n
+}
+
+// SafePoint is how much of a process's history can be garbage collected at
+// the end of the trace: its first Counter events are causally stable, so no
+// future event can depend on them without already having them in its past.
+type SafePoint struct {
+	Process string
+	Counter int // events of Process known to every process
+	Event   int // trace index of the last collectable event, -1 if none
+	Events  int // events of Process in the trace
+	// HeldBackBy is a process that knows least of Process's history, the
+	// one whose progress would advance the safe point.
+	HeldBackBy string
+}
+
+// GCSafePoints computes the safe point of every process, sorted by process.
+// It is the smallest entry for the process over the last clocks of all
+// processes: exactly the longest prefix of its events that is stable.
+func GCSafePoints(trace t.Trace) []SafePoint {
+	last := make(map[string]t.VectorClock)
+	byCounter := make(map[string][]int)
+	var processes []string
+	for i, e := range trace {
+		if _, ok := last[e.Process]; !ok {
+			processes = append(processes, e.Process)
+		}
+		last[e.Process] = e.VClock
+		byCounter[e.Process] = append(byCounter[e.Process], i)
+	}
+	sort.Strings(processes)
+
+	out := make([]SafePoint, 0, len(processes))
+	for _, p := range processes {
+		sp := SafePoint{Process: p, Counter: -1, Event: -1, Events: len(byCounter[p])}
+		for _, q := range processes {
+			if n := last[q][p]; sp.Counter < 0 || n < sp.Counter {
+				sp.Counter, sp.HeldBackBy = n, q
+			}
+		}
+		events := byCounter[p]
+		k := sort.Search(len(events), func(k int) bool { return trace[events[k]].VClock[p] > sp.Counter })
+		if k > 0 {
+			sp.Event = events[k-1]
+		}
+		out = append(out, sp)
+	}
+	return out
+}
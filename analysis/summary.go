@@ -0,0 +1,94 @@
+package analysis
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	t "github.com/traces/types"
+)
+
+// Summary is a small precomputed view of a trace, enough for listings and
+// dashboards without loading its events.
+type Summary struct {
+	Metrics   Metrics        `json:"metrics"`
+	Processes []string       `json:"processes"`
+	Channels  []ChannelStats `json:"channels"`
+	// Start and End are the earliest and latest physical timestamps, if the
+	// events have any.
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// ChannelStats describes the messages delivered from one process to
+// another.
+type ChannelStats struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Messages int    `json:"messages"`
+	// MeanLatency is the mean time from send to receive over the messages
+	// whose events both carry a timestamp, or 0 if none do.
+	MeanLatency time.Duration `json:"mean_latency_ns,omitempty"`
+}
+
+// Summarize computes the summary of a trace.
+func Summarize(trace t.Trace) Summary {
+	s := Summary{Metrics: Compute(trace), Processes: []string{}, Channels: []ChannelStats{}}
+
+	type key struct {
+		correlationKey string
+		messageID      int
+	}
+	type channel struct{ from, to string }
+	sends := make(map[key]t.Event)
+	stats := make(map[channel]*ChannelStats)
+	timed := make(map[channel]int)
+	seen := make(map[string]bool)
+	for _, e := range trace {
+		if !seen[e.Process] {
+			seen[e.Process] = true
+			s.Processes = append(s.Processes, e.Process)
+		}
+		if !e.Timestamp.IsZero() {
+			if s.Start == nil || e.Timestamp.Before(*s.Start) {
+				s.Start = &e.Timestamp
+			}
+			if s.End == nil || e.Timestamp.After(*s.End) {
+				s.End = &e.Timestamp
+			}
+		}
+		k := key{e.CorrelationKey, e.MessageID}
+		switch e.Type {
+		case t.EventSend:
+			sends[k] = e
+		case t.EventReceive:
+			send, ok := sends[k]
+			if !ok {
+				continue
+			}
+			ch := channel{send.Process, e.Process}
+			cs := stats[ch]
+			if cs == nil {
+				cs = &ChannelStats{From: ch.from, To: ch.to}
+				stats[ch] = cs
+			}
+			cs.Messages++
+			if !send.Timestamp.IsZero() && !e.Timestamp.IsZero() {
+				cs.MeanLatency += e.Timestamp.Sub(send.Timestamp)
+				timed[ch]++
+			}
+		}
+	}
+	for ch, cs := range stats {
+		if n := timed[ch]; n > 0 {
+			cs.MeanLatency /= time.Duration(n)
+		}
+		s.Channels = append(s.Channels, *cs)
+	}
+	slices.Sort(s.Processes)
+	slices.SortFunc(s.Channels, func(a, b ChannelStats) int {
+		return cmp.Or(strings.Compare(a.From, b.From), strings.Compare(a.To, b.To))
+	})
+	return s
+}
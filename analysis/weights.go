@@ -0,0 +1,194 @@
+package analysis
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/traces/dag"
+	t "github.com/traces/types"
+)
+
+// Weights assigns costs, such as durations, to events and to the direct
+// causal dependencies between them.
+type Weights struct {
+	// Event is the cost of an event; nil counts every event as 1.
+	Event func(trace t.Trace, i int) float64
+	// Edge is the cost of the dependency of trace[to] on trace[from], for
+	// instance a message's network latency; nil makes edges free.
+	Edge func(trace t.Trace, from, to int) float64
+}
+
+// LabelWeights reads event costs from eventLabel and the cost of a
+// message's SEND -> RECV edge from edgeLabel on the RECV. Values are plain
+// numbers or Go durations such as "1.5ms", taken in seconds. Events
+// without the label cost nothing; an empty label name leaves the default.
+func LabelWeights(eventLabel, edgeLabel string) Weights {
+	var w Weights
+	if eventLabel != "" {
+		w.Event = func(trace t.Trace, i int) float64 { return parseWeight(trace[i].Labels[eventLabel]) }
+	}
+	if edgeLabel != "" {
+		w.Edge = func(trace t.Trace, from, to int) float64 {
+			if trace[from].Type != t.EventSend || trace[to].Type != t.EventReceive || trace[from].MessageID != trace[to].MessageID {
+				return 0
+			}
+			return parseWeight(trace[to].Labels[edgeLabel])
+		}
+	}
+	return w
+}
+
+func parseWeight(s string) float64 {
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d.Seconds()
+	}
+	return 0
+}
+
+// costs returns the event and edge cost functions, with their defaults.
+func (w Weights) costs() (func(t.Trace, int) float64, func(t.Trace, int, int) float64) {
+	eventCost, edgeCost := w.Event, w.Edge
+	if eventCost == nil {
+		eventCost = func(t.Trace, int) float64 { return 1 }
+	}
+	if edgeCost == nil {
+		edgeCost = func(t.Trace, int, int) float64 { return 0 }
+	}
+	return eventCost, edgeCost
+}
+
+// earliestFinish returns, for every event, the cost of the costliest chain
+// ending with it, and its direct dependency on that chain (-1 if none).
+func earliestFinish(trace t.Trace, w Weights) (cost []float64, via []int) {
+	eventCost, edgeCost := w.costs()
+	preds := dag.CausalPreds(trace)
+	cost = make([]float64, len(trace))
+	via = make([]int, len(trace))
+	for _, j := range causalOrder(trace) {
+		via[j] = -1
+		for _, i := range preds(j) {
+			if c := cost[i] + edgeCost(trace, i, j); via[j] < 0 || c > cost[j] {
+				cost[j], via[j] = c, i
+			}
+		}
+		cost[j] += eventCost(trace, j)
+	}
+	return cost, via
+}
+
+// Slack returns, for every event, how much it could be delayed without
+// extending the weighted critical path: the latest it may finish minus the
+// earliest it can. Events with zero slack lie on a critical path.
+func Slack(trace t.Trace, w Weights) []float64 {
+	eventCost, edgeCost := w.costs()
+	earliest, _ := earliestFinish(trace, w)
+	span := 0.0
+	for _, c := range earliest {
+		span = max(span, c)
+	}
+
+	preds := dag.CausalPreds(trace)
+	succs := make([][]int, len(trace))
+	for j := range trace {
+		for _, i := range preds(j) {
+			succs[i] = append(succs[i], j)
+		}
+	}
+	order := causalOrder(trace)
+	latest := make([]float64, len(trace))
+	slack := make([]float64, len(trace))
+	for k := len(order) - 1; k >= 0; k-- {
+		i := order[k]
+		latest[i] = span
+		for _, j := range succs[i] {
+			latest[i] = min(latest[i], latest[j]-eventCost(trace, j)-edgeCost(trace, i, j))
+		}
+		slack[i] = latest[i] - earliest[i]
+	}
+	return slack
+}
+
+// ZeroSlack reports whether a slack returned by Slack is zero, allowing for
+// rounding in the sums of fractional costs.
+func ZeroSlack(slack float64) bool { return slack < 1e-9 }
+
+// WeightedPath is the costliest chain of direct causal dependencies.
+type WeightedPath struct {
+	Events []int // trace indices in causal order
+	Cost   float64
+	// ByProcess is the cost of the path's events on each process, and
+	// Communication the cost of its edges.
+	ByProcess     map[string]float64
+	Communication float64
+}
+
+// WeightedCriticalPath returns the chain of events whose event and edge
+// costs add up to the most, the weighted counterpart of
+// CriticalPathLength.
+func WeightedCriticalPath(trace t.Trace, w Weights) WeightedPath {
+	eventCost, edgeCost := w.costs()
+	cost, via := earliestFinish(trace, w)
+	end := -1
+	for j := range trace {
+		if end < 0 || cost[j] > cost[end] {
+			end = j
+		}
+	}
+
+	path := WeightedPath{ByProcess: make(map[string]float64)}
+	if end < 0 {
+		return path
+	}
+	path.Cost = cost[end]
+	for j := end; j >= 0; j = via[j] {
+		path.Events = append([]int{j}, path.Events...)
+		path.ByProcess[trace[j].Process] += eventCost(trace, j)
+		if via[j] >= 0 {
+			path.Communication += edgeCost(trace, via[j], j)
+		}
+	}
+	return path
+}
+
+// WorkSpan is the work/span analysis of a weighted trace: Work is the total
+// cost of all events and of the direct dependencies between them, Span the
+// cost of the weighted critical path. Both count edges, so the span never
+// exceeds the work.
+type WorkSpan struct {
+	Work, Span float64
+}
+
+// ComputeWorkSpan measures work and span under the given weights.
+func ComputeWorkSpan(trace t.Trace, w Weights) WorkSpan {
+	eventCost, edgeCost := w.costs()
+	ws := WorkSpan{Span: WeightedCriticalPath(trace, w).Cost}
+	preds := dag.CausalPreds(trace)
+	for j := range trace {
+		ws.Work += eventCost(trace, j)
+		for _, i := range preds(j) {
+			ws.Work += edgeCost(trace, i, j)
+		}
+	}
+	return ws
+}
+
+// Parallelism is Work/Span, the speedup no number of processors can beat.
+func (ws WorkSpan) Parallelism() float64 {
+	if ws.Span == 0 {
+		return 0
+	}
+	return ws.Work / ws.Span
+}
+
+// SpeedupBounds returns the speedup on p processors that a greedy scheduler
+// is guaranteed by Brent's theorem, T_p <= Work/p + Span, and the upper
+// bound min(p, Work/Span).
+func (ws WorkSpan) SpeedupBounds(p int) (lower, upper float64) {
+	if ws.Span == 0 || p <= 0 {
+		return 0, 0
+	}
+	return ws.Work / (ws.Work/float64(p) + ws.Span), min(float64(p), ws.Parallelism())
+}
@@ -0,0 +1,43 @@
+package analysis
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/traces/messages"
+	"github.com/traces/script"
+)
+
+func TestWorkSpanEdgeWeights(tt *testing.T) {
+	// A sends to B over a link costing 10, with no other work in parallel
+	trace := script.MustCompile("A -> B: m1; B: internal")
+	trace[1].Labels["latency"] = "10"
+	ws := ComputeWorkSpan(trace, LabelWeights("", "latency"))
+	if ws.Work != 13 || ws.Span != 13 {
+		tt.Errorf("work %g, span %g, want 13 and 13", ws.Work, ws.Span)
+	}
+	if p := ws.Parallelism(); p != 1 {
+		tt.Errorf("parallelism %g of a sequential trace, want 1", p)
+	}
+}
+
+func TestWeightedCriticalPathRendezvous(tt *testing.T) {
+	// The partners of a rendezvous are each other's direct dependencies
+	trace := messages.Generate(messages.Config{
+		Processes:   []string{"A", "B", "C"},
+		NumEvents:   30,
+		Synchronous: true,
+	}, rand.New(rand.NewSource(1)))
+	path := WeightedCriticalPath(trace, Weights{})
+	if want := float64(CriticalPathLength(trace)); path.Cost != want || len(path.Events) != int(want) {
+		tt.Errorf("path of %d events costing %g, want %g", len(path.Events), path.Cost, want)
+	}
+	for i, s := range Slack(trace, Weights{}) {
+		if s < 0 {
+			tt.Errorf("e-%d has negative slack %g", i, s)
+		}
+	}
+	if ws := ComputeWorkSpan(trace, Weights{}); ws.Span > ws.Work {
+		tt.Errorf("span %g exceeds work %g", ws.Span, ws.Work)
+	}
+}
@@ -0,0 +1,505 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/traces/analysis"
+	"github.com/traces/dag"
+	"github.com/traces/fingerprint"
+	"github.com/traces/property"
+	"github.com/traces/report"
+	t "github.com/traces/types"
+)
+
+// entry is an uploaded trace with its derived results. The summary is
+// computed on upload, while a persisted trace is read only once a request
+// needs its events. The trace, causal graph and reachability index are each
+// built once, on first use, and then shared by all requests without locking.
+// Coarsened graphs are kept for the last few bucket sizes asked for.
+type entry struct {
+	summary analysis.Summary
+	path    string // file holding the trace, if persisted
+
+	traceOnce sync.Once
+	trace     t.Trace
+	traceErr  error
+	graphOnce sync.Once
+	graph     *dag.DAG
+	reachOnce sync.Once
+	reach     *dag.Reachability
+	report    *report.Report
+
+	coarseMu sync.Mutex
+	coarse   map[int]*dag.Coarse // by bucket size
+}
+
+// maxCoarse bounds the bucket sizes whose coarsened graphs an entry keeps.
+const maxCoarse = 4
+
+// events returns the trace of the entry, reading it from its file once.
+func (e *entry) events() (t.Trace, error) {
+	e.traceOnce.Do(func() {
+		if e.trace == nil && e.path != "" {
+			e.trace, e.traceErr = t.LoadFile(e.path)
+		}
+	})
+	return e.trace, e.traceErr
+}
+
+// query returns the events of the entry selected by q. A persisted trace is
+// filtered while it is read from its file, so only the selected events are
+// held in memory.
+func (e *entry) query(q t.Query) (t.Trace, error) {
+	if e.path != "" {
+		return t.QueryFile(e.path, q)
+	}
+	return q.Filter(e.trace), nil
+}
+
+// causalGraph returns the causal graph of the entry, building it once. Like
+// reachability, it may only be called once events succeeded.
+func (e *entry) causalGraph() *dag.DAG {
+	e.graphOnce.Do(func() { e.graph = dag.BuildDAG(e.trace) })
+	return e.graph
+}
+
+// reachability returns the reachability index of the entry, building it once.
+func (e *entry) reachability() *dag.Reachability {
+	e.reachOnce.Do(func() { e.reach = dag.NewReachability(e.trace) })
+	return e.reach
+}
+
+// coarsened returns the causal graph of the entry with size events per
+// bucket, building it once per size. Like causalGraph, it may only be called
+// once events succeeded.
+func (e *entry) coarsened(size int) *dag.Coarse {
+	e.coarseMu.Lock()
+	defer e.coarseMu.Unlock()
+	if c, ok := e.coarse[size]; ok {
+		return c
+	}
+	if e.coarse == nil || len(e.coarse) == maxCoarse {
+		e.coarse = make(map[int]*dag.Coarse)
+	}
+	c := dag.Coarsen(e.trace, size)
+	e.coarse[size] = c
+	return c
+}
+
+// Server is a REST API for uploading traces, building their causal graphs,
+// running property checks and downloading the results:
+//
+//	POST   /traces                 upload a JSON trace (or JSONL with Content-Type application/x-ndjson)
+//	GET    /traces                 list uploaded traces with their summaries
+//	GET    /traces/{id}            download a trace as JSON
+//	GET    /traces/{id}/summary    download the summary computed on upload
+//	GET    /traces/{id}/events     download the events selected by
+//	                               ?process=&type=&label=&min=&max=
+//	                               (see types.ParseQuery)
+//	DELETE /traces/{id}            forget a trace
+//	POST   /traces/{id}/graph      build the causal graph
+//	GET    /traces/{id}/graph.dot  download the graph as DOT, coarsened with ?bucket=<events per node>
+//	GET    /traces/{id}/order      causal order of events ?a=<index>&b=<index>, with a chain of dependencies
+//	POST   /traces/{id}/check      check ?property=fifo,causal or ?suite=<installed suite name>,
+//	                               with its variables set by ?var=<name>=<value>
+//	GET    /traces/{id}/report     download the last check report as JSON
+//
+// Traces are kept in memory unless Persist gives the server a directory.
+// Uploads over MaxUploadSize are refused.
+type Server struct {
+	mu     sync.Mutex
+	traces map[string]*entry
+	nextID int
+	dir    string
+	mux    *http.ServeMux
+}
+
+// NewServer returns an empty server.
+func NewServer() *Server {
+	s := &Server{traces: make(map[string]*entry), mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /traces", s.upload)
+	s.mux.HandleFunc("GET /traces", s.list)
+	s.mux.HandleFunc("GET /traces/{id}", s.download)
+	s.mux.HandleFunc("GET /traces/{id}/summary", s.summary)
+	s.mux.HandleFunc("GET /traces/{id}/events", s.events)
+	s.mux.HandleFunc("DELETE /traces/{id}", s.remove)
+	s.mux.HandleFunc("POST /traces/{id}/graph", s.buildGraph)
+	s.mux.HandleFunc("GET /traces/{id}/graph.dot", s.graphDOT)
+	s.mux.HandleFunc("GET /traces/{id}/order", s.order)
+	s.mux.HandleFunc("POST /traces/{id}/check", s.check)
+	s.mux.HandleFunc("GET /traces/{id}/report", s.report)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// MaxUploadSize is the largest request body an upload may have.
+const MaxUploadSize = 256 << 20
+
+// lastIDFile holds the highest ID a persisting server has issued, so IDs of
+// deleted traces are not handed out again after a restart.
+const lastIDFile = "last-id"
+
+// Persist keeps the traces in dir, each as <id>.json next to its summary in
+// <id>.summary.json, and serves the traces already there. Their summaries
+// are read right away; the traces themselves when first needed.
+func (s *Server) Persist(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	last := 0
+	if data, err := os.ReadFile(filepath.Join(dir, lastIDFile)); err == nil {
+		if last, err = strconv.Atoi(strings.TrimSpace(string(data))); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Join(dir, lastIDFile), err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.summary.json"))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID = max(s.nextID, last)
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".summary.json")
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var summary analysis.Summary
+		if err := json.Unmarshal(data, &summary); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		s.traces[id] = &entry{summary: summary, path: filepath.Join(dir, id+".json")}
+		s.nextID = max(s.nextID, n)
+	}
+	s.dir = dir
+	return nil
+}
+
+// Add stores a trace, summarizing it, and returns its ID.
+func (s *Server) Add(trace t.Trace) (string, error) {
+	e := &entry{summary: analysis.Summarize(trace), trace: trace}
+	s.mu.Lock()
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	dir := s.dir
+	if dir != "" {
+		// Written under the lock, so a lower ID never overwrites a higher one
+		if err := os.WriteFile(filepath.Join(dir, lastIDFile), []byte(id+"\n"), 0o644); err != nil {
+			s.mu.Unlock()
+			return "", err
+		}
+	}
+	s.mu.Unlock()
+
+	if dir != "" {
+		e.path = filepath.Join(dir, id+".json")
+		if err := t.SaveFile(e.path, trace); err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(e.summary)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(dir, id+".summary.json"), data, 0o644); err != nil {
+			return "", err
+		}
+		// Read back only if a request needs the events
+		e.trace = nil
+	}
+	s.mu.Lock()
+	s.traces[id] = e
+	s.mu.Unlock()
+	return id, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// lookup returns the entry of the request's {id}, writing a 404 if absent.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (*entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.traces[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no trace %q", r.PathValue("id")))
+	}
+	return e, ok
+}
+
+// lookupTrace is lookup that also reads the entry's trace, writing a 500 if
+// it cannot be.
+func (s *Server) lookupTrace(w http.ResponseWriter, r *http.Request) (*entry, t.Trace, bool) {
+	e, ok := s.lookup(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	trace, err := e.events()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, nil, false
+	}
+	return e, trace, true
+}
+
+func (s *Server) upload(w http.ResponseWriter, r *http.Request) {
+	var trace t.Trace
+	var err error
+	body := http.MaxBytesReader(w, r.Body, MaxUploadSize)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-ndjson" || mediaType == "application/jsonl" {
+		trace, err = t.NewTraceReader(body).ReadAll()
+	} else {
+		trace, err = t.Load(body)
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("upload exceeds the limit of %d bytes", MaxUploadSize))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	id, err := s.Add(trace)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"id": id, "events": len(trace)})
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	type item struct {
+		ID      string           `json:"id"`
+		Events  int              `json:"events"`
+		Summary analysis.Summary `json:"summary"`
+	}
+	s.mu.Lock()
+	items := make([]item, 0, len(s.traces))
+	for id, e := range s.traces {
+		items = append(items, item{ID: id, Events: e.summary.Metrics.Events, Summary: e.summary})
+	}
+	s.mu.Unlock()
+	sort.Slice(items, func(i, j int) bool {
+		a, _ := strconv.Atoi(items[i].ID)
+		b, _ := strconv.Atoi(items[j].ID)
+		return a < b
+	})
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) download(w http.ResponseWriter, r *http.Request) {
+	if _, trace, ok := s.lookupTrace(w, r); ok {
+		w.Header().Set("Content-Type", "application/json")
+		t.Save(w, trace)
+	}
+}
+
+func (s *Server) summary(w http.ResponseWriter, r *http.Request) {
+	if e, ok := s.lookup(w, r); ok {
+		writeJSON(w, http.StatusOK, e.summary)
+	}
+}
+
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	e, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	params := make(map[string]string)
+	for name := range r.URL.Query() {
+		params[name] = r.URL.Query().Get(name)
+	}
+	q, err := t.ParseQuery(params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	trace, err := e.query(q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	t.Save(w, trace)
+}
+
+func (s *Server) remove(w http.ResponseWriter, r *http.Request) {
+	e, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	delete(s.traces, r.PathValue("id"))
+	s.mu.Unlock()
+	if e.path != "" {
+		os.Remove(e.path)
+		os.Remove(strings.TrimSuffix(e.path, ".json") + ".summary.json")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) buildGraph(w http.ResponseWriter, r *http.Request) {
+	if e, _, ok := s.lookupTrace(w, r); ok {
+		g := e.causalGraph()
+		writeJSON(w, http.StatusOK, map[string]any{"processes": len(g.Nodes), "edges": len(g.Edges)})
+	}
+}
+
+func (s *Server) graphDOT(w http.ResponseWriter, r *http.Request) {
+	e, _, ok := s.lookupTrace(w, r)
+	if !ok {
+		return
+	}
+	if b := r.URL.Query().Get("bucket"); b != "" {
+		size, err := strconv.Atoi(b)
+		if err != nil || size < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("bucket %q is not a positive number", b))
+			return
+		}
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		io.WriteString(w, e.coarsened(size).ToGraphviz())
+		return
+	}
+	w.Header().Set("Content-Type", "text/vnd.graphviz")
+	io.WriteString(w, e.causalGraph().ToGraphviz())
+}
+
+func (s *Server) order(w http.ResponseWriter, r *http.Request) {
+	e, trace, ok := s.lookupTrace(w, r)
+	if !ok {
+		return
+	}
+	var ab [2]int
+	for i, name := range []string{"a", "b"} {
+		v := r.URL.Query().Get(name)
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n >= len(trace) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("%s %q is not an event index below %d", name, v, len(trace)))
+			return
+		}
+		ab[i] = n
+	}
+	a, b := ab[0], ab[1]
+
+	reach := e.reachability()
+	res := map[string]any{"a": a, "b": b}
+	switch {
+	case a == b:
+		res["order"] = "same"
+	case reach.HappensBefore(a, b):
+		res["order"] = "before"
+		res["path"] = reach.Path(a, b)
+	case reach.HappensBefore(b, a):
+		res["order"] = "after"
+		res["path"] = reach.Path(b, a)
+	default:
+		res["order"] = "concurrent"
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// bindVars binds the template variables of a suite to values given as
+// name=value.
+func bindVars(suite property.Suite, vars []string) (property.Suite, error) {
+	values := make(map[string]string, len(vars))
+	for _, kv := range vars {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return property.Suite{}, fmt.Errorf("var %q: expected name=value", kv)
+		}
+		values[name] = value
+	}
+	return suite.Bind(values)
+}
+
+func (s *Server) check(w http.ResponseWriter, r *http.Request) {
+	e, trace, ok := s.lookupTrace(w, r)
+	if !ok {
+		return
+	}
+
+	var props []property.Property
+	var err error
+	if name := r.URL.Query().Get("suite"); name != "" {
+		var suite property.Suite
+		if suite, err = property.InstalledSuite(name); err == nil {
+			if vars := r.URL.Query()["var"]; len(vars) > 0 {
+				suite, err = bindVars(suite, vars)
+			}
+			if err == nil {
+				props, err = suite.Properties()
+			}
+		}
+	} else {
+		list := r.URL.Query().Get("property")
+		if list == "" {
+			list = "fifo,causal"
+		}
+		for _, name := range strings.Split(list, ",") {
+			var p property.Property
+			if p, err = property.Lookup(strings.TrimSpace(name)); err != nil {
+				break
+			}
+			props = append(props, p)
+		}
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	violations := property.Check(trace, props...)
+	grouper := fingerprint.NewGrouper()
+	grouper.Add(trace, violations)
+	rep := report.New(r.PathValue("id"), len(trace), violations, grouper.Groups())
+	rep.Locate(trace)
+
+	s.mu.Lock()
+	e.report = &rep
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, rep)
+}
+
+func (s *Server) report(w http.ResponseWriter, r *http.Request) {
+	e, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	rep := e.report
+	s.mu.Unlock()
+	if rep == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("trace has not been checked"))
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/traces/script"
+)
+
+func TestPersistDoesNotReuseDeletedIDs(t *testing.T) {
+	dir := t.TempDir()
+	trace := script.MustCompile("A sends to B")
+
+	s := NewServer()
+	if err := s.Persist(dir); err != nil {
+		t.Fatal(err)
+	}
+	var last string
+	for range 2 {
+		id, err := s.Add(trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = id
+	}
+	req := httptest.NewRequest(http.MethodDelete, "/traces/"+last, nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE /traces/%s: status %d", last, rec.Code)
+	}
+
+	restarted := NewServer()
+	if err := restarted.Persist(dir); err != nil {
+		t.Fatal(err)
+	}
+	id, err := restarted.Add(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == last {
+		t.Errorf("restarted server reissued ID %s of a deleted trace", id)
+	}
+}
+
+// spaces is an endless JSON body that never completes a value.
+type spaces struct{}
+
+func (spaces) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = ' '
+	}
+	return len(p), nil
+}
+
+func TestUploadLimit(t *testing.T) {
+	s := NewServer()
+	body := io.MultiReader(strings.NewReader("{"), io.LimitReader(spaces{}, MaxUploadSize+1))
+	req := httptest.NewRequest(http.MethodPost, "/traces", body)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized upload: status %d, want %d: %s", rec.Code, http.StatusRequestEntityTooLarge, rec.Body)
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/traces/alert"
+	"github.com/traces/ingest"
+)
+
+// Watch serves the rolling metrics of a collector receiving a live trace:
+//
+//	GET /live/metrics  the metrics over the last window as JSON
+//	GET /live/report   the report of the monitor's last check, if m is not nil
+//	GET /live          a page showing the metrics, refreshed every second
+func (s *Server) Watch(c *ingest.Collector, window time.Duration, m *alert.Monitor) {
+	s.mux.HandleFunc("GET /live/metrics", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Metrics(window))
+	})
+	if m != nil {
+		s.mux.HandleFunc("GET /live/report", func(w http.ResponseWriter, r *http.Request) {
+			rep := m.Latest()
+			if rep == nil {
+				writeError(w, http.StatusNotFound, fmt.Errorf("the live trace has not been checked yet"))
+				return
+			}
+			writeJSON(w, http.StatusOK, rep)
+		})
+	}
+	s.mux.HandleFunc("GET /live", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, livePage)
+	})
+}
+
+const livePage = `<!DOCTYPE html>
+<html>
+<head><title>Live trace</title>
+<style>body{font-family:sans-serif} td,th{padding:2px 12px;text-align:right}</style>
+</head>
+<body>
+<h1>Live trace</h1>
+<p id="summary"></p>
+<table><thead><tr><th>process</th><th>events/s</th></tr></thead><tbody id="rates"></tbody></table>
+<script>
+async function refresh() {
+  const m = await (await fetch("live/metrics")).json();
+  document.getElementById("summary").textContent =
+    m.events + " events, width " + m.width + ", " + m.open_messages + " open messages, " +
+    m.pending + " held back (last " + m.window_seconds + "s)";
+  const rows = Object.keys(m.events_per_sec).sort().map(p =>
+    "<tr><td>" + p.replace(/[<&]/g, c => c == "<" ? "&lt;" : "&amp;") + "</td><td>" + m.events_per_sec[p].toFixed(2) + "</td></tr>");
+  document.getElementById("rates").innerHTML = rows.join("");
+}
+refresh();
+setInterval(refresh, 1000);
+</script>
+</body>
+</html>
+`
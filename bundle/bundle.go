@@ -0,0 +1,191 @@
+// Package bundle writes a complete analysis of a trace as one HTML file with
+// no outside dependencies, to attach to a ticket or send by mail: the trace
+// itself, gzip-compressed, its metrics, the property results and a viewer
+// drawing the trace as a space-time diagram.
+package bundle
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/traces/analysis"
+	"github.com/traces/provenance"
+	"github.com/traces/report"
+	t "github.com/traces/types"
+)
+
+// Write writes the bundle of trace and its check report, titled title, with
+// a footer showing how it was produced unless stamp is nil. The embedded
+// trace carries the stamp too, and keeps raw process names: the viewer shows
+// their aliases instead.
+func Write(w io.Writer, title string, trace t.Trace, rep report.Report, aliases t.Aliases, stamp *provenance.Stamp) error {
+	var raw bytes.Buffer
+	zw := gzip.NewWriter(&raw)
+	var prov any
+	if stamp != nil {
+		prov = stamp
+	}
+	if err := t.SaveWithProvenance(zw, trace, prov); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	// encoding/json escapes <, > and &, so neither document can end the
+	// script element it is embedded in
+	metrics, err := json.Marshal(analysis.Compute(trace))
+	if err != nil {
+		return err
+	}
+	results, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	if aliases == nil {
+		aliases = t.Aliases{}
+	}
+	names, err := json.Marshal(aliases)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, page,
+		html.EscapeString(title), html.EscapeString(title),
+		base64.StdEncoding.EncodeToString(raw.Bytes()), metrics, results, names, viewer, stamp.HTML())
+	return err
+}
+
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body{font-family:sans-serif;margin:1em 2em}
+td,th{padding:2px 12px;text-align:left}
+#diagram{overflow:auto;border:1px solid #ccc;max-height:70vh}
+#details{font-family:monospace;white-space:pre;background:#f6f6f6;padding:6px;min-height:3em}
+.group{cursor:pointer}
+.group:hover,.group.on{background:#fee}
+circle{cursor:pointer}
+</style>
+</head>
+<body>
+<h1>%s</h1>
+<h2>Metrics</h2>
+<table id="metrics"></table>
+<h2>Properties</h2>
+<p id="summary"></p>
+<table id="groups"></table>
+<h2>Trace</h2>
+<p><button id="download">Download trace JSON</button> Click an event for its details, a violation to highlight its events.</p>
+<div id="diagram"></div>
+<div id="details"></div>
+<script id="trace" type="application/octet-stream">%s</script>
+<script id="metrics-data" type="application/json">%s</script>
+<script id="report-data" type="application/json">%s</script>
+<script id="aliases-data" type="application/json">%s</script>
+<script>%s</script>
+%s</body>
+</html>
+`
+
+// viewer renders the embedded documents. The trace is inflated with the
+// browser's DecompressionStream, so nothing is fetched.
+const viewer = `
+const esc = s => String(s).replace(/[<>&"]/g, c => ({"<": "&lt;", ">": "&gt;", "&": "&amp;", '"': "&quot;"})[c]);
+const metrics = JSON.parse(document.getElementById("metrics-data").textContent);
+const report = JSON.parse(document.getElementById("report-data").textContent);
+const aliases = JSON.parse(document.getElementById("aliases-data").textContent);
+const name = p => Object.hasOwn(aliases, p) ? aliases[p] : p;
+
+document.getElementById("metrics").innerHTML = Object.keys(metrics).map(k =>
+  "<tr><th>" + esc(k) + "</th><td>" + esc(metrics[k]) + "</td></tr>").join("");
+
+async function inflate() {
+  const b64 = document.getElementById("trace").textContent.trim();
+  const bytes = Uint8Array.from(atob(b64), c => c.charCodeAt(0));
+  const stream = new Blob([bytes]).stream().pipeThrough(new DecompressionStream("gzip"));
+  return new Response(stream).text();
+}
+
+const colors = {SEND: "#1f77b4", RECV: "#2ca02c", INTERNAL: "#7f7f7f", ACQUIRE: "#9467bd",
+  RELEASE: "#8c564b", BARRIER: "#17becf", CRASH: "#d62728", RECOVER: "#bcbd22"};
+
+function draw(events) {
+  const procs = [...new Set(events.map(e => e.process))].sort();
+  const lane = Object.fromEntries(procs.map((p, i) => [p, i]));
+  const dx = 26, dy = 50, left = 90;
+  const x = i => left + i * dx, y = e => 30 + lane[e.process] * dy;
+  const sends = {};
+  events.forEach((e, i) => { if (e.type == "SEND") sends[e.message_id] = i; });
+  let svg = '<svg xmlns="http://www.w3.org/2000/svg" width="' + (left + events.length * dx + 20) +
+    '" height="' + (procs.length * dy + 20) + '"><defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" ' +
+    'markerWidth="6" markerHeight="6" orient="auto"><path d="M0,0L10,5L0,10z" fill="#999"/></marker></defs>';
+  procs.forEach((p, i) => {
+    svg += '<text x="4" y="' + (34 + i * dy) + '">' + esc(name(p)) + '</text>' +
+      '<line x1="' + left + '" x2="' + (left + events.length * dx) + '" y1="' + (30 + i * dy) +
+      '" y2="' + (30 + i * dy) + '" stroke="#ddd"/>';
+  });
+  events.forEach((e, i) => {
+    const s = sends[e.message_id];
+    if (e.type == "RECV" && s !== undefined) {
+      svg += '<line x1="' + x(s) + '" y1="' + y(events[s]) + '" x2="' + x(i) + '" y2="' + y(e) +
+        '" stroke="#999" marker-end="url(#arrow)"/>';
+    }
+  });
+  events.forEach((e, i) => {
+    svg += '<circle id="e' + i + '" cx="' + x(i) + '" cy="' + y(e) + '" r="7" fill="' +
+      (colors[e.type] || "#000") + '" stroke-width="3"><title>e-' + i + ' ' + esc(e.type) + '</title></circle>';
+  });
+  document.getElementById("diagram").innerHTML = svg + "</svg>";
+  events.forEach((e, i) => {
+    document.getElementById("e" + i).onclick = () => {
+      document.getElementById("details").textContent = "e-" + i + " on " + name(e.process) + "\n" + JSON.stringify(e, null, 2);
+    };
+  });
+}
+
+function highlight(indices) {
+  document.querySelectorAll("circle").forEach(c => c.setAttribute("stroke", "none"));
+  indices.forEach(i => {
+    const c = document.getElementById("e" + i);
+    if (c) {
+      c.setAttribute("stroke", "red");
+      c.scrollIntoView({block: "nearest", inline: "center"});
+    }
+  });
+}
+
+const groups = report.groups || [];
+document.getElementById("summary").textContent = (report.violations || []).length +
+  " violations in " + groups.length + " distinct groups";
+const sources = v => (v.sources || []).filter(s => s).map(s =>
+  '<a href="' + esc("file://" + s.file + "#L" + s.line) + '">' + esc(s.file + ":" + s.line) + "</a>").join(" ");
+document.getElementById("groups").innerHTML = groups.map((g, i) =>
+  '<tr class="group" data-i="' + i + '"><td>' + esc(g.fingerprint) + "</td><td>" + g.count +
+  "x</td><td>[" + esc(g.example.property) + "] " + esc(g.example.message) + "</td><td>" +
+  sources(g.example) + "</td></tr>").join("");
+document.querySelectorAll(".group").forEach(row => {
+  row.onclick = () => {
+    document.querySelectorAll(".group").forEach(r => r.classList.remove("on"));
+    row.classList.add("on");
+    highlight(groups[row.dataset.i].example.events);
+  };
+});
+
+inflate().then(text => {
+  draw(JSON.parse(text).events);
+  document.getElementById("download").onclick = () => {
+    const a = document.createElement("a");
+    a.href = URL.createObjectURL(new Blob([text], {type: "application/json"}));
+    a.download = "trace.json";
+    a.click();
+  };
+});
+`
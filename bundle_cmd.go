@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/traces/bundle"
+	"github.com/traces/fingerprint"
+	"github.com/traces/property"
+	"github.com/traces/report"
+)
+
+// runBundle implements `trace bundle`: it writes one self-contained HTML file
+// holding the trace, its metrics, property results and a viewer.
+func runBundle(args []string) error {
+	fs := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	props := fs.String("property", "fifo,causal", "comma separated properties to check")
+	title := fs.String("title", "", "page title (default: the trace file name)")
+	out := fs.String("out", "", "HTML file or object storage URL to write")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || *out == "" {
+		return fmt.Errorf("-in and -out are required")
+	}
+
+	properties, err := parseProperties(*props)
+	if err != nil {
+		return err
+	}
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	violations := property.Check(trace, properties...)
+	grouper := fingerprint.NewGrouper()
+	grouper.Add(trace, violations)
+	rep := report.New(*in, len(trace), violations, grouper.Groups())
+	rep.Provenance = stamp
+	rep.Locate(trace)
+	rep = displayReport(rep, trace, aliases, grouper, properties)
+	if *title == "" {
+		*title = *in
+	}
+
+	return writeOutput(*out, func(w io.Writer) error { return bundle.Write(w, *title, trace, rep, aliases, stamp) })
+}
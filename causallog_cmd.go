@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/traces/dag"
+	t "github.com/traces/types"
+)
+
+// runCausalLog implements `trace causal-log`: it prints the log lines of an
+// imported trace in a linear extension of happens-before instead of the
+// order they were written in, each indented by its process, so that the
+// sequence of causes and effects reads from top to bottom.
+func runCausalLog(args []string) error {
+	fs := flag.NewFlagSet("causal-log", flag.ContinueOnError)
+	in := fs.String("in", "", "imported trace whose log lines to print")
+	order := fs.String("order", "process", "linear extension to print: "+strings.Join(dag.LinearOrderNames(), ", "))
+	indent := fs.Int("indent", 4, "columns of indentation per process, in order of first appearance")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	events, err := dag.Linearize(trace, *order)
+	if err != nil {
+		return err
+	}
+
+	column := make(map[string]int)
+	for _, e := range trace {
+		if _, ok := column[e.Process]; !ok {
+			column[e.Process] = len(column)
+		}
+	}
+	lines := make(map[string][]string) // log files read so far
+	for _, i := range events {
+		e := trace[i]
+		text, err := logLine(e, lines)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s%s: %s\n", strings.Repeat(" ", column[e.Process]**indent), t.QuoteName(aliases.Name(e.Process)), text)
+	}
+	return nil
+}
+
+// logLine returns the text an event was imported from: its source line if
+// it has one, else its "log" label (see formats.LoadOTLPLogs), else a
+// description of the event. Source files are read once into lines.
+func logLine(e t.Event, lines map[string][]string) (string, error) {
+	if src := e.Source; src != nil {
+		file, ok := lines[src.File]
+		if !ok {
+			f, err := t.Open(src.File)
+			if err != nil {
+				return "", err
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+			file = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			lines[src.File] = file
+		}
+		if src.Line >= 1 && src.Line <= len(file) {
+			return strings.TrimRight(file[src.Line-1], "\r"), nil
+		}
+	}
+	if msg, ok := e.Labels["log"]; ok {
+		return msg, nil
+	}
+	desc := e.Type.String()
+	if e.Type.IsMessage() {
+		desc = fmt.Sprintf("%s Msg-%d", e.Type, e.MessageID)
+	}
+	keys := make([]string, 0, len(e.Labels))
+	for k := range e.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		desc += " " + k + "=" + e.Labels[k]
+	}
+	return desc, nil
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/traces/dag"
+	"github.com/traces/diff"
+	"github.com/traces/fingerprint"
+	"github.com/traces/messages"
+	"github.com/traces/minimize"
+	"github.com/traces/property"
+	"github.com/traces/protocols"
+	"github.com/traces/report"
+	"github.com/traces/sink"
+	t "github.com/traces/types"
+)
+
+// parseProperties resolves a comma separated list of built-in properties,
+// each a kind optionally followed by parameters, e.g. "quorum:size=3".
+func parseProperties(list string) ([]property.Property, error) {
+	var props []property.Property
+	for _, spec := range strings.Split(list, ",") {
+		kind, params, err := parseKind(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, fmt.Errorf("property %w", err)
+		}
+		p, err := property.New(kind, params)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, p)
+	}
+	return props, nil
+}
+
+// parseKind splits "kind:name=value;name=value" into the kind and its
+// parameters.
+func parseKind(s string) (string, map[string]string, error) {
+	kind, rest, _ := strings.Cut(s, ":")
+	params := make(map[string]string)
+	for _, kv := range strings.Split(rest, ";") {
+		if kv == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return "", nil, fmt.Errorf("parameter %q: expected name=value", kv)
+		}
+		params[name] = value
+	}
+	return kind, params, nil
+}
+
+// varFlag collects repeated -var name=value flags.
+type varFlag map[string]string
+
+func (v varFlag) String() string { return "" }
+
+func (v varFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	v[name] = value
+	return nil
+}
+
+// loadFile adds the name=value lines of a file; values already set on the
+// command line take precedence.
+func (v varFlag) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s: expected name=value, got %q", path, line)
+		}
+		if _, set := v[strings.TrimSpace(name)]; !set {
+			v[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return nil
+}
+
+// runCheck implements `trace check`: it checks properties on a JSON trace.
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	in := fs.String("in", "", "JSON trace to read")
+	props := fs.String("property", "fifo,causal", "comma separated properties to check, e.g. fifo,quorum:size=3;response=ack")
+	suite := fs.String("suite", "", "check a property suite (file or installed name) instead of -property")
+	vars := make(varFlag)
+	fs.Var(vars, "var", "template variable for the suite as name=value (repeatable)")
+	varsFile := fs.String("vars", "", "file of name=value template variables, one per line")
+	profile := fs.Bool("profile", false, "report time and work spent per property")
+	limit := fs.Int("limit", report.DefaultLimit, "maximum violations and groups printed (0 for all)")
+	reportPath := fs.String("report", "", "write the full report as JSON to this file or object storage URL")
+	aliasesPath := aliasesFlag(fs)
+	var rules ruleFlag
+	fs.Var(&rules, "rule", "extra happens-before rule as kind[:name=value;...] (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	var properties []property.Property
+	var err error
+	if *suite != "" {
+		var s property.Suite
+		if *varsFile != "" {
+			if err = vars.loadFile(*varsFile); err != nil {
+				return err
+			}
+		}
+		if s, err = property.ResolveSuite(*suite); err == nil {
+			if s, err = s.Bind(vars); err == nil {
+				properties, err = s.Properties()
+			}
+		}
+	} else {
+		properties, err = parseProperties(*props)
+	}
+	if err != nil {
+		return err
+	}
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	if trace, err = dag.ApplyRules(trace, rules...); err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+
+	violations, profiles := property.CheckProfiled(trace, properties...)
+	grouper := fingerprint.NewGrouper()
+	grouper.Add(trace, violations)
+	rep := report.New(*in, len(trace), violations, grouper.Groups())
+	rep.Locate(trace)
+
+	console := displayReport(rep, trace, aliases, grouper, properties)
+	console.PrintSummary(os.Stdout, *limit)
+	if *profile {
+		fmt.Println("Profile (slowest first):")
+		for _, p := range profiles {
+			fmt.Println(" ", p)
+		}
+	}
+	if *reportPath != "" {
+		rep.Provenance = stamp
+		return rep.WriteJSONFile(*reportPath)
+	}
+	return nil
+}
+
+// displayReport returns the copy of a check report shown to people. Its
+// violation messages name processes, so with aliases they are checked again
+// on the display trace, while the report written for machines keeps raw
+// identifiers. Fingerprints stay those of the raw trace in both.
+func displayReport(rep report.Report, trace t.Trace, aliases t.Aliases, grouper *fingerprint.Grouper, properties []property.Property) report.Report {
+	if len(aliases) == 0 {
+		return rep
+	}
+	vs := property.Check(aliases.Apply(trace), properties...)
+	byEvents := make(map[string]property.Violation, len(vs))
+	for _, v := range vs {
+		byEvents[fmt.Sprint(v.Property, v.Events)] = v
+	}
+	groups := grouper.Groups()
+	for i, g := range groups {
+		groups[i].Example = byEvents[fmt.Sprint(g.Example.Property, g.Example.Events)]
+	}
+	display := report.New(rep.Trace, rep.Events, vs, groups)
+	display.Provenance = rep.Provenance
+	display.Locate(trace)
+	return display
+}
+
+// runMinimize implements `trace minimize`: it finds the smallest generated
+// trace that still violates the given properties.
+func runMinimize(args []string) error {
+	fs := flag.NewFlagSet("minimize", flag.ContinueOnError)
+	props := fs.String("property", "fifo", "comma separated properties to violate")
+	events := fs.Int("events", 50, "events in the failing trace")
+	procs := fs.String("processes", "A,B,C", "comma separated process names")
+	seed := fs.Int64("seed", 1, "seed of the failing trace")
+	tries := fs.Int("tries", 20, "seeds to try per event count")
+	out := fs.String("out", "", "write the minimal trace as JSON to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	properties, err := parseProperties(*props)
+	if err != nil {
+		return err
+	}
+	cfg := messages.Config{
+		Processes: strings.Split(*procs, ","),
+		NumEvents: *events,
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	repro, err := minimize.Seed(cfg, *seed, *tries, properties...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Smallest failing configuration: -events %d -seed %d\n", repro.NumEvents, repro.Seed)
+	fmt.Printf("Shrunk reproducer (%d events):\n%s", len(repro.Trace), repro.Trace)
+	for _, v := range property.Check(repro.Trace, properties...) {
+		fmt.Println(v)
+	}
+	if *out != "" {
+		return saveTrace(*out, repro.Trace)
+	}
+	return nil
+}
+
+// runSynthesize implements `trace synthesize`: it searches the generator, or
+// a protocol scenario, for a small trace violating the properties, to confirm
+// that they reject the behaviour they are meant to.
+func runSynthesize(args []string) error {
+	fs := flag.NewFlagSet("synthesize", flag.ContinueOnError)
+	props := fs.String("property", "", "comma separated properties to violate, e.g. pre-post:pre=SEND;post=RECV:msg=commit")
+	events := fs.Int("max-events", 50, "largest trace to try")
+	seed := fs.Int64("seed", 1, "first seed to try")
+	tries := fs.Int("tries", 50, "seeds to try per event count")
+	procs := fs.String("processes", "A,B,C", "comma separated process names for the generator")
+	broadcast := fs.Float64("broadcast", 0, "generator broadcast probability")
+	duplicate := fs.Float64("duplicate", 0, "generator duplicate delivery probability")
+	crash := fs.Float64("crash", 0, "generator crash probability")
+	recoverRate := fs.Float64("recover", 0, "generator recovery probability")
+	scenario := fs.String("scenario", "", "search this protocol scenario instead of the generator: "+strings.Join(protocols.Names(), ", "))
+	size := fs.Int("size", 3, "processes in the scenario")
+	rounds := fs.Int("rounds", 2, "rounds of the scenario")
+	out := fs.String("out", "", "write the counterexample as JSON to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *props == "" {
+		return fmt.Errorf("-property is required")
+	}
+
+	properties, err := parseProperties(*props)
+	if err != nil {
+		return err
+	}
+	var generate func(n int, s int64) t.Trace
+	if *scenario != "" {
+		opts := protocols.Options{Processes: *size, Rounds: *rounds}
+		if _, err := protocols.Run(*scenario, opts, *seed, 1); err != nil {
+			return err
+		}
+		generate = func(n int, s int64) t.Trace {
+			trace, _ := protocols.Run(*scenario, opts, s, n)
+			return trace
+		}
+	} else {
+		cfg := messages.Config{
+			Processes:     strings.Split(*procs, ","),
+			NumEvents:     *events,
+			BroadcastRate: *broadcast,
+			DuplicateRate: *duplicate,
+			CrashRate:     *crash,
+			RecoverRate:   *recoverRate,
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		generate = func(n int, s int64) t.Trace {
+			c := cfg
+			c.NumEvents = n
+			return messages.Generate(c, rand.New(rand.NewSource(s)))
+		}
+	}
+	repro, err := minimize.Search(generate, *events, *seed, *tries, properties...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Found with -max-events %d -seed %d, shrunk to %d events:\n%s", repro.NumEvents, repro.Seed, len(repro.Trace), repro.Trace)
+	for _, v := range property.Check(repro.Trace, properties...) {
+		fmt.Println(v)
+	}
+	if *out != "" {
+		return saveTrace(*out, repro.Trace)
+	}
+	return nil
+}
+
+// runDiff implements `trace diff`: differential checking of two traces.
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	a := fs.String("a", "", "JSON trace of the first implementation")
+	b := fs.String("b", "", "JSON trace of the second implementation")
+	props := fs.String("property", "fifo,causal", "comma separated properties to check on both")
+	dot := fs.String("dot", "", "also write an overlay of both causal graphs as DOT to this file or object storage URL")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *a == "" || *b == "" {
+		return fmt.Errorf("-a and -b are required")
+	}
+
+	properties, err := parseProperties(*props)
+	if err != nil {
+		return err
+	}
+	traceA, err := loadTrace(*a)
+	if err != nil {
+		return err
+	}
+	traceB, err := loadTrace(*b)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(diff.Compare(traceA, traceB, properties...).String())
+	if *dot != "" {
+		return sink.WriteFile(*dot, []byte(stamp.DOT()+dag.Overlay(dag.BuildDAG(traceA), dag.BuildDAG(traceB))))
+	}
+	return nil
+}
+
+// runWhatIf implements `trace whatif`: it re-checks properties as if the
+// given orderings had been observed and reports what changes.
+func runWhatIf(args []string) error {
+	fs := flag.NewFlagSet("whatif", flag.ContinueOnError)
+	in := fs.String("in", "", "JSON trace to read")
+	assume := fs.String("assume", "", "comma separated orderings to assume, e.g. e-3->e-7,5->9")
+	props := fs.String("property", "fifo,causal", "comma separated properties to re-check")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || *assume == "" {
+		return fmt.Errorf("-in and -assume are required")
+	}
+
+	links, err := dag.ParseLinks(*assume)
+	if err != nil {
+		return err
+	}
+	properties, err := parseProperties(*props)
+	if err != nil {
+		return err
+	}
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	// Orderings are by index, so they hold on the display trace as well
+	h, err := diff.Hypothesize(aliases.Apply(trace), links, properties...)
+	if err != nil {
+		return err
+	}
+	fmt.Print(h)
+	return nil
+}
+
+// runSuggest implements `trace suggest`: it proposes the fewest extra
+// orderings that would make failing properties hold.
+func runSuggest(args []string) error {
+	fs := flag.NewFlagSet("suggest", flag.ContinueOnError)
+	in := fs.String("in", "", "JSON trace to read")
+	props := fs.String("property", "quorum", "comma separated properties that must hold")
+	maxEdges := fs.Int("max-edges", 3, "largest set of orderings to search")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	properties, err := parseProperties(*props)
+	if err != nil {
+		return err
+	}
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	if !property.Fails(trace, properties...) {
+		fmt.Println("the properties already hold")
+		return nil
+	}
+	links := diff.Strengthen(trace, *maxEdges, properties...)
+	if links == nil {
+		return fmt.Errorf("no set of at most %d orderings between violating events makes the properties hold", *maxEdges)
+	}
+	// Links are by index, so they hold on the display trace as well
+	shown := aliases.Apply(trace)
+	for _, l := range links {
+		fmt.Println(diff.Suggestion(shown, l))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/traces/property"
+)
+
+// runConflicts implements `trace conflicts`: it lists concurrent writes to
+// the same key and how later reads resolved them.
+func runConflicts(args []string) error {
+	fs := flag.NewFlagSet("conflicts", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	op := fs.String("op", "op", "label holding the operation, read or write")
+	key := fs.String("key", "key", "label holding the key read or written")
+	value := fs.String("value", "value", "label holding the value written or returned")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	conflicts := property.WriteConflicts(trace, property.SessionLabels{Op: *op, Key: *key, Value: *value})
+	n := 0
+	for _, kc := range conflicts {
+		n += len(kc.Conflicts)
+		fmt.Print(kc)
+	}
+	fmt.Printf("%d concurrent write pairs on %d keys\n", n, len(conflicts))
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/traces/analysis"
+	"github.com/traces/messages"
+)
+
+// runCorrelate implements `trace correlate`: it analyses every correlation
+// key of a trace as an independent sub-trace.
+func runCorrelate(args []string) error {
+	fs := flag.NewFlagSet("correlate", flag.ContinueOnError)
+	keys := fs.Int("keys", 5, "number of correlation keys to spread generated messages over")
+	events := fs.Int("events", 60, "events in the generated trace")
+	procs := fs.String("processes", "A,B,C", "comma separated process names")
+	seed := fs.Int64("seed", 1, "generator seed")
+	workers := fs.Int("workers", 0, "parallel workers (default: number of CPUs)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := messages.Config{
+		Processes: strings.Split(*procs, ","),
+		NumEvents: *events,
+		Keys:      *keys,
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	trace := messages.Generate(cfg, rand.New(rand.NewSource(*seed)))
+
+	fmt.Print(analysis.AnalyzeByKey(trace, *workers).String())
+	return nil
+}
@@ -0,0 +1,152 @@
+package coverage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/traces/sim"
+	t "github.com/traces/types"
+)
+
+// Tracker accumulates structural coverage over a campaign of traces.
+//
+// Two kinds of behaviour are tracked:
+//   - reorderings: for every pair of messages received by the same process
+//     whose sends were concurrent, which sender's message arrived first; and
+//     for causally ordered sends, whether delivery inverted that order.
+//   - fault combinations: the set of fault kinds injected into a trace.
+type Tracker struct {
+	traces      int
+	reorderings map[string]int
+	faultCombos map[string]int
+	// growth[i] is the number of distinct reorderings after i+1 traces
+	growth []int
+}
+
+// NewTracker returns an empty tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		reorderings: make(map[string]int),
+		faultCombos: make(map[string]int),
+	}
+}
+
+// Add records the behaviours exercised by one trace and the faults that were
+// injected while producing it (nil for fault-free traces).
+func (c *Tracker) Add(trace t.Trace, faults []sim.InjectedFault) {
+	c.traces++
+	for sig := range Reorderings(trace) {
+		c.reorderings[sig]++
+	}
+	c.faultCombos[faultCombination(faults)]++
+	c.growth = append(c.growth, len(c.reorderings))
+}
+
+// Reorderings returns the reordering signatures present in a trace.
+func Reorderings(trace t.Trace) map[string]bool {
+	sends := make(map[int]t.Event)
+	for _, e := range trace {
+		if e.Type == t.EventSend {
+			sends[e.MessageID] = e
+		}
+	}
+
+	// Receives per process in delivery order, with their matching send
+	type delivery struct{ send, recv t.Event }
+	perProcess := make(map[string][]delivery)
+	for _, e := range trace {
+		if s, ok := sends[e.MessageID]; ok && e.Type == t.EventReceive {
+			perProcess[e.Process] = append(perProcess[e.Process], delivery{s, e})
+		}
+	}
+
+	sigs := make(map[string]bool)
+	for receiver, ds := range perProcess {
+		for i := range ds {
+			for j := i + 1; j < len(ds); j++ {
+				first, second := ds[i].send, ds[j].send
+				switch {
+				case second.VClock.HappensBefore(first.VClock):
+					sigs[fmt.Sprintf("%s->%s@%s:inverted", second.Process, first.Process, receiver)] = true
+				case !first.VClock.HappensBefore(second.VClock):
+					sigs[fmt.Sprintf("%s|%s@%s:%s-first", first.Process, second.Process, receiver, first.Process)] = true
+				}
+			}
+		}
+	}
+	return sigs
+}
+
+// faultCombination returns a canonical name for the set of fault kinds.
+func faultCombination(faults []sim.InjectedFault) string {
+	kinds := make(map[string]bool)
+	for _, f := range faults {
+		kinds[f.Kind] = true
+	}
+	if len(kinds) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(kinds))
+	for k := range kinds {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "+")
+}
+
+// Report is a snapshot of the coverage reached so far.
+type Report struct {
+	Traces              int
+	DistinctReorderings int
+	DistinctFaultCombos int
+	// LastNewTrace is the 1-based index of the last trace that contributed a
+	// new reordering; far below Traces means the campaign has saturated.
+	LastNewTrace int
+	Reorderings  map[string]int
+	FaultCombos  map[string]int
+}
+
+// Report summarises the coverage of all traces added so far.
+func (c *Tracker) Report() Report {
+	last := 0
+	for i, n := range c.growth {
+		if i == 0 || n > c.growth[i-1] {
+			last = i + 1
+		}
+	}
+	return Report{
+		Traces:              c.traces,
+		DistinctReorderings: len(c.reorderings),
+		DistinctFaultCombos: len(c.faultCombos),
+		LastNewTrace:        last,
+		Reorderings:         c.reorderings,
+		FaultCombos:         c.faultCombos,
+	}
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Coverage over %d traces\n", r.Traces)
+	fmt.Fprintf(&b, "  distinct reorderings:        %d (last new at trace %d)\n", r.DistinctReorderings, r.LastNewTrace)
+	fmt.Fprintf(&b, "  distinct fault combinations: %d\n", r.DistinctFaultCombos)
+
+	writeCounts := func(title string, counts map[string]int) {
+		keys := make([]string, 0, len(counts))
+		for k := range counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		width := 28
+		for _, k := range keys {
+			width = max(width, len(k))
+		}
+		fmt.Fprintf(&b, "%s:\n", title)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "  %-*s %d\n", width, k, counts[k])
+		}
+	}
+	writeCounts("Reorderings", r.Reorderings)
+	writeCounts("Fault combinations", r.FaultCombos)
+	return b.String()
+}
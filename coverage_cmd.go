@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/traces/coverage"
+	"github.com/traces/messages"
+	"github.com/traces/sim"
+)
+
+// runCoverage implements `trace coverage`: it generates a campaign of traces
+// and reports how many structurally different behaviours they exercised,
+// including the combinations of faults injected into them.
+func runCoverage(args []string) error {
+	fs := flag.NewFlagSet("coverage", flag.ContinueOnError)
+	runs := fs.Int("runs", 1000, "number of traces in the campaign")
+	firstSeed := fs.Int64("first-seed", 1, "seed of the first trace")
+	events := fs.Int("events", 30, "events per generated trace")
+	procs := fs.String("processes", "A,B,C", "comma separated process names")
+	loss := fs.Float64("loss", 0, "probability that a sent message is lost")
+	duplicate := fs.Float64("duplicate", 0, "probability that a delivered message is delivered again later")
+	crash := fs.Float64("crash", 0, "probability that a scheduled process crashes")
+	recoverRate := fs.Float64("recover", 0, "probability that a scheduled crashed process recovers")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var faults []sim.InjectedFault
+	cfg := messages.Config{
+		Processes:     strings.Split(*procs, ","),
+		NumEvents:     *events,
+		LossRate:      *loss,
+		DuplicateRate: *duplicate,
+		CrashRate:     *crash,
+		RecoverRate:   *recoverRate,
+		Faults:        func(f sim.InjectedFault) { faults = append(faults, f) },
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	tracker := coverage.NewTracker()
+	for i := range *runs {
+		r := rand.New(rand.NewSource(*firstSeed + int64(i)))
+		faults = nil
+		trace := messages.Generate(cfg, r)
+		tracker.Add(trace, faults)
+	}
+	fmt.Print(tracker.Report().String())
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/traces/analysis"
+	t "github.com/traces/types"
+)
+
+// runCriticalPath implements `trace critical-path`: it prints the costliest
+// causal chain of a trace, how much each process contributes to it and the
+// speedup the traced computation's work and span allow.
+func runCriticalPath(args []string) error {
+	fs := flag.NewFlagSet("critical-path", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	weight := fs.String("weight", "", "label holding the cost of an event (empty: every event costs 1)")
+	edgeWeight := fs.String("edge-weight", "", "label on a RECV holding the cost of its message")
+	processors := fs.Int("processors", 0, "processors for the speedup bound (0: the trace's processes)")
+	verbose := fs.Bool("v", false, "list the events on the path")
+	slack := fs.Bool("slack", false, "list every event's slack, zero-slack events marked *")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	weights := analysis.LabelWeights(*weight, *edgeWeight)
+	path := analysis.WeightedCriticalPath(trace, weights)
+	fmt.Printf("critical path: %d events, cost %.6g\n", len(path.Events), path.Cost)
+	if *verbose {
+		for _, i := range path.Events {
+			fmt.Printf("  e-%-4d %-8s on %s\n", i, trace[i].Type, t.QuoteName(aliases.Name(trace[i].Process)))
+		}
+	}
+	for _, p := range slices.Sorted(maps.Keys(path.ByProcess)) {
+		share := 0.0
+		if path.Cost > 0 {
+			share = 100 * path.ByProcess[p] / path.Cost
+		}
+		fmt.Printf("  %s: %.6g (%.1f%%)\n", t.QuoteName(aliases.Name(p)), path.ByProcess[p], share)
+	}
+	if path.Communication > 0 {
+		fmt.Printf("  communication: %.6g (%.1f%%)\n", path.Communication, 100*path.Communication/path.Cost)
+	}
+
+	if *slack {
+		fmt.Println("slack (how much each event could be delayed without extending the critical path):")
+		for i, s := range analysis.Slack(trace, weights) {
+			mark := " "
+			if analysis.ZeroSlack(s) {
+				mark, s = "*", 0
+			}
+			fmt.Printf(" %s e-%-4d %-8s on %s: %.6g\n", mark, i, trace[i].Type, t.QuoteName(aliases.Name(trace[i].Process)), s)
+		}
+	}
+
+	ws := analysis.ComputeWorkSpan(trace, weights)
+	procs := *processors
+	if procs <= 0 {
+		seen := make(map[string]bool)
+		for _, e := range trace {
+			seen[e.Process] = true
+		}
+		procs = len(seen)
+	}
+	lower, upper := ws.SpeedupBounds(procs)
+	fmt.Printf("work %.6g, span %.6g, parallelism %.2f\n", ws.Work, ws.Span, ws.Parallelism())
+	fmt.Printf("speedup on %d processors: at least %.2f with a greedy scheduler (Brent), at most %.2f\n", procs, lower, upper)
+	return nil
+}
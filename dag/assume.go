@@ -0,0 +1,42 @@
+package dag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// Assume is a rule of orderings the trace does not show but a user wants to
+// reason with, such as "A's write reached B before B's read".
+type Assume struct {
+	Edges []Link
+}
+
+func (Assume) Name() string { return "assume" }
+
+func (a Assume) Links(t.Trace) []Link { return a.Edges }
+
+// ParseLinks reads a comma separated list of edges written "3->7" or
+// "e-3->e-7".
+func ParseLinks(s string) ([]Link, error) {
+	var links []Link
+	for _, edge := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(edge), "->")
+		if !ok {
+			return nil, fmt.Errorf("edge %q: expected from->to", edge)
+		}
+		a, errA := parseEventRef(from)
+		b, errB := parseEventRef(to)
+		if errA != nil || errB != nil {
+			return nil, fmt.Errorf("edge %q: events are trace indices such as 3 or e-3", edge)
+		}
+		links = append(links, Link{From: a, To: b})
+	}
+	return links, nil
+}
+
+func parseEventRef(s string) (int, error) {
+	return strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(s), "e-"))
+}
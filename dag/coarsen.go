@@ -0,0 +1,89 @@
+package dag
+
+import (
+	"fmt"
+
+	t "github.com/traces/types"
+)
+
+// Bucket is a run of consecutive events of one process.
+type Bucket struct {
+	Process string
+	Index   int   // position among the process's buckets
+	Events  []int // trace indices in program order
+}
+
+// CoarseEdge aggregates the direct causal dependencies, as listed by
+// DirectPreds, from the events of one bucket to those of another.
+type CoarseEdge struct {
+	From, To int // indices into Coarse.Buckets
+	Count    int
+}
+
+// Coarse is an overview of a DAG: the events of every process grouped into
+// buckets, and the edges between different buckets counted.
+type Coarse struct {
+	Buckets []Bucket
+	Edges   []CoarseEdge
+}
+
+// Coarsen buckets the events of each process into chunks of size events,
+// the last of which may be smaller, so that a long trace becomes a graph of
+// a few nodes per process.
+func Coarsen(trace t.Trace, size int) *Coarse {
+	size = max(size, 1)
+	c := &Coarse{}
+	of := make([]int, len(trace)) // event -> its bucket
+	last := make(map[string]int)  // process -> its open bucket
+	for i, e := range trace {
+		b, ok := last[e.Process]
+		if !ok || len(c.Buckets[b].Events) == size {
+			index := 0
+			if ok {
+				index = c.Buckets[b].Index + 1
+			}
+			b = len(c.Buckets)
+			c.Buckets = append(c.Buckets, Bucket{Process: e.Process, Index: index})
+			last[e.Process] = b
+		}
+		c.Buckets[b].Events = append(c.Buckets[b].Events, i)
+		of[i] = b
+	}
+
+	edges := make(map[[2]int]int) // from, to -> index into c.Edges
+	preds := DirectPreds(trace)
+	for j := range trace {
+		for _, i := range preds(j) {
+			from, to := of[i], of[j]
+			if from == to {
+				continue
+			}
+			k, ok := edges[[2]int{from, to}]
+			if !ok {
+				k = len(c.Edges)
+				edges[[2]int{from, to}] = k
+				c.Edges = append(c.Edges, CoarseEdge{From: from, To: to})
+			}
+			c.Edges[k].Count++
+		}
+	}
+	return c
+}
+
+// Name identifies a bucket, as in "A#2".
+func (b Bucket) Name() string { return fmt.Sprintf("%s#%d", b.Process, b.Index) }
+
+// ToGraphviz draws the buckets labelled with their event ranges and the
+// edges between them labelled with their counts.
+func (c *Coarse) ToGraphviz() string {
+	out := "digraph G {\n"
+	for _, b := range c.Buckets {
+		label := fmt.Sprintf("%s: e-%d..e-%d (%d events)", b.Name(), b.Events[0], b.Events[len(b.Events)-1], len(b.Events))
+		out += fmt.Sprintf(" %s [shape=box, label=%s];\n", dotQuote(b.Name()), dotQuote(label))
+	}
+	for _, e := range c.Edges {
+		out += fmt.Sprintf(" %s -> %s [label=\"%d\"];\n", dotQuote(c.Buckets[e.From].Name()), dotQuote(c.Buckets[e.To].Name()), e.Count)
+	}
+	out += "}\n"
+	return out
+}
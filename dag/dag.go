@@ -1,7 +1,15 @@
+// Package dag builds the causal graph of a trace and answers queries about
+// it. A DAG, Index, Coarse or Reachability is not modified once built, so
+// any number of goroutines may read and query it at the same time; fields
+// such as DAG.Highlight must be set before it is shared.
 package dag
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
 
 	t "github.com/traces/types"
 )
@@ -14,9 +22,19 @@ type Edge struct {
 type DAG struct {
 	Nodes map[string][]t.Event
 	Edges []Edge
+	// Highlight holds the clocks (VectorClock.Key) of events to draw
+	// emphasised, such as a critical path, with the edges between them.
+	Highlight map[string]bool
+	// ShowLabels draws the labels of each event next to it.
+	ShowLabels bool
 }
 
+// BuildDAG builds the causal graph of a trace. Processes may join the trace
+// midway, their events' clocks naming fewer processes than later ones; every
+// event of the graph is given a clock over all processes of the trace, so
+// that events draw alike whenever they joined.
 func BuildDAG(trace t.Trace) *DAG {
+	trace = t.PadClocks(trace)
 	nodes := make(map[string][]t.Event)
 	var edges []Edge
 
@@ -24,54 +42,12 @@ func BuildDAG(trace t.Trace) *DAG {
 		nodes[e.Process] = append(nodes[e.Process], e)
 	}
 
-	for _, procEvents := range nodes {
-		for k := 0; k < len(procEvents)-1; k++ {
-			edges = append(edges, Edge{
-				From: procEvents[k],
-				To:   procEvents[k+1],
-			})
-		}
-	}
-
-	// ---
-	// 3. Add all INTER-PROCESS edges (Causal Order)
-	// This is the O(n^3) check for immediate causal dependencies
-	// between different processes.
-	// ---
-	for i, a := range trace {
-		for j, b := range trace {
-			if i == j {
-				continue
-			}
-
-			// We ONLY check for inter-process edges here.
-			// The intra-process ones are already handled.
-			if a.Process != b.Process {
-
-				// Check if a -> b
-				if a.VClock.HappensBefore(b.VClock) {
-
-					// Now, check if this edge (a, b) is *immediate*.
-					// It is NOT immediate if there exists any other event 'c'
-					// such that a -> c -> b.
-					isImmediate := true
-					for k, c := range trace {
-						if k == i || k == j {
-							continue // Don't check 'a' or 'b' as 'c'
-						}
-
-						// Check for the transitive path a -> c -> b
-						if a.VClock.HappensBefore(c.VClock) && c.VClock.HappensBefore(b.VClock) {
-							isImmediate = false
-							break // Found an intermediate event
-						}
-					}
-
-					if isImmediate {
-						edges = append(edges, Edge{From: a, To: b})
-					}
-				}
-			}
+	// Intra-process edges follow program order; inter-process edges are the
+	// immediate causal dependencies between different processes.
+	idx := NewIndex(trace)
+	for i, succs := range idx.Succs {
+		for _, j := range succs {
+			edges = append(edges, Edge{From: trace[i], To: trace[j]})
 		}
 	}
 
@@ -81,12 +57,107 @@ func BuildDAG(trace t.Trace) *DAG {
 	}
 }
 
-// Graphviz exporter (no change needed)
+// Processes returns the processes of the DAG sorted by name.
+func (d *DAG) Processes() []string {
+	return slices.Sorted(maps.Keys(d.Nodes))
+}
+
+// Graphviz exporter. Nodes are listed by process name and edges in trace
+// order, so equal traces always give byte-identical output.
 func (d *DAG) ToGraphviz() string {
 	out := "digraph G {\n"
+	// Imported events link back to the log line they came from
+	for _, p := range d.Processes() {
+		for _, e := range d.Nodes[p] {
+			if e.Source != nil {
+				out += fmt.Sprintf(" %s [tooltip=%s, URL=%s];\n",
+					dotQuote(e.VClock.String()), dotQuote(e.Source.String()), dotQuote(e.Source.URL()))
+			}
+		}
+	}
+	// Crashes and recoveries stand out from ordinary events
+	for _, p := range d.Processes() {
+		for _, e := range d.Nodes[p] {
+			switch e.Type {
+			case t.EventCrash:
+				out += fmt.Sprintf(" %s [shape=box, color=red, xlabel=\"CRASH\"];\n", dotQuote(e.VClock.String()))
+			case t.EventRecover:
+				out += fmt.Sprintf(" %s [shape=box, color=darkgreen, xlabel=\"RECOVER\"];\n", dotQuote(e.VClock.String()))
+			}
+		}
+	}
+	if d.ShowLabels {
+		for _, p := range d.Processes() {
+			for _, e := range d.Nodes[p] {
+				if len(e.Labels) == 0 {
+					continue
+				}
+				var kvs []string
+				for _, k := range slices.Sorted(maps.Keys(e.Labels)) {
+					kvs = append(kvs, k+"="+e.Labels[k])
+				}
+				out += fmt.Sprintf(" %s [xlabel=%s];\n", dotQuote(e.VClock.String()), dotQuote(strings.Join(kvs, " ")))
+			}
+		}
+	}
+	for _, p := range d.Processes() {
+		for _, e := range d.Nodes[p] {
+			if d.Highlight[e.VClock.Key()] {
+				out += fmt.Sprintf(" %s [color=red, penwidth=2];\n", dotQuote(e.VClock.String()))
+			}
+		}
+	}
+	// Events without any edge (a trace of one event, or events concurrent
+	// with everything else) would otherwise not be drawn at all
+	linked := make(map[string]bool)
 	for _, e := range d.Edges {
-		out += fmt.Sprintf(" \"%s\" -> \"%s\";\n", e.From.VClock, e.To.VClock)
+		linked[e.From.VClock.String()] = true
+		linked[e.To.VClock.String()] = true
+	}
+	for _, p := range d.Processes() {
+		for _, e := range d.Nodes[p] {
+			if id := e.VClock.String(); !linked[id] && e.Source == nil {
+				out += fmt.Sprintf(" %s;\n", dotQuote(id))
+			}
+		}
+	}
+	// The events of a rendezvous share a clock, hence a node, and would
+	// repeat each other's edges
+	drawn := make(map[string]bool)
+	for _, e := range d.Edges {
+		edge := fmt.Sprintf(" %s -> %s;\n", dotQuote(e.From.VClock.String()), dotQuote(e.To.VClock.String()))
+		if d.Highlight[e.From.VClock.Key()] && d.Highlight[e.To.VClock.Key()] {
+			edge = fmt.Sprintf(" %s -> %s [color=red, penwidth=2];\n", dotQuote(e.From.VClock.String()), dotQuote(e.To.VClock.String()))
+		} else if e.From.Type == t.EventCrash {
+			// The process is down between a crash and what follows it
+			edge = fmt.Sprintf(" %s -> %s [style=dashed];\n", dotQuote(e.From.VClock.String()), dotQuote(e.To.VClock.String()))
+		}
+		if !drawn[edge] {
+			drawn[edge] = true
+			out += edge
+		}
 	}
 	out += "}\n"
 	return out
 }
+
+// dotQuote returns s as a double-quoted DOT string. Quotes and backslashes
+// are escaped and control characters are written as visible \xNN text, so a
+// label never spans lines or closes its string early.
+func dotQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case unicode.IsControl(r):
+			fmt.Fprintf(&b, "\\\\x%02x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
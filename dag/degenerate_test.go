@@ -0,0 +1,69 @@
+package dag
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	t "github.com/traces/types"
+)
+
+// chain is a trace of n INTERNAL events on one process.
+func chain(n int) t.Trace {
+	var trace t.Trace
+	for i := 1; i <= n; i++ {
+		trace = append(trace, t.Event{Type: t.EventInternal, Process: "A", VClock: t.VectorClock{"A": i}, MessageID: -1})
+	}
+	return trace
+}
+
+// concurrent is a trace of one INTERNAL event on each of n processes, none
+// of which knows of the others.
+func concurrent(n int) t.Trace {
+	var trace t.Trace
+	for i := range n {
+		p := fmt.Sprintf("P%d", i)
+		trace = append(trace, t.Event{Type: t.EventInternal, Process: p, VClock: t.VectorClock{p: 1}, MessageID: -1})
+	}
+	return trace
+}
+
+func TestGraphvizEmpty(t *testing.T) {
+	if got := BuildDAG(nil).ToGraphviz(); got != "digraph G {\n}\n" {
+		t.Errorf("empty trace drawn as %q", got)
+	}
+}
+
+func TestGraphvizChain(t *testing.T) {
+	d := BuildDAG(chain(4))
+	if len(d.Edges) != 3 {
+		t.Errorf("chain of 4 events has %d edges, want 3", len(d.Edges))
+	}
+	if got := strings.Count(d.ToGraphviz(), " -> "); got != 3 {
+		t.Errorf("chain of 4 events drawn with %d edges, want 3", got)
+	}
+}
+
+func TestGraphvizConcurrent(t *testing.T) {
+	d := BuildDAG(concurrent(3))
+	if len(d.Edges) != 0 {
+		t.Errorf("concurrent events have %d edges, want none", len(d.Edges))
+	}
+	dot := d.ToGraphviz()
+	for _, p := range []string{"P0", "P1", "P2"} {
+		if !strings.Contains(dot, fmt.Sprintf("%s:1", p)) {
+			t.Errorf("event of %s not drawn:\n%s", p, dot)
+		}
+	}
+}
+
+func TestLinearizeDegenerate(tt *testing.T) {
+	for _, trace := range []t.Trace{nil, chain(3), concurrent(3)} {
+		for _, order := range LinearOrderNames() {
+			got, err := Linearize(trace, order)
+			if err != nil || len(got) != len(trace) {
+				tt.Errorf("Linearize(%d events, %s) = %v, %v", len(trace), order, got, err)
+			}
+		}
+	}
+}
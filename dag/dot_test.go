@@ -0,0 +1,63 @@
+package dag
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	t "github.com/traces/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got with testdata/name, or rewrites it with -update.
+func golden(tb testing.TB, name string, got []byte) {
+	tb.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			tb.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if string(got) != string(want) {
+		tb.Errorf("%s differs from the golden file:\n--- got\n%s\n--- want\n%s", name, got, want)
+	}
+}
+
+// hostileNames are process names DOT must quote: spaces, quotes,
+// backslashes, newlines and non-ASCII letters.
+var hostileNames = []string{"web server", `say "hi"`, `C:\tmp`, "line\nbreak", "nœud-α"}
+
+// hostileTrace passes a message from every hostile process to the next.
+func hostileTrace(tb testing.TB) t.Trace {
+	tb.Helper()
+	var trace t.Trace
+	for i, p := range hostileNames {
+		q := hostileNames[(i+1)%len(hostileNames)]
+		trace = append(trace,
+			t.Event{Type: t.EventSend, Process: p, MessageID: i, Labels: map[string]string{"note": p}},
+			t.Event{Type: t.EventReceive, Process: q, MessageID: i, Labels: map[string]string{"note": p}},
+		)
+	}
+	rebuilt, err := t.ReconstructClocks(trace)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return rebuilt
+}
+
+func TestGraphvizHostileNames(t *testing.T) {
+	d := BuildDAG(hostileTrace(t))
+	d.ShowLabels = true
+	golden(t, "hostile.dot", []byte(d.ToGraphviz()))
+}
+
+func TestCoarseGraphvizHostileNames(t *testing.T) {
+	golden(t, "hostile-coarse.dot", []byte(Coarsen(hostileTrace(t), 1).ToGraphviz()))
+}
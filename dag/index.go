@@ -0,0 +1,55 @@
+package dag
+
+import (
+	t "github.com/traces/types"
+)
+
+// Index is the transitively reduced DAG of a trace addressed by trace index:
+// Preds[i] and Succs[i] hold the immediate predecessors and successors of
+// trace[i].
+type Index struct {
+	Preds [][]int
+	Succs [][]int
+}
+
+// NewIndex computes the immediate causal edges of the trace: consecutive
+// events of the same process, and inter-process pairs a -> b with no event c
+// such that a -> c -> b.
+func NewIndex(trace t.Trace) *Index {
+	n := len(trace)
+	idx := &Index{Preds: make([][]int, n), Succs: make([][]int, n)}
+	add := func(a, b int) {
+		idx.Succs[a] = append(idx.Succs[a], b)
+		idx.Preds[b] = append(idx.Preds[b], a)
+	}
+
+	last := make(map[string]int)
+	for i, e := range trace {
+		if prev, ok := last[e.Process]; ok {
+			add(prev, i)
+		}
+		last[e.Process] = i
+	}
+
+	for i, a := range trace {
+		for j, b := range trace {
+			if i == j || a.Process == b.Process || !a.VClock.HappensBefore(b.VClock) {
+				continue
+			}
+			isImmediate := true
+			for k, c := range trace {
+				if k == i || k == j {
+					continue
+				}
+				if a.VClock.HappensBefore(c.VClock) && c.VClock.HappensBefore(b.VClock) {
+					isImmediate = false
+					break
+				}
+			}
+			if isImmediate {
+				add(i, j)
+			}
+		}
+	}
+	return idx
+}
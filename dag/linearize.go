@@ -0,0 +1,116 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// linearOrders choose the next event of a linear extension among those whose
+// predecessors are all placed: next(ready, last) returns the position in
+// ready, which is sorted by trace index, of the event to place after the
+// event last (-1 at the start).
+var linearOrders = map[string]func(trace t.Trace, depth []int) func(ready []int, last int) int{
+	// Events in the order the trace lists them
+	"trace": func(t.Trace, []int) func([]int, int) int {
+		return func([]int, int) int { return 0 }
+	},
+	// Events layer by layer of causal rank, as drawn by the DAG
+	"rank": func(_ t.Trace, depth []int) func([]int, int) int {
+		return func(ready []int, _ int) int {
+			best := 0
+			for k, i := range ready {
+				if depth[i] < depth[ready[best]] {
+					best = k
+				}
+			}
+			return best
+		}
+	},
+	// Events by timestamp where causality allows it
+	"time": func(trace t.Trace, _ []int) func([]int, int) int {
+		return func(ready []int, _ int) int {
+			best := 0
+			for k, i := range ready {
+				if trace[i].Timestamp.Before(trace[ready[best]].Timestamp) {
+					best = k
+				}
+			}
+			return best
+		}
+	},
+	// As long a run of each process as causality allows, so that the
+	// output switches between processes only where a message forces it
+	"process": func(trace t.Trace, _ []int) func([]int, int) int {
+		return func(ready []int, last int) int {
+			for k, i := range ready {
+				if last >= 0 && trace[i].Process == trace[last].Process {
+					return k
+				}
+			}
+			return 0
+		}
+	},
+}
+
+// LinearOrderNames returns the names of the orders Linearize knows.
+func LinearOrderNames() []string {
+	names := make([]string, 0, len(linearOrders))
+	for n := range linearOrders {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Linearize returns the indices of the trace in a linear extension of
+// happens-before, chosen by the named order among the many a trace has:
+// "trace" keeps the order of the trace where it is causal, "rank" lists
+// events by causal rank, "time" by timestamp and "process" keeps the
+// events of each process together as far as messages allow. Ties go to the
+// event listed first in the trace.
+func Linearize(trace t.Trace, order string) ([]int, error) {
+	f, ok := linearOrders[order]
+	if !ok {
+		return nil, fmt.Errorf("unknown order %q (known: %s)", order, strings.Join(LinearOrderNames(), ", "))
+	}
+	// The events' direct dependencies are enough to tell when they are
+	// ready, and cost far less than an Index
+	preds := CausalPreds(trace)
+	succs := make([][]int, len(trace))
+	depth := make([]int, len(trace))
+	waiting := make([]int, len(trace))
+	var ready []int
+	for j := range trace {
+		for _, i := range preds(j) {
+			succs[i] = append(succs[i], j)
+			waiting[j]++
+		}
+		if waiting[j] == 0 {
+			ready = append(ready, j)
+		}
+	}
+
+	next := f(trace, depth)
+	out := make([]int, 0, len(trace))
+	last := -1
+	for len(ready) > 0 {
+		k := next(ready, last)
+		last = ready[k]
+		ready = append(ready[:k], ready[k+1:]...)
+		out = append(out, last)
+		for _, j := range succs[last] {
+			depth[j] = max(depth[j], depth[last]+1)
+			if waiting[j]--; waiting[j] == 0 {
+				i := sort.SearchInts(ready, j)
+				ready = append(ready[:i], append([]int{j}, ready[i:]...)...)
+			}
+		}
+	}
+	if len(out) < len(trace) {
+		return nil, fmt.Errorf("events cannot be ordered: the causal graph has a cycle")
+	}
+	return out, nil
+}
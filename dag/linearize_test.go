@@ -0,0 +1,33 @@
+package dag
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/traces/messages"
+)
+
+func TestLinearizeRendezvous(tt *testing.T) {
+	trace := messages.Generate(messages.Config{
+		Processes:     []string{"A", "B", "C", "D"},
+		NumEvents:     60,
+		BroadcastRate: 0.3,
+		Synchronous:   true,
+	}, rand.New(rand.NewSource(1)))
+	for _, order := range LinearOrderNames() {
+		out, err := Linearize(trace, order)
+		if err != nil {
+			tt.Fatalf("%s: %v", order, err)
+		}
+		if len(out) != len(trace) {
+			tt.Fatalf("%s: placed %d of %d events", order, len(out), len(trace))
+		}
+		for a := range out {
+			for _, b := range out[a+1:] {
+				if trace[b].VClock.HappensBefore(trace[out[a]].VClock) {
+					tt.Errorf("%s: e-%d placed before e-%d, which happens before it", order, out[a], b)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package dag
+
+import (
+	"fmt"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// Colours of the overlay drawn by Overlay.
+const (
+	OnlyAColor  = "blue"
+	OnlyBColor  = "orange"
+	CommonColor = "gray"
+)
+
+// Overlay draws the causal graphs of two traces on top of each other. Events
+// are matched by vector clock (VectorClock.Key), so an event is common when
+// both runs reached it with the same causal history, even if one run had
+// heard of processes the other had not; an edge is common when both runs
+// have it between common events. What only a has is drawn in OnlyAColor,
+// what only b has in OnlyBColor and the rest in CommonColor.
+func Overlay(a, b *DAG) string {
+	type node struct {
+		id    string
+		event t.Event
+	}
+	nodes := func(d *DAG) ([]node, map[string]bool) {
+		var out []node
+		seen := make(map[string]bool)
+		for _, p := range d.Processes() {
+			for _, e := range d.Nodes[p] {
+				if id := e.VClock.Key(); !seen[id] {
+					seen[id] = true
+					out = append(out, node{id, e})
+				}
+			}
+		}
+		return out, seen
+	}
+	edges := func(d *DAG) ([][2]string, map[[2]string]bool) {
+		var out [][2]string
+		seen := make(map[[2]string]bool)
+		for _, e := range d.Edges {
+			k := [2]string{e.From.VClock.Key(), e.To.VClock.Key()}
+			if !seen[k] {
+				seen[k] = true
+				out = append(out, k)
+			}
+		}
+		return out, seen
+	}
+	nodesA, inA := nodes(a)
+	nodesB, inB := nodes(b)
+	edgesA, hasA := edges(a)
+	edgesB, hasB := edges(b)
+
+	var out strings.Builder
+	out.WriteString("digraph G {\n")
+	fmt.Fprintf(&out, " label=%s;\n", dotQuote(fmt.Sprintf("%s: only in A, %s: only in B, %s: both", OnlyAColor, OnlyBColor, CommonColor)))
+	drawNode := func(n node, color string) {
+		label := fmt.Sprintf("%s %s %s", n.event.Process, n.event.Type, n.event.VClock)
+		fmt.Fprintf(&out, " %s [label=%s, color=%s, fontcolor=%s];\n", dotQuote(n.id), dotQuote(label), color, color)
+	}
+	for _, n := range nodesA {
+		if inB[n.id] {
+			drawNode(n, CommonColor)
+		} else {
+			drawNode(n, OnlyAColor)
+		}
+	}
+	for _, n := range nodesB {
+		if !inA[n.id] {
+			drawNode(n, OnlyBColor)
+		}
+	}
+	drawEdge := func(k [2]string, color string) {
+		fmt.Fprintf(&out, " %s -> %s [color=%s];\n", dotQuote(k[0]), dotQuote(k[1]), color)
+	}
+	for _, k := range edgesA {
+		if hasB[k] {
+			drawEdge(k, CommonColor)
+		} else {
+			drawEdge(k, OnlyAColor)
+		}
+	}
+	for _, k := range edgesB {
+		if !hasA[k] {
+			drawEdge(k, OnlyBColor)
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
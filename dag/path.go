@@ -0,0 +1,133 @@
+package dag
+
+import (
+	t "github.com/traces/types"
+)
+
+// CausalPath returns a shortest chain of direct dependencies from trace[from]
+// to trace[to], both included, or nil if trace[from] does not happen before
+// trace[to]. A direct dependency is the previous event of the same process,
+// the SEND of a RECV, or an event whose clock entry the next one merged by
+// other means (such as an edge rule). The chain is the causal evidence that
+// to could have seen from's effects.
+func CausalPath(trace t.Trace, from, to int) []int {
+	return causalPath(trace, DirectPreds(trace), from, to)
+}
+
+func causalPath(trace t.Trace, preds func(j int) []int, from, to int) []int {
+	if from == to {
+		return []int{from}
+	}
+	if !trace[from].VClock.HappensBefore(trace[to].VClock) {
+		return nil
+	}
+
+	prev := map[int]int{to: to}
+	queue := []int{to}
+	for len(queue) > 0 {
+		j := queue[0]
+		queue = queue[1:]
+		for _, i := range preds(j) {
+			if _, seen := prev[i]; seen {
+				continue
+			}
+			if i != from && !trace[from].VClock.HappensBefore(trace[i].VClock) {
+				continue
+			}
+			prev[i] = j
+			if i == from {
+				path := []int{from}
+				for k := from; k != to; {
+					k = prev[k]
+					path = append(path, k)
+				}
+				return path
+			}
+			queue = append(queue, i)
+		}
+	}
+	return nil
+}
+
+// DirectPreds returns a function listing the direct dependencies of an
+// event, as used by CausalPath.
+func DirectPreds(trace t.Trace) func(j int) []int {
+	type slot struct {
+		process string
+		counter int
+	}
+	at := make(map[slot]int, len(trace))
+	before := make([]int, len(trace)) // previous event of the same process, or -1
+	last := make(map[string]int)
+	sends := make(map[int]int)
+	for i, e := range trace {
+		at[slot{e.Process, e.VClock[e.Process]}] = i
+		before[i] = -1
+		if p, ok := last[e.Process]; ok {
+			before[i] = p
+		}
+		last[e.Process] = i
+		if e.Type == t.EventSend {
+			sends[e.MessageID] = i
+		}
+	}
+
+	return func(j int) []int {
+		e := trace[j]
+		var out []int
+		var known []t.VectorClock // clocks of the dependencies found so far
+		if p := before[j]; p >= 0 {
+			out = append(out, p)
+			known = append(known, trace[p].VClock)
+		}
+		if s, ok := sends[e.MessageID]; ok && e.Type == t.EventReceive && s != j {
+			out = append(out, s)
+			known = append(known, trace[s].VClock)
+		}
+	next:
+		for _, q := range e.VClock.Processes() {
+			if q == e.Process {
+				continue
+			}
+			for _, vc := range known {
+				if vc[q] >= e.VClock[q] {
+					continue next
+				}
+			}
+			if i, ok := at[slot{q, e.VClock[q]}]; ok {
+				out = append(out, i)
+			}
+		}
+		return out
+	}
+}
+
+// CausalPreds is like DirectPreds but lists only dependencies that happen
+// strictly before the event. The partners of a rendezvous share a clock and
+// are each other's direct dependencies, which would make a cycle: those of
+// the event are replaced by their own dependencies, and those of a
+// dependency are dependencies too.
+func CausalPreds(trace t.Trace) func(j int) []int {
+	direct := DirectPreds(trace)
+	return func(j int) []int {
+		var out []int
+		seen := map[int]bool{j: true}
+		queue := []int{j}
+		for len(queue) > 0 {
+			k := queue[0]
+			queue = queue[1:]
+			before := trace[k].VClock.HappensBefore(trace[j].VClock)
+			for _, i := range direct(k) {
+				if seen[i] || before && trace[i].VClock.HappensBefore(trace[k].VClock) {
+					continue
+				}
+				seen[i] = true
+				if trace[i].VClock.HappensBefore(trace[j].VClock) {
+					out = append(out, i)
+				}
+				queue = append(queue, i)
+			}
+		}
+		return out
+	}
+}
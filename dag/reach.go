@@ -0,0 +1,50 @@
+package dag
+
+import (
+	t "github.com/traces/types"
+)
+
+// Reachability answers causal order queries about the events of one trace,
+// addressed by trace index. HappensBefore and Concurrent only compare the
+// recorded vector clocks; what it caches are the direct dependencies of
+// every event, computed once so that repeated Preds and Path queries do not
+// pay for them again. It is safe for concurrent use.
+type Reachability struct {
+	trace t.Trace
+	preds [][]int
+}
+
+// NewReachability indexes a trace for causal order queries. The trace must
+// not be modified while the index is in use.
+func NewReachability(trace t.Trace) *Reachability {
+	direct := DirectPreds(trace)
+	preds := make([][]int, len(trace))
+	for j := range trace {
+		preds[j] = direct(j)
+	}
+	return &Reachability{trace: trace, preds: preds}
+}
+
+// Len returns the number of events indexed.
+func (r *Reachability) Len() int { return len(r.trace) }
+
+// HappensBefore reports whether event a happens before event b.
+func (r *Reachability) HappensBefore(a, b int) bool {
+	return r.trace[a].VClock.HappensBefore(r.trace[b].VClock)
+}
+
+// Concurrent reports whether neither of two distinct events happens before
+// the other.
+func (r *Reachability) Concurrent(a, b int) bool {
+	return a != b && !r.HappensBefore(a, b) && !r.HappensBefore(b, a)
+}
+
+// Preds returns the direct dependencies of event j, as DirectPreds does.
+// The slice is shared and must not be modified.
+func (r *Reachability) Preds(j int) []int { return r.preds[j] }
+
+// Path returns a shortest chain of direct dependencies from event from to
+// event to, as CausalPath does.
+func (r *Reachability) Path(from, to int) []int {
+	return causalPath(r.trace, r.Preds, from, to)
+}
@@ -0,0 +1,70 @@
+package dag
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	t "github.com/traces/types"
+)
+
+// ring returns a trace of n processes passing a message around a ring
+// rounds times, with an internal event after every receive.
+func ring(tb testing.TB, n, rounds int) t.Trace {
+	tb.Helper()
+	var trace t.Trace
+	msg := 0
+	for r := 0; r < rounds; r++ {
+		for p := 0; p < n; p++ {
+			from, to := fmt.Sprintf("P%d", p), fmt.Sprintf("P%d", (p+1)%n)
+			msg++
+			trace = append(trace,
+				t.Event{Type: t.EventSend, Process: from, MessageID: msg},
+				t.Event{Type: t.EventReceive, Process: to, MessageID: msg},
+				t.Event{Type: t.EventInternal, Process: to},
+			)
+		}
+	}
+	rebuilt, err := t.ReconstructClocks(trace)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return rebuilt
+}
+
+// TestReachabilityConcurrent queries one Reachability and one DAG from many
+// goroutines; run with -race to check that queries do not write shared
+// state.
+func TestReachabilityConcurrent(t *testing.T) {
+	trace := ring(t, 4, 5)
+	r := NewReachability(trace)
+	d := BuildDAG(trace)
+	want := d.ToGraphviz()
+	last := r.Len() - 1
+
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := 0; a <= last; a++ {
+				for b := 0; b <= last; b++ {
+					hb := r.HappensBefore(a, b)
+					if hb && r.HappensBefore(b, a) {
+						t.Errorf("e-%d and e-%d happen before each other", a, b)
+					}
+					if r.Concurrent(a, b) && hb {
+						t.Errorf("e-%d happens before e-%d but is concurrent with it", a, b)
+					}
+				}
+				if path := r.Path(0, a); r.HappensBefore(0, a) && (len(path) == 0 || path[0] != 0 || path[len(path)-1] != a) {
+					t.Errorf("path from e-0 to e-%d is %v", a, path)
+				}
+			}
+			if got := d.ToGraphviz(); got != want {
+				t.Errorf("ToGraphviz changed between calls")
+			}
+		}()
+	}
+	wg.Wait()
+}
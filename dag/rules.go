@@ -0,0 +1,252 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// Link orders trace[From] before trace[To].
+type Link struct {
+	From, To int
+}
+
+// Rule infers happens-before edges that message passing does not show, such
+// as the acquisition order of a shared lock or commit timestamps of a
+// database.
+type Rule interface {
+	Name() string
+	Links(trace t.Trace) []Link
+}
+
+// RuleFunc adapts a function to the Rule interface.
+type RuleFunc struct {
+	RuleName string
+	Fn       func(trace t.Trace) []Link
+}
+
+func (r RuleFunc) Name() string               { return r.RuleName }
+func (r RuleFunc) Links(trace t.Trace) []Link { return r.Fn(trace) }
+
+// KeyOrder orders events sharing a correlation key by their position in the
+// trace. With a Prefix only keys starting with it take part, so lock
+// acquisitions logged with keys like "lock:accounts" can be ordered without
+// affecting request keys.
+type KeyOrder struct {
+	Prefix string
+}
+
+func (r KeyOrder) Name() string {
+	if r.Prefix == "" {
+		return "key-order"
+	}
+	return "key-order(" + r.Prefix + ")"
+}
+
+func (r KeyOrder) Links(trace t.Trace) []Link {
+	var links []Link
+	last := make(map[string]int)
+	for i, e := range trace {
+		if e.CorrelationKey == "" || !strings.HasPrefix(e.CorrelationKey, r.Prefix) {
+			continue
+		}
+		if prev, ok := last[e.CorrelationKey]; ok && trace[prev].Process != e.Process {
+			links = append(links, Link{prev, i})
+		}
+		last[e.CorrelationKey] = i
+	}
+	return links
+}
+
+// LockOrder orders the critical sections of each lock: every RELEASE of a
+// lock happens before the next ACQUIRE of that lock in the trace.
+type LockOrder struct{}
+
+func (LockOrder) Name() string { return "locks" }
+
+func (LockOrder) Links(trace t.Trace) []Link {
+	var links []Link
+	released := make(map[string]int)
+	for i, e := range trace {
+		switch e.Type {
+		case t.EventRelease:
+			released[e.Lock] = i
+		case t.EventAcquire:
+			if prev, ok := released[e.Lock]; ok && trace[prev].Process != e.Process {
+				links = append(links, Link{prev, i})
+			}
+		}
+	}
+	return links
+}
+
+// BarrierOrder synchronises the processes at every barrier: the last event
+// of each process before its BARRIER of phase k happens before the BARRIER
+// of phase k of every other process.
+type BarrierOrder struct{}
+
+func (BarrierOrder) Name() string { return "barriers" }
+
+func (BarrierOrder) Links(trace t.Trace) []Link {
+	type arrival struct{ barrier, before int }
+	phases := make(map[int][]arrival)
+	var order []int
+	last := make(map[string]int)
+	for i, e := range trace {
+		if e.Type == t.EventBarrier {
+			before, ok := last[e.Process]
+			if !ok {
+				before = -1
+			}
+			if _, seen := phases[e.Phase]; !seen {
+				order = append(order, e.Phase)
+			}
+			phases[e.Phase] = append(phases[e.Phase], arrival{i, before})
+		}
+		last[e.Process] = i
+	}
+
+	var links []Link
+	for _, phase := range order {
+		arrivals := phases[phase]
+		for _, a := range arrivals {
+			for _, b := range arrivals {
+				if a.before >= 0 && a.barrier != b.barrier && trace[a.barrier].Process != trace[b.barrier].Process {
+					links = append(links, Link{a.before, b.barrier})
+				}
+			}
+		}
+	}
+	return links
+}
+
+// RuleFactory builds a rule from its parameters.
+type RuleFactory func(params map[string]string) (Rule, error)
+
+var rules = map[string]RuleFactory{
+	"locks":    func(map[string]string) (Rule, error) { return LockOrder{}, nil },
+	"barriers": func(map[string]string) (Rule, error) { return BarrierOrder{}, nil },
+	"key-order": func(params map[string]string) (Rule, error) {
+		return KeyOrder{Prefix: params["prefix"]}, nil
+	},
+	"assume": func(params map[string]string) (Rule, error) {
+		links, err := ParseLinks(params["edges"])
+		return Assume{Edges: links}, err
+	},
+}
+
+// RegisterRule makes an edge rule available to NewRule.
+func RegisterRule(kind string, f RuleFactory) {
+	rules[kind] = f
+}
+
+// NewRule builds a registered rule of the given kind.
+func NewRule(kind string, params map[string]string) (Rule, error) {
+	f, ok := rules[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown edge rule %q (known: %s)", kind, strings.Join(RuleNames(), ", "))
+	}
+	return f(params)
+}
+
+// RuleNames returns the names of all registered rule kinds.
+func RuleNames() []string {
+	names := make([]string, 0, len(rules))
+	for n := range rules {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyRules returns a copy of the trace whose vector clocks also reflect the
+// links inferred by the rules, so that BuildDAG and every analysis built on
+// happens-before see the extra edges. Events keep their trace order unless a
+// link points backwards, in which case they are moved to a linear extension
+// of the extended order. It fails if a link would make an event happen
+// before itself.
+func ApplyRules(trace t.Trace, rs ...Rule) (t.Trace, error) {
+	if len(rs) == 0 {
+		return trace, nil
+	}
+	out, _, err := ApplyRulesOrder(trace, rs...)
+	return out, err
+}
+
+// ApplyRulesOrder is ApplyRules that also returns, for every event of the
+// new trace, its index in the original one.
+func ApplyRulesOrder(trace t.Trace, rs ...Rule) (t.Trace, []int, error) {
+	n := len(trace)
+	idx := NewIndex(trace)
+	preds := make([][]int, n)
+	for i := range trace {
+		preds[i] = append(preds[i], idx.Preds[i]...)
+	}
+	type inferred struct {
+		Link
+		rule string
+	}
+	var links []inferred
+	for _, r := range rs {
+		for _, l := range r.Links(trace) {
+			if l.From < 0 || l.From >= n || l.To < 0 || l.To >= n || l.From == l.To {
+				return nil, nil, fmt.Errorf("rule %s: link %d -> %d out of range", r.Name(), l.From, l.To)
+			}
+			preds[l.To] = append(preds[l.To], l.From)
+			links = append(links, inferred{l, r.Name()})
+		}
+	}
+
+	// Kahn's algorithm over the extended edges, always taking the earliest
+	// ready event so consistent traces keep their order
+	indegree := make([]int, n)
+	succs := make([][]int, n)
+	for b, ps := range preds {
+		indegree[b] = len(ps)
+		for _, a := range ps {
+			succs[a] = append(succs[a], b)
+		}
+	}
+	var ready []int
+	for i := range n {
+		if indegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	clocks := make([]t.VectorClock, n)
+	out := make(t.Trace, 0, n)
+	order := make([]int, 0, n)
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		b := ready[0]
+		ready = ready[1:]
+
+		clock := t.DeepCopy(trace[b].VClock)
+		for _, a := range preds[b] {
+			clock.Merge(clocks[a])
+		}
+		clocks[b] = clock
+		e := trace[b]
+		e.VClock = clock
+		out = append(out, e)
+		order = append(order, b)
+
+		for _, c := range succs[b] {
+			if indegree[c]--; indegree[c] == 0 {
+				ready = append(ready, c)
+			}
+		}
+	}
+	if len(out) < n {
+		for _, l := range links {
+			if indegree[l.To] > 0 && indegree[l.From] > 0 {
+				return nil, nil, fmt.Errorf("rule %s: ordering e-%d before e-%d contradicts the trace's causality", l.rule, l.From, l.To)
+			}
+		}
+		return nil, nil, fmt.Errorf("inferred edges form a cycle")
+	}
+	return out, order, nil
+}
@@ -0,0 +1,111 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/traces/coverage"
+	"github.com/traces/dag"
+	"github.com/traces/property"
+	t "github.com/traces/types"
+)
+
+// PropertyResult compares the outcome of one property on both traces.
+type PropertyResult struct {
+	Property    string
+	ViolationsA int
+	ViolationsB int
+}
+
+// Agree reports whether the property holds on both traces or fails on both.
+func (r PropertyResult) Agree() bool {
+	return (r.ViolationsA == 0) == (r.ViolationsB == 0)
+}
+
+// Report is the differential comparison of two traces produced by different
+// implementations of the same protocol under the same workload.
+type Report struct {
+	Properties []PropertyResult
+	OnlyInA    []string // behaviours observed only in trace A
+	OnlyInB    []string // behaviours observed only in trace B
+}
+
+// Behaviours abstracts a trace into a set of process-level behaviours that can
+// be compared across implementations regardless of event counts: the
+// channels used, the kinds of immediate causal edges, and the reorderings of
+// messages at each receiver.
+func Behaviours(trace t.Trace) map[string]bool {
+	out := make(map[string]bool)
+
+	sender := make(map[int]string)
+	for _, e := range trace {
+		if e.Type == t.EventSend {
+			sender[e.MessageID] = e.Process
+		}
+	}
+	for _, e := range trace {
+		if s, ok := sender[e.MessageID]; ok && e.Type == t.EventReceive {
+			out[fmt.Sprintf("channel %s->%s", s, e.Process)] = true
+		}
+	}
+
+	for _, edge := range dag.BuildDAG(trace).Edges {
+		out[fmt.Sprintf("edge %s:%s -> %s:%s",
+			edge.From.Process, edge.From.Type, edge.To.Process, edge.To.Type)] = true
+	}
+
+	for sig := range coverage.Reorderings(trace) {
+		out["reordering "+sig] = true
+	}
+	return out
+}
+
+// Compare checks both traces against the same properties and reports
+// behaviours present in one causal structure but not the other.
+func Compare(a, b t.Trace, props ...property.Property) Report {
+	var r Report
+	for _, p := range props {
+		r.Properties = append(r.Properties, PropertyResult{
+			Property:    p.Name(),
+			ViolationsA: len(p.Check(a)),
+			ViolationsB: len(p.Check(b)),
+		})
+	}
+
+	ba, bb := Behaviours(a), Behaviours(b)
+	for k := range ba {
+		if !bb[k] {
+			r.OnlyInA = append(r.OnlyInA, k)
+		}
+	}
+	for k := range bb {
+		if !ba[k] {
+			r.OnlyInB = append(r.OnlyInB, k)
+		}
+	}
+	sort.Strings(r.OnlyInA)
+	sort.Strings(r.OnlyInB)
+	return r
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s %6s %6s\n", "property", "A", "B")
+	for _, p := range r.Properties {
+		mark := ""
+		if !p.Agree() {
+			mark = "  <- differs"
+		}
+		fmt.Fprintf(&b, "%-12s %6d %6d%s\n", p.Property, p.ViolationsA, p.ViolationsB, mark)
+	}
+	fmt.Fprintf(&b, "Only in A (%d):\n", len(r.OnlyInA))
+	for _, k := range r.OnlyInA {
+		fmt.Fprintf(&b, "  %s\n", k)
+	}
+	fmt.Fprintf(&b, "Only in B (%d):\n", len(r.OnlyInB))
+	for _, k := range r.OnlyInB {
+		fmt.Fprintf(&b, "  %s\n", k)
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/traces/dag"
+	"github.com/traces/property"
+	t "github.com/traces/types"
+)
+
+// MaxCandidates bounds the edges Strengthen considers, keeping the search
+// over subsets tractable.
+const MaxCandidates = 64
+
+// Strengthen searches for a smallest set of at most maxEdges additional
+// orderings under which none of the properties is violated. Candidates
+// order concurrent events of which at least one takes part in a violation,
+// in either direction. It returns nil if the trace already satisfies the
+// properties or no set within the bound does.
+func Strengthen(trace t.Trace, maxEdges int, props ...property.Property) []dag.Link {
+	violations := property.Check(trace, props...)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	involved := make(map[int]bool)
+	for _, v := range violations {
+		for _, e := range v.Events {
+			involved[e] = true
+		}
+	}
+	var candidates []dag.Link
+	for a := range trace {
+		for b := range trace {
+			if a != b && (involved[a] || involved[b]) && trace[a].VClock.ConcurrentWith(trace[b].VClock) {
+				candidates = append(candidates, dag.Link{From: a, To: b})
+			}
+		}
+	}
+	// Orderings between two violating events come first, then the closest
+	// pairs, which are the likeliest missing synchronizations
+	rank := func(l dag.Link) int {
+		d := max(l.From-l.To, l.To-l.From)
+		if involved[l.From] && involved[l.To] {
+			return d
+		}
+		return len(trace) + d
+	}
+	slices.SortStableFunc(candidates, func(x, y dag.Link) int { return rank(x) - rank(y) })
+	candidates = candidates[:min(len(candidates), MaxCandidates)]
+
+	for size := 1; size <= maxEdges && size <= len(candidates); size++ {
+		if found := searchLinks(trace, candidates, nil, size, props); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// searchLinks extends chosen with size more candidates, in candidate order,
+// and returns the first extension under which the properties hold.
+func searchLinks(trace t.Trace, candidates, chosen []dag.Link, size int, props []property.Property) []dag.Link {
+	if size == 0 {
+		strengthened, err := dag.ApplyRules(trace, dag.Assume{Edges: chosen})
+		if err != nil || property.Fails(strengthened, props...) {
+			return nil
+		}
+		return slices.Clone(chosen)
+	}
+	for i, c := range candidates {
+		if found := searchLinks(trace, candidates[i+1:], append(chosen, c), size-1, props); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Suggestion phrases an ordering for developers.
+func Suggestion(trace t.Trace, l dag.Link) string {
+	a, b := trace[l.From], trace[l.To]
+	return fmt.Sprintf("you need to order e-%d (%s on %s) before e-%d (%s on %s)",
+		l.From, a.Type, t.QuoteName(a.Process), l.To, b.Type, t.QuoteName(b.Process))
+}
@@ -0,0 +1,110 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/traces/dag"
+	"github.com/traces/property"
+	t "github.com/traces/types"
+)
+
+// Hypothesis is the outcome of re-checking a trace under assumed orderings.
+// Violations refer to events by their index in the observed trace, in their
+// Events and their messages alike, even where an assumption reorders it.
+type Hypothesis struct {
+	Assumed []dag.Link
+	// Implied are the assumptions the trace already shows, which change
+	// nothing.
+	Implied []dag.Link
+	// Properties compares violation counts, A as observed and B under the
+	// hypothesis.
+	Properties []PropertyResult
+	Resolved   []property.Violation // violated as observed but not under the hypothesis
+	Introduced []property.Violation // violated only under the hypothesis
+}
+
+// eventRef matches the references to events in violation messages.
+var eventRef = regexp.MustCompile(`\be-\d+\b`)
+
+// Hypothesize adds the assumed links to the trace's happens-before order,
+// re-checks the properties and compares the violations with those of the
+// trace as observed. It fails if an assumption contradicts the causality
+// the trace shows.
+func Hypothesize(trace t.Trace, assumed []dag.Link, props ...property.Property) (Hypothesis, error) {
+	h := Hypothesis{Assumed: assumed}
+	for _, l := range assumed {
+		if l.From >= 0 && l.From < len(trace) && l.To >= 0 && l.To < len(trace) &&
+			trace[l.From].VClock.HappensBefore(trace[l.To].VClock) {
+			h.Implied = append(h.Implied, l)
+		}
+	}
+	what, order, err := dag.ApplyRulesOrder(trace, dag.Assume{Edges: assumed})
+	if err != nil {
+		return h, err
+	}
+
+	key := func(v property.Violation) string { return fmt.Sprint(v.Property, v.Events) }
+	for _, p := range props {
+		observed, hypothetical := p.Check(trace), p.Check(what)
+		h.Properties = append(h.Properties, PropertyResult{
+			Property:    p.Name(),
+			ViolationsA: len(observed),
+			ViolationsB: len(hypothetical),
+		})
+		before := make(map[string]bool, len(observed))
+		for _, v := range observed {
+			before[key(v)] = true
+		}
+		after := make(map[string]bool, len(hypothetical))
+		for _, v := range hypothetical {
+			events := make([]int, len(v.Events))
+			for i, e := range v.Events {
+				events[i] = order[e]
+			}
+			v.Events = events
+			v.Message = eventRef.ReplaceAllStringFunc(v.Message, func(ref string) string {
+				if e, err := strconv.Atoi(ref[2:]); err == nil && e < len(order) {
+					return fmt.Sprintf("e-%d", order[e])
+				}
+				return ref
+			})
+			after[key(v)] = true
+			if !before[key(v)] {
+				h.Introduced = append(h.Introduced, v)
+			}
+		}
+		for _, v := range observed {
+			if !after[key(v)] {
+				h.Resolved = append(h.Resolved, v)
+			}
+		}
+	}
+	return h, nil
+}
+
+func (h Hypothesis) String() string {
+	var b strings.Builder
+	for _, l := range h.Implied {
+		fmt.Fprintf(&b, "e-%d -> e-%d already holds in the trace\n", l.From, l.To)
+	}
+	fmt.Fprintf(&b, "%-12s %8s %8s\n", "property", "observed", "assumed")
+	for _, p := range h.Properties {
+		mark := ""
+		if !p.Agree() {
+			mark = "  <- differs"
+		}
+		fmt.Fprintf(&b, "%-12s %8d %8d%s\n", p.Property, p.ViolationsA, p.ViolationsB, mark)
+	}
+	fmt.Fprintf(&b, "Resolved by the assumption (%d):\n", len(h.Resolved))
+	for _, v := range h.Resolved {
+		fmt.Fprintf(&b, "  %s\n", v)
+	}
+	fmt.Fprintf(&b, "Introduced by the assumption (%d):\n", len(h.Introduced))
+	for _, v := range h.Introduced {
+		fmt.Fprintf(&b, "  %s\n", v)
+	}
+	return b.String()
+}
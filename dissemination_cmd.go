@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/traces/analysis"
+	t "github.com/traces/types"
+)
+
+// runDissemination implements `trace dissemination`: it reports how far and
+// how fast the information of one event spread, as for a gossiped rumour.
+func runDissemination(args []string) error {
+	fs := flag.NewFlagSet("dissemination", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	origin := fs.Int("origin", 0, "trace index of the event whose information spreads")
+	timeLabel := fs.String("time", "time", "label holding the time of an event, for latencies")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	if *origin < 0 || *origin >= len(trace) {
+		return fmt.Errorf("-origin %d is outside the trace of %d events", *origin, len(trace))
+	}
+	processes := make(map[string]bool)
+	for _, e := range trace {
+		processes[e.Process] = true
+	}
+	reach := analysis.Dissemination(trace, *origin, *timeLabel)
+	maxHops, maxDepth, maxLatency := 0, 0, 0.0
+	for _, r := range reach {
+		fmt.Printf("%s: e-%d after %d hops, causal depth %d, latency %.6g\n", t.QuoteName(aliases.Name(r.Process)), r.Event, r.Hops, r.Depth, r.Latency)
+		maxHops, maxDepth, maxLatency = max(maxHops, r.Hops), max(maxDepth, r.Depth), max(maxLatency, r.Latency)
+	}
+	fmt.Printf("reached %d of %d processes; at most %d hops, causal depth %d, latency %.6g\n",
+		len(reach), len(processes), maxHops, maxDepth, maxLatency)
+	return nil
+}
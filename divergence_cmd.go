@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/traces/analysis"
+)
+
+// runDivergence implements `trace divergence`: it reports when the replicas
+// of each object disagreed on the updates they had seen.
+func runDivergence(args []string) error {
+	fs := flag.NewFlagSet("divergence", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	format := fs.String("format", "text", "output format: text, csv (timeline rows) or svg (timeline chart)")
+	out := fs.String("out", "", "write the output to this file or object storage URL instead of stdout")
+	op := fs.String("op", "op", "label holding the operation, read or write")
+	key := fs.String("key", "key", "label holding the object read or written")
+	at := fs.String("time", "time", "label holding the wall time of an event")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	objects := analysis.Divergence(trace, analysis.KVLabels{Op: *op, Key: *key, Time: *at})
+
+	var write func(w io.Writer) error
+	switch *format {
+	case "text":
+		write = func(w io.Writer) error {
+			for _, o := range objects {
+				replicas := make([]string, len(o.Replicas))
+				for i, p := range o.Replicas {
+					replicas[i] = aliases.Name(p)
+				}
+				o.Replicas = replicas
+				fmt.Fprintln(w, o)
+				for _, win := range o.Windows {
+					fmt.Fprintf(w, "  diverged e-%d..e-%d (%d events)\n", win.Start, win.End, win.Events())
+				}
+			}
+			return nil
+		}
+	case "csv":
+		write = func(w io.Writer) error { return analysis.WriteDivergenceCSV(w, objects) }
+	case "svg":
+		write = func(w io.Writer) error { return analysis.WriteDivergenceSVG(w, objects, len(trace)) }
+	default:
+		return fmt.Errorf("unknown divergence format %q", *format)
+	}
+	return writeOutput(*out, write)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/traces/analysis"
+)
+
+// runEffects implements `trace effects`: it prints the effect ledger of the
+// messages in a trace, showing which were applied exactly once, twice or
+// not at all across crashes and recoveries of their receivers.
+func runEffects(args []string) error {
+	fs := flag.NewFlagSet("effects", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	label := fs.String("label", "effect", "label holding the ID of the message an event applies")
+	crashed := fs.Bool("crashed", false, "only list messages delivered before a crash of their receiver")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	// The ledger is only printed, so it can name the display processes
+	trace = aliases.Apply(trace)
+	counts := make(map[analysis.EffectOutcome]int)
+	ledger := analysis.EffectLedger(trace, *label)
+	for _, e := range ledger {
+		counts[e.Outcome]++
+		if !*crashed || e.AcrossCrash() {
+			fmt.Println(e)
+		}
+	}
+	fmt.Printf("%d messages: %d exactly once, %d duplicate, %d lost, %d without effect\n", len(ledger),
+		counts[analysis.ExactlyOnce], counts[analysis.DuplicateEffect], counts[analysis.LostEffect], counts[analysis.NoEffect])
+	return nil
+}
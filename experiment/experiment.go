@@ -0,0 +1,147 @@
+package experiment
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/traces/analysis"
+	"github.com/traces/fingerprint"
+	"github.com/traces/messages"
+	"github.com/traces/property"
+)
+
+// Config describes a multi-seed experiment.
+type Config struct {
+	Generator messages.Config
+	Seeds     int   // number of traces to generate
+	FirstSeed int64 // seed of the first run; run i uses FirstSeed+i
+	// Properties, if any, are checked on every run and their violations
+	// triaged by fingerprint.
+	Properties []property.Property
+}
+
+// Distribution summarises the values a metric took across all runs.
+type Distribution struct {
+	Mean   float64
+	StdDev float64
+	Min    float64
+	P50    float64
+	P90    float64
+	P99    float64
+	Max    float64
+}
+
+// Result holds the per-run metrics and their distributions.
+type Result struct {
+	Config  Config
+	Runs    []analysis.Metrics
+	Summary map[string]Distribution
+	// Violations groups the property violations of all runs by fingerprint.
+	Violations []fingerprint.Group
+}
+
+// MetricNames lists the metrics reported by an experiment, in output order.
+var MetricNames = []string{"critical_path", "width", "edges", "messages"}
+
+func metricValue(m analysis.Metrics, name string) float64 {
+	switch name {
+	case "critical_path":
+		return float64(m.CriticalPath)
+	case "width":
+		return float64(m.Width)
+	case "edges":
+		return float64(m.Edges)
+	case "messages":
+		return float64(m.Messages)
+	default:
+		return math.NaN()
+	}
+}
+
+// Run generates one trace per seed, analyses it and summarises the metrics.
+func Run(cfg Config) Result {
+	runs := make([]analysis.Metrics, 0, cfg.Seeds)
+	grouper := fingerprint.NewGrouper()
+	for i := range cfg.Seeds {
+		r := rand.New(rand.NewSource(cfg.FirstSeed + int64(i)))
+		trace := messages.Generate(cfg.Generator, r)
+		runs = append(runs, analysis.Compute(trace))
+		if len(cfg.Properties) > 0 {
+			grouper.Add(trace, property.Check(trace, cfg.Properties...))
+		}
+	}
+
+	summary := make(map[string]Distribution)
+	for _, name := range MetricNames {
+		values := make([]float64, len(runs))
+		for i, m := range runs {
+			values[i] = metricValue(m, name)
+		}
+		summary[name] = Summarize(values)
+	}
+
+	return Result{Config: cfg, Runs: runs, Summary: summary, Violations: grouper.Groups()}
+}
+
+// Summarize computes the distribution of the given values.
+func Summarize(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+
+	var sq float64
+	for _, v := range sorted {
+		sq += (v - mean) * (v - mean)
+	}
+
+	return Distribution{
+		Mean:   mean,
+		StdDev: math.Sqrt(sq / float64(len(sorted))),
+		Min:    sorted[0],
+		P50:    percentile(sorted, 50),
+		P90:    percentile(sorted, 90),
+		P99:    percentile(sorted, 99),
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+	return sorted[rank-1]
+}
+
+func (r Result) String() string {
+	var b strings.Builder
+	gen := r.Config.Generator
+	fmt.Fprintf(&b, "Experiment: %d seeds, %d events, processes %s\n",
+		r.Config.Seeds, gen.NumEvents, strings.Join(gen.Processes, ","))
+	fmt.Fprintf(&b, "%-14s %8s %8s %8s %8s %8s %8s %8s\n",
+		"metric", "mean", "stddev", "min", "p50", "p90", "p99", "max")
+	for _, name := range MetricNames {
+		d := r.Summary[name]
+		fmt.Fprintf(&b, "%-14s %8.2f %8.2f %8.0f %8.0f %8.0f %8.0f %8.0f\n",
+			name, d.Mean, d.StdDev, d.Min, d.P50, d.P90, d.P99, d.Max)
+	}
+
+	if len(r.Config.Properties) > 0 {
+		fmt.Fprintf(&b, "Violations by fingerprint (%d groups):\n", len(r.Violations))
+		for _, g := range r.Violations {
+			fmt.Fprintf(&b, "  %-9s %s in %d/%d runs (%d total)  e.g. %s\n",
+				g.Frequency(r.Config.Seeds), g.Fingerprint, g.Traces, r.Config.Seeds, g.Count, g.Example)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,194 @@
+package experiment
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/traces/messages"
+)
+
+// Parameters a sweep can be plotted against.
+const (
+	ParamProcesses = "processes"
+	ParamEvents    = "events"
+	ParamLossRate  = "loss_rate"
+)
+
+// CheckParam reports an error unless a sweep can be plotted against param.
+func CheckParam(param string) error {
+	switch param {
+	case ParamProcesses, ParamEvents, ParamLossRate:
+		return nil
+	default:
+		return fmt.Errorf("unknown plot parameter %q", param)
+	}
+}
+
+// paramValue returns the value of the x-axis parameter for a result.
+func paramValue(r Result, param string) (float64, error) {
+	gen := r.Config.Generator
+	switch param {
+	case ParamProcesses:
+		return float64(len(gen.Processes)), nil
+	case ParamEvents:
+		return float64(gen.NumEvents), nil
+	case ParamLossRate:
+		return gen.LossRate, nil
+	default:
+		return 0, fmt.Errorf("unknown plot parameter %q", param)
+	}
+}
+
+// seriesName identifies the curve a result belongs to: every parameter except
+// the one on the x-axis.
+func seriesName(r Result, param string) string {
+	gen := r.Config.Generator
+	topo := gen.Topology
+	if topo == "" {
+		topo = messages.TopologyComplete
+	}
+	name := string(topo)
+	if param != ParamProcesses {
+		name += " p=" + strconv.Itoa(len(gen.Processes))
+	}
+	if param != ParamEvents {
+		name += " n=" + strconv.Itoa(gen.NumEvents)
+	}
+	if param != ParamLossRate {
+		name += " loss=" + strconv.FormatFloat(gen.LossRate, 'g', -1, 64)
+	}
+	return name
+}
+
+type plotPoint struct {
+	Series string  `json:"series"`
+	Metric string  `json:"metric"`
+	X      float64 `json:"x"`
+	Mean   float64 `json:"mean"`
+	Lower  float64 `json:"lower"`
+	Upper  float64 `json:"upper"`
+}
+
+func plotPoints(results []Result, param string) ([]plotPoint, error) {
+	var points []plotPoint
+	for _, r := range results {
+		x, err := paramValue(r, param)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range MetricNames {
+			d := r.Summary[name]
+			points = append(points, plotPoint{
+				Series: seriesName(r, param),
+				Metric: name,
+				X:      x,
+				Mean:   d.Mean,
+				Lower:  d.Mean - d.StdDev,
+				Upper:  d.Mean + d.StdDev,
+			})
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		if points[i].Series != points[j].Series {
+			return points[i].Series < points[j].Series
+		}
+		return points[i].X < points[j].X
+	})
+	return points, nil
+}
+
+// WriteVegaLite writes a Vega-Lite specification plotting the mean of every
+// metric (with a ±1 stddev band) against param, one panel per metric.
+func WriteVegaLite(w io.Writer, results []Result, param string) error {
+	points, err := plotPoints(results, param)
+	if err != nil {
+		return err
+	}
+
+	x := map[string]any{"field": "x", "type": "quantitative", "title": param}
+	color := map[string]any{"field": "series", "type": "nominal"}
+	spec := map[string]any{
+		"$schema": "https://vega.github.io/schema/vega-lite/v5.json",
+		"data":    map[string]any{"values": points},
+		"facet":   map[string]any{"row": map[string]any{"field": "metric", "type": "nominal"}},
+		"resolve": map[string]any{"scale": map[string]any{"y": "independent"}},
+		"spec": map[string]any{
+			"layer": []any{
+				map[string]any{
+					"mark": map[string]any{"type": "area", "opacity": 0.2},
+					"encoding": map[string]any{
+						"x":     x,
+						"y":     map[string]any{"field": "lower", "type": "quantitative", "title": "value"},
+						"y2":    map[string]any{"field": "upper"},
+						"color": color,
+					},
+				},
+				map[string]any{
+					"mark": map[string]any{"type": "line", "point": true},
+					"encoding": map[string]any{
+						"x":     x,
+						"y":     map[string]any{"field": "mean", "type": "quantitative"},
+						"color": color,
+					},
+				},
+			},
+		},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(spec)
+}
+
+// WriteGnuplot writes a self-contained gnuplot script rendering one PNG panel
+// per metric to output.
+func WriteGnuplot(w io.Writer, results []Result, param, output string) error {
+	points, err := plotPoints(results, param)
+	if err != nil {
+		return err
+	}
+
+	var series []string
+	seen := make(map[string]bool)
+	for _, p := range points {
+		if !seen[p.Series] {
+			seen[p.Series] = true
+			series = append(series, p.Series)
+		}
+	}
+
+	fmt.Fprintf(w, "set terminal pngcairo size 900,%d\n", 300*len(MetricNames))
+	fmt.Fprintf(w, "set output %q\n", output)
+	fmt.Fprintf(w, "set multiplot layout %d,1\n", len(MetricNames))
+	fmt.Fprintf(w, "set xlabel %q\n", param)
+	fmt.Fprintln(w, "set key outside right")
+
+	for mi, metric := range MetricNames {
+		for si, s := range series {
+			fmt.Fprintf(w, "$m%ds%d << EOD\n", mi, si)
+			for _, p := range points {
+				if p.Metric == metric && p.Series == s {
+					fmt.Fprintf(w, "%g %g %g %g\n", p.X, p.Mean, p.Lower, p.Upper)
+				}
+			}
+			fmt.Fprintln(w, "EOD")
+		}
+	}
+
+	for mi, metric := range MetricNames {
+		fmt.Fprintf(w, "set title %q\n", metric)
+		fmt.Fprint(w, "plot ")
+		for si, s := range series {
+			if si > 0 {
+				fmt.Fprint(w, ", \\\n     ")
+			}
+			fmt.Fprintf(w, "$m%ds%d using 1:2:3:4 with yerrorlines title %q", mi, si, s)
+		}
+		fmt.Fprintln(w)
+	}
+	_, err = fmt.Fprintln(w, "unset multiplot")
+	return err
+}
@@ -0,0 +1,86 @@
+package experiment
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/traces/messages"
+)
+
+// Sweep describes a grid of generator configurations. Every combination of
+// the listed values is run as its own experiment.
+type Sweep struct {
+	ProcessCounts []int
+	EventCounts   []int
+	LossRates     []float64
+	Topologies    []messages.Topology
+	Seeds         int
+	FirstSeed     int64
+}
+
+// ProcessNames returns the names used for a sweep configuration with n processes.
+func ProcessNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("P%d", i)
+	}
+	return names
+}
+
+// RunSweep runs an experiment for every configuration of the sweep.
+func RunSweep(s Sweep) []Result {
+	var results []Result
+	for _, procs := range s.ProcessCounts {
+		for _, events := range s.EventCounts {
+			for _, loss := range s.LossRates {
+				for _, topo := range s.Topologies {
+					results = append(results, Run(Config{
+						Generator: messages.Config{
+							Processes: ProcessNames(procs),
+							NumEvents: events,
+							LossRate:  loss,
+							Topology:  topo,
+						},
+						Seeds:     s.Seeds,
+						FirstSeed: s.FirstSeed,
+					}))
+				}
+			}
+		}
+	}
+	return results
+}
+
+// WriteCSV writes the results in tidy form: one row per configuration and metric.
+func WriteCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	header := []string{"processes", "events", "loss_rate", "topology", "seeds",
+		"metric", "mean", "stddev", "min", "p50", "p90", "p99", "max"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	for _, r := range results {
+		gen := r.Config.Generator
+		topo := gen.Topology
+		if topo == "" {
+			topo = messages.TopologyComplete
+		}
+		for _, name := range MetricNames {
+			d := r.Summary[name]
+			row := []string{
+				strconv.Itoa(len(gen.Processes)), strconv.Itoa(gen.NumEvents), f(gen.LossRate),
+				string(topo), strconv.Itoa(r.Config.Seeds), name,
+				f(d.Mean), f(d.StdDev), f(d.Min), f(d.P50), f(d.P90), f(d.P99), f(d.Max),
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
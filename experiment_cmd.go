@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/traces/experiment"
+	"github.com/traces/messages"
+	"github.com/traces/property"
+)
+
+// runExperiment implements `trace experiment`.
+func runExperiment(args []string) error {
+	fs := flag.NewFlagSet("experiment", flag.ContinueOnError)
+	seeds := fs.Int("seeds", 100, "number of seeds to run")
+	firstSeed := fs.Int64("first-seed", 1, "seed of the first run")
+	events := fs.Int("events", 30, "events per generated trace")
+	procs := fs.String("processes", "A,B,C", "comma separated process names")
+	props := fs.String("property", "", "comma separated properties to triage across seeds")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *seeds <= 0 {
+		return fmt.Errorf("-seeds must be positive, got %d", *seeds)
+	}
+	var properties []property.Property
+	if *props != "" {
+		var err error
+		if properties, err = parseProperties(*props); err != nil {
+			return err
+		}
+	}
+
+	gen := messages.Config{
+		Processes: strings.Split(*procs, ","),
+		NumEvents: *events,
+	}
+	if err := gen.Validate(); err != nil {
+		return err
+	}
+
+	result := experiment.Run(experiment.Config{
+		Generator:  gen,
+		Seeds:      *seeds,
+		FirstSeed:  *firstSeed,
+		Properties: properties,
+	})
+	fmt.Print(result.String())
+	return nil
+}
+
+// runSweep implements `trace sweep`.
+func runSweep(args []string) error {
+	fs := flag.NewFlagSet("sweep", flag.ContinueOnError)
+	procCounts := fs.String("process-counts", "3", "comma separated process counts")
+	eventCounts := fs.String("events", "30", "comma separated event counts")
+	lossRates := fs.String("loss", "0", "comma separated message loss rates")
+	topologies := fs.String("topology", "complete", "comma separated topologies (complete, ring, star, tree)")
+	seeds := fs.Int("seeds", 20, "number of seeds per configuration")
+	firstSeed := fs.Int64("first-seed", 1, "seed of the first run")
+	out := fs.String("out", "", "write the CSV to this file or object storage URL instead of stdout")
+	plot := fs.String("plot", "", "also emit a plot spec: vega or gnuplot")
+	plotOut := fs.String("plot-out", "", "file or object storage URL for the plot spec (default sweep.vl.json or sweep.gp)")
+	plotX := fs.String("x", experiment.ParamProcesses, "parameter on the plot's x-axis: processes, events or loss_rate")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var plotPath string
+	var writePlot func(w io.Writer, results []experiment.Result) error
+	if *plot != "" {
+		var err error
+		if plotPath, writePlot, err = plotWriter(*plot, *plotOut, *plotX); err != nil {
+			return err
+		}
+	}
+
+	sweep := experiment.Sweep{Seeds: *seeds, FirstSeed: *firstSeed}
+	var err error
+	if sweep.ProcessCounts, err = parseList(*procCounts, strconv.Atoi); err != nil {
+		return fmt.Errorf("-process-counts: %w", err)
+	}
+	if sweep.EventCounts, err = parseList(*eventCounts, strconv.Atoi); err != nil {
+		return fmt.Errorf("-events: %w", err)
+	}
+	parseFloat := func(s string) (float64, error) { return strconv.ParseFloat(s, 64) }
+	if sweep.LossRates, err = parseList(*lossRates, parseFloat); err != nil {
+		return fmt.Errorf("-loss: %w", err)
+	}
+	if sweep.Topologies, err = parseList(*topologies, messages.ParseTopology); err != nil {
+		return fmt.Errorf("-topology: %w", err)
+	}
+	for _, n := range sweep.ProcessCounts {
+		if n < 1 {
+			return fmt.Errorf("-process-counts: need at least 1 process, got %d", n)
+		}
+	}
+
+	results := experiment.RunSweep(sweep)
+
+	if err := writeOutput(*out, func(w io.Writer) error { return experiment.WriteCSV(w, results) }); err != nil {
+		return err
+	}
+
+	if writePlot == nil {
+		return nil
+	}
+	return writeOutput(plotPath, func(w io.Writer) error { return writePlot(w, results) })
+}
+
+// plotWriter returns where to write a Vega-Lite or gnuplot specification of
+// sweep results and how. Unknown kinds and parameters are reported here, so
+// that no output is created for them.
+func plotWriter(kind, path, param string) (string, func(w io.Writer, results []experiment.Result) error, error) {
+	if err := experiment.CheckParam(param); err != nil {
+		return "", nil, err
+	}
+	if path == "" {
+		switch kind {
+		case "vega":
+			path = "sweep.vl.json"
+		case "gnuplot":
+			path = "sweep.gp"
+		}
+	}
+
+	switch kind {
+	case "vega":
+		return path, func(w io.Writer, results []experiment.Result) error {
+			return experiment.WriteVegaLite(w, results, param)
+		}, nil
+	case "gnuplot":
+		return path, func(w io.Writer, results []experiment.Result) error {
+			return experiment.WriteGnuplot(w, results, param, strings.TrimSuffix(path, ".gp")+".png")
+		}, nil
+	default:
+		return "", nil, fmt.Errorf("unknown plot kind %q", kind)
+	}
+}
+
+// parseList splits a comma separated flag value and parses each element.
+func parseList[T any](s string, parse func(string) (T, error)) ([]T, error) {
+	var out []T
+	for _, part := range strings.Split(s, ",") {
+		v, err := parse(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSweepRejectsPlotBeforeWriting(t *testing.T) {
+	for _, args := range [][]string{
+		{"-plot", "svg"},
+		{"-plot", "vega", "-x", "seeds"},
+	} {
+		dir := t.TempDir()
+		csv, spec := filepath.Join(dir, "sweep.csv"), filepath.Join(dir, "sweep.spec")
+		args = append(args, "-seeds", "1", "-events", "5", "-out", csv, "-plot-out", spec)
+		if err := runSweep(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+		for _, path := range []string{csv, spec} {
+			if _, err := os.Stat(path); err == nil {
+				t.Errorf("%v: %s was created", args, filepath.Base(path))
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package explore
+
+import (
+	"strings"
+
+	"github.com/traces/property"
+	t "github.com/traces/types"
+)
+
+// Independence declares which kinds of events commute: exchanging two of
+// them, such as the deliveries of two votes, cannot change whether a
+// property holds, so exploring one order of them is enough.
+type Independence [][2]property.Kind
+
+// ParseCommute parses "K1~K2", declaring that events of kind K1 commute with
+// those of K2, or "K", declaring that events of kind K commute with each
+// other.
+func ParseCommute(s string) ([2]property.Kind, error) {
+	a, b, ok := strings.Cut(s, "~")
+	if !ok {
+		b = a
+	}
+	ka, err := property.ParseKind(a)
+	if err != nil {
+		return [2]property.Kind{}, err
+	}
+	kb, err := property.ParseKind(b)
+	if err != nil {
+		return [2]property.Kind{}, err
+	}
+	return [2]property.Kind{ka, kb}, nil
+}
+
+// Commute reports whether a and b were declared to commute, in either order.
+func (ind Independence) Commute(a, b t.Event) bool {
+	for _, p := range ind {
+		if (p[0].Matches(a) && p[1].Matches(b)) || (p[0].Matches(b) && p[1].Matches(a)) {
+			return true
+		}
+	}
+	return false
+}
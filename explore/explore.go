@@ -0,0 +1,180 @@
+// Package explore checks properties on the other executions a trace could
+// have had: the same processes taking the same steps, but with messages
+// delivered to each process in a different order.
+package explore
+
+import (
+	"math"
+
+	"github.com/traces/property"
+	t "github.com/traces/types"
+)
+
+// Counterexample is a reordering of the trace that violates a property.
+type Counterexample struct {
+	Trace      t.Trace
+	Violations []property.Violation
+}
+
+// Result summarises an exploration.
+type Result struct {
+	// Orders is the number of delivery orders without reduction, counting
+	// infeasible ones; it may be +Inf.
+	Orders float64
+	// Explored orders were generated after reduction, of which Infeasible
+	// ones had a message delivered before it could have been sent.
+	Explored, Infeasible int
+	Truncated            bool // the limit stopped the exploration
+	// Violating orders number all explored orders that violate a property,
+	// of which the first MaxCounterexamples are kept as Counterexamples.
+	Violating       int
+	Counterexamples []Counterexample
+}
+
+// MaxCounterexamples bounds the counterexamples a Result keeps, as an
+// exploration without a limit may find violations in most of a great many
+// orders.
+const MaxCounterexamples = 100
+
+// Deliveries checks the properties on every order in which each process could
+// have received its messages, stopping after limit orders (0: no limit).
+// Program order is kept otherwise: the k-th receive of a process stays its
+// k-th event of that type, so only what it receives changes. Barriers and
+// rendezvous still join their participants in every order.
+//
+// Orders that differ only by exchanging adjacent receives declared to commute
+// by ind are explored once, as the lexicographically least of them. Receives
+// are adjacent if the process has no other event between them, or only
+// events that ind declares to commute with both.
+func Deliveries(trace t.Trace, ind Independence, limit int, props ...property.Property) Result {
+	var processes []string
+	slots := make(map[string][]int) // process -> its RECVs
+	for i, e := range trace {
+		if e.Type != t.EventReceive {
+			continue
+		}
+		if _, ok := slots[e.Process]; !ok {
+			processes = append(processes, e.Process)
+		}
+		slots[e.Process] = append(slots[e.Process], i)
+	}
+
+	r := Result{Orders: 1}
+	for _, p := range processes {
+		r.Orders *= permutations(trace, slots[p])
+	}
+
+	candidate := make(t.Trace, len(trace))
+	copy(candidate, trace)
+	var visit func(k int) bool
+	visit = func(k int) bool {
+		if k == len(processes) {
+			if limit > 0 && r.Explored >= limit {
+				r.Truncated = true
+				return false
+			}
+			r.Explored++
+			rebuilt, err := t.ReconstructClocks(candidate)
+			if err != nil {
+				r.Infeasible++
+				return true
+			}
+			if vs := property.Check(rebuilt, props...); len(vs) > 0 {
+				if r.Violating++; len(r.Counterexamples) < MaxCounterexamples {
+					r.Counterexamples = append(r.Counterexamples, Counterexample{rebuilt, vs})
+				}
+			}
+			return true
+		}
+		return orders(trace, slots[processes[k]], ind, func(order []int) bool {
+			for s, i := range order {
+				candidate[slots[processes[k]][s]] = trace[i]
+			}
+			return visit(k + 1)
+		})
+	}
+	visit(0)
+	return r
+}
+
+// orders calls yield with every order of the receives at slots that is in
+// normal form under ind, until yield returns false. Receives of the same
+// message are interchangeable and yield each order once.
+func orders(trace t.Trace, slots []int, ind Independence, yield func([]int) bool) bool {
+	used := make([]bool, len(slots))
+	order := make([]int, 0, len(slots))
+	var extend func() bool
+	extend = func() bool {
+		if len(order) == len(slots) {
+			return yield(order)
+		}
+		for n, i := range slots {
+			if used[n] || !first(trace, slots, used, n) || !normal(trace, slots, order, i, ind) {
+				continue
+			}
+			used[n] = true
+			order = append(order, i)
+			ok := extend()
+			order = order[:len(order)-1]
+			used[n] = false
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return extend()
+}
+
+// first reports whether slots[n] is the first unused receive of its message.
+func first(trace t.Trace, slots []int, used []bool, n int) bool {
+	for m := range n {
+		a, b := trace[slots[m]], trace[slots[n]]
+		if !used[m] && a.MessageID == b.MessageID && a.CorrelationKey == b.CorrelationKey {
+			return false
+		}
+	}
+	return true
+}
+
+// normal reports whether appending receive i keeps order in lexicographic
+// normal form: no earlier receive j > i could be exchanged with i by moving i
+// back over receives that all commute with it. The receives are placed at
+// slots, and the events of the process between two slots must commute with
+// both receives exchanged across them.
+func normal(trace t.Trace, slots, order []int, i int, ind Independence) bool {
+	for k := len(order) - 1; k >= 0; k-- {
+		j := order[k]
+		if !ind.Commute(trace[j], trace[i]) {
+			return true
+		}
+		for m := slots[k] + 1; m < slots[k+1]; m++ {
+			e := trace[m]
+			if e.Process == trace[slots[k]].Process && (!ind.Commute(e, trace[i]) || !ind.Commute(e, trace[j])) {
+				return true
+			}
+		}
+		if j > i {
+			return false
+		}
+	}
+	return true
+}
+
+// permutations counts the distinct orders of the receives at slots.
+func permutations(trace t.Trace, slots []int) float64 {
+	type message struct {
+		id  int
+		key string
+	}
+	copies := make(map[message]int)
+	for _, i := range slots {
+		copies[message{trace[i].MessageID, trace[i].CorrelationKey}]++
+	}
+	lg, _ := math.Lgamma(float64(len(slots) + 1))
+	for _, c := range copies {
+		l, _ := math.Lgamma(float64(c + 1))
+		lg -= l
+	}
+	return math.Round(math.Exp(lg))
+}
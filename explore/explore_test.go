@@ -0,0 +1,34 @@
+package explore
+
+import (
+	"testing"
+
+	"github.com/traces/property"
+	"github.com/traces/script"
+	t "github.com/traces/types"
+)
+
+// always fails on every trace, so that each explored order is kept.
+type always struct{}
+
+func (always) Name() string { return "always" }
+
+func (always) Check(t.Trace) []property.Violation {
+	return []property.Violation{{Property: "always"}}
+}
+
+func TestDeliveriesKeepBarriers(tt *testing.T) {
+	trace := script.MustCompile(`A sends m1 to C; B sends m2 to C; barrier
+C receives m1; C receives m2`)
+	r := Deliveries(trace, nil, 0, always{})
+	if r.Explored != 2 || len(r.Counterexamples) != 2 {
+		tt.Fatalf("explored %d orders with %d kept, want 2 and 2", r.Explored, len(r.Counterexamples))
+	}
+	for _, c := range r.Counterexamples {
+		for _, e := range c.Trace {
+			if e.Type == t.EventBarrier && (e.VClock["A"] < 1 || e.VClock["B"] < 1) {
+				tt.Errorf("%s leaves the barrier with %s, not knowing every send", e.Process, e.VClock)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/traces/explore"
+	"github.com/traces/property"
+)
+
+// runExplore implements `trace explore`: it checks properties on every order
+// in which the processes of a trace could have received their messages.
+func runExplore(args []string) error {
+	fs := flag.NewFlagSet("explore", flag.ContinueOnError)
+	in := fs.String("in", "", "JSON trace to read")
+	props := fs.String("property", "fifo", "comma separated properties to check")
+	limit := fs.Int("limit", 10000, "stop after this many delivery orders (0: no limit)")
+	out := fs.String("out", "", "write the first violating reordering to this file")
+	aliasesPath := aliasesFlag(fs)
+	var ind explore.Independence
+	fs.Func("commute", "event kinds that commute, as KIND or KIND~KIND, e.g. RECV:msg=vote (repeatable)", func(s string) error {
+		pair, err := explore.ParseCommute(s)
+		if err != nil {
+			return err
+		}
+		ind = append(ind, pair)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	properties, err := parseProperties(*props)
+	if err != nil {
+		return err
+	}
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	r := explore.Deliveries(trace, ind, *limit, properties...)
+	fmt.Printf("%.6g delivery orders; explored %d after reduction, %d infeasible\n", r.Orders, r.Explored, r.Infeasible)
+	if r.Truncated {
+		fmt.Printf("stopped at the limit of %d orders\n", *limit)
+	}
+	if len(r.Counterexamples) == 0 {
+		fmt.Println("no explored order violates the properties")
+		return nil
+	}
+	fmt.Printf("%d orders violate the properties; the first:\n", r.Violating)
+	// Violations name processes, so with aliases they are checked again on
+	// the display trace; the reordering written out keeps the raw names
+	violations := r.Counterexamples[0].Violations
+	if len(aliases) > 0 {
+		violations = property.Check(aliases.Apply(r.Counterexamples[0].Trace), properties...)
+	}
+	for _, v := range violations {
+		fmt.Println(" ", v)
+	}
+	if *out != "" {
+		return saveTrace(*out, r.Counterexamples[0].Trace)
+	}
+	return nil
+}
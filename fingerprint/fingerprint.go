@@ -0,0 +1,136 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/traces/dag"
+	"github.com/traces/property"
+	t "github.com/traces/types"
+)
+
+// Radius is the number of causal hops included in an event's neighbourhood.
+const Radius = 1
+
+// Event returns a stable fingerprint of the causal neighbourhood of
+// trace[i]: the set of processes and kinds of the events within Radius
+// immediate edges before and after it. Clock values and message IDs are ignored, so the
+// same situation recurring in a different run fingerprints identically.
+func Event(trace t.Trace, idx *dag.Index, i int) string {
+	var parts []string
+	walk := func(dir string, next [][]int) {
+		frontier := []int{i}
+		seen := map[int]bool{i: true}
+		for d := 1; d <= Radius; d++ {
+			var level []int
+			for _, u := range frontier {
+				for _, v := range next[u] {
+					if !seen[v] {
+						seen[v] = true
+						level = append(level, v)
+						parts = append(parts, fmt.Sprintf("%s%d:%s:%s", dir, d, trace[v].Process, trace[v].Type))
+					}
+				}
+			}
+			frontier = level
+		}
+	}
+	walk("-", idx.Preds)
+	walk("+", idx.Succs)
+	sort.Strings(parts)
+	parts = slices.Compact(parts)
+
+	self := fmt.Sprintf("%s:%s", trace[i].Process, trace[i].Type)
+	return hash(self + "|" + strings.Join(parts, ","))
+}
+
+// Violation fingerprints a violation by its property and the neighbourhoods
+// of the events involved, in the order the property reported them.
+func Violation(trace t.Trace, idx *dag.Index, v property.Violation) string {
+	parts := []string{v.Property}
+	for _, e := range v.Events {
+		parts = append(parts, Event(trace, idx, e))
+	}
+	return hash(strings.Join(parts, "|"))
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Group is a set of violations sharing a fingerprint.
+type Group struct {
+	Fingerprint string             `json:"fingerprint"`
+	Property    string             `json:"property"`
+	Count       int                `json:"count"`
+	Example     property.Violation `json:"example"` // first occurrence
+	Traces      int                `json:"traces"`  // number of traces the group occurred in
+}
+
+// Grouper accumulates violations from any number of traces and groups
+// recurring ones by fingerprint.
+type Grouper struct {
+	groups map[string]*Group
+	order  []string
+	traces int
+}
+
+// NewGrouper returns an empty grouper.
+func NewGrouper() *Grouper {
+	return &Grouper{groups: make(map[string]*Group)}
+}
+
+// Add records the violations found on one trace.
+func (g *Grouper) Add(trace t.Trace, violations []property.Violation) {
+	g.traces++
+	if len(violations) == 0 {
+		return
+	}
+	idx := dag.NewIndex(trace)
+	inTrace := make(map[string]bool)
+	for _, v := range violations {
+		fp := Violation(trace, idx, v)
+		grp, ok := g.groups[fp]
+		if !ok {
+			grp = &Group{Fingerprint: fp, Property: v.Property, Example: v}
+			g.groups[fp] = grp
+			g.order = append(g.order, fp)
+		}
+		grp.Count++
+		if !inTrace[fp] {
+			inTrace[fp] = true
+			grp.Traces++
+		}
+	}
+}
+
+// Traces returns the number of traces added.
+func (g *Grouper) Traces() int { return g.traces }
+
+// Groups returns the groups, most frequent first.
+func (g *Grouper) Groups() []Group {
+	out := make([]Group, 0, len(g.order))
+	for _, fp := range g.order {
+		out = append(out, *g.groups[fp])
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	return out
+}
+
+// Frequency classifies how often a group recurred over runs traces:
+// "always" (every run), "once" (a single run) or "sometimes".
+func (grp Group) Frequency(runs int) string {
+	switch {
+	case grp.Traces >= runs:
+		return "always"
+	case grp.Traces == 1:
+		return "once"
+	default:
+		return "sometimes"
+	}
+}
@@ -0,0 +1,57 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	t "github.com/traces/types"
+)
+
+var degenerate = map[string]t.Trace{
+	"empty": nil,
+	"one process": {
+		{Type: t.EventInternal, Process: "A", VClock: t.VectorClock{"A": 1}, MessageID: -1},
+		{Type: t.EventInternal, Process: "A", VClock: t.VectorClock{"A": 2}, MessageID: -1},
+	},
+	"concurrent": {
+		{Type: t.EventInternal, Process: "A", VClock: t.VectorClock{"A": 1}, MessageID: -1},
+		{Type: t.EventInternal, Process: "B", VClock: t.VectorClock{"B": 1}, MessageID: -1},
+	},
+}
+
+// TestExportDegenerate writes every export of the degenerate traces, and
+// reads back the GoVector log, which holds every event.
+func TestExportDegenerate(tt *testing.T) {
+	for shape, trace := range degenerate {
+		var jaeger, govector, vcd, nodes, edges, npz bytes.Buffer
+		if err := SaveJaeger(&jaeger, trace, "t"); err != nil {
+			tt.Errorf("%s: jaeger: %v", shape, err)
+		} else {
+			// Spans only become events through calls between services, so
+			// count the spans written instead of reading them back
+			var doc jaegerDoc
+			if err := json.Unmarshal(jaeger.Bytes(), &doc); err != nil || len(doc.Data) != 1 || len(doc.Data[0].Spans) != len(trace) {
+				tt.Errorf("%s: jaeger wrote %s, %v", shape, jaeger.Bytes(), err)
+			}
+		}
+		if err := SaveGoVector(&govector, trace); err != nil {
+			tt.Errorf("%s: govector: %v", shape, err)
+		} else if back, err := LoadGoVector(&govector, "log"); err != nil || len(back) != len(trace) {
+			tt.Errorf("%s: govector read back %d events, %v", shape, len(back), err)
+		}
+		if err := SaveVCD(&vcd, trace); err != nil {
+			tt.Errorf("%s: vcd: %v", shape, err)
+		}
+		f := ExtractFeatures(trace)
+		if err := f.WriteNodesCSV(&nodes); err != nil {
+			tt.Errorf("%s: nodes csv: %v", shape, err)
+		}
+		if err := f.WriteEdgesCSV(&edges); err != nil {
+			tt.Errorf("%s: edges csv: %v", shape, err)
+		}
+		if err := f.WriteNPZ(&npz); err != nil {
+			tt.Errorf("%s: npz: %v", shape, err)
+		}
+	}
+}
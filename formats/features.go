@@ -0,0 +1,201 @@
+package formats
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/traces/dag"
+	t "github.com/traces/types"
+)
+
+// Features holds per-event feature vectors and the adjacency of the reduced
+// DAG, for machine-learning pipelines.
+type Features struct {
+	Names  []string    // column names of Matrix
+	Matrix [][]float64 // one row per trace event
+	Edges  [][2]int    // immediate causal edges as (from, to) event indices
+}
+
+// ExtractFeatures computes, for every event: in/out degree and depth in the
+// reduced DAG, a one-hot event kind, a one-hot process and the per-process
+// clock deltas since the previous event of the same process.
+func ExtractFeatures(trace t.Trace) Features {
+	idx := dag.NewIndex(trace)
+
+	procSet := make(map[string]bool)
+	for _, e := range trace {
+		procSet[e.Process] = true
+		for p := range e.VClock {
+			procSet[p] = true
+		}
+	}
+	procs := make([]string, 0, len(procSet))
+	for p := range procSet {
+		procs = append(procs, p)
+	}
+	sort.Strings(procs)
+
+	f := Features{Names: []string{"in_degree", "out_degree", "depth", "is_send", "is_recv", "is_internal"}}
+	for _, p := range procs {
+		f.Names = append(f.Names, "proc_"+p)
+	}
+	for _, p := range procs {
+		f.Names = append(f.Names, "dclock_"+p)
+	}
+
+	depth := depths(trace, idx)
+	prev := make(map[string]t.VectorClock)
+	for i, e := range trace {
+		row := []float64{
+			float64(len(idx.Preds[i])), float64(len(idx.Succs[i])), float64(depth[i]),
+			oneHot(e.Type == t.EventSend), oneHot(e.Type == t.EventReceive), oneHot(e.Type == t.EventInternal),
+		}
+		for _, p := range procs {
+			row = append(row, oneHot(e.Process == p))
+		}
+		for _, p := range procs {
+			row = append(row, float64(e.VClock[p]-prev[e.Process][p]))
+		}
+		prev[e.Process] = e.VClock
+		f.Matrix = append(f.Matrix, row)
+
+		for _, j := range idx.Succs[i] {
+			f.Edges = append(f.Edges, [2]int{i, j})
+		}
+	}
+	return f
+}
+
+func oneHot(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// depths returns the length of the longest chain of immediate edges ending at
+// every event. Events are visited in increasing clock-sum order, which is a
+// linear extension of happens-before.
+func depths(trace t.Trace, idx *dag.Index) []int {
+	order := make([]int, len(trace))
+	sums := make([]int, len(trace))
+	for i, e := range trace {
+		order[i] = i
+		for _, v := range e.VClock {
+			sums[i] += v
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return sums[order[a]] < sums[order[b]] })
+
+	depth := make([]int, len(trace))
+	for _, i := range order {
+		for _, p := range idx.Preds[i] {
+			depth[i] = max(depth[i], depth[p]+1)
+		}
+	}
+	return depth
+}
+
+// WriteNodesCSV writes the feature matrix with a header row and an event
+// index column.
+func (f Features) WriteNodesCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"event"}, f.Names...)); err != nil {
+		return err
+	}
+	for i, row := range f.Matrix {
+		rec := []string{strconv.Itoa(i)}
+		for _, v := range row {
+			rec = append(rec, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteEdgesCSV writes the edge list as "source,target" rows.
+func (f Features) WriteEdgesCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"source", "target"}); err != nil {
+		return err
+	}
+	for _, e := range f.Edges {
+		if err := cw.Write([]string{strconv.Itoa(e[0]), strconv.Itoa(e[1])}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteNPZ writes a NumPy .npz archive holding "features" (float64, events x
+// features) and "edges" (int64, edges x 2). Column names are only available
+// from the CSV export.
+func (f Features) WriteNPZ(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	features := make([]float64, 0, len(f.Matrix)*len(f.Names))
+	for _, row := range f.Matrix {
+		features = append(features, row...)
+	}
+	if err := writeNPY(zw, "features.npy", "<f8", []int{len(f.Matrix), len(f.Names)}, features); err != nil {
+		return err
+	}
+
+	edges := make([]int64, 0, 2*len(f.Edges))
+	for _, e := range f.Edges {
+		edges = append(edges, int64(e[0]), int64(e[1]))
+	}
+	if err := writeNPY(zw, "edges.npy", "<i8", []int{len(f.Edges), 2}, edges); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeNPY adds a version 1.0 .npy file to the archive.
+func writeNPY[T float64 | int64](zw *zip.Writer, name, dtype string, shape []int, data []T) error {
+	dims := make([]string, len(shape))
+	for i, d := range shape {
+		dims[i] = strconv.Itoa(d)
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }",
+		dtype, strings.Join(dims, ", ")+",")
+	// Magic (6) + version (2) + length (2) + header + '\n' must be a multiple of 64
+	pad := 64 - (10+len(header)+1)%64
+	header += strings.Repeat(" ", pad%64) + "\n"
+
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write([]byte("\x93NUMPY\x01\x00")); err != nil {
+		return err
+	}
+	if err := binary.Write(fw, binary.LittleEndian, uint16(len(header))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(fw, header); err != nil {
+		return err
+	}
+	buf := make([]byte, 8*len(data))
+	for i, v := range data {
+		switch v := any(v).(type) {
+		case float64:
+			binary.LittleEndian.PutUint64(buf[8*i:], math.Float64bits(v))
+		case int64:
+			binary.LittleEndian.PutUint64(buf[8*i:], uint64(v))
+		}
+	}
+	_, err = fw.Write(buf)
+	return err
+}
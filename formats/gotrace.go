@@ -0,0 +1,117 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// GoTraceMagic starts every binary Go execution trace file ("go 1.22 trace").
+const GoTraceMagic = "go 1."
+
+// LoadGoTrace converts the text dump of a Go execution trace, as printed by
+// `go tool trace -d=parsed trace.out`, into a trace with one process per
+// goroutine ("g1", "g7", ...). The happens-before edges the runtime records
+// become messages:
+//
+//   - a goroutine starting another one (NotExist->Runnable) sends to it, and
+//   - a goroutine waking a blocked one (Waiting->Runnable), which is how a
+//     channel send, receive, close, mutex unlock or WaitGroup.Done hands
+//     over, sends to the goroutine it wakes.
+//
+// The woken goroutine runs nothing until it is scheduled, so its receive is
+// placed at the wake-up. Wake-ups done by the runtime outside any goroutine
+// (timers, the network poller) carry no causality between goroutines and
+// are dropped. Each receive's correlation key is the reason the goroutine
+// was blocked, e.g. "chan receive". name is recorded as the Source file.
+func LoadGoTrace(r io.Reader, name string) (t.Trace, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var trace t.Trace
+	blockedOn := make(map[string]string)
+	var offset int64
+	nextID := 0
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		start := offset
+		offset += int64(len(text)) + 1
+		if !strings.HasPrefix(text, "M=") {
+			continue // stacks, blank lines
+		}
+		fields, err := goTraceFields(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if fields["kind"] != "StateTransition" || fields["GoID"] == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(fields["transition"], "->")
+		if !ok {
+			return nil, fmt.Errorf("line %d: no state transition", line)
+		}
+		target := "g" + fields["GoID"]
+		if to == "Waiting" {
+			blockedOn[target] = fields["Reason"]
+		}
+		actor := fields["G"]
+		if to != "Runnable" || (from != "NotExist" && from != "Waiting") || actor == "" || actor == "-1" {
+			continue
+		}
+
+		key := blockedOn[target]
+		if from == "NotExist" {
+			key = "go"
+		}
+		src := &t.Source{File: name, Line: line, Offset: start}
+		trace = append(trace,
+			t.Event{Type: t.EventSend, Process: "g" + actor, MessageID: nextID, CorrelationKey: key, Source: src},
+			t.Event{Type: t.EventReceive, Process: target, MessageID: nextID, CorrelationKey: key, Source: src})
+		nextID++
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return t.ReconstructClocks(trace)
+}
+
+// goTraceFields splits an event line of the parsed dump into its key=value
+// fields. The event kind is stored under "kind" and a bare "A->B" state
+// change under "transition"; quoted values are unquoted.
+func goTraceFields(line string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for n := 0; line != ""; n++ {
+		line = strings.TrimLeft(line, " ")
+		var tok string
+		key, rest, hasValue := strings.Cut(line, "=")
+		if hasValue && !strings.ContainsAny(key, " ") && strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", key, err)
+			}
+			tok = key + "=" + quoted
+		} else if i := strings.IndexByte(line, ' '); i >= 0 {
+			tok = line[:i]
+		} else {
+			tok = line
+		}
+		line = line[len(tok):]
+
+		switch k, v, ok := strings.Cut(tok, "="); {
+		case ok:
+			if uq, err := strconv.Unquote(v); err == nil {
+				v = uq
+			}
+			fields[k] = v
+		case strings.Contains(tok, "->"):
+			fields["transition"] = tok
+		case n == 3:
+			fields["kind"] = tok
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,162 @@
+package formats
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// LoadGoVector reads a GoVector (ShiViz) log: every event is two lines, the
+// process name followed by its JSON vector clock, then a free-form event
+// description.
+//
+//	client {"client":1}
+//	Initializing RPC
+//
+// GoVector does not record message IDs, so sends and receives are inferred
+// from the clocks: an event that advances another process' component is the
+// receive of the message sent by that process' event with the new value.
+// Events that are neither become INTERNAL; this includes the receive of a
+// message whose send the receiver already knew about, which leaves no trace
+// in the clock. name is recorded as the Source file of every event.
+//
+// Processes are identified by the process field of each event, whose own
+// clock entry orders its events. The trace lists events by the sum of their
+// clock entries, then by process and own entry, so it does not depend on the
+// order of the lines: logs of several processes may be concatenated or
+// interleaved in any way.
+func LoadGoVector(r io.Reader, name string) (t.Trace, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var trace t.Trace
+	var offset int64
+	for line := 1; sc.Scan(); line += 2 {
+		header := sc.Text()
+		start := offset
+		offset += int64(len(header)) + 1
+		if strings.TrimSpace(header) == "" {
+			line--
+			continue
+		}
+		process, clockJSON, ok := strings.Cut(header, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"<process> <clock>\"", line)
+		}
+		clock := make(t.VectorClock)
+		if err := json.Unmarshal([]byte(clockJSON), &clock); err != nil {
+			return nil, fmt.Errorf("line %d: clock: %w", line, err)
+		}
+		if clock[process] <= 0 {
+			return nil, fmt.Errorf("line %d: clock has no entry for process %s", line, t.QuoteName(process))
+		}
+		if !sc.Scan() {
+			return nil, fmt.Errorf("line %d: missing event description", line+1)
+		}
+		offset += int64(len(sc.Text())) + 1
+
+		trace = append(trace, t.Event{
+			Type:      t.EventInternal,
+			Process:   process,
+			VClock:    clock,
+			MessageID: -1,
+			Source:    &t.Source{File: name, Line: line, Offset: start},
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	// A clock that happens before another has a smaller sum, so this is a
+	// linear extension of happens-before
+	sum := func(c t.VectorClock) int {
+		n := 0
+		for _, v := range c {
+			n += v
+		}
+		return n
+	}
+	slices.SortStableFunc(trace, func(a, b t.Event) int {
+		return cmp.Or(cmp.Compare(sum(a.VClock), sum(b.VClock)),
+			cmp.Compare(a.Process, b.Process),
+			cmp.Compare(a.VClock[a.Process], b.VClock[b.Process]))
+	})
+	inferMessages(trace)
+	return trace, nil
+}
+
+// inferMessages marks receives and their sends in a trace whose events carry
+// clocks but no message information, assigning fresh message IDs.
+func inferMessages(trace t.Trace) {
+	type ref struct {
+		process string
+		value   int
+	}
+	byClock := make(map[ref]int)
+	prev := make(map[string]t.VectorClock)
+	nextID := 0
+
+	for i, e := range trace {
+		byClock[ref{e.Process, e.VClock[e.Process]}] = i
+		before := prev[e.Process]
+		prev[e.Process] = e.VClock
+
+		// The sender is the process whose event explains every advanced
+		// component of the receiver's clock.
+		for _, q := range e.VClock.Processes() {
+			v := e.VClock[q]
+			if q == e.Process || v <= before[q] {
+				continue
+			}
+			s, ok := byClock[ref{q, v}]
+			if !ok || !explains(trace[s].VClock, before, e) {
+				continue
+			}
+			if trace[s].Type == t.EventInternal {
+				trace[s].Type = t.EventSend
+				trace[s].MessageID = nextID
+				nextID++
+			}
+			// A send received twice keeps a single message ID
+			trace[i].Type = t.EventReceive
+			trace[i].MessageID = trace[s].MessageID
+			break
+		}
+	}
+}
+
+// explains reports whether merging send into before yields e's clock for
+// every process other than e's own.
+func explains(send, before t.VectorClock, e t.Event) bool {
+	for p, v := range e.VClock {
+		if p != e.Process && v != max(before[p], send[p]) {
+			return false
+		}
+	}
+	return true
+}
+
+// SaveGoVector writes a trace as a GoVector log. The description line holds
+// the event kind and message ID.
+func SaveGoVector(w io.Writer, trace t.Trace) error {
+	b := bufio.NewWriter(w)
+	for _, e := range trace {
+		clock, err := json.Marshal(e.VClock)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "%s %s\n", e.Process, clock)
+		if !e.Type.IsMessage() {
+			fmt.Fprintf(b, "%s\n", e.Type)
+		} else {
+			fmt.Fprintf(b, "%s Msg-%d\n", e.Type, e.MessageID)
+		}
+	}
+	return b.Flush()
+}
@@ -0,0 +1,142 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/traces/dag"
+	t "github.com/traces/types"
+)
+
+// Jaeger's JSON trace format, as served by its query API and UI download.
+type jaegerDoc struct {
+	Data []jaegerTrace `json:"data"`
+}
+
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+}
+
+type jaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []jaegerReference `json:"references"`
+	StartTime     int64             `json:"startTime"` // microseconds since epoch
+	Duration      int64             `json:"duration"`  // microseconds
+	ProcessID     string            `json:"processID"`
+	Tags          []jaegerTag       `json:"tags,omitempty"`
+}
+
+type jaegerTag struct {
+	Key   string `json:"key"`
+	Type  string `json:"type,omitempty"`
+	Value any    `json:"value"`
+}
+
+type jaegerReference struct {
+	RefType string `json:"refType"` // CHILD_OF or FOLLOWS_FROM
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+type jaegerProcess struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// LoadJaeger converts Jaeger JSON into a trace. Services become processes;
+// CHILD_OF references across services become request/reply messages and
+// FOLLOWS_FROM references become one-way messages, as for LoadOTLP. Span
+// tags label the messages like OTLP attributes.
+func LoadJaeger(r io.Reader) (t.Trace, error) {
+	var doc jaegerDoc
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding Jaeger JSON: %w", err)
+	}
+
+	var spans []span
+	for _, jt := range doc.Data {
+		for _, js := range jt.Spans {
+			proc, ok := jt.Processes[js.ProcessID]
+			if !ok {
+				return nil, fmt.Errorf("span %s: unknown process %q", js.SpanID, js.ProcessID)
+			}
+			sp := span{
+				traceID: js.TraceID,
+				id:      js.SpanID,
+				service: proc.ServiceName,
+				start:   js.StartTime * 1000,
+				end:     (js.StartTime + js.Duration) * 1000,
+			}
+			for _, ref := range js.References {
+				if ref.RefType == "CHILD_OF" && sp.parent == "" {
+					sp.parent = ref.SpanID
+				} else {
+					sp.links = append(sp.links, ref.SpanID)
+				}
+			}
+			for _, tag := range js.Tags {
+				if sp.attributes == nil {
+					sp.attributes = make(map[string]string)
+				}
+				sp.attributes[tag.Key] = fmt.Sprint(tag.Value)
+			}
+			spans = append(spans, sp)
+		}
+	}
+	return spansToTrace(spans, nil), nil
+}
+
+// SaveJaeger exports the reduced causal DAG of a trace as a Jaeger trace:
+// every event is a one-microsecond span on its process' service, ordered by
+// trace position. The previous event of the same process is the CHILD_OF
+// parent and every other immediate causal predecessor is a FOLLOWS_FROM
+// reference. Event labels become string tags.
+func SaveJaeger(w io.Writer, trace t.Trace, traceID string) error {
+	idx := dag.NewIndex(trace)
+	jt := jaegerTrace{TraceID: traceID, Spans: []jaegerSpan{}, Processes: make(map[string]jaegerProcess)}
+	procIDs := make(map[string]string)
+	spanID := func(i int) string { return fmt.Sprintf("%016x", i+1) }
+
+	for i, e := range trace {
+		pid, ok := procIDs[e.Process]
+		if !ok {
+			pid = fmt.Sprintf("p%d", len(procIDs)+1)
+			procIDs[e.Process] = pid
+			jt.Processes[pid] = jaegerProcess{ServiceName: e.Process}
+		}
+
+		op := fmt.Sprintf("%s Msg-%d", e.Type, e.MessageID)
+		if !e.Type.IsMessage() {
+			op = e.Type.String()
+		}
+		js := jaegerSpan{
+			TraceID:       traceID,
+			SpanID:        spanID(i),
+			OperationName: op,
+			StartTime:     int64(i),
+			Duration:      1,
+			ProcessID:     pid,
+		}
+		for _, k := range slices.Sorted(maps.Keys(e.Labels)) {
+			js.Tags = append(js.Tags, jaegerTag{Key: k, Type: "string", Value: e.Labels[k]})
+		}
+		for _, p := range idx.Preds[i] {
+			refType := "FOLLOWS_FROM"
+			if trace[p].Process == e.Process {
+				refType = "CHILD_OF"
+			}
+			js.References = append(js.References, jaegerReference{RefType: refType, TraceID: traceID, SpanID: spanID(p)})
+		}
+		jt.Spans = append(jt.Spans, js)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jaegerDoc{Data: []jaegerTrace{jt}})
+}
@@ -0,0 +1,203 @@
+package formats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// LogRule turns application log lines matching Pattern into events. The other
+// fields are templates expanded with the pattern's capture groups ($1, $name
+// or ${name}); a field left empty is not set on the event.
+//
+// Type must expand to SEND, RECV or INTERNAL (or one of the values listed in
+// SendValues/RecvValues of the enclosing LogFormat). Clock, if set, must
+// expand to a list of process:counter pairs such as "A:1 B:3" or
+// {"A":1,"B":3}.
+type LogRule struct {
+	Pattern   string `json:"pattern"`
+	Type      string `json:"type"`
+	Process   string `json:"process"`
+	MessageID string `json:"message_id,omitempty"`
+	Key       string `json:"correlation_key,omitempty"`
+	Clock     string `json:"clock,omitempty"`
+	Role      string `json:"role,omitempty"`
+	// Labels maps label names to templates; labels expanding to "" are
+	// not set.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// LogFormat is a list of rules tried in order against every log line; the
+// first rule that matches produces the line's event and lines that match no
+// rule are skipped.
+type LogFormat struct {
+	Rules      []LogRule `json:"rules"`
+	SendValues []string  `json:"send_values,omitempty"`
+	RecvValues []string  `json:"recv_values,omitempty"`
+}
+
+// LoadLogFormat reads and compiles a JSON encoded LogFormat.
+func LoadLogFormat(path string) (*LogFormat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f LogFormat
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("decoding log format: %w", err)
+	}
+	if err := f.Compile(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Compile checks the rules and compiles their patterns.
+func (f *LogFormat) Compile() error {
+	if len(f.Rules) == 0 {
+		return fmt.Errorf("log format has no rules")
+	}
+	for i := range f.Rules {
+		r := &f.Rules[i]
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if r.Type == "" || r.Process == "" {
+			return fmt.Errorf("rule %d: type and process are required", i+1)
+		}
+		r.re = re
+	}
+	return nil
+}
+
+func (f *LogFormat) eventType(s string) (t.EventType, error) {
+	for _, v := range f.SendValues {
+		if s == v {
+			return t.EventSend, nil
+		}
+	}
+	for _, v := range f.RecvValues {
+		if s == v {
+			return t.EventReceive, nil
+		}
+	}
+	return t.ParseEventType(strings.ToUpper(s))
+}
+
+var clockEntry = regexp.MustCompile(`"?([^\s"{},:=]+)"?\s*[:=]\s*(\d+)`)
+
+// parseClock reads process:counter pairs in any of the common notations.
+func parseClock(s string) t.VectorClock {
+	vc := make(t.VectorClock)
+	for _, m := range clockEntry.FindAllStringSubmatch(s, -1) {
+		n, _ := strconv.Atoi(m[2])
+		vc[m[1]] = n
+	}
+	return vc
+}
+
+// LoadLog parses an application log with the rules of f. name is recorded as
+// the Source file of every event. If no rule captures a clock, the clocks are
+// reconstructed from the order of the log and the message IDs. Receives
+// logged without a correlation key inherit the key of their send.
+func LoadLog(r io.Reader, name string, f *LogFormat) (t.Trace, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var trace t.Trace
+	var offset int64
+	clocks := false
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		start := offset
+		offset += int64(len(text)) + 1
+
+		for _, rule := range f.Rules {
+			m := rule.re.FindStringSubmatchIndex(text)
+			if m == nil {
+				continue
+			}
+			expand := func(tmpl string) string {
+				return string(rule.re.ExpandString(nil, tmpl, text, m))
+			}
+
+			e := t.Event{
+				Process:        expand(rule.Process),
+				MessageID:      -1,
+				CorrelationKey: expand(rule.Key),
+				Role:           expand(rule.Role),
+				Source:         &t.Source{File: name, Line: line, Offset: start},
+			}
+			typ, err := f.eventType(expand(rule.Type))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			e.Type = typ
+			if e.Process == "" {
+				return nil, fmt.Errorf("line %d: empty process name", line)
+			}
+			if id := expand(rule.MessageID); id != "" {
+				if e.MessageID, err = strconv.Atoi(id); err != nil {
+					return nil, fmt.Errorf("line %d: message id: %w", line, err)
+				}
+			} else if e.Type.IsMessage() {
+				return nil, fmt.Errorf("line %d: %s event without a message id", line, e.Type)
+			}
+			for k, tmpl := range rule.Labels {
+				if v := expand(tmpl); v != "" {
+					if e.Labels == nil {
+						e.Labels = make(map[string]string)
+					}
+					e.Labels[k] = v
+				}
+			}
+			if rule.Clock != "" {
+				e.VClock = parseClock(expand(rule.Clock))
+				clocks = true
+			} else {
+				e.VClock = make(t.VectorClock)
+			}
+			trace = append(trace, e)
+			break
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	inheritKeys(trace)
+	if !clocks {
+		return t.ReconstructClocks(trace)
+	}
+	return trace, nil
+}
+
+// inheritKeys gives receives logged without a correlation key the key of
+// their send, as long as the message ID identifies a single send.
+func inheritKeys(trace t.Trace) {
+	keys := make(map[int]string)
+	ambiguous := make(map[int]bool)
+	for _, e := range trace {
+		if e.Type != t.EventSend {
+			continue
+		}
+		if k, ok := keys[e.MessageID]; ok && k != e.CorrelationKey {
+			ambiguous[e.MessageID] = true
+		}
+		keys[e.MessageID] = e.CorrelationKey
+	}
+	for i, e := range trace {
+		if e.Type == t.EventReceive && e.CorrelationKey == "" && !ambiguous[e.MessageID] {
+			trace[i].CorrelationKey = keys[e.MessageID]
+		}
+	}
+}
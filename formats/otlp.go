@@ -0,0 +1,323 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	t "github.com/traces/types"
+)
+
+// OTLP JSON encoding of an ExportTraceServiceRequest, restricted to the
+// fields needed to recover causality.
+type otlpRequest struct {
+	ResourceSpans []struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []struct {
+			Spans []otlpSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue is an AnyValue, of which at most one field is set. Integers are
+// strings in OTLP/JSON but may be numbers.
+type otlpValue struct {
+	StringValue *string         `json:"stringValue"`
+	IntValue    json.RawMessage `json:"intValue"`
+	BoolValue   *bool           `json:"boolValue"`
+	DoubleValue *float64        `json:"doubleValue"`
+	BytesValue  *string         `json:"bytesValue"` // base64
+	ArrayValue  *struct {
+		Values []otlpValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []otlpAttribute `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+// String formats the value as a label: strings as they are, numbers and
+// booleans as Go prints them, arrays as "[a, b]" and key-value lists as
+// "{k=v, ...}". An empty value is the empty string.
+func (v otlpValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.IntValue != nil:
+		return strings.Trim(string(v.IntValue), `"`)
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'g', -1, 64)
+	case v.BytesValue != nil:
+		return *v.BytesValue
+	case v.ArrayValue != nil:
+		items := make([]string, len(v.ArrayValue.Values))
+		for i, item := range v.ArrayValue.Values {
+			items[i] = item.String()
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case v.KvlistValue != nil:
+		items := make([]string, len(v.KvlistValue.Values))
+		for i, kv := range v.KvlistValue.Values {
+			items[i] = kv.Key + "=" + kv.Value.String()
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return ""
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId"`
+	Name              string          `json:"name"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Links             []struct {
+		TraceID string `json:"traceId"`
+		SpanID  string `json:"spanId"`
+	} `json:"links"`
+}
+
+// span is a decoded span attributed to the service that emitted it.
+type span struct {
+	traceID, id, parent string
+	service             string
+	start, end          int64
+	links               []string          // span IDs
+	attributes          map[string]string // span attributes, formatted
+}
+
+// OTLP JSON encoding of an ExportLogsServiceRequest, restricted to the
+// fields needed to place log records in a trace.
+type otlpLogsRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			LogRecords []otlpLogRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string          `json:"timeUnixNano"`
+	ObservedTimeUnixNano string          `json:"observedTimeUnixNano"`
+	SeverityText         string          `json:"severityText"`
+	Body                 otlpValue       `json:"body"`
+	Attributes           []otlpAttribute `json:"attributes"`
+	TraceID              string          `json:"traceId"`
+	SpanID               string          `json:"spanId"`
+}
+
+// logRecord is a decoded log record attributed to the service that logged it.
+type logRecord struct {
+	service string
+	at      int64
+	labels  map[string]string
+}
+
+// timedEvent is an event placed on the wall clock before clocks are rebuilt.
+type timedEvent struct {
+	at    int64
+	event t.Event
+}
+
+// LoadOTLP converts OTLP/JSON span data into a trace. Every span whose
+// parent or link belongs to another service becomes a message: a SEND on the
+// caller when the span starts and a RECV on the callee, plus a reply message
+// back to the parent's service when the span ends. Services are the
+// processes (resource attribute service.name). Events are ordered by span
+// timestamps and vector clocks are reconstructed from that order. Events
+// keep their span times as timestamps and the OTLP trace ID as correlation
+// key; the attributes of the callee's span label the messages of the call,
+// formatted as text.
+func LoadOTLP(r io.Reader) (t.Trace, error) {
+	spans, err := decodeOTLPSpans(r)
+	if err != nil {
+		return nil, err
+	}
+	return spansToTrace(spans, nil), nil
+}
+
+// LoadOTLPLogs converts OTLP/JSON span data into a trace like LoadOTLP, and
+// adds the OTLP/JSON log records of logs that carry the ID of one of its
+// traces as INTERNAL events. A record is placed on the service that logged
+// it at its timestamp, held within the span it names if that span is known,
+// so it falls between the messages of the span. Its event is labelled with
+// the record's body ("log"), severity ("severity"), trace and span IDs
+// ("trace_id", "span_id") and attributes, which property predicates and
+// reports can then refer to; attributes named like those labels are
+// ignored. Records of other traces, or without trace context, are dropped.
+func LoadOTLPLogs(traces, logs io.Reader) (t.Trace, error) {
+	spans, err := decodeOTLPSpans(traces)
+	if err != nil {
+		return nil, err
+	}
+	var req otlpLogsRequest
+	if err := json.NewDecoder(logs).Decode(&req); err != nil {
+		return nil, fmt.Errorf("decoding OTLP logs: %w", err)
+	}
+
+	known := make(map[string]bool)
+	byID := make(map[string]span, len(spans))
+	for _, s := range spans {
+		known[s.traceID] = true
+		byID[s.id] = s
+	}
+	var records []logRecord
+	for _, rl := range req.ResourceLogs {
+		service := otlpService(rl.Resource.Attributes)
+		for _, sl := range rl.ScopeLogs {
+			for _, lr := range sl.LogRecords {
+				if !known[lr.TraceID] {
+					continue
+				}
+				stamp := lr.TimeUnixNano
+				if stamp == "" || stamp == "0" {
+					stamp = lr.ObservedTimeUnixNano
+				}
+				at, err := strconv.ParseInt(stamp, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("log record of span %s: time: %w", lr.SpanID, err)
+				}
+				rec := logRecord{service: service, at: at, labels: map[string]string{
+					"log":      lr.Body.String(),
+					"trace_id": lr.TraceID,
+				}}
+				if lr.SeverityText != "" {
+					rec.labels["severity"] = lr.SeverityText
+				}
+				if sp, ok := byID[lr.SpanID]; ok {
+					rec.labels["span_id"] = lr.SpanID
+					// Ahead of the reply sent when the span ends
+					rec.at = max(sp.start, min(sp.end-1, rec.at))
+					if rec.service == "unknown" {
+						rec.service = sp.service
+					}
+				}
+				for _, a := range lr.Attributes {
+					// The record's own labels take precedence
+					if !otlpLogLabels[a.Key] {
+						rec.labels[a.Key] = a.Value.String()
+					}
+				}
+				records = append(records, rec)
+			}
+		}
+	}
+	return spansToTrace(spans, records), nil
+}
+
+// otlpLogLabels are the labels LoadOTLPLogs derives from a log record itself,
+// which its attributes may not replace.
+var otlpLogLabels = map[string]bool{"log": true, "severity": true, "trace_id": true, "span_id": true}
+
+// decodeOTLPSpans reads the spans of an OTLP/JSON ExportTraceServiceRequest.
+func decodeOTLPSpans(r io.Reader) ([]span, error) {
+	var req otlpRequest
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return nil, fmt.Errorf("decoding OTLP: %w", err)
+	}
+
+	var spans []span
+	for _, rs := range req.ResourceSpans {
+		service := otlpService(rs.Resource.Attributes)
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				start, err := strconv.ParseInt(s.StartTimeUnixNano, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("span %s: start time: %w", s.SpanID, err)
+				}
+				end, err := strconv.ParseInt(s.EndTimeUnixNano, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("span %s: end time: %w", s.SpanID, err)
+				}
+				sp := span{traceID: s.TraceID, id: s.SpanID, parent: s.ParentSpanID,
+					service: service, start: start, end: end}
+				for _, l := range s.Links {
+					sp.links = append(sp.links, l.SpanID)
+				}
+				for _, a := range s.Attributes {
+					if sp.attributes == nil {
+						sp.attributes = make(map[string]string)
+					}
+					sp.attributes[a.Key] = a.Value.String()
+				}
+				spans = append(spans, sp)
+			}
+		}
+	}
+	return spans, nil
+}
+
+// otlpService returns the service.name resource attribute, or "unknown".
+func otlpService(attributes []otlpAttribute) string {
+	service := "unknown"
+	for _, a := range attributes {
+		if a.Key == "service.name" {
+			service = a.Value.String()
+		}
+	}
+	return service
+}
+
+// spansToTrace turns cross-service parent/child and link relations into
+// message events, adds the log records as INTERNAL events and rebuilds
+// vector clocks.
+func spansToTrace(spans []span, records []logRecord) t.Trace {
+	byID := make(map[string]span, len(spans))
+	for _, s := range spans {
+		byID[s.id] = s
+	}
+
+	var events []timedEvent
+	nextID := 0
+	message := func(from, to string, at int64, key string, labels map[string]string) {
+		events = append(events,
+			timedEvent{at, t.Event{Type: t.EventSend, Process: from, MessageID: nextID, CorrelationKey: key, Labels: maps.Clone(labels)}},
+			timedEvent{at, t.Event{Type: t.EventReceive, Process: to, MessageID: nextID, CorrelationKey: key, Labels: maps.Clone(labels)}})
+		nextID++
+	}
+
+	for _, s := range spans {
+		if parent, ok := byID[s.parent]; ok && parent.service != s.service {
+			message(parent.service, s.service, s.start, s.traceID, s.attributes)
+			message(s.service, parent.service, s.end, s.traceID, s.attributes)
+		}
+		for _, l := range s.links {
+			if linked, ok := byID[l]; ok && linked.service != s.service {
+				message(linked.service, s.service, max(linked.start, min(linked.end, s.start)), s.traceID, s.attributes)
+			}
+		}
+	}
+
+	for _, r := range records {
+		events = append(events, timedEvent{r.at, t.Event{Type: t.EventInternal, Process: r.service, MessageID: -1, CorrelationKey: r.labels["trace_id"], Labels: r.labels}})
+	}
+
+	// The stable sort keeps every SEND ahead of its RECV at equal timestamps
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at < events[j].at })
+	trace := make(t.Trace, len(events))
+	for i, e := range events {
+		trace[i] = e.event
+		trace[i].Timestamp = time.Unix(0, e.at).UTC()
+	}
+	// Cannot fail: every RECV directly follows its SEND
+	rebuilt, _ := t.ReconstructClocks(trace)
+	return rebuilt
+}
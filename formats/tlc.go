@@ -0,0 +1,421 @@
+package formats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// TLCMapping tells LoadTLC how a specification's state maps onto processes
+// and messages.
+//
+// ProcessVar names a function or record keyed by process (typically the
+// PlusCal pc): the processes whose entry changed in a step performed it.
+// Without it, or when no entry changed, the step is attributed to a process
+// named after the action.
+//
+// MessageVar names the network: a set, sequence or bag (a function from
+// message to count) of messages. Messages that appear in a step are sent by
+// the acting process and messages that disappear are received by it.
+type TLCMapping struct {
+	ProcessVar string `json:"process_var,omitempty"`
+	MessageVar string `json:"message_var,omitempty"`
+}
+
+// LoadTLCMapping reads a JSON encoded TLCMapping.
+func LoadTLCMapping(path string) (TLCMapping, error) {
+	var m TLCMapping
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("decoding TLC mapping: %w", err)
+	}
+	return m, nil
+}
+
+// tlcState is one state of a TLC error trace.
+type tlcState struct {
+	action string
+	line   int
+	offset int64
+	vars   map[string]string
+}
+
+var tlcHeader = regexp.MustCompile(`^(?:State )?(\d+): (?:<([^ >(]+)[^>]*>|(.*))$`)
+
+// LoadTLC reads the error trace printed by the TLC model checker (plain or
+// -tool output) and turns every step of the behaviour into events on the
+// process that took it, as described by m. A step that neither sends nor
+// receives becomes an INTERNAL event. name is recorded as the Source file of
+// every event; the events point at the "State n:" line of the step.
+func LoadTLC(r io.Reader, name string, m TLCMapping) (t.Trace, error) {
+	states, err := readTLCStates(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, fmt.Errorf("no TLC states found")
+	}
+
+	var trace t.Trace
+	inFlight := make(map[string][]int) // message text -> IDs not yet received
+	nextID := 0
+	for i := 1; i < len(states); i++ {
+		prev, cur := states[i-1], states[i]
+		src := &t.Source{File: name, Line: cur.line, Offset: cur.offset}
+
+		actors, err := tlcActors(prev, cur, m.ProcessVar)
+		if err != nil {
+			return nil, fmt.Errorf("state %d: %w", i+1, err)
+		}
+		if len(actors) == 0 {
+			actors = []string{cur.action}
+		}
+		actor := actors[0]
+
+		var sent, received []string
+		if m.MessageVar != "" {
+			if sent, received, err = tlcMessageDiff(prev.vars[m.MessageVar], cur.vars[m.MessageVar]); err != nil {
+				return nil, fmt.Errorf("state %d: %s: %w", i+1, m.MessageVar, err)
+			}
+		}
+
+		for _, msg := range received {
+			e := t.Event{Type: t.EventReceive, Process: actor, MessageID: -1, Source: src}
+			if ids := inFlight[msg]; len(ids) > 0 {
+				e.MessageID, inFlight[msg] = ids[0], ids[1:]
+			} else {
+				// Present in the initial state: nobody in the trace sent it
+				e.Type = t.EventInternal
+			}
+			trace = append(trace, e)
+		}
+		for _, msg := range sent {
+			inFlight[msg] = append(inFlight[msg], nextID)
+			trace = append(trace, t.Event{Type: t.EventSend, Process: actor, MessageID: nextID, Source: src})
+			nextID++
+		}
+		for _, p := range actors {
+			if p == actor && len(sent)+len(received) > 0 {
+				continue
+			}
+			trace = append(trace, t.Event{Type: t.EventInternal, Process: p, MessageID: -1, Source: src})
+		}
+	}
+	return t.ReconstructClocks(trace)
+}
+
+// readTLCStates parses the states of the error trace, skipping TLC's other
+// output, tool-mode markers and the final stuttering or "back to state" line
+// of a liveness counterexample.
+func readTLCStates(r io.Reader) ([]tlcState, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var states []tlcState
+	var cur *tlcState
+	var varName string
+	var offset int64
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		start := offset
+		offset += int64(len(text)) + 1
+		trimmed := strings.TrimSpace(text)
+
+		if m := tlcHeader.FindStringSubmatch(trimmed); m != nil {
+			if m[3] != "" && !strings.HasPrefix(m[3], "<") {
+				cur = nil // "Stuttering", "Back to state 3"
+				continue
+			}
+			states = append(states, tlcState{action: m[2], line: line, offset: start, vars: make(map[string]string)})
+			cur = &states[len(states)-1]
+			varName = ""
+			continue
+		}
+		if cur == nil || trimmed == "" || strings.HasPrefix(trimmed, "@!@!@") {
+			if trimmed == "" {
+				cur = nil
+			}
+			continue
+		}
+
+		if def, ok := strings.CutPrefix(trimmed, "/\\ "); ok {
+			lhs, rhs, found := strings.Cut(def, " = ")
+			if !found {
+				return nil, fmt.Errorf("line %d: expected \"/\\ var = value\"", line)
+			}
+			varName = strings.TrimSpace(lhs)
+			cur.vars[varName] = strings.TrimSpace(rhs)
+		} else if lhs, rhs, found := strings.Cut(trimmed, " = "); found && len(cur.vars) == 0 && !strings.ContainsAny(lhs, " [{(<") {
+			// A state with a single variable has no conjunction bullet
+			varName = lhs
+			cur.vars[varName] = strings.TrimSpace(rhs)
+		} else if varName != "" {
+			cur.vars[varName] += " " + trimmed // continuation of a long value
+		}
+	}
+	return states, sc.Err()
+}
+
+// tlcActors returns the keys of the process variable whose value changed
+// between two states, sorted.
+func tlcActors(prev, cur tlcState, processVar string) ([]string, error) {
+	if processVar == "" {
+		return nil, nil
+	}
+	before, err := parseTLA(prev.vars[processVar])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", processVar, err)
+	}
+	after, err := parseTLA(cur.vars[processVar])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", processVar, err)
+	}
+	old := before.entries()
+	var actors []string
+	for k, v := range after.entries() {
+		if old[k] != v {
+			actors = append(actors, unquoteTLA(k))
+		}
+	}
+	slices.Sort(actors)
+	return actors, nil
+}
+
+// tlcMessageDiff returns the messages that appear and disappear between two
+// values of the network variable, as multisets.
+func tlcMessageDiff(before, after string) (sent, received []string, err error) {
+	b, err := parseTLA(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := parseTLA(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	old, cur := b.multiset(), a.multiset()
+	for _, msg := range sortedKeys(cur) {
+		for range cur[msg] - old[msg] {
+			sent = append(sent, msg)
+		}
+	}
+	for _, msg := range sortedKeys(old) {
+		for range old[msg] - cur[msg] {
+			received = append(received, msg)
+		}
+	}
+	return sent, received, nil
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// tlaValue is a parsed TLA+ value as printed by TLC.
+type tlaValue struct {
+	kind  byte // 'a'tom, 's'et, 'q' sequence, 'f'unction or record
+	atom  string
+	elems []tlaValue
+	keys  []tlaValue // function keys, parallel to elems
+}
+
+func (v tlaValue) String() string {
+	parts := make([]string, len(v.elems))
+	for i, e := range v.elems {
+		parts[i] = e.String()
+		if v.kind == 'f' {
+			parts[i] = v.keys[i].String() + " :> " + parts[i]
+		}
+	}
+	switch v.kind {
+	case 's':
+		return "{" + strings.Join(parts, ", ") + "}"
+	case 'q':
+		return "<<" + strings.Join(parts, ", ") + ">>"
+	case 'f':
+		return "(" + strings.Join(parts, " @@ ") + ")"
+	default:
+		return v.atom
+	}
+}
+
+// entries maps the keys of a function, record or sequence to their values.
+func (v tlaValue) entries() map[string]string {
+	out := make(map[string]string)
+	for i, e := range v.elems {
+		switch v.kind {
+		case 'f':
+			out[v.keys[i].String()] = e.String()
+		case 'q':
+			out[fmt.Sprint(i+1)] = e.String()
+		}
+	}
+	return out
+}
+
+// multiset counts the messages of a set, sequence or bag.
+func (v tlaValue) multiset() map[string]int {
+	out := make(map[string]int)
+	for i, e := range v.elems {
+		if v.kind == 'f' {
+			// A bag maps each message to its number of copies
+			n := 0
+			fmt.Sscan(e.atom, &n)
+			out[v.keys[i].String()] += n
+		} else {
+			out[e.String()]++
+		}
+	}
+	return out
+}
+
+func unquoteTLA(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+var tlaToken = regexp.MustCompile(`\s*("(?:[^"\\]|\\.)*"|\|->|:>|@@|<<|>>|[{}\[\](),]|[^\s{}\[\](),"<>|:@]+|.)`)
+
+// parseTLA parses a value printed by TLC. An empty string is the empty set.
+func parseTLA(s string) (tlaValue, error) {
+	var toks []string
+	for _, m := range tlaToken.FindAllStringSubmatch(s, -1) {
+		toks = append(toks, m[1])
+	}
+	if len(toks) == 0 {
+		return tlaValue{kind: 's'}, nil
+	}
+	p := tlaParser{toks: toks}
+	v, err := p.value()
+	if err == nil && p.pos < len(toks) {
+		err = fmt.Errorf("unexpected %q", toks[p.pos])
+	}
+	return v, err
+}
+
+type tlaParser struct {
+	toks []string
+	pos  int
+}
+
+func (p *tlaParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *tlaParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *tlaParser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		return fmt.Errorf("expected %q, got %q", tok, got)
+	}
+	return nil
+}
+
+func (p *tlaParser) value() (tlaValue, error) {
+	switch tok := p.next(); tok {
+	case "{":
+		v := tlaValue{kind: 's'}
+		err := p.list("}", func() error {
+			e, err := p.value()
+			v.elems = append(v.elems, e)
+			return err
+		})
+		return v, err
+	case "<<":
+		v := tlaValue{kind: 'q'}
+		err := p.list(">>", func() error {
+			e, err := p.value()
+			v.elems = append(v.elems, e)
+			return err
+		})
+		return v, err
+	case "[":
+		// Record: [field |-> value, ...]
+		v := tlaValue{kind: 'f'}
+		err := p.list("]", func() error {
+			k := tlaValue{kind: 'a', atom: `"` + p.next() + `"`}
+			if err := p.expect("|->"); err != nil {
+				return err
+			}
+			e, err := p.value()
+			v.keys, v.elems = append(v.keys, k), append(v.elems, e)
+			return err
+		})
+		return v, err
+	case "(":
+		// Function: (k1 :> v1 @@ k2 :> v2), or a parenthesised value
+		v := tlaValue{kind: 'f'}
+		for {
+			k, err := p.value()
+			if err != nil {
+				return v, err
+			}
+			if p.peek() == ")" && len(v.elems) == 0 {
+				p.next()
+				return k, nil
+			}
+			if err := p.expect(":>"); err != nil {
+				return v, err
+			}
+			e, err := p.value()
+			if err != nil {
+				return v, err
+			}
+			v.keys, v.elems = append(v.keys, k), append(v.elems, e)
+			switch p.next() {
+			case "@@":
+			case ")":
+				return v, nil
+			default:
+				return v, fmt.Errorf("expected @@ or ) in function")
+			}
+		}
+	case "", "}", ">>", "]", ")", ",", "|->", ":>", "@@":
+		return tlaValue{}, fmt.Errorf("unexpected %q", tok)
+	default:
+		return tlaValue{kind: 'a', atom: tok}, nil
+	}
+}
+
+// list parses comma separated items up to the closing token.
+func (p *tlaParser) list(end string, item func() error) error {
+	if p.peek() == end {
+		p.next()
+		return nil
+	}
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		switch tok := p.next(); tok {
+		case ",":
+		case end:
+			return nil
+		default:
+			return fmt.Errorf("expected , or %s, got %q", end, tok)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package formats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/traces/dag"
+	t "github.com/traces/types"
+)
+
+// vcdSignals are the signals SaveVCD gives every process, with their widths.
+var vcdSignals = []struct {
+	name  string
+	kind  string
+	width int
+}{
+	{"up", "wire", 1},
+	{"event", "wire", 1},
+	{"send", "wire", 1},
+	{"recv", "wire", 1},
+	{"type", "wire", 3},
+	{"msg", "wire", 32},
+	{"clock", "integer", 32},
+}
+
+// SaveVCD writes a trace as a value change dump, the waveform format of
+// logic analyzers, for viewers such as GTKWave. Time is the causal rank of
+// the events, their depth in the reduced DAG as in ExtractFeatures, so
+// events at the same time are concurrent. Each rank spans two time units:
+// strobes rise at the first and fall at the second. Every process is a
+// scope with these signals:
+//
+//	up     1 from the first event of the process until it crashes, and again once it recovers
+//	event  strobe at every event
+//	send   strobe at every SEND
+//	recv   strobe at every RECV
+//	type   type of the last event, numbered as types.EventType
+//	msg    message ID of the last SEND or RECV
+//	clock  entry of the process in the clock of its last event
+func SaveVCD(w io.Writer, trace t.Trace) error {
+	var processes []string
+	for _, e := range trace {
+		if !slices.Contains(processes, e.Process) {
+			processes = append(processes, e.Process)
+		}
+	}
+	slices.Sort(processes)
+
+	// ids[p][k] is the identifier code of signal k of process p
+	ids := make(map[string][]string, len(processes))
+	n := 0
+	for _, p := range processes {
+		for range vcdSignals {
+			ids[p] = append(ids[p], vcdID(n))
+			n++
+		}
+	}
+
+	b := bufio.NewWriter(w)
+	b.WriteString("$version trace export $end\n")
+	b.WriteString("$comment time is the causal rank of events, two units per rank $end\n")
+	b.WriteString("$timescale 1ns $end\n")
+	b.WriteString("$scope module trace $end\n")
+	for _, p := range processes {
+		fmt.Fprintf(b, "$scope module %s $end\n", vcdName(p))
+		for k, s := range vcdSignals {
+			fmt.Fprintf(b, "$var %s %d %s %s $end\n", s.kind, s.width, ids[p][k], s.name)
+		}
+		b.WriteString("$upscope $end\n")
+	}
+	b.WriteString("$upscope $end\n")
+	b.WriteString("$enddefinitions $end\n")
+
+	value := func(k, v int, id string) {
+		if vcdSignals[k].width == 1 {
+			fmt.Fprintf(b, "%d%s\n", v, id)
+		} else {
+			fmt.Fprintf(b, "b%s %s\n", strconv.FormatUint(uint64(uint32(v)), 2), id)
+		}
+	}
+	b.WriteString("#0\n$dumpvars\n")
+	for _, p := range processes {
+		for k := range vcdSignals {
+			value(k, 0, ids[p][k])
+		}
+	}
+	b.WriteString("$end\n")
+
+	depth := depths(trace, dag.NewIndex(trace))
+	ranks := make(map[int][]int)
+	last := 0
+	for i := range trace {
+		ranks[depth[i]] = append(ranks[depth[i]], i)
+		last = max(last, depth[i])
+	}
+	up := make(map[string]bool)
+	for r := 0; r <= last; r++ {
+		if len(ranks[r]) == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "#%d\n", 2*r+1)
+		for _, i := range ranks[r] {
+			e := trace[i]
+			id := ids[e.Process]
+			if alive := e.Type != t.EventCrash; alive != up[e.Process] {
+				up[e.Process] = alive
+				value(0, oneBit(alive), id[0])
+			}
+			value(1, 1, id[1])
+			switch e.Type {
+			case t.EventSend:
+				value(2, 1, id[2])
+			case t.EventReceive:
+				value(3, 1, id[3])
+			}
+			value(4, int(e.Type), id[4])
+			if e.Type.IsMessage() {
+				value(5, e.MessageID, id[5])
+			}
+			value(6, e.VClock[e.Process], id[6])
+		}
+		fmt.Fprintf(b, "#%d\n", 2*r+2)
+		for _, i := range ranks[r] {
+			e := trace[i]
+			id := ids[e.Process]
+			value(1, 0, id[1])
+			switch e.Type {
+			case t.EventSend:
+				value(2, 0, id[2])
+			case t.EventReceive:
+				value(3, 0, id[3])
+			}
+		}
+	}
+	return b.Flush()
+}
+
+func oneBit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// vcdID returns the n-th identifier code, written in base 94 with the
+// printable ASCII characters as digits.
+func vcdID(n int) string {
+	var id []byte
+	for {
+		id = append(id, byte('!'+n%94))
+		n /= 94
+		if n == 0 {
+			return string(id)
+		}
+		n--
+	}
+}
+
+// vcdName makes a process name a VCD scope name, which may not be empty or
+// contain whitespace.
+func vcdName(p string) string {
+	if p == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, p)
+}
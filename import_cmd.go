@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/traces/formats"
+	t "github.com/traces/types"
+)
+
+// runImport implements `trace import`: it converts a foreign trace format
+// into the JSON trace format.
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	in := fs.String("in", "", "file to import")
+	format := fs.String("format", "csv", "input format: csv, otlp, jaeger, govector, log, gotrace or tlc")
+	mapping := fs.String("mapping", "", "JSON column mapping for CSV input, JSON parsing rules for log input, or the JSON state mapping for TLC input")
+	logs := fs.String("logs", "", "OTLP/JSON logs whose records to add to an otlp trace as labelled INTERNAL events")
+	out := fs.String("out", "", "write the trace to this file instead of stdout")
+	reconstruct := fs.Bool("reconstruct", false, "recompute vector clocks from process order and message IDs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	stamp.AddInput(*in)
+	f, err := t.Open(*in)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var trace t.Trace
+	switch *format {
+	case "csv":
+		m := t.DefaultCSVMapping()
+		if *mapping != "" {
+			if m, err = t.LoadCSVMapping(*mapping); err != nil {
+				return err
+			}
+		}
+		trace, err = t.LoadCSV(f, *in, m)
+	case "otlp":
+		if *logs == "" {
+			trace, err = formats.LoadOTLP(f)
+			break
+		}
+		stamp.AddInput(*logs)
+		var lf io.ReadCloser
+		if lf, err = t.Open(*logs); err != nil {
+			return err
+		}
+		defer lf.Close()
+		trace, err = formats.LoadOTLPLogs(f, lf)
+	case "jaeger":
+		trace, err = formats.LoadJaeger(f)
+	case "govector":
+		trace, err = formats.LoadGoVector(f, *in)
+	case "log":
+		if *mapping == "" {
+			return fmt.Errorf("-format log requires -mapping")
+		}
+		var lf *formats.LogFormat
+		if lf, err = formats.LoadLogFormat(*mapping); err != nil {
+			return err
+		}
+		trace, err = formats.LoadLog(f, *in, lf)
+	case "gotrace":
+		trace, err = loadGoTrace(f, *in)
+	case "tlc":
+		var m formats.TLCMapping
+		if *mapping != "" {
+			if m, err = formats.LoadTLCMapping(*mapping); err != nil {
+				return err
+			}
+		}
+		trace, err = formats.LoadTLC(f, *in, m)
+	default:
+		err = fmt.Errorf("unknown import format %q", *format)
+	}
+	if err == nil && *reconstruct {
+		trace, err = t.ReconstructClocks(trace)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return writeTrace(os.Stdout, trace)
+	}
+	return saveTrace(*out, trace)
+}
+
+// runExport implements `trace export`: it converts a trace into a foreign
+// format.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to export")
+	format := fs.String("format", "jaeger", "output format: jaeger, govector, vcd, features-csv (writes <out>.nodes.csv and <out>.edges.csv) or npz")
+	out := fs.String("out", "", "write to this file or object storage URL instead of stdout")
+	traceID := fs.String("trace-id", "0000000000000001", "trace ID for Jaeger output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	// The encoder is resolved first, so an unknown format leaves -out as it
+	// was
+	var encode func(w io.Writer, trace t.Trace) error
+	switch *format {
+	case "jaeger":
+		encode = func(w io.Writer, trace t.Trace) error { return formats.SaveJaeger(w, trace, *traceID) }
+	case "govector":
+		encode = formats.SaveGoVector
+	case "vcd":
+		encode = formats.SaveVCD
+	case "npz":
+		encode = func(w io.Writer, trace t.Trace) error { return formats.ExtractFeatures(trace).WriteNPZ(w) }
+	case "features-csv":
+		// Two files, written by writeFeatureCSVs
+	default:
+		return fmt.Errorf("unknown export format %q", *format)
+	}
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	if encode == nil {
+		return writeFeatureCSVs(formats.ExtractFeatures(trace), *out)
+	}
+	return writeStamped(*out, func(w io.Writer) error { return encode(w, trace) })
+}
+
+// writeFeatureCSVs writes the node features and edge list next to each other.
+func writeFeatureCSVs(f formats.Features, prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("-out is required for features-csv")
+	}
+	if err := writeStamped(prefix+".nodes.csv", f.WriteNodesCSV); err != nil {
+		return err
+	}
+	return writeStamped(prefix+".edges.csv", f.WriteEdgesCSV)
+}
+
+// loadGoTrace reads either the text dump of `go tool trace -d=parsed` or a
+// binary execution trace, which it converts by running that command. Source
+// lines always refer to the parsed dump.
+func loadGoTrace(r io.Reader, path string) (t.Trace, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(formats.GoTraceMagic))
+	if string(magic) != formats.GoTraceMagic {
+		return formats.LoadGoTrace(br, path)
+	}
+
+	// The input may have been decompressed or reassembled, so hand the tool
+	// a plain copy
+	tmp, err := os.CreateTemp("", "gotrace-*.out")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, br)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", "tool", "trace", "-d=parsed", tmp.Name())
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go tool trace: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return formats.LoadGoTrace(bytes.NewReader(out), path)
+}
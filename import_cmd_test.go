@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/traces/script"
+)
+
+func TestExportUnknownFormatKeepsOutput(t *testing.T) {
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "trace.json"), filepath.Join(dir, "export.out")
+	if err := saveTrace(in, script.MustCompile("A sends to B")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(out, []byte("previous export\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runExport([]string{"-in", in, "-format", "zipkin", "-out", out}); err == nil {
+		t.Fatal("expected an unknown format to be rejected")
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "previous export\n" {
+		t.Errorf("-out was overwritten with %q", data)
+	}
+}
@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"fmt"
+
+	"github.com/traces/dag"
+	t "github.com/traces/types"
+)
+
+// Backfill places a stored trace in the collector ahead of live events,
+// keeping its causal structure: every event merges the clocks of its direct
+// dependencies, as dag.DirectPreds finds them, so orderings from barriers or
+// edge rules survive along with messages. Receives of messages sent before
+// the trace, by an earlier backfill, are stitched to their sends, and the
+// sends are kept so live receives stitch to them in turn. Backfilled events
+// count towards the rolling rates only by their Timestamp, if any.
+//
+// The partners of a rendezvous depend on each other. The earlier ones are
+// placed without the later ones' clocks, and merge them once those are
+// placed, so the partners end up with the same clock as they were recorded.
+func (c *Collector) Backfill(trace t.Trace) error {
+	for i, e := range trace {
+		if e.Process == "" {
+			return fmt.Errorf("event %d without process", i)
+		}
+	}
+	preds := dag.DirectPreds(trace)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	placed := make([]int, len(trace)) // trace index -> global index
+	partners := make(map[int][]int)   // trace index -> earlier partners waiting for it
+	for i, e := range trace {
+		clock, ok := c.clocks[e.Process]
+		if !ok {
+			c.processes = append(c.processes, e.Process)
+			clock = t.NewVectorClock([]string{e.Process})
+			c.clocks[e.Process] = clock
+		}
+		clock[e.Process]++
+		for _, j := range preds(i) {
+			if j >= i {
+				partners[j] = append(partners[j], i) // merged once j is placed
+				continue
+			}
+			clock.Merge(c.trace[placed[j]].VClock)
+		}
+		key := messageKey{e.CorrelationKey, e.MessageID}
+		if e.Type == t.EventReceive {
+			clock.Merge(c.sends[key])
+		}
+
+		e.VClock = t.DeepCopy(clock)
+		for _, j := range partners[i] {
+			// A send's clock is shared with c.sends, which sees the merge too
+			c.trace[placed[j]].VClock.Merge(e.VClock)
+			c.clocks[trace[j].Process].Merge(e.VClock)
+		}
+		switch e.Type {
+		case t.EventSend:
+			c.sends[key] = e.VClock
+			c.open[key] = true
+		case t.EventReceive:
+			delete(c.open, key)
+		}
+		placed[i] = len(c.trace)
+		c.place(e, e.Timestamp)
+	}
+	// Held back receives may have been waiting for a backfilled send
+	c.drain()
+	return nil
+}
@@ -0,0 +1,42 @@
+package ingest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/traces/messages"
+	t "github.com/traces/types"
+)
+
+func TestBackfillRendezvous(tt *testing.T) {
+	trace := messages.Generate(messages.Config{
+		Processes:   []string{"A", "B", "C"},
+		NumEvents:   40,
+		Synchronous: true,
+	}, rand.New(rand.NewSource(1)))
+	c := NewCollector()
+	if err := c.Backfill(trace); err != nil {
+		tt.Fatal(err)
+	}
+	got := c.Trace()
+	sends := make(map[int]int)
+	for i, e := range trace {
+		if e.Type == t.EventSend {
+			sends[e.MessageID] = i
+		}
+	}
+	checked := 0
+	for i, e := range trace {
+		s, ok := sends[e.MessageID]
+		if e.Type != t.EventReceive || !ok {
+			continue
+		}
+		checked++
+		if got[s].VClock.Key() != got[i].VClock.Key() {
+			tt.Errorf("Msg-%d: send e-%d has clock %s, receive e-%d has %s", e.MessageID, s, got[s].VClock, i, got[i].VClock)
+		}
+	}
+	if checked == 0 {
+		tt.Fatal("no rendezvous generated")
+	}
+}
@@ -0,0 +1,155 @@
+package ingest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	t "github.com/traces/types"
+)
+
+// messageKey identifies a message; IDs are unique per correlation key.
+type messageKey struct {
+	correlationKey string
+	messageID      int
+}
+
+// Collector assembles a global trace from events streamed by remote
+// processes, assigning vector clocks as events arrive. Processes may join at
+// any time. A receive that arrives before its send is held back, together
+// with every later event of the same process, until the send shows up. It is
+// safe for concurrent use.
+type Collector struct {
+	mu        sync.Mutex
+	processes []string
+	clocks    map[string]t.VectorClock
+	sends     map[messageKey]t.VectorClock
+	backlog   map[string][]t.Event // per process, events waiting on a send
+	trace     t.Trace
+	arrived   []time.Time         // when each event of trace was placed
+	open      map[messageKey]bool // sends not received yet
+	now       func() time.Time
+
+	// Rolling state of Metrics, kept up to date as events are placed so
+	// that reading it never rescans the trace
+	latest map[string]int // process -> index of its last event in trace
+	recent map[string]int // per process, events at or after trace[first]
+	first  int            // oldest event that may be in the window
+	window time.Duration  // of the last Metrics call
+}
+
+// NewCollector returns an empty collector.
+func NewCollector() *Collector {
+	return &Collector{
+		clocks:  make(map[string]t.VectorClock),
+		sends:   make(map[messageKey]t.VectorClock),
+		backlog: make(map[string][]t.Event),
+		open:    make(map[messageKey]bool),
+		now:     time.Now,
+		latest:  make(map[string]int),
+		recent:  make(map[string]int),
+	}
+}
+
+// Add ingests one event. Its VClock, if any, is ignored.
+func (c *Collector) Add(e t.Event) error {
+	if e.Process == "" {
+		return fmt.Errorf("event without process")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.clocks[e.Process]; !ok {
+		c.processes = append(c.processes, e.Process)
+		c.clocks[e.Process] = t.NewVectorClock([]string{e.Process})
+	}
+	c.backlog[e.Process] = append(c.backlog[e.Process], e)
+	c.drain()
+	return nil
+}
+
+// drain applies every backlogged event whose dependencies are satisfied.
+func (c *Collector) drain() {
+	for progressed := true; progressed; {
+		progressed = false
+		for _, p := range c.processes {
+			for len(c.backlog[p]) > 0 && c.apply(c.backlog[p][0]) {
+				c.backlog[p] = c.backlog[p][1:]
+				progressed = true
+			}
+		}
+	}
+}
+
+// apply stamps and records e, or reports false if e is a receive whose send
+// has not arrived yet.
+func (c *Collector) apply(e t.Event) bool {
+	key := messageKey{e.CorrelationKey, e.MessageID}
+	sendClock, sent := c.sends[key]
+	if e.Type == t.EventReceive && !sent {
+		return false
+	}
+
+	clock := c.clocks[e.Process]
+	clock[e.Process]++
+	if e.Type == t.EventReceive {
+		clock.Merge(sendClock)
+	}
+	e.VClock = t.DeepCopy(clock)
+	switch e.Type {
+	case t.EventSend:
+		c.sends[key] = e.VClock
+		c.open[key] = true
+	case t.EventReceive:
+		delete(c.open, key)
+	}
+	c.place(e, c.now())
+	return true
+}
+
+// place appends a stamped event to the trace, placed at the given time.
+func (c *Collector) place(e t.Event, at time.Time) {
+	c.latest[e.Process] = len(c.trace)
+	c.recent[e.Process]++
+	c.trace = append(c.trace, e)
+	c.arrived = append(c.arrived, at)
+}
+
+// Len returns the number of events placed in the global trace.
+func (c *Collector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.trace)
+}
+
+// Pending returns the number of events held back waiting for a send.
+func (c *Collector) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, events := range c.backlog {
+		n += len(events)
+	}
+	return n
+}
+
+// Trace returns a copy of the global trace assembled so far.
+func (c *Collector) Trace() t.Trace {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append(t.Trace(nil), c.trace...)
+}
+
+// HappensBefore reports whether event a happens before event b, and whether
+// they are concurrent, by their index in the global trace.
+func (c *Collector) HappensBefore(a, b int) (before, concurrent bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if a < 0 || a >= len(c.trace) || b < 0 || b >= len(c.trace) {
+		return false, false, fmt.Errorf("event index out of range [0, %d)", len(c.trace))
+	}
+	va, vb := c.trace[a].VClock, c.trace[b].VClock
+	before = va.HappensBefore(vb)
+	concurrent = a != b && !before && !vb.HappensBefore(va)
+	return before, concurrent, nil
+}
@@ -0,0 +1,204 @@
+package ingest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/traces/internal/wire"
+	t "github.com/traces/types"
+)
+
+// gRPC status codes used by the server.
+const (
+	codeOK                = 0
+	codeInvalidArgument   = 3
+	codeResourceExhausted = 8
+	codeUnimplemented     = 12
+)
+
+// Server serves the Ingest service of ingest.proto over gRPC's HTTP/2
+// framing, implemented on net/http so no gRPC runtime is needed. Serve it
+// with unencrypted HTTP/2 enabled (see NewHTTPServer) or over TLS.
+type Server struct {
+	Collector *Collector
+}
+
+// NewHTTPServer returns an http.Server on addr accepting cleartext HTTP/2
+// (h2c prior knowledge), as used by gRPC clients without TLS.
+func NewHTTPServer(addr string, c *Collector) *http.Server {
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Server{Addr: addr, Handler: &Server{Collector: c}, Protocols: &protocols}
+}
+
+type grpcError struct {
+	code int
+	msg  string
+}
+
+func (e *grpcError) Error() string { return e.msg }
+
+func invalid(format string, args ...any) error {
+	return &grpcError{code: codeInvalidArgument, msg: fmt.Sprintf(format, args...)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "gRPC requires POST", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc+proto")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+
+	var err error
+	switch r.URL.Path {
+	case "/traces.v1.Ingest/StreamEvents":
+		err = s.streamEvents(w, r.Body)
+	case "/traces.v1.Ingest/HappensBefore":
+		err = s.happensBefore(w, r.Body)
+	default:
+		err = &grpcError{code: codeUnimplemented, msg: "unknown method " + r.URL.Path}
+	}
+
+	code, msg := codeOK, ""
+	var ge *grpcError
+	if errors.As(err, &ge) {
+		code, msg = ge.code, ge.msg
+	} else if err != nil {
+		code, msg = codeInvalidArgument, err.Error()
+	}
+	w.Header().Set("Grpc-Status", fmt.Sprint(code))
+	w.Header().Set("Grpc-Message", msg)
+}
+
+// MaxMessageSize is the largest gRPC message the server accepts, gRPC's
+// default receive limit.
+const MaxMessageSize = 4 << 20
+
+// readFrame reads one length-prefixed gRPC message; io.EOF ends the stream.
+// Messages over MaxMessageSize are refused before any buffer is allocated.
+func readFrame(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, invalid("compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > MaxMessageSize {
+		return nil, &grpcError{code: codeResourceExhausted, msg: fmt.Sprintf("message of %d bytes exceeds the limit of %d", size, MaxMessageSize)}
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeFrame(w io.Writer, msg []byte) error {
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(msg)))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+func (s *Server) streamEvents(w io.Writer, body io.Reader) error {
+	for {
+		msg, err := readFrame(body)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		e, err := decodeIngestEvent(msg)
+		if err != nil {
+			return invalid("event: %v", err)
+		}
+		if err := s.Collector.Add(e); err != nil {
+			return invalid("%v", err)
+		}
+	}
+
+	var summary wire.Buffer
+	summary.Uvarint(1, uint64(s.Collector.Len()))
+	summary.Uvarint(2, uint64(s.Collector.Pending()))
+	return writeFrame(w, summary)
+}
+
+func decodeIngestEvent(msg []byte) (t.Event, error) {
+	fs, err := wire.Fields(msg)
+	if err != nil {
+		return t.Event{}, err
+	}
+	var e t.Event
+	for _, f := range fs {
+		switch f.Num {
+		case 1:
+			e.Process = string(f.Data)
+		case 2:
+			e.Type = t.EventType(f.Value)
+		case 3:
+			e.MessageID = int(wire.Unzigzag(f.Value))
+		case 4:
+			e.CorrelationKey = string(f.Data)
+		case 5:
+			entry, err := wire.Fields(f.Data)
+			if err != nil {
+				return t.Event{}, fmt.Errorf("labels: %w", err)
+			}
+			var k, v string
+			for _, ef := range entry {
+				switch ef.Num {
+				case 1:
+					k = string(ef.Data)
+				case 2:
+					v = string(ef.Data)
+				}
+			}
+			if e.Labels == nil {
+				e.Labels = make(map[string]string)
+			}
+			e.Labels[k] = v
+		}
+	}
+	return e, nil
+}
+
+func (s *Server) happensBefore(w io.Writer, body io.Reader) error {
+	msg, err := readFrame(body)
+	if err != nil {
+		return invalid("reading request: %v", err)
+	}
+	fs, err := wire.Fields(msg)
+	if err != nil {
+		return invalid("request: %v", err)
+	}
+	var a, b uint64
+	for _, f := range fs {
+		switch f.Num {
+		case 1:
+			a = f.Value
+		case 2:
+			b = f.Value
+		}
+	}
+
+	before, concurrent, err := s.Collector.HappensBefore(int(a), int(b))
+	if err != nil {
+		return invalid("%v", err)
+	}
+	var resp wire.Buffer
+	resp.Bool(1, before)
+	resp.Bool(2, concurrent)
+	return writeFrame(w, resp)
+}
@@ -0,0 +1,66 @@
+package ingest
+
+import "time"
+
+// LiveMetrics are rolling metrics of the events a collector placed recently.
+type LiveMetrics struct {
+	Window          time.Duration      `json:"-"`
+	WindowSeconds   float64            `json:"window_seconds"`
+	Events          int                `json:"events"`         // placed in total
+	EventsPerSecond map[string]float64 `json:"events_per_sec"` // per process, over the window
+	// Width is the number of processes whose latest event no other process
+	// has heard of yet, the width of the trace's current frontier.
+	Width        int `json:"width"`
+	OpenMessages int `json:"open_messages"` // sent but not yet received
+	Pending      int `json:"pending"`       // held back waiting for a send
+}
+
+// Metrics returns the rolling metrics over the last window. The counts of
+// the window are kept as events are placed, so a call only drops the events
+// that left the window since the last one, unless window changed.
+func (c *Collector) Metrics(window time.Duration) LiveMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := LiveMetrics{
+		Window:          window,
+		WindowSeconds:   window.Seconds(),
+		Events:          len(c.trace),
+		EventsPerSecond: make(map[string]float64),
+		OpenMessages:    len(c.open),
+	}
+	for _, events := range c.backlog {
+		m.Pending += len(events)
+	}
+
+	if window != c.window {
+		// Count afresh: a longer window may hold events already dropped
+		c.window, c.first = window, 0
+		clear(c.recent)
+		for _, e := range c.trace {
+			c.recent[e.Process]++
+		}
+	}
+	since := c.now().Add(-window)
+	for ; c.first < len(c.trace) && !c.arrived[c.first].After(since); c.first++ {
+		p := c.trace[c.first].Process
+		if c.recent[p]--; c.recent[p] == 0 {
+			delete(c.recent, p)
+		}
+	}
+	for p, n := range c.recent {
+		m.EventsPerSecond[p] = float64(n) / max(window.Seconds(), 1e-9)
+	}
+	for p, i := range c.latest {
+		frontier := true
+		for q, j := range c.latest {
+			if p != q && c.trace[i].VClock.HappensBefore(c.trace[j].VClock) {
+				frontier = false
+				break
+			}
+		}
+		if frontier {
+			m.Width++
+		}
+	}
+	return m
+}
@@ -0,0 +1,45 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	t "github.com/traces/types"
+)
+
+func TestMetricsWindow(tt *testing.T) {
+	c := NewCollector()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	add := func(p string) {
+		if err := c.Add(t.Event{Type: t.EventInternal, Process: p}); err != nil {
+			tt.Fatal(err)
+		}
+	}
+	rate := func(window time.Duration, p string) float64 {
+		return c.Metrics(window).EventsPerSecond[p] * window.Seconds()
+	}
+
+	add("A")
+	add("B")
+	now = now.Add(5 * time.Second)
+	add("A")
+	if n := rate(10*time.Second, "A"); n != 2 {
+		tt.Errorf("A has %g events in the window, want 2", n)
+	}
+	now = now.Add(6 * time.Second)
+	add("B")
+	if n := rate(10*time.Second, "A"); n != 1 {
+		tt.Errorf("A has %g events in the window after 11s, want 1", n)
+	}
+	if n := rate(10*time.Second, "B"); n != 1 {
+		tt.Errorf("B has %g events in the window after 11s, want 1", n)
+	}
+	// A longer window counts the events a shorter one dropped
+	if n := rate(time.Minute, "A"); n != 2 {
+		tt.Errorf("A has %g events in a minute, want 2", n)
+	}
+	if m := c.Metrics(time.Minute); m.Events != 4 || m.Width != 2 {
+		tt.Errorf("%d events of width %d, want 4 of width 2", m.Events, m.Width)
+	}
+}
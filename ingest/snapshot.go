@@ -0,0 +1,104 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	t "github.com/traces/types"
+)
+
+// snapshotVersion is the version of the snapshot format written by Snapshot.
+const snapshotVersion = 1
+
+// snapshot is the state of a collector on disk. The clocks of the processes
+// and the messages sent are not stored: they follow from the trace.
+type snapshot struct {
+	Version   int             `json:"version"`
+	Processes []string        `json:"processes"`
+	Trace     json.RawMessage `json:"trace"`   // placed events, as written by types.Save
+	Arrived   []time.Time     `json:"arrived"` // when each event of Trace was placed
+	Backlog   json.RawMessage `json:"backlog"` // events held back, per process in arrival order
+}
+
+// Snapshot writes the state of the collector as JSON: the trace assembled so
+// far and the events held back waiting for a send. RestoreCollector resumes
+// from it, so a restarted service neither loses nor renumbers events.
+func (c *Collector) Snapshot(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var backlog t.Trace
+	for _, p := range c.processes {
+		backlog = append(backlog, c.backlog[p]...)
+	}
+	var tr, bl bytes.Buffer
+	if err := t.Save(&tr, c.trace); err != nil {
+		return err
+	}
+	if err := t.Save(&bl, backlog); err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(snapshot{
+		Version:   snapshotVersion,
+		Processes: c.processes,
+		Trace:     tr.Bytes(),
+		Arrived:   c.arrived,
+		Backlog:   bl.Bytes(),
+	})
+}
+
+// RestoreCollector returns a collector in the state written by Snapshot.
+func RestoreCollector(r io.Reader) (*Collector, error) {
+	var s snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	trace, err := t.Load(bytes.NewReader(s.Trace))
+	if err != nil {
+		return nil, fmt.Errorf("snapshot trace: %w", err)
+	}
+	backlog, err := t.Load(bytes.NewReader(s.Backlog))
+	if err != nil {
+		return nil, fmt.Errorf("snapshot backlog: %w", err)
+	}
+	if len(s.Arrived) != len(trace) {
+		return nil, fmt.Errorf("snapshot has %d arrival times for %d events", len(s.Arrived), len(trace))
+	}
+
+	c := NewCollector()
+	for _, p := range s.Processes {
+		c.processes = append(c.processes, p)
+		c.clocks[p] = t.NewVectorClock([]string{p})
+	}
+	for i, e := range trace {
+		if _, ok := c.clocks[e.Process]; !ok {
+			return nil, fmt.Errorf("snapshot event of unknown process %q", e.Process)
+		}
+		c.clocks[e.Process] = t.DeepCopy(e.VClock)
+		c.latest[e.Process] = i
+		key := messageKey{e.CorrelationKey, e.MessageID}
+		switch e.Type {
+		case t.EventSend:
+			c.sends[key] = e.VClock
+			c.open[key] = true
+		case t.EventReceive:
+			delete(c.open, key)
+		}
+	}
+	for _, e := range backlog {
+		if _, ok := c.clocks[e.Process]; !ok {
+			return nil, fmt.Errorf("snapshot event of unknown process %q", e.Process)
+		}
+		c.backlog[e.Process] = append(c.backlog[e.Process], e)
+	}
+	// The window counts are rebuilt by the first Metrics call
+	c.trace = trace
+	c.arrived = s.Arrived
+	return c, nil
+}
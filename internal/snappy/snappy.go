@@ -0,0 +1,86 @@
+// Package snappy decodes the Snappy block format, the default compression of
+// Parquet files written by most data-science tools.
+package snappy
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errCorrupt = errors.New("snappy: corrupt input")
+
+// Decode returns the decompressed form of a Snappy block.
+func Decode(src []byte) ([]byte, error) {
+	n, k := binary.Uvarint(src)
+	if k <= 0 || n > 1<<32 {
+		return nil, errCorrupt
+	}
+	src = src[k:]
+	// The declared length is not trusted with an allocation: no element
+	// expands more than 64 bytes from 3, so larger blocks are corrupt
+	if n > 22*uint64(len(src)) {
+		return nil, errCorrupt
+	}
+	dst := make([]byte, 0, n)
+
+	for len(src) > 0 {
+		tag := src[0]
+		var length, offset int
+		switch tag & 3 {
+		case 0: // literal
+			length = int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				extra := length - 59
+				if len(src) < extra {
+					return nil, errCorrupt
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[i])
+				}
+				src = src[extra:]
+			}
+			length++
+			if length > len(src) {
+				return nil, errCorrupt
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case 1: // copy with 1-byte offset
+			if len(src) < 2 {
+				return nil, errCorrupt
+			}
+			length = 4 + int(tag>>2)&7
+			offset = int(tag>>5)<<8 | int(src[1])
+			src = src[2:]
+		case 2: // copy with 2-byte offset
+			if len(src) < 3 {
+				return nil, errCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+		case 3: // copy with 4-byte offset
+			if len(src) < 5 {
+				return nil, errCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+		if offset <= 0 || offset > len(dst) || uint64(len(dst)+length) > n {
+			return nil, errCorrupt
+		}
+		// Copies may overlap their own output, so go byte by byte
+		start := len(dst) - offset
+		for i := range length {
+			dst = append(dst, dst[start+i])
+		}
+	}
+	if uint64(len(dst)) != n {
+		return nil, errCorrupt
+	}
+	return dst, nil
+}
@@ -0,0 +1,14 @@
+package snappy
+
+import "testing"
+
+func TestDecodeDeclaredLength(tt *testing.T) {
+	// A 4 GiB block declared in six bytes
+	if _, err := Decode([]byte{0x80, 0x80, 0x80, 0x80, 0x10, 0}); err == nil {
+		tt.Error("block declaring more than its input can hold accepted")
+	}
+	got, err := Decode([]byte{5, 4 << 2, 'h', 'e', 'l', 'l', 'o'})
+	if err != nil || string(got) != "hello" {
+		tt.Errorf("decoded %q, %v", got, err)
+	}
+}
@@ -0,0 +1,282 @@
+// Package thrift implements the subset of the Thrift compact protocol used by
+// the Parquet file format: structs of integers, binaries, lists and nested
+// structs.
+package thrift
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Compact protocol type codes.
+const (
+	True   = 1
+	False  = 2
+	Byte   = 3
+	I16    = 4
+	I32    = 5
+	I64    = 6
+	Double = 7
+	Binary = 8
+	List   = 9
+	Set    = 10
+	Map    = 11
+	Struct = 12
+)
+
+// Writer encodes one struct. Fields must be written in ascending id order.
+type Writer struct {
+	b    []byte
+	last int
+}
+
+func (w *Writer) header(id int, typ byte) {
+	if delta := id - w.last; delta > 0 && delta <= 15 {
+		w.b = append(w.b, byte(delta)<<4|typ)
+	} else {
+		w.b = append(w.b, typ)
+		w.b = binary.AppendVarint(w.b, int64(id))
+	}
+	w.last = id
+}
+
+// I32 appends an i32 field.
+func (w *Writer) I32(id int, v int32) {
+	w.header(id, I32)
+	w.b = binary.AppendVarint(w.b, int64(v))
+}
+
+// I64 appends an i64 field.
+func (w *Writer) I64(id int, v int64) {
+	w.header(id, I64)
+	w.b = binary.AppendVarint(w.b, v)
+}
+
+// String appends a binary field.
+func (w *Writer) String(id int, s string) {
+	w.header(id, Binary)
+	w.b = binary.AppendUvarint(w.b, uint64(len(s)))
+	w.b = append(w.b, s...)
+}
+
+// Struct appends a nested struct field.
+func (w *Writer) Struct(id int, s *Writer) {
+	w.header(id, Struct)
+	w.b = append(w.b, s.Bytes()...)
+}
+
+func (w *Writer) list(id int, typ byte, n int) {
+	w.header(id, List)
+	if n < 15 {
+		w.b = append(w.b, byte(n)<<4|typ)
+	} else {
+		w.b = append(w.b, 0xf0|typ)
+		w.b = binary.AppendUvarint(w.b, uint64(n))
+	}
+}
+
+// I32List appends a list<i32> field.
+func (w *Writer) I32List(id int, vs []int32) {
+	w.list(id, I32, len(vs))
+	for _, v := range vs {
+		w.b = binary.AppendVarint(w.b, int64(v))
+	}
+}
+
+// StringList appends a list<binary> field.
+func (w *Writer) StringList(id int, vs []string) {
+	w.list(id, Binary, len(vs))
+	for _, s := range vs {
+		w.b = binary.AppendUvarint(w.b, uint64(len(s)))
+		w.b = append(w.b, s...)
+	}
+}
+
+// StructList appends a list<struct> field.
+func (w *Writer) StructList(id int, vs []*Writer) {
+	w.list(id, Struct, len(vs))
+	for _, s := range vs {
+		w.b = append(w.b, s.Bytes()...)
+	}
+}
+
+// Bytes returns the encoded struct, including its stop byte.
+func (w *Writer) Bytes() []byte {
+	return append(w.b[:len(w.b):len(w.b)], 0)
+}
+
+// Value is one decoded value. Integers, booleans and the bits of doubles
+// are held in Int, binaries in Bin, lists and sets in List and structs in Fields.
+type Value struct {
+	Type   byte
+	Int    int64
+	Bin    []byte
+	List   []Value
+	Fields map[int]Value
+}
+
+// Field returns the integer value of a struct field and whether it was set.
+func (v Value) Field(id int) (int64, bool) {
+	f, ok := v.Fields[id]
+	return f.Int, ok
+}
+
+// Decode reads one struct from data and returns it with the number of bytes
+// it occupied. Maps are skipped.
+func Decode(data []byte) (Value, int, error) {
+	d := decoder{data: data}
+	v, err := d.structValue(0)
+	return v, d.pos, err
+}
+
+// maxDepth bounds nesting so hostile input cannot exhaust the stack.
+const maxDepth = 64
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+var errTruncated = errors.New("thrift: truncated input")
+
+func (d *decoder) byte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errTruncated
+	}
+	d.pos++
+	return d.data[d.pos-1], nil
+}
+
+func (d *decoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *decoder) varint() (int64, error) {
+	v, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *decoder) structValue(depth int) (Value, error) {
+	if depth > maxDepth {
+		return Value{}, errors.New("thrift: nesting too deep")
+	}
+	v := Value{Type: Struct, Fields: make(map[int]Value)}
+	last := 0
+	for {
+		h, err := d.byte()
+		if err != nil {
+			return v, err
+		}
+		if h == 0 {
+			return v, nil
+		}
+		typ, id := h&0x0f, last+int(h>>4)
+		if h>>4 == 0 {
+			long, err := d.varint()
+			if err != nil {
+				return v, err
+			}
+			id = int(long)
+		}
+		last = id
+
+		var f Value
+		switch typ {
+		case True, False:
+			f = Value{Type: typ, Int: int64(2 - typ)}
+		default:
+			if f, err = d.value(typ, depth); err != nil {
+				return v, fmt.Errorf("field %d: %w", id, err)
+			}
+		}
+		v.Fields[id] = f
+	}
+}
+
+func (d *decoder) value(typ byte, depth int) (Value, error) {
+	v := Value{Type: typ}
+	var err error
+	switch typ {
+	case True, False:
+		// Inside lists booleans take a byte of their own
+		var b byte
+		b, err = d.byte()
+		v.Int = int64(b & 1)
+	case Byte:
+		var b byte
+		b, err = d.byte()
+		v.Int = int64(int8(b))
+	case I16, I32, I64:
+		v.Int, err = d.varint()
+	case Double:
+		if d.pos+8 > len(d.data) {
+			return v, errTruncated
+		}
+		v.Int = int64(binary.LittleEndian.Uint64(d.data[d.pos:])) // raw IEEE 754 bits
+		d.pos += 8
+	case Binary:
+		var n uint64
+		if n, err = d.uvarint(); err != nil {
+			return v, err
+		}
+		if n > uint64(len(d.data)-d.pos) {
+			return v, errTruncated
+		}
+		v.Bin = d.data[d.pos : d.pos+int(n)]
+		d.pos += int(n)
+	case List, Set:
+		var h byte
+		if h, err = d.byte(); err != nil {
+			return v, err
+		}
+		n, elem := uint64(h>>4), h&0x0f
+		if n == 15 {
+			if n, err = d.uvarint(); err != nil {
+				return v, err
+			}
+		}
+		if n > uint64(len(d.data)-d.pos) {
+			return v, errTruncated // every element takes at least one byte
+		}
+		v.List = make([]Value, 0, n)
+		for range n {
+			var e Value
+			if e, err = d.value(elem, depth+1); err != nil {
+				return v, err
+			}
+			v.List = append(v.List, e)
+		}
+	case Map:
+		var n uint64
+		if n, err = d.uvarint(); err != nil || n == 0 {
+			return v, err
+		}
+		var kv byte
+		if kv, err = d.byte(); err != nil {
+			return v, err
+		}
+		for range n {
+			if _, err = d.value(kv>>4, depth+1); err != nil {
+				return v, err
+			}
+			if _, err = d.value(kv&0x0f, depth+1); err != nil {
+				return v, err
+			}
+		}
+	case Struct:
+		return d.structValue(depth + 1)
+	default:
+		err = fmt.Errorf("thrift: unknown type %d", typ)
+	}
+	return v, err
+}
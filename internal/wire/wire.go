@@ -0,0 +1,112 @@
+// Package wire implements the subset of the protobuf wire format used by
+// the trace formats and services: varints and length-delimited fields, and
+// decoding of fixed-width ones.
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Wire types.
+const (
+	Varint  = 0
+	Fixed64 = 1
+	Bytes   = 2
+	Fixed32 = 5
+)
+
+// Buffer accumulates an encoded message.
+type Buffer []byte
+
+func (b *Buffer) tag(field, wire int) {
+	*b = binary.AppendUvarint(*b, uint64(field)<<3|uint64(wire))
+}
+
+// Uvarint appends a varint field, omitting the proto3 default of zero.
+func (b *Buffer) Uvarint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, Varint)
+	*b = binary.AppendUvarint(*b, v)
+}
+
+// Bool appends a bool field, omitting false.
+func (b *Buffer) Bool(field int, v bool) {
+	if v {
+		b.Uvarint(field, 1)
+	}
+}
+
+// Bytes appends a length-delimited field, including empty ones.
+func (b *Buffer) Bytes(field int, data []byte) {
+	b.tag(field, Bytes)
+	*b = binary.AppendUvarint(*b, uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+// String appends a string field, omitting the empty string.
+func (b *Buffer) String(field int, s string) {
+	if s != "" {
+		b.Bytes(field, []byte(s))
+	}
+}
+
+// Zigzag encodes a signed value for a sint64 field.
+func Zigzag(v int64) uint64 { return uint64(v<<1) ^ uint64(v>>63) }
+
+// Unzigzag decodes a sint64 field.
+func Unzigzag(v uint64) int64 { return int64(v>>1) ^ -int64(v&1) }
+
+// Field is one decoded field: either a varint or fixed-width value, or a
+// length-delimited payload.
+type Field struct {
+	Num   int
+	Value uint64
+	Data  []byte
+}
+
+// Fields decodes the top-level fields of a message. Fixed-width fields, such
+// as doubles, have their little-endian bits in Value; the deprecated group
+// wire types are rejected.
+func Fields(msg []byte) ([]Field, error) {
+	var out []Field
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return nil, errors.New("malformed tag")
+		}
+		msg = msg[n:]
+		f := Field{Num: int(key >> 3)}
+		switch key & 7 {
+		case Varint:
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return nil, errors.New("malformed varint")
+			}
+			f.Value, msg = v, msg[n:]
+		case Fixed64:
+			if len(msg) < 8 {
+				return nil, errors.New("truncated field")
+			}
+			f.Value, msg = binary.LittleEndian.Uint64(msg), msg[8:]
+		case Fixed32:
+			if len(msg) < 4 {
+				return nil, errors.New("truncated field")
+			}
+			f.Value, msg = uint64(binary.LittleEndian.Uint32(msg)), msg[4:]
+		case Bytes:
+			l, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < l {
+				return nil, errors.New("truncated field")
+			}
+			f.Data, msg = msg[n:n+int(l)], msg[n+int(l):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d", key&7)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
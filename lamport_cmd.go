@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/traces/analysis"
+	t "github.com/traces/types"
+)
+
+// runLamport implements `trace lamport`: it shows where Lamport clocks order
+// events that are in fact concurrent, and can stamp a trace with them.
+func runLamport(args []string) error {
+	fs := flag.NewFlagSet("lamport", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	examples := fs.Int("examples", 5, "misordered pairs to list")
+	out := fs.String("out", "", "write the trace with Lamport clocks derived from its vector clocks to this file")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	c := analysis.CompareLamport(trace, *examples)
+	source := "derived from vector clocks"
+	if c.Recorded {
+		source = "as recorded"
+	}
+	fmt.Printf("Lamport clocks %s, 1..%d over %d events\n", source, maxClock(c.Clocks), len(trace))
+	fmt.Printf("causally ordered pairs: %d, contradicted by Lamport clocks: %d\n", c.Ordered, len(c.Contradictions))
+	for _, p := range c.Contradictions[:min(len(c.Contradictions), *examples)] {
+		fmt.Printf("  e-%d (L=%d) happens before e-%d (L=%d)\n", p[0], c.Clocks[p[0]], p[1], c.Clocks[p[1]])
+	}
+	if c.Concurrent > 0 {
+		fmt.Printf("concurrent pairs: %d, ordered by Lamport clocks: %d (%.1f%%)\n",
+			c.Concurrent, c.Misordered, 100*float64(c.Misordered)/float64(c.Concurrent))
+	}
+	for _, p := range c.Examples {
+		fmt.Printf("  e-%d %s (L=%d) < e-%d %s (L=%d), yet concurrent\n",
+			p[0], t.QuoteName(aliases.Name(trace[p[0]].Process)), c.Clocks[p[0]], p[1], t.QuoteName(aliases.Name(trace[p[1]].Process)), c.Clocks[p[1]])
+	}
+
+	if *out != "" {
+		t.AssignLamport(trace)
+		return saveTrace(*out, trace)
+	}
+	return nil
+}
+
+func maxClock(clocks []t.LamportClock) t.LamportClock {
+	var m t.LamportClock
+	for _, c := range clocks {
+		m = max(m, c)
+	}
+	return m
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/traces/sink"
+	t "github.com/traces/types"
+)
+
+// loadTrace reads a trace file, choosing the format from its extension.
+// Compressed and split files are read transparently.
+func loadTrace(path string) (t.Trace, error) {
+	return queryTrace(path, t.Query{})
+}
+
+// queryTrace reads the events of a trace file selected by q, filtering them
+// while they are decoded.
+func queryTrace(path string, q t.Query) (t.Trace, error) {
+	stamp.AddInput(path)
+	switch t.Ext(path) {
+	case ".jsonl", ".ndjson":
+		f, err := t.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		tr := t.NewTraceReader(f)
+		tr.Name = path
+		return tr.ReadQuery(q)
+	case ".pb":
+		f, err := t.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		return t.QueryProto(data, q)
+	case ".parquet":
+		return t.QueryParquetFile(path, q)
+	default:
+		return t.QueryFile(path, q)
+	}
+}
+
+// streamTrace calls yield with each event of a JSONL trace file selected by
+// q as it is decoded, so that the trace is never held in memory as a whole.
+func streamTrace(path string, q t.Query, yield func(t.Event) error) error {
+	stamp.AddInput(path)
+	f, err := t.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tr := t.NewTraceReader(f)
+	tr.Name = path
+	for {
+		e, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if q.Match(e) {
+			if err := yield(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// isJSONL reports whether a trace path names newline-delimited JSON.
+func isJSONL(path string) bool {
+	switch t.Ext(path) {
+	case ".jsonl", ".ndjson":
+		return true
+	}
+	return false
+}
+
+// saveTrace writes a trace to a local path or object storage URL, choosing
+// the format from its extension. JSON traces record the provenance of the
+// run; other formats get it beside them (see writeStamped).
+func saveTrace(path string, trace t.Trace) error {
+	switch filepath.Ext(path) {
+	case ".jsonl", ".ndjson":
+		return writeStamped(path, func(w io.Writer) error { return t.SaveJSONL(w, trace) })
+	case ".pb":
+		if err := sink.WriteFile(path, t.MarshalProto(trace)); err != nil {
+			return err
+		}
+		return writeProvenance(path)
+	case ".parquet":
+		if err := sink.WriteFile(path, t.MarshalParquet(trace)); err != nil {
+			return err
+		}
+		return writeProvenance(path)
+	default:
+		return writeOutput(path, func(w io.Writer) error { return writeTrace(w, trace) })
+	}
+}
+
+// writeOutput writes an artifact to dest, a local path or an object storage
+// URL such as s3://bucket/key (see sink.Create), or to stdout if dest is
+// empty.
+func writeOutput(dest string, write func(w io.Writer) error) error {
+	if dest == "" {
+		return write(os.Stdout)
+	}
+	w, err := sink.Create(dest)
+	if err != nil {
+		return err
+	}
+	if err := write(w); err != nil {
+		sink.Abort(w)
+		return err
+	}
+	return w.Close()
+}
+
+// writeStamped writes an artifact in a format with no room for provenance
+// like writeOutput, and records the provenance of the run in a
+// <dest>.provenance.json file beside it. Output to stdout is not stamped.
+func writeStamped(dest string, write func(w io.Writer) error) error {
+	if err := writeOutput(dest, write); err != nil || dest == "" {
+		return err
+	}
+	return writeProvenance(dest)
+}
+
+// writeProvenance writes the stamp of the run to <dest>.provenance.json.
+func writeProvenance(dest string) error {
+	return writeOutput(dest+".provenance.json", func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stamp)
+	})
+}
+
+// writeTrace writes a trace as JSON, stamped with the provenance of the run.
+func writeTrace(w io.Writer, trace t.Trace) error {
+	return t.SaveWithProvenance(w, trace, stamp)
+}
+
+// loadAliases reads the display-name mapping, if one was given.
+func loadAliases(path string) (t.Aliases, error) {
+	if path == "" {
+		return nil, nil
+	}
+	stamp.AddInput(path)
+	return t.LoadAliases(path)
+}
+
+// aliasesFlag registers the -aliases flag of the commands that print process
+// names. Only their human-facing output uses the display names; traces and
+// other machine-readable output keep the raw ones.
+func aliasesFlag(fs *flag.FlagSet) *string {
+	return fs.String("aliases", "", "JSON map of raw process names to display names shown in human-readable output")
+}
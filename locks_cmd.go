@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/traces/analysis"
+	"github.com/traces/dag"
+)
+
+// runLocks implements `trace locks`: it prints the lock-order graph of a
+// trace and the lock-order inversions it contains.
+func runLocks(args []string) error {
+	fs := flag.NewFlagSet("locks", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	// The graph is only printed, so it can name the display processes
+	trace = aliases.Apply(trace)
+	// Critical sections of the same lock are ordered even when the trace's
+	// clocks did not record it
+	if trace, err = dag.ApplyRules(trace, dag.LockOrder{}); err != nil {
+		return err
+	}
+
+	g := analysis.LockOrderGraph(trace)
+	fmt.Print(g)
+	inversions := g.Inversions(trace)
+	fmt.Printf("%d lock-order inversions\n", len(inversions))
+	for _, inv := range inversions {
+		fmt.Println(inv)
+	}
+	return nil
+}
@@ -1,15 +1,114 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/traces/dag"
 	"github.com/traces/messages"
+	"github.com/traces/provenance"
 )
 
+// stamp is the provenance of this run, embedded in the artifacts it writes.
+var stamp *provenance.Stamp
+
 func main() {
+	stamp = provenance.New("trace", os.Args[1:])
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		var err error
+		switch os.Args[1] {
+		case "generate":
+			err = runGenerate(os.Args[2:])
+		case "import":
+			err = runImport(os.Args[2:])
+		case "export":
+			err = runExport(os.Args[2:])
+		case "graph":
+			err = runGraph(os.Args[2:])
+		case "scenario":
+			err = runScenario(os.Args[2:])
+		case "script":
+			err = runScript(os.Args[2:])
+		case "abstract":
+			err = runAbstract(os.Args[2:])
+		case "check":
+			err = runCheck(os.Args[2:])
+		case "locks":
+			err = runLocks(os.Args[2:])
+		case "conflicts":
+			err = runConflicts(os.Args[2:])
+		case "divergence":
+			err = runDivergence(os.Args[2:])
+		case "critical-path":
+			err = runCriticalPath(os.Args[2:])
+		case "bundle":
+			err = runBundle(os.Args[2:])
+		case "lamport":
+			err = runLamport(os.Args[2:])
+		case "timing":
+			err = runTiming(os.Args[2:])
+		case "dissemination":
+			err = runDissemination(os.Args[2:])
+		case "stability":
+			err = runStability(os.Args[2:])
+		case "rpc":
+			err = runRPC(os.Args[2:])
+		case "effects":
+			err = runEffects(os.Args[2:])
+		case "replay":
+			err = runReplay(os.Args[2:])
+		case "suite":
+			err = runSuite(os.Args[2:])
+		case "mutate":
+			err = runMutate(os.Args[2:])
+		case "minimize":
+			err = runMinimize(os.Args[2:])
+		case "synthesize":
+			err = runSynthesize(os.Args[2:])
+		case "diff":
+			err = runDiff(os.Args[2:])
+		case "explore":
+			err = runExplore(os.Args[2:])
+		case "whatif":
+			err = runWhatIf(os.Args[2:])
+		case "suggest":
+			err = runSuggest(os.Args[2:])
+		case "serve":
+			err = runServe(os.Args[2:])
+		case "experiment":
+			err = runExperiment(os.Args[2:])
+		case "sweep":
+			err = runSweep(os.Args[2:])
+		case "correlate":
+			err = runCorrelate(os.Args[2:])
+		case "coverage":
+			err = runCoverage(os.Args[2:])
+		case "query":
+			err = runQuery(os.Args[2:])
+		case "validate":
+			err = runValidate(os.Args[2:])
+		case "causal-log":
+			err = runCausalLog(os.Args[2:])
+		case "stitch":
+			err = runStitch(os.Args[2:])
+		default:
+			err = fmt.Errorf("unknown command %q", os.Args[1])
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fs := flag.NewFlagSet("trace", flag.ExitOnError)
+	seed := fs.Int64("seed", 1, "generator seed of the demo trace")
+	fs.Parse(os.Args[1:])
+
 	processes := []string{"A", "B", "C"}
-	trace := messages.GenerateAsyncTrace(processes, 30)
+	trace := messages.GenerateAsyncTrace(processes, 30, *seed)
 
 	fmt.Println("Trace:")
 	fmt.Println(trace.String())
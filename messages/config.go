@@ -0,0 +1,382 @@
+package messages
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/traces/sim"
+)
+
+// Topology restricts which processes may send messages to each other.
+type Topology string
+
+const (
+	TopologyComplete Topology = "complete" // every process may send to every other
+	TopologyRing     Topology = "ring"     // processes only talk to their ring neighbours
+	TopologyStar     Topology = "star"     // the first process is the hub, others only talk to it
+	TopologyTree     Topology = "tree"     // a binary tree in process order; processes talk to parent and children
+)
+
+// ParseTopology validates a topology name.
+func ParseTopology(name string) (Topology, error) {
+	switch tp := Topology(name); tp {
+	case TopologyComplete, TopologyRing, TopologyStar, TopologyTree:
+		return tp, nil
+	case "":
+		return TopologyComplete, nil
+	default:
+		return "", fmt.Errorf("unknown topology %q", name)
+	}
+}
+
+// Neighbors returns the processes p may send to under the topology.
+func (tp Topology) Neighbors(processes []string, p string) []string {
+	idx := slices.Index(processes, p)
+	if idx < 0 {
+		return nil
+	}
+	n := len(processes)
+
+	switch tp {
+	case TopologyRing:
+		if n < 2 {
+			return nil
+		}
+		next, prev := processes[(idx+1)%n], processes[(idx+n-1)%n]
+		if next == prev {
+			return []string{next}
+		}
+		return []string{next, prev}
+	case TopologyStar:
+		if idx == 0 {
+			return slices.Clone(processes[1:])
+		}
+		return []string{processes[0]}
+	case TopologyTree:
+		var out []string
+		if idx > 0 {
+			out = append(out, processes[(idx-1)/2])
+		}
+		for _, c := range []int{2*idx + 1, 2*idx + 2} {
+			if c < n {
+				out = append(out, processes[c])
+			}
+		}
+		return out
+	default:
+		var others []string
+		for _, q := range processes {
+			if q != p {
+				others = append(others, q)
+			}
+		}
+		return others
+	}
+}
+
+// Adjacency is an arbitrary communication graph: each process maps to the
+// processes it may send to. Edges are directed; list both directions for a
+// two-way link.
+type Adjacency map[string][]string
+
+// LoadAdjacency reads an Adjacency from a JSON object such as
+// {"A": ["B", "C"], "B": ["A"], "C": ["A"]}.
+func LoadAdjacency(path string) (Adjacency, error) {
+	var adj Adjacency
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &adj); err != nil {
+		return nil, fmt.Errorf("decoding adjacency: %w", err)
+	}
+	return adj, nil
+}
+
+// Processes returns every process the graph mentions, sorted.
+func (adj Adjacency) Processes() []string {
+	seen := make(map[string]bool)
+	for p, qs := range adj {
+		seen[p] = true
+		for _, q := range qs {
+			seen[q] = true
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for p := range seen {
+		out = append(out, p)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Roles of the messages of RPC mode.
+const (
+	RoleRequest  = "request"
+	RoleResponse = "response"
+)
+
+// Config controls the shape of a generated trace.
+type Config struct {
+	Processes []string
+	NumEvents int // event budget; zero means no event budget if Rounds is set
+	// Rounds, when positive, bounds the number of scheduling rounds: every
+	// round picks a process that either emits one event or, if it has
+	// nothing it is allowed to do, idles. Generation stops at whichever
+	// budget runs out first.
+	Rounds   int
+	LossRate float64  // probability that a sent message is never delivered
+	Topology Topology // defaults to TopologyComplete
+	// Adjacency, when set, replaces Topology with an arbitrary graph.
+	Adjacency Adjacency
+	// BroadcastRate is the probability that a send goes to every process it
+	// may send to rather than to one of them. Each copy of a broadcast is
+	// received (or lost) independently, under the same MessageID.
+	BroadcastRate float64
+	// DuplicateRate is the probability that a delivered message is queued
+	// again, so that its receiver later gets it a second time under the
+	// same MessageID, as at-least-once channels do. Redeliveries may come
+	// after newer messages even with FIFO or CausalDelivery.
+	DuplicateRate float64
+	// CrashRate is the probability that a scheduled process crashes instead
+	// of acting, emitting a CRASH event. A crashed process emits nothing
+	// until it recovers; its undelivered messages and those sent to it
+	// meanwhile are lost.
+	CrashRate float64
+	// RecoverRate is the probability that a crashed process, when
+	// scheduled, restarts with a RECOVER event. With no RecoverRate crashes
+	// are permanent and generation stops once every process has crashed.
+	RecoverRate float64
+	// Synchronous makes every message a rendezvous (CSP-style): the SEND is
+	// immediately followed by the RECV of each receiver and all of them
+	// carry the same merged clock, so the exchange is a single causal step.
+	// Synchronous messages cannot be lost.
+	Synchronous bool
+	// FIFO delivers the messages of each sender -> receiver channel in the
+	// order they were sent, like TCP, instead of in any order.
+	FIFO bool
+	// CausalDelivery holds back a message until every message to the same
+	// receiver whose send happens before its send has been delivered, as
+	// causally ordered middleware (ISIS CBCAST) does. It implies FIFO.
+	// Lost messages are never waited for.
+	CausalDelivery bool
+	// Weights skews scheduling: a process is picked with probability
+	// proportional to its weight, 1 if it has none. Nil picks uniformly.
+	Weights map[string]float64
+	// ReceiveRates sets, per process, the probability of receiving rather
+	// than sending when it has a message waiting; 0.5 if it has none.
+	ReceiveRates map[string]float64
+	// Capacity, when positive, bounds the messages waiting for each
+	// receiver; Capacities overrides it per receiver. A process about to
+	// send to a full queue blocks: it idles for that round, as if not
+	// scheduled, until the receiver catches up. Redeliveries of
+	// DuplicateRate are not held back.
+	Capacity   int
+	Capacities map[string]int
+	// RPC makes every message a request or a response: each received
+	// request obliges its receiver to answer the sender, which it does,
+	// oldest request first, the next times it is scheduled to send.
+	// Requests carry the key "rpc-<MessageID>" and role RoleRequest; the
+	// response carries the same key and RoleResponse.
+	RPC bool
+	// Keys, when positive, tags every message with one of Keys correlation
+	// keys ("req-0" ...); the receive inherits the key of its send.
+	Keys int
+	// Payload gives every message application data: for each label, in
+	// label order, one of its values drawn at random. The SEND and every
+	// RECV of the message carry the same labels.
+	Payload map[string][]string
+	// Timing, if set, stamps every event with a physical time.
+	Timing *Timing
+	// BarrierEvery, when positive, makes all processes pass a barrier after
+	// every BarrierEvery events: each emits a BARRIER event that follows
+	// everything any process did before the barrier. A barrier phase is
+	// never cut short, so the trace may exceed NumEvents by one phase.
+	BarrierEvery int
+
+	// Decisions, if set, receives a log of the generator's choices at
+	// DecisionLevel (DecisionsActions when left unset).
+	Decisions     io.Writer
+	DecisionLevel DecisionLevel
+	// Faults, if set, is called with every fault the generator injects:
+	// crashes, messages lost in the network ("drop") or to a crashed
+	// receiver ("crashed-receiver"), and redeliveries ("duplicate").
+	Faults func(sim.InjectedFault)
+}
+
+// Validate reports configurations the generator cannot honour.
+func (cfg Config) Validate() error {
+	if len(cfg.Processes) == 0 {
+		return fmt.Errorf("no processes")
+	}
+	seen := make(map[string]bool, len(cfg.Processes))
+	for _, p := range cfg.Processes {
+		if p == "" {
+			return fmt.Errorf("empty process name")
+		}
+		if seen[p] {
+			return fmt.Errorf("duplicate process %q", p)
+		}
+		seen[p] = true
+	}
+	if cfg.NumEvents < 0 {
+		return fmt.Errorf("negative event budget %d", cfg.NumEvents)
+	}
+	if cfg.Rounds < 0 {
+		return fmt.Errorf("negative round budget %d", cfg.Rounds)
+	}
+	if !inUnit(cfg.LossRate) {
+		return fmt.Errorf("loss rate %g outside [0, 1]", cfg.LossRate)
+	}
+	if cfg.Synchronous && cfg.LossRate > 0 {
+		return fmt.Errorf("synchronous messages cannot be lost (loss rate %g)", cfg.LossRate)
+	}
+	if !inUnit(cfg.DuplicateRate) {
+		return fmt.Errorf("duplicate rate %g outside [0, 1]", cfg.DuplicateRate)
+	}
+	if cfg.Synchronous && cfg.DuplicateRate > 0 {
+		return fmt.Errorf("synchronous messages cannot be duplicated (duplicate rate %g)", cfg.DuplicateRate)
+	}
+	if !inUnit(cfg.CrashRate) {
+		return fmt.Errorf("crash rate %g outside [0, 1]", cfg.CrashRate)
+	}
+	if !inUnit(cfg.RecoverRate) {
+		return fmt.Errorf("recover rate %g outside [0, 1]", cfg.RecoverRate)
+	}
+	if cfg.CrashRate > 0 && cfg.BarrierEvery > 0 {
+		return fmt.Errorf("crashed processes cannot pass barriers")
+	}
+	if !inUnit(cfg.BroadcastRate) {
+		return fmt.Errorf("broadcast rate %g outside [0, 1]", cfg.BroadcastRate)
+	}
+	if _, err := ParseTopology(string(cfg.Topology)); err != nil {
+		return err
+	}
+	total := 0.0
+	for _, p := range cfg.Processes {
+		w, ok := cfg.Weights[p]
+		if !ok {
+			w = 1
+		}
+		total += w
+	}
+	for p, w := range cfg.Weights {
+		if !seen[p] {
+			return fmt.Errorf("weight for unknown process %q", p)
+		}
+		if math.IsNaN(w) || math.IsInf(w, 0) {
+			return fmt.Errorf("weight %g for %q is not a finite number", w, p)
+		}
+		if w < 0 {
+			return fmt.Errorf("negative weight %g for %q", w, p)
+		}
+	}
+	if cfg.Weights != nil && total <= 0 {
+		return fmt.Errorf("process weights sum to zero")
+	}
+	for p, r := range cfg.ReceiveRates {
+		if !seen[p] {
+			return fmt.Errorf("receive rate for unknown process %q", p)
+		}
+		if !inUnit(r) {
+			return fmt.Errorf("receive rate %g for %q outside [0, 1]", r, p)
+		}
+	}
+	if cfg.Capacity > 0 || len(cfg.Capacities) > 0 {
+		receives := false
+		for _, p := range cfg.Processes {
+			if r, ok := cfg.ReceiveRates[p]; !ok || r > 0 {
+				receives = true
+			}
+		}
+		if !receives {
+			return fmt.Errorf("bounded queues with all receive rates zero block every sender")
+		}
+	}
+	for p, qs := range cfg.Adjacency {
+		if !seen[p] {
+			return fmt.Errorf("adjacency names unknown process %q", p)
+		}
+		for _, q := range qs {
+			if !seen[q] {
+				return fmt.Errorf("adjacency names unknown process %q", q)
+			}
+			if q == p {
+				return fmt.Errorf("adjacency links %q to itself", p)
+			}
+		}
+	}
+	if cfg.BarrierEvery < 0 {
+		return fmt.Errorf("negative barrier interval %d", cfg.BarrierEvery)
+	}
+	if cfg.Capacity < 0 {
+		return fmt.Errorf("negative channel capacity %d", cfg.Capacity)
+	}
+	for p, c := range cfg.Capacities {
+		if !seen[p] {
+			return fmt.Errorf("capacity for unknown process %q", p)
+		}
+		if c < 1 {
+			return fmt.Errorf("capacity %d for %q is not positive", c, p)
+		}
+	}
+	if cfg.Synchronous && (cfg.Capacity > 0 || len(cfg.Capacities) > 0) {
+		return fmt.Errorf("synchronous messages are never queued, so capacities do not apply")
+	}
+	if cfg.RPC && (cfg.Keys > 0 || cfg.Synchronous) {
+		return fmt.Errorf("RPC mode assigns its own keys and needs asynchronous messages")
+	}
+	if cfg.Keys < 0 {
+		return fmt.Errorf("negative key count %d", cfg.Keys)
+	}
+	if tm := cfg.Timing; tm != nil && (tm.Step < 0 || tm.Latency < 0 || tm.Skew < 0) {
+		return fmt.Errorf("negative step, latency or skew in timing")
+	}
+	for label, values := range cfg.Payload {
+		if label == "" {
+			return fmt.Errorf("empty payload label")
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("payload label %q has no values", label)
+		}
+	}
+	return nil
+}
+
+// inUnit reports whether a probability lies in [0, 1], which NaN does not.
+func inUnit(p float64) bool { return p >= 0 && p <= 1 }
+
+// payload draws the labels of a new message, nil without a Payload.
+func (cfg Config) payload(d *decider) map[string]string {
+	if len(cfg.Payload) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(cfg.Payload))
+	for _, label := range slices.Sorted(maps.Keys(cfg.Payload)) {
+		values := cfg.Payload[label]
+		labels[label] = values[d.intn(len(values), "payload "+label)]
+	}
+	return labels
+}
+
+// capacity returns the bound on messages waiting for p, 0 if unbounded.
+func (cfg Config) capacity(p string) int {
+	if c, ok := cfg.Capacities[p]; ok {
+		return c
+	}
+	return cfg.Capacity
+}
+
+// Neighbors returns the processes p may send to under the configured
+// adjacency or topology.
+func (cfg Config) Neighbors(p string) []string {
+	if cfg.Adjacency != nil {
+		return cfg.Adjacency[p]
+	}
+	return cfg.Topology.Neighbors(cfg.Processes, p)
+}
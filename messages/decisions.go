@@ -0,0 +1,68 @@
+package messages
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+)
+
+// DecisionLevel selects how much of the generator's reasoning is logged.
+type DecisionLevel int
+
+const (
+	DecisionsOff     DecisionLevel = iota
+	DecisionsActions               // which process acted and which message it sent or received
+	DecisionsDraws                 // additionally every random draw and what it decided
+)
+
+// decider draws random choices for the generator and, if configured, logs
+// them so a surprising trace can be audited step by step.
+type decider struct {
+	r     *rand.Rand
+	log   io.Writer
+	level DecisionLevel
+}
+
+func newDecider(cfg Config, r *rand.Rand) *decider {
+	d := &decider{r: r, log: cfg.Decisions, level: cfg.DecisionLevel}
+	if d.log == nil {
+		d.level = DecisionsOff
+	} else if d.level == DecisionsOff {
+		d.level = DecisionsActions
+	}
+	return d
+}
+
+// intn returns r.Intn(n), logging the draw as purpose.
+func (d *decider) intn(n int, purpose string) int {
+	v := d.r.Intn(n)
+	if d.level >= DecisionsDraws {
+		fmt.Fprintf(d.log, "    draw %-16s Intn(%d) = %d\n", purpose, n, v)
+	}
+	return v
+}
+
+// float64 returns r.Float64(), logging the draw as purpose.
+func (d *decider) float64(purpose string) float64 {
+	v := d.r.Float64()
+	if d.level >= DecisionsDraws {
+		fmt.Fprintf(d.log, "    draw %-16s Float64() = %.6f\n", purpose, v)
+	}
+	return v
+}
+
+// expFloat64 returns r.ExpFloat64(), logging the draw as purpose.
+func (d *decider) expFloat64(purpose string) float64 {
+	v := d.r.ExpFloat64()
+	if d.level >= DecisionsDraws {
+		fmt.Fprintf(d.log, "    draw %-16s ExpFloat64() = %.6f\n", purpose, v)
+	}
+	return v
+}
+
+// action logs a decision that produced (or skipped) an event.
+func (d *decider) action(format string, args ...any) {
+	if d.level >= DecisionsActions {
+		fmt.Fprintf(d.log, format+"\n", args...)
+	}
+}
@@ -1,14 +1,50 @@
 package messages
 
 import (
+	"fmt"
+	"maps"
 	"math/rand"
-	"time"
+	"slices"
+	"strings"
 
+	"github.com/traces/sim"
 	t "github.com/traces/types"
 )
 
-func GenerateAsyncTrace(processes []string, numEvents int) t.Trace {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+// GenerateAsyncTrace generates a trace from the given seed; the same seed
+// always yields the same trace.
+func GenerateAsyncTrace(processes []string, numEvents int, seed int64) t.Trace {
+	return GenerateAsyncTraceWithRand(processes, numEvents, rand.New(rand.NewSource(seed)))
+}
+
+// GenerateAsyncTraceWithRand generates a trace drawing all random choices from r,
+// so callers controlling the source get reproducible traces.
+func GenerateAsyncTraceWithRand(processes []string, numEvents int, r *rand.Rand) t.Trace {
+	return Generate(Config{Processes: processes, NumEvents: numEvents}, r)
+}
+
+// GenerateAsyncTraceOn generates a trace like GenerateAsyncTrace in which
+// processes only send along the edges of adj.
+func GenerateAsyncTraceOn(adj Adjacency, numEvents int, seed int64) t.Trace {
+	cfg := Config{Processes: adj.Processes(), NumEvents: numEvents, Adjacency: adj}
+	return Generate(cfg, rand.New(rand.NewSource(seed)))
+}
+
+// maxIdleSteps is the number of draws in a row in which no process takes a
+// step after which Generate gives up, as the processes are then all but
+// certainly deadlocked, e.g. blocked on full queues nobody drains.
+const maxIdleSteps = 10000
+
+// Generate generates an asynchronous trace shaped by cfg. A single process
+// has nobody to talk to and yields a chain of INTERNAL events. The trace ends
+// early if no process takes a step for maxIdleSteps draws in a row. It
+// returns nil if cfg is invalid; callers taking configurations from users
+// should report cfg.Validate() first.
+func Generate(cfg Config, r *rand.Rand) t.Trace {
+	if cfg.Validate() != nil {
+		return nil
+	}
+	processes, numEvents := cfg.Processes, cfg.NumEvents
 
 	trace := make(t.Trace, 0, numEvents)
 	processClocks := make(map[string]t.VectorClock)
@@ -21,13 +57,130 @@ func GenerateAsyncTrace(processes []string, numEvents int) t.Trace {
 	}
 
 	messageCounter := 0
+	crashed := make(map[string]bool)   // processes down until they recover
+	owed := make(map[string][]t.Event) // RPC requests each process has yet to answer
+	d := newDecider(cfg, r)
+	inject := func(f sim.InjectedFault) {
+		if cfg.Faults != nil {
+			cfg.Faults(f)
+		}
+	}
+	phase, barrierEnd := 0, 0
 
-	for len(trace) < numEvents {
-		process, action := getRandomProcessAction(processes, r, pendingMessages)
+	idle, lastLen := 0, 0
+	for round := 0; ; round++ {
+		if cfg.Rounds > 0 && round >= cfg.Rounds {
+			break
+		}
+		if len(trace) == lastLen {
+			idle++
+		} else {
+			idle, lastLen = 0, len(trace)
+		}
+		if idle > maxIdleSteps {
+			d.action("step %d: no process can make progress", len(trace))
+			break
+		}
+		if (numEvents > 0 || cfg.Rounds <= 0) && len(trace) >= numEvents {
+			break
+		}
+		if cfg.BarrierEvery > 0 && len(trace)-barrierEnd >= cfg.BarrierEvery {
+			phase++
+			// Every process leaves the barrier knowing what all the others
+			// did before reaching it
+			merged := make(t.VectorClock, len(processes))
+			for _, p := range processes {
+				merged.Merge(processClocks[p])
+			}
+			for _, p := range processes {
+				clock := t.DeepCopy(merged)
+				clock[p]++
+				processClocks[p] = clock
+				trace = append(trace, t.Event{
+					Type:      t.EventBarrier,
+					Process:   p,
+					VClock:    t.DeepCopy(clock),
+					MessageID: -1,
+					Phase:     phase,
+				})
+			}
+			barrierEnd = len(trace)
+			d.action("e-%d..e-%d: all processes pass barrier %d", barrierEnd-len(processes), barrierEnd-1, phase)
+			continue
+		}
+		if len(processes) == 1 {
+			clock := processClocks[processes[0]]
+			clock[processes[0]]++
+			trace = append(trace, t.Event{
+				Type:      t.EventInternal,
+				Process:   processes[0],
+				VClock:    t.DeepCopy(clock),
+				MessageID: -1,
+			})
+			d.action("e-%d: %s steps locally", len(trace)-1, processes[0])
+			continue
+		}
+		process, action := getRandomProcessAction(cfg, d, pendingMessages)
+		if crashed[process] {
+			if cfg.RecoverRate > 0 && d.float64("recover") < cfg.RecoverRate {
+				crashed[process] = false
+				trace = appendLocal(trace, processClocks, process, t.EventRecover)
+				d.action("e-%d: %s recovers", len(trace)-1, process)
+			} else if cfg.RecoverRate <= 0 && len(crashed) == len(processes) {
+				d.action("step %d: every process has crashed", len(trace))
+				break
+			}
+			continue
+		}
+		if cfg.CrashRate > 0 && d.float64("crash") < cfg.CrashRate {
+			crashed[process] = true
+			trace = appendLocal(trace, processClocks, process, t.EventCrash)
+			inject(sim.InjectedFault{Kind: "crash", Process: process, Event: len(trace) - 1})
+			d.action("e-%d: %s crashes, losing %d pending messages", len(trace)-1, process, len(pendingMessages[process]))
+			pendingMessages[process] = pendingMessages[process][:0]
+			owed[process] = nil
+			continue
+		}
 
 		switch action {
 		case t.EventSend:
-			receiverName := getRandomOtherProcess(r, processes, process)
+			var receivers []string
+			var request *t.Event // the request this send answers, in RPC mode
+			if cfg.RPC && len(owed[process]) > 0 {
+				oldest := owed[process][0]
+				request, owed[process] = &oldest, owed[process][1:]
+				receivers = []string{request.Process}
+			} else if cfg.BroadcastRate > 0 && d.float64("broadcast") < cfg.BroadcastRate {
+				receivers = cfg.Neighbors(process)
+			} else if cfg.Adjacency == nil && (cfg.Topology == "" || cfg.Topology == TopologyComplete) {
+				receivers = []string{getRandomOtherProcess(d, processes, process)}
+			} else if neighbors := cfg.Neighbors(process); len(neighbors) > 0 {
+				receivers = []string{neighbors[d.intn(len(neighbors), "receiver")]}
+			}
+			if cfg.Synchronous {
+				// A rendezvous needs its receivers up
+				receivers = slices.DeleteFunc(receivers, func(p string) bool { return crashed[p] })
+			}
+			if len(receivers) == 0 {
+				d.action("step %d: %s has no neighbours to send to", len(trace), process)
+				continue
+			}
+			if full := slices.IndexFunc(receivers, func(p string) bool {
+				c := cfg.capacity(p)
+				return c > 0 && !crashed[p] && len(pendingMessages[p]) >= c
+			}); full >= 0 {
+				if request != nil {
+					owed[process] = append([]t.Event{*request}, owed[process]...)
+				}
+				d.action("step %d: %s blocks, the queue of %s is full", len(trace), process, receivers[full])
+				continue
+			}
+
+			if cfg.Synchronous {
+				trace = rendezvous(trace, processClocks, process, receivers, messageCounter, cfg.Keys, cfg.payload(d), d)
+				messageCounter++
+				continue
+			}
 
 			// Increment sender's clock
 			senderClock := processClocks[process]
@@ -39,17 +192,48 @@ func GenerateAsyncTrace(processes []string, numEvents int) t.Trace {
 				VClock:    t.DeepCopy(senderClock),
 				MessageID: messageCounter,
 			}
+			if request != nil {
+				sendEvent.CorrelationKey, sendEvent.Role = request.CorrelationKey, RoleResponse
+			} else if cfg.RPC {
+				sendEvent.CorrelationKey, sendEvent.Role = fmt.Sprintf("rpc-%d", messageCounter), RoleRequest
+			} else if cfg.Keys > 0 {
+				sendEvent.CorrelationKey = fmt.Sprintf("req-%d", d.intn(cfg.Keys, "correlation key"))
+			}
+			sendEvent.Labels = cfg.payload(d)
 
 			// The send event happens now, so add it to the trace
 			trace = append(trace, sendEvent)
-			// Queue up the message for the receiver
-			pendingMessages[receiverName] = append(pendingMessages[receiverName], sendEvent)
+			// Queue up the message for each receiver, unless the network loses it
+			for _, receiverName := range receivers {
+				fault := sim.InjectedFault{Process: process, To: receiverName, Event: len(trace) - 1, MessageID: sendEvent.MessageID}
+				if crashed[receiverName] {
+					fault.Kind = "crashed-receiver"
+					inject(fault)
+					d.action("e-%d: %s sends Msg-%d to %s, lost as it has crashed", len(trace)-1, process, sendEvent.MessageID, receiverName)
+				} else if cfg.LossRate <= 0 || d.float64("loss") >= cfg.LossRate {
+					pendingMessages[receiverName] = append(pendingMessages[receiverName], sendEvent)
+					d.action("e-%d: %s sends Msg-%d to %s", len(trace)-1, process, sendEvent.MessageID, receiverName)
+				} else {
+					fault.Kind = "drop"
+					inject(fault)
+					d.action("e-%d: %s sends Msg-%d to %s, lost in the network", len(trace)-1, process, sendEvent.MessageID, receiverName)
+				}
+			}
 			messageCounter++
 
 		case t.EventReceive:
 			// This process was selected to receive a message
 			// Dequeue a random message that was sent to it
-			msgIdx := r.Intn(len(pendingMessages[process]))
+			msgIdx := d.intn(len(pendingMessages[process]), "pending message")
+			if cfg.FIFO {
+				// Take the oldest message on the drawn message's channel;
+				// the queue is in send order
+				sender := pendingMessages[process][msgIdx].Process
+				msgIdx = slices.IndexFunc(pendingMessages[process], func(e t.Event) bool { return e.Process == sender })
+			}
+			if cfg.CausalDelivery {
+				msgIdx = causallyFirst(pendingMessages[process], msgIdx)
+			}
 			msgToReceive := pendingMessages[process][msgIdx]
 			pendingMessages[process] = append(
 				pendingMessages[process][:msgIdx],
@@ -65,39 +249,157 @@ func GenerateAsyncTrace(processes []string, numEvents int) t.Trace {
 			}
 
 			recvEvent := t.Event{
-				Type:      t.EventReceive,
-				Process:   process,
-				VClock:    t.DeepCopy(receiverClock),
-				MessageID: msgToReceive.MessageID,
+				Type:           t.EventReceive,
+				Process:        process,
+				VClock:         t.DeepCopy(receiverClock),
+				MessageID:      msgToReceive.MessageID,
+				CorrelationKey: msgToReceive.CorrelationKey,
+				Role:           msgToReceive.Role,
+				Labels:         maps.Clone(msgToReceive.Labels),
+			}
+			if cfg.RPC && msgToReceive.Role == RoleRequest {
+				owed[process] = append(owed[process], msgToReceive)
 			}
 
 			// The receive event happens now, add it to the trace
 			trace = append(trace, recvEvent)
+			d.action("e-%d: %s receives Msg-%d from %s (%d still pending)",
+				len(trace)-1, process, recvEvent.MessageID, msgToReceive.Process, len(pendingMessages[process]))
+			if cfg.DuplicateRate > 0 && d.float64("duplicate") < cfg.DuplicateRate {
+				pendingMessages[process] = append(pendingMessages[process], msgToReceive)
+				inject(sim.InjectedFault{Kind: "duplicate", Process: msgToReceive.Process, To: process,
+					Event: len(trace) - 1, MessageID: recvEvent.MessageID})
+				d.action("e-%d: Msg-%d will be delivered to %s again", len(trace)-1, recvEvent.MessageID, process)
+			}
 		}
 	}
 
+	if cfg.Timing != nil {
+		cfg.Timing.stamp(trace, processes, cfg.Synchronous, d)
+	}
 	return trace
 }
 
+// rendezvous appends a synchronous exchange of one message between sender
+// and receivers: every participant takes one step and they all leave it with
+// the merged clock, which the SEND and RECV events share along with labels.
+func rendezvous(trace t.Trace, clocks map[string]t.VectorClock, sender string, receivers []string, id, keys int, labels map[string]string, d *decider) t.Trace {
+	participants := append([]string{sender}, receivers...)
+	merged := make(t.VectorClock)
+	for _, p := range participants {
+		merged.Merge(clocks[p])
+	}
+	for _, p := range participants {
+		merged[p]++
+	}
+	for _, p := range participants {
+		clocks[p] = t.DeepCopy(merged)
+	}
+
+	key := ""
+	if keys > 0 {
+		key = fmt.Sprintf("req-%d", d.intn(keys, "correlation key"))
+	}
+	trace = append(trace, t.Event{Type: t.EventSend, Process: sender, VClock: t.DeepCopy(merged), MessageID: id, CorrelationKey: key, Labels: labels})
+	for _, p := range receivers {
+		trace = append(trace, t.Event{Type: t.EventReceive, Process: p, VClock: t.DeepCopy(merged), MessageID: id, CorrelationKey: key, Labels: maps.Clone(labels)})
+	}
+	d.action("e-%d..e-%d: %s exchanges Msg-%d with %s synchronously",
+		len(trace)-1-len(receivers), len(trace)-1, sender, id, strings.Join(receivers, ", "))
+	return trace
+}
+
+// appendLocal appends an event of the given type that only advances the
+// process's own clock.
+func appendLocal(trace t.Trace, clocks map[string]t.VectorClock, process string, typ t.EventType) t.Trace {
+	clock := clocks[process]
+	clock[process]++
+	return append(trace, t.Event{Type: typ, Process: process, VClock: t.DeepCopy(clock), MessageID: -1})
+}
+
+// causallyFirst returns a pending message that may be delivered before the
+// one at i: i itself, or one whose send happens before it and that no other
+// pending message causally precedes.
+func causallyFirst(pending []t.Event, i int) int {
+	for {
+		j := slices.IndexFunc(pending, func(e t.Event) bool { return e.VClock.HappensBefore(pending[i].VClock) })
+		if j < 0 {
+			return i
+		}
+		i = j
+	}
+}
+
 // getRandomProcessAction selects a random process and determines whether it will send or receive a message.
 // If the selected process has pending messages, it has a 50% chance to receive; otherwise, it will send.
-func getRandomProcessAction(processes []string, r *rand.Rand, pendingMessages map[string][]t.Event) (string, t.EventType) {
-	processName := processes[r.Intn(len(processes))]
+// Configured weights and receive rates skew both choices.
+func getRandomProcessAction(cfg Config, d *decider, pendingMessages map[string][]t.Event) (string, t.EventType) {
+	processes := cfg.Processes
+	var processName string
+	if cfg.Weights == nil {
+		processName = processes[d.intn(len(processes), "process")]
+	} else {
+		processName = weightedProcess(cfg, d)
+	}
 	canReceive := len(pendingMessages[processName]) > 0
 	action := t.EventSend
-	if canReceive && r.Intn(2) == 0 {
+	if rate, ok := cfg.ReceiveRates[processName]; ok {
+		if canReceive && d.float64("send or receive") < rate {
+			action = t.EventReceive
+		}
+	} else if canReceive && d.intn(2, "send or receive") == 0 {
 		action = t.EventReceive
 	}
 	return processName, action
 }
 
-func getRandomOtherProcess(r *rand.Rand, processes []string, exclude string) string {
-	for {
-		p := processes[r.Intn(len(processes))]
+// weightedProcess picks a process with probability proportional to its
+// weight.
+func weightedProcess(cfg Config, d *decider) string {
+	weight := func(p string) float64 {
+		if w, ok := cfg.Weights[p]; ok {
+			return w
+		}
+		return 1
+	}
+	total := 0.0
+	for _, p := range cfg.Processes {
+		total += weight(p)
+	}
+	x := d.float64("process") * total
+	for _, p := range cfg.Processes {
+		if x < weight(p) {
+			return p
+		}
+		x -= weight(p)
+	}
+	// Rounding can leave x just past the last weight
+	for i := len(cfg.Processes) - 1; ; i-- {
+		if weight(cfg.Processes[i]) > 0 {
+			return cfg.Processes[i]
+		}
+	}
+}
+
+// maxRedraws bounds the rejection sampling in getRandomOtherProcess.
+const maxRedraws = 64
+
+// getRandomOtherProcess picks a process other than exclude. It redraws when
+// it hits exclude, which keeps the draws of existing seeds unchanged, but
+// falls back to the first other process so that it always terminates.
+func getRandomOtherProcess(d *decider, processes []string, exclude string) string {
+	for range maxRedraws {
+		p := processes[d.intn(len(processes), "receiver")]
 		if p != exclude {
 			return p
 		}
 	}
+	for _, p := range processes {
+		if p != exclude {
+			return p
+		}
+	}
+	return exclude
 }
 
 func max(a, b int) int {
@@ -105,4 +407,4 @@ func max(a, b int) int {
 		return a
 	}
 	return b
-}
\ No newline at end of file
+}
@@ -0,0 +1,41 @@
+package messages
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	t "github.com/traces/types"
+)
+
+func TestGenerateNoEvents(t *testing.T) {
+	trace := Generate(Config{Processes: []string{"A", "B"}}, rand.New(rand.NewSource(1)))
+	if len(trace) != 0 {
+		t.Errorf("generated %d events with a budget of none", len(trace))
+	}
+}
+
+func TestGenerateOneProcess(tt *testing.T) {
+	trace := Generate(Config{Processes: []string{"A"}, NumEvents: 5}, rand.New(rand.NewSource(1)))
+	if len(trace) != 5 {
+		tt.Fatalf("generated %d events, want 5", len(trace))
+	}
+	for i, e := range trace {
+		if e.Type != t.EventInternal || e.Process != "A" || e.VClock["A"] != i+1 {
+			tt.Errorf("e-%d is %s on %s with clock %s, want INTERNAL on A with A:%d", i, e.Type, e.Process, e.VClock, i+1)
+		}
+	}
+}
+
+func TestValidateNonFinite(tt *testing.T) {
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		cfg := Config{Processes: []string{"A", "B"}, NumEvents: 5, Weights: map[string]float64{"A": v}}
+		if err := cfg.Validate(); err == nil {
+			tt.Errorf("weight %g accepted", v)
+		}
+		cfg = Config{Processes: []string{"A", "B"}, NumEvents: 5, ReceiveRates: map[string]float64{"A": v}}
+		if err := cfg.Validate(); err == nil {
+			tt.Errorf("receive rate %g accepted", v)
+		}
+	}
+}
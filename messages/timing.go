@@ -0,0 +1,79 @@
+package messages
+
+import (
+	"time"
+
+	t "github.com/traces/types"
+)
+
+// Timing makes the generator stamp events with physical times. Each process
+// runs in real time, taking on average Step between its own events, and
+// messages arrive on average Latency after they were sent, never faster
+// than half of it. Each process reads a clock that is off by a fixed amount
+// of at most Skew either way, so timestamps of causally related events on
+// different processes may disagree with happens-before. With HLC set, events
+// also get the hybrid logical clocks their processes would keep from those
+// skewed readings.
+type Timing struct {
+	Start   time.Time // real time the run begins
+	Step    time.Duration
+	Latency time.Duration
+	Skew    time.Duration
+	HLC     bool
+}
+
+// stamp sets the Timestamp, and with HLC the HLC, of every event of the
+// trace.
+func (tm Timing) stamp(trace t.Trace, processes []string, synchronous bool, d *decider) {
+	offset := make(map[string]time.Duration, len(processes))
+	if tm.Skew > 0 {
+		for _, p := range processes {
+			offset[p] = time.Duration((2*d.float64("skew "+p) - 1) * float64(tm.Skew))
+			d.action("%s's clock is off by %v", p, offset[p])
+		}
+	}
+	exp := func(mean time.Duration, purpose string) time.Duration {
+		if mean <= 0 {
+			return 0
+		}
+		return time.Duration(d.expFloat64(purpose) * float64(mean))
+	}
+
+	now := make(map[string]time.Duration) // real time of each process' last event
+	sent := make(map[int]time.Duration)   // real time of each message's SEND
+	barrier, phase := time.Duration(0), 0
+	for i, e := range trace {
+		at := now[e.Process] + exp(tm.Step, "step")
+		switch e.Type {
+		case t.EventSend:
+			sent[e.MessageID] = at
+		case t.EventReceive:
+			if synchronous {
+				at = later(at, sent[e.MessageID])
+			} else {
+				at = later(at, sent[e.MessageID]+tm.Latency/2+exp(tm.Latency/2, "latency"))
+			}
+		case t.EventBarrier:
+			// Nobody leaves a barrier before everyone has reached it
+			if e.Phase != phase {
+				phase = e.Phase
+				for _, p := range processes {
+					barrier = later(barrier, now[p])
+				}
+			}
+			at = later(at, barrier)
+		}
+		now[e.Process] = at
+		trace[i].Timestamp = tm.Start.Add(at + offset[e.Process])
+	}
+	if tm.HLC {
+		t.AssignHLC(trace)
+	}
+}
+
+func later(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,94 @@
+package minimize
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/traces/messages"
+	"github.com/traces/property"
+	t "github.com/traces/types"
+)
+
+// Reproducer is the smallest failing configuration found by Seed.
+type Reproducer struct {
+	NumEvents int
+	Seed      int64
+	Trace     t.Trace // the generated trace, shrunk by Trace
+}
+
+// Seed searches for the smallest event count (and a seed in [seed,
+// seed+tries)) for which the generator produces a trace violating one of the
+// properties, then shrinks that trace further. It returns an error if the
+// original configuration does not fail.
+func Seed(cfg messages.Config, seed int64, tries int, props ...property.Property) (Reproducer, error) {
+	generate := func(n int, s int64) t.Trace {
+		c := cfg
+		c.NumEvents = n
+		return messages.Generate(c, rand.New(rand.NewSource(s)))
+	}
+
+	if !property.Fails(generate(cfg.NumEvents, seed), props...) {
+		return Reproducer{}, fmt.Errorf("seed %d with %d events does not violate any property", seed, cfg.NumEvents)
+	}
+
+	return Search(generate, cfg.NumEvents, seed, tries, props...)
+}
+
+// Search looks for the smallest event count up to maxEvents, and a seed in
+// [seed, seed+tries), for which generate returns a trace violating one of the
+// properties, then shrinks that trace with Trace. It synthesizes
+// counterexamples from any generator, such as a protocol scenario cut short
+// after n events.
+func Search(generate func(n int, seed int64) t.Trace, maxEvents int, seed int64, tries int, props ...property.Property) (Reproducer, error) {
+	for n := 1; n <= maxEvents; n++ {
+		for s := seed; s < seed+int64(max(tries, 1)); s++ {
+			trace := generate(n, s)
+			if property.Fails(trace, props...) {
+				return Reproducer{NumEvents: n, Seed: s, Trace: Trace(trace, props...)}, nil
+			}
+		}
+	}
+	return Reproducer{}, fmt.Errorf("no trace of at most %d events from seeds %d to %d violates the properties",
+		maxEvents, seed, seed+int64(max(tries, 1))-1)
+}
+
+// Trace greedily removes messages (their SEND and RECV together) from a
+// failing trace as long as it keeps violating one of the properties, and
+// returns the smallest trace found. Clocks are recomputed after each removal.
+func Trace(trace t.Trace, props ...property.Property) t.Trace {
+	current := trace
+	for changed := true; changed; {
+		changed = false
+		for _, id := range messageIDs(current) {
+			candidate, err := t.ReconstructClocks(without(current, id))
+			if err != nil || !property.Fails(candidate, props...) {
+				continue
+			}
+			current = candidate
+			changed = true
+		}
+	}
+	return current
+}
+
+func messageIDs(trace t.Trace) []int {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, e := range trace {
+		if !seen[e.MessageID] {
+			seen[e.MessageID] = true
+			ids = append(ids, e.MessageID)
+		}
+	}
+	return ids
+}
+
+func without(trace t.Trace, messageID int) t.Trace {
+	out := make(t.Trace, 0, len(trace))
+	for _, e := range trace {
+		if e.MessageID != messageID {
+			out = append(out, e)
+		}
+	}
+	return out
+}
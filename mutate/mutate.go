@@ -0,0 +1,193 @@
+// Package mutate perturbs traces in controlled ways, producing variants for
+// testing how analyses cope with reordered, lossy or corrupt input.
+package mutate
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// Mutation records one perturbation applied to a trace.
+type Mutation struct {
+	Op          string
+	Events      []int // trace indices of the events involved, before the mutation
+	Description string
+}
+
+func (m Mutation) String() string { return fmt.Sprintf("[%s] %s", m.Op, m.Description) }
+
+// Op is a kind of perturbation. Apply changes trace in place or returns a new
+// one; it reports false if the trace offers nothing to perturb.
+type Op interface {
+	Name() string
+	Apply(trace t.Trace, r *rand.Rand) (t.Trace, Mutation, bool)
+}
+
+// SwapConcurrent exchanges two adjacent concurrent events, giving another
+// valid linear extension of happens-before.
+type SwapConcurrent struct{}
+
+func (SwapConcurrent) Name() string { return "swap" }
+
+func (SwapConcurrent) Apply(trace t.Trace, r *rand.Rand) (t.Trace, Mutation, bool) {
+	var pairs []int
+	for i := 0; i+1 < len(trace); i++ {
+		if trace[i].VClock.ConcurrentWith(trace[i+1].VClock) {
+			pairs = append(pairs, i)
+		}
+	}
+	if len(pairs) == 0 {
+		return trace, Mutation{}, false
+	}
+	i := pairs[r.Intn(len(pairs))]
+	trace[i], trace[i+1] = trace[i+1], trace[i]
+	return trace, Mutation{
+		Op:          "swap",
+		Events:      []int{i, i + 1},
+		Description: fmt.Sprintf("swapped concurrent e-%d and e-%d", i, i+1),
+	}, true
+}
+
+// DropReceive removes a RECV, as if the network lost the message, and
+// recomputes clocks so the variant stays consistent.
+type DropReceive struct{}
+
+func (DropReceive) Name() string { return "drop-receive" }
+
+func (DropReceive) Apply(trace t.Trace, r *rand.Rand) (t.Trace, Mutation, bool) {
+	var recvs []int
+	for i, e := range trace {
+		if e.Type == t.EventReceive {
+			recvs = append(recvs, i)
+		}
+	}
+	if len(recvs) == 0 {
+		return trace, Mutation{}, false
+	}
+	i := recvs[r.Intn(len(recvs))]
+	m := Mutation{
+		Op:          "drop-receive",
+		Events:      []int{i},
+		Description: fmt.Sprintf("dropped e-%d, the receive of Msg-%d on %s", i, trace[i].MessageID, trace[i].Process),
+	}
+	rebuilt, err := t.ReconstructClocks(append(trace[:i:i], trace[i+1:]...))
+	if err != nil {
+		return trace, Mutation{}, false
+	}
+	return rebuilt, m, true
+}
+
+// CorruptClock changes one entry of one event's vector clock by a small
+// amount, leaving the rest of the trace untouched.
+type CorruptClock struct{}
+
+func (CorruptClock) Name() string { return "corrupt-clock" }
+
+func (CorruptClock) Apply(trace t.Trace, r *rand.Rand) (t.Trace, Mutation, bool) {
+	if len(trace) == 0 {
+		return trace, Mutation{}, false
+	}
+	i := r.Intn(len(trace))
+	clock := trace[i].VClock
+	if len(clock) == 0 {
+		return trace, Mutation{}, false
+	}
+	processes := make([]string, 0, len(clock))
+	for p := range clock {
+		processes = append(processes, p)
+	}
+	sort.Strings(processes)
+	p := processes[r.Intn(len(processes))]
+	old := clock[p]
+	delta := r.Intn(3) + 1
+	if old >= delta && r.Intn(2) == 0 {
+		delta = -delta
+	}
+	clock[p] = old + delta
+	return trace, Mutation{
+		Op:          "corrupt-clock",
+		Events:      []int{i},
+		Description: fmt.Sprintf("changed %s's entry of e-%d's clock from %d to %d", p, i, old, old+delta),
+	}, true
+}
+
+var known = map[string]Op{}
+
+// Register makes an op available to Lookup and ParseOps.
+func Register(op Op) {
+	known[op.Name()] = op
+}
+
+func init() {
+	Register(SwapConcurrent{})
+	Register(DropReceive{})
+	Register(CorruptClock{})
+}
+
+// Lookup returns the op registered under name.
+func Lookup(name string) (Op, error) {
+	op, ok := known[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown mutation %q (known: %s)", name, strings.Join(Names(), ", "))
+	}
+	return op, nil
+}
+
+// Names returns the names of all registered ops.
+func Names() []string {
+	names := make([]string, 0, len(known))
+	for n := range known {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseOps parses a comma separated list of op names, each optionally
+// repeated as in "swap*3".
+func ParseOps(list string) ([]Op, error) {
+	var out []Op
+	for _, item := range strings.Split(list, ",") {
+		name, count, repeated := strings.Cut(strings.TrimSpace(item), "*")
+		n := 1
+		if repeated {
+			var err error
+			if n, err = strconv.Atoi(count); err != nil || n < 1 {
+				return nil, fmt.Errorf("mutation %q: bad repeat count", item)
+			}
+		}
+		op, err := Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		for range n {
+			out = append(out, op)
+		}
+	}
+	return out, nil
+}
+
+// MutateTrace applies the ops in order to a copy of trace and returns the
+// variant with the mutations made. Ops that find nothing to perturb are
+// skipped.
+func MutateTrace(trace t.Trace, r *rand.Rand, ops ...Op) (t.Trace, []Mutation) {
+	variant := make(t.Trace, len(trace))
+	for i, e := range trace {
+		e.VClock = t.DeepCopy(e.VClock)
+		variant[i] = e
+	}
+	var done []Mutation
+	for _, op := range ops {
+		var m Mutation
+		var ok bool
+		if variant, m, ok = op.Apply(variant, r); ok {
+			done = append(done, m)
+		}
+	}
+	return variant, done
+}
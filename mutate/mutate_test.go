@@ -0,0 +1,22 @@
+package mutate
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/traces/script"
+	t "github.com/traces/types"
+)
+
+func TestDropReceiveKeepsBarriers(tt *testing.T) {
+	trace := script.MustCompile("A -> B: m1; A steps; barrier; B steps")
+	mutant, m, ok := DropReceive{}.Apply(trace, rand.New(rand.NewSource(1)))
+	if !ok {
+		tt.Fatal("no receive dropped")
+	}
+	for _, e := range mutant {
+		if e.Type == t.EventBarrier && e.Process == "B" && e.VClock["A"] < 2 {
+			tt.Errorf("after %s, B leaves the barrier with %s, not knowing A's two steps", m.Description, e.VClock)
+		}
+	}
+}
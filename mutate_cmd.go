@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/traces/mutate"
+)
+
+// runMutate implements `trace mutate`: it writes a perturbed variant of a
+// trace and lists the perturbations on stderr.
+func runMutate(args []string) error {
+	fs := flag.NewFlagSet("mutate", flag.ContinueOnError)
+	in := fs.String("in", "", "JSON trace to read")
+	list := fs.String("ops", "swap", "comma separated mutations, each optionally repeated as swap*3: "+strings.Join(mutate.Names(), ", "))
+	seed := fs.Int64("seed", 1, "seed choosing what to perturb")
+	out := fs.String("out", "", "write the variant to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	ops, err := mutate.ParseOps(*list)
+	if err != nil {
+		return err
+	}
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	variant, mutations := mutate.MutateTrace(trace, rand.New(rand.NewSource(*seed)), ops...)
+	for _, m := range mutations {
+		fmt.Fprintln(os.Stderr, m)
+	}
+	if len(mutations) < len(ops) {
+		fmt.Fprintf(os.Stderr, "%d of %d mutations found nothing to perturb\n", len(ops)-len(mutations), len(ops))
+	}
+	if *out == "" {
+		return writeTrace(os.Stdout, variant)
+	}
+	return saveTrace(*out, variant)
+}
@@ -0,0 +1,104 @@
+package property
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/traces/analysis"
+	t "github.com/traces/types"
+)
+
+// WriteConflict is a pair of concurrent writes to the same key, neither of
+// which saw the other, with how later reads resolved it.
+type WriteConflict struct {
+	Writes [2]int // trace indices, in trace order
+	// Winner is the write returned by the first read that follows both
+	// writes and returns one of them, -1 if no read did.
+	Winner int
+	// Witness is that read, -1 if there is none.
+	Witness int
+	// Flipped lists later reads following both writes that returned the
+	// other write: replicas disagreed on the resolution.
+	Flipped []int
+}
+
+// Resolved reports whether a read observed which write won.
+func (c WriteConflict) Resolved() bool { return c.Winner >= 0 }
+
+// KeyConflicts are the write conflicts of one key.
+type KeyConflicts struct {
+	Key       string
+	Conflicts []WriteConflict
+}
+
+// WriteConflicts finds every pair of concurrent writes to the same key in a
+// key-value labeled trace, grouped by key in key order.
+func WriteConflicts(trace t.Trace, labels SessionLabels) []KeyConflicts {
+	writes := make(map[string][]int)
+	var reads []analysis.KVOp
+	for _, op := range labels.Ops(trace) {
+		if op.Write {
+			writes[op.Key] = append(writes[op.Key], op.Index)
+		} else {
+			reads = append(reads, op)
+		}
+	}
+
+	var out []KeyConflicts
+	for _, key := range slices.Sorted(maps.Keys(writes)) {
+		ws := writes[key]
+		kc := KeyConflicts{Key: key}
+		for i, a := range ws {
+			for _, b := range ws[i+1:] {
+				if !trace[a].VClock.ConcurrentWith(trace[b].VClock) {
+					continue
+				}
+				c := WriteConflict{Writes: [2]int{a, b}, Winner: -1, Witness: -1}
+				for _, r := range reads {
+					if r.Key != key || (r.From != a && r.From != b) {
+						continue
+					}
+					rc := trace[r.Index].VClock
+					if !trace[a].VClock.HappensBefore(rc) || !trace[b].VClock.HappensBefore(rc) {
+						continue
+					}
+					if c.Winner < 0 {
+						c.Winner, c.Witness = r.From, r.Index
+					} else if r.From != c.Winner {
+						c.Flipped = append(c.Flipped, r.Index)
+					}
+				}
+				kc.Conflicts = append(kc.Conflicts, c)
+			}
+		}
+		if len(kc.Conflicts) > 0 {
+			out = append(out, kc)
+		}
+	}
+	return out
+}
+
+func (kc KeyConflicts) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "key %s: %d concurrent write pair(s)\n", t.QuoteName(kc.Key), len(kc.Conflicts))
+	for _, c := range kc.Conflicts {
+		a, w := c.Writes[0], c.Writes[1]
+		fmt.Fprintf(&b, "  e-%d || e-%d: ", a, w)
+		if !c.Resolved() {
+			b.WriteString("unresolved\n")
+			continue
+		}
+		fmt.Fprintf(&b, "e-%d won (read e-%d)", c.Winner, c.Witness)
+		if len(c.Flipped) > 0 {
+			refs := make([]string, len(c.Flipped))
+			for i, r := range c.Flipped {
+				refs[i] = fmt.Sprintf("e-%d", r)
+			}
+			fmt.Fprintf(&b, ", but %s returned the other write", strings.Join(refs, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package property
+
+import (
+	"fmt"
+
+	t "github.com/traces/types"
+)
+
+// CrashStop requires a crashed process to stay silent until it recovers:
+// after a CRASH its next event, if any, must be a RECOVER, and a RECOVER
+// must follow a CRASH.
+type CrashStop struct{}
+
+func (CrashStop) Name() string { return "crash-stop" }
+
+func (CrashStop) Check(trace t.Trace) []Violation {
+	var out []Violation
+	down := make(map[string]int) // process -> its CRASH, while it is down
+	for i, e := range trace {
+		crash, isDown := down[e.Process]
+		switch {
+		case e.Type == t.EventRecover && !isDown:
+			out = append(out, Violation{
+				Property: "crash-stop",
+				Events:   []int{i},
+				Message:  fmt.Sprintf("%s recovered without having crashed", e.Process),
+			})
+		case e.Type != t.EventRecover && isDown:
+			out = append(out, Violation{
+				Property: "crash-stop",
+				Events:   []int{crash, i},
+				Message:  fmt.Sprintf("%s emitted a %s event while crashed", e.Process, e.Type),
+			})
+		}
+		switch e.Type {
+		case t.EventCrash:
+			down[e.Process] = i
+		case t.EventRecover:
+			delete(down, e.Process)
+		}
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package property
+
+import (
+	"testing"
+
+	t "github.com/traces/types"
+)
+
+// TestDegenerateTraces checks every property that needs no parameters on an
+// empty trace, a single process and events that are all concurrent: none of
+// them has anything to violate.
+func TestDegenerateTraces(tt *testing.T) {
+	traces := map[string]t.Trace{
+		"empty": nil,
+		"one process": {
+			{Type: t.EventInternal, Process: "A", VClock: t.VectorClock{"A": 1}, MessageID: -1},
+			{Type: t.EventInternal, Process: "A", VClock: t.VectorClock{"A": 2}, MessageID: -1},
+		},
+		"concurrent": {
+			{Type: t.EventInternal, Process: "A", VClock: t.VectorClock{"A": 1}, MessageID: -1},
+			{Type: t.EventInternal, Process: "B", VClock: t.VectorClock{"B": 1}, MessageID: -1},
+		},
+	}
+	for _, name := range Names() {
+		p, err := Lookup(name)
+		if err != nil {
+			continue // needs parameters
+		}
+		for shape, trace := range traces {
+			if vs := p.Check(trace); len(vs) > 0 {
+				tt.Errorf("%s on the %s trace: %v", name, shape, vs)
+			}
+		}
+	}
+}
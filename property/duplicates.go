@@ -0,0 +1,36 @@
+package property
+
+import (
+	"fmt"
+
+	t "github.com/traces/types"
+)
+
+// DuplicateDelivery is violated by every RECV of a message its process had
+// already received: an at-least-once channel redelivered it, and unless
+// the receiver is idempotent its effect happened twice.
+type DuplicateDelivery struct{}
+
+func (DuplicateDelivery) Name() string { return "duplicates" }
+
+func (DuplicateDelivery) Check(trace t.Trace) []Violation {
+	var out []Violation
+	first := make(map[recipient]int)
+	for i, e := range trace {
+		if e.Type != t.EventReceive {
+			continue
+		}
+		r := recipient{e.MessageID, e.Process}
+		f, ok := first[r]
+		if !ok {
+			first[r] = i
+			continue
+		}
+		out = append(out, Violation{
+			Property: "duplicates",
+			Events:   []int{f, i},
+			Message:  fmt.Sprintf("Msg-%d delivered to %s again, first delivered at e-%d", e.MessageID, e.Process, f),
+		})
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package property
+
+import (
+	"fmt"
+
+	t "github.com/traces/types"
+)
+
+// ElectionSafety requires at most one leader per term: no two processes may
+// have an event labelled as becoming leader in the same term. The labels
+// default to those of the raft scenario, role=leader and term.
+type ElectionSafety struct {
+	RoleLabel, Leader, TermLabel string
+}
+
+func (ElectionSafety) Name() string { return "election-safety" }
+
+func (p ElectionSafety) Check(trace t.Trace) []Violation {
+	role, leader, term := p.RoleLabel, p.Leader, p.TermLabel
+	if role == "" {
+		role = "role"
+	}
+	if leader == "" {
+		leader = "leader"
+	}
+	if term == "" {
+		term = "term"
+	}
+
+	var out []Violation
+	elected := make(map[string]int) // term -> first leader event
+	for i, e := range trace {
+		if e.Labels[role] != leader {
+			continue
+		}
+		tm := e.Labels[term]
+		first, ok := elected[tm]
+		if !ok {
+			elected[tm] = i
+			continue
+		}
+		if trace[first].Process != e.Process {
+			out = append(out, Violation{
+				Property: "election-safety",
+				Events:   []int{first, i},
+				Message:  fmt.Sprintf("%s and %s both became leader in term %s", trace[first].Process, e.Process, tm),
+			})
+		}
+	}
+	return out
+}
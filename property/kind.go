@@ -0,0 +1,55 @@
+package property
+
+import (
+	"fmt"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// Kind selects events by type and, optionally, one label value, written
+// "RECV", "RECV:msg=vote", "msg=vote" or "*".
+type Kind struct {
+	Type         *t.EventType // nil matches any type
+	Label, Value string
+}
+
+// ParseKind parses a Kind.
+func ParseKind(s string) (Kind, error) {
+	var k Kind
+	typ, label, hasLabel := strings.Cut(strings.TrimSpace(s), ":")
+	if !hasLabel && strings.Contains(typ, "=") {
+		typ, label, hasLabel = "*", typ, true
+	}
+	if typ != "*" && typ != "" {
+		et, err := t.ParseEventType(typ)
+		if err != nil {
+			return Kind{}, err
+		}
+		k.Type = &et
+	}
+	if hasLabel {
+		name, value, ok := strings.Cut(label, "=")
+		if !ok || name == "" {
+			return Kind{}, fmt.Errorf("kind %q: expected label=value after the type", s)
+		}
+		k.Label, k.Value = name, value
+	}
+	return k, nil
+}
+
+// Matches reports whether e is of the kind.
+func (k Kind) Matches(e t.Event) bool {
+	return (k.Type == nil || *k.Type == e.Type) && (k.Label == "" || e.Labels[k.Label] == k.Value)
+}
+
+func (k Kind) String() string {
+	s := "*"
+	if k.Type != nil {
+		s = k.Type.String()
+	}
+	if k.Label != "" {
+		s += ":" + k.Label + "=" + k.Value
+	}
+	return s
+}
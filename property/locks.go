@@ -0,0 +1,33 @@
+package property
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/traces/analysis"
+	t "github.com/traces/types"
+)
+
+// LockOrder is violated by every cycle in the trace's lock-order graph: the
+// processes nested locks in conflicting orders, so some interleaving of the
+// same code can deadlock even though this run did not.
+type LockOrder struct{}
+
+func (LockOrder) Name() string { return "lock-order" }
+
+func (LockOrder) Check(trace t.Trace) []Violation {
+	var out []Violation
+	for _, inv := range analysis.LockOrderGraph(trace).Inversions(trace) {
+		var events []int
+		for _, e := range inv.Edges {
+			events = append(events, e.Outer, e.Inner)
+		}
+		msg := fmt.Sprintf("locks taken in conflicting orders: %s -> %s",
+			strings.Join(inv.Locks, " -> "), inv.Locks[0])
+		if inv.Concurrent {
+			msg += " by concurrent critical sections"
+		}
+		out = append(out, Violation{Property: "lock-order", Events: events, Message: msg})
+	}
+	return out
+}
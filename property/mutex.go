@@ -0,0 +1,58 @@
+package property
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	t "github.com/traces/types"
+)
+
+// MutualExclusion is violated by every pair of concurrent critical sections
+// on the same lock: neither section's RELEASE happens before the other's
+// ACQUIRE, so nothing kept them from overlapping. A section without a
+// RELEASE lasts to the end of the trace.
+type MutualExclusion struct{}
+
+func (MutualExclusion) Name() string { return "mutex" }
+
+func (MutualExclusion) Check(trace t.Trace) []Violation {
+	type section struct{ acquire, release int }
+	sections := make(map[string][]section)
+	open := make(map[[2]string]int) // process, lock -> index into sections[lock]
+	for i, e := range trace {
+		key := [2]string{e.Process, e.Lock}
+		switch e.Type {
+		case t.EventAcquire:
+			open[key] = len(sections[e.Lock])
+			sections[e.Lock] = append(sections[e.Lock], section{i, -1})
+		case t.EventRelease:
+			if s, ok := open[key]; ok {
+				sections[e.Lock][s].release = i
+				delete(open, key)
+			}
+		}
+	}
+
+	ordered := func(a, b section) bool {
+		return a.release >= 0 && trace[a.release].VClock.HappensBefore(trace[b.acquire].VClock)
+	}
+	var out []Violation
+	for _, lock := range slices.Sorted(maps.Keys(sections)) {
+		ss := sections[lock]
+		for i, a := range ss {
+			for _, b := range ss[i+1:] {
+				if ordered(a, b) || ordered(b, a) {
+					continue
+				}
+				out = append(out, Violation{
+					Property: "mutex",
+					Events:   []int{a.acquire, b.acquire},
+					Message: fmt.Sprintf("critical sections on %s of %s and %s are concurrent", t.QuoteName(lock),
+						trace[a.acquire].Process, trace[b.acquire].Process),
+				})
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package property
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// Func turns a callback into a property, so that code embedding the checker
+// can reason about application data carried in labels without declaring a
+// type. Explain is called for every event of kind Match and returns why the
+// event violates the property, or "" if it does not.
+type Func struct {
+	PropName string
+	Match    Kind
+	Explain  func(trace t.Trace, i int) string
+}
+
+func (f Func) Name() string { return f.PropName }
+
+func (f Func) Check(trace t.Trace) []Violation {
+	var out []Violation
+	for i, e := range trace {
+		if !f.Match.Matches(e) {
+			continue
+		}
+		if msg := f.Explain(trace, i); msg != "" {
+			out = append(out, Violation{Property: f.PropName, Events: []int{i}, Message: msg})
+		}
+	}
+	return out
+}
+
+// newPayload builds the "payload" property: every event of kind match (any
+// event by default) must carry label, with one of values when given as
+// "a|b|c".
+func newPayload(params map[string]string) (Property, error) {
+	label := params["label"]
+	if label == "" {
+		return nil, fmt.Errorf("payload needs a label, e.g. payload:match=SEND;label=op;values=get|put")
+	}
+	match := Kind{}
+	if params["match"] != "" {
+		var err error
+		if match, err = ParseKind(params["match"]); err != nil {
+			return nil, err
+		}
+	}
+	var values []string
+	if params["values"] != "" {
+		values = strings.Split(params["values"], "|")
+	}
+	return Func{
+		PropName: "payload",
+		Match:    match,
+		Explain: func(trace t.Trace, i int) string {
+			e := trace[i]
+			v, ok := e.Labels[label]
+			if !ok {
+				return fmt.Sprintf("%s event on %s has no %s label", e.Type, e.Process, label)
+			}
+			if values != nil && !slices.Contains(values, v) {
+				return fmt.Sprintf("%s event on %s has %s=%s, expected one of %s", e.Type, e.Process, label, v, strings.Join(values, ", "))
+			}
+			return ""
+		},
+	}, nil
+}
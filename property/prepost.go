@@ -0,0 +1,58 @@
+package property
+
+import (
+	"fmt"
+
+	t "github.com/traces/types"
+)
+
+// PrePost pairs a precondition with the events that need it: every event of
+// kind Post must be causally preceded by an event of kind Pre, such as a
+// commit by the votes asking for it. With Same set, the two events must also
+// agree on that label, for instance the transaction ID.
+type PrePost struct {
+	Pre, Post Kind
+	Same      string
+}
+
+func newPrePost(params map[string]string) (Property, error) {
+	if params["pre"] == "" || params["post"] == "" {
+		return nil, fmt.Errorf("pre-post needs pre and post event kinds, e.g. pre-post:pre=RECV:msg=vote;post=SEND:msg=commit")
+	}
+	pre, err := ParseKind(params["pre"])
+	if err != nil {
+		return nil, err
+	}
+	post, err := ParseKind(params["post"])
+	if err != nil {
+		return nil, err
+	}
+	return PrePost{Pre: pre, Post: post, Same: params["same"]}, nil
+}
+
+func (PrePost) Name() string { return "pre-post" }
+
+func (p PrePost) Check(trace t.Trace) []Violation {
+	var out []Violation
+	for i, e := range trace {
+		if !p.Post.Matches(e) {
+			continue
+		}
+		met := false
+		for _, pre := range trace[:i] {
+			if p.Pre.Matches(pre) && (p.Same == "" || pre.Labels[p.Same] == e.Labels[p.Same]) &&
+				(pre.VClock.HappensBefore(e.VClock)) {
+				met = true
+				break
+			}
+		}
+		if !met {
+			out = append(out, Violation{
+				Property: "pre-post",
+				Events:   []int{i},
+				Message:  fmt.Sprintf("%s event on %s has no preceding %s event", p.Post, e.Process, p.Pre),
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package property
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	t "github.com/traces/types"
+)
+
+// Stats counts the work a property performs while checking a trace.
+type Stats struct {
+	Triggers int            // candidate situations the predicate was evaluated on
+	Visits   int            // events inspected
+	Regions  map[string]int // events inspected per process
+}
+
+// visit records that the event was inspected. It is safe on a nil Stats.
+func (s *Stats) visit(e t.Event) {
+	if s == nil {
+		return
+	}
+	s.Visits++
+	if s.Regions == nil {
+		s.Regions = make(map[string]int)
+	}
+	s.Regions[e.Process]++
+}
+
+// trigger records one predicate evaluation. It is safe on a nil Stats.
+func (s *Stats) trigger() {
+	if s != nil {
+		s.Triggers++
+	}
+}
+
+// Instrumented is implemented by properties that can report the work they do.
+type Instrumented interface {
+	CheckProfiled(trace t.Trace, stats *Stats) []Violation
+}
+
+// Profile is the cost of checking one property.
+type Profile struct {
+	Property   string
+	Duration   time.Duration
+	Violations int
+	Stats
+}
+
+// CheckProfiled checks every property like Check, additionally recording
+// how long each took and, for instrumented properties, how many predicates
+// were evaluated and which processes' events were visited.
+func CheckProfiled(trace t.Trace, props ...Property) ([]Violation, []Profile) {
+	var out []Violation
+	profiles := make([]Profile, 0, len(props))
+	for _, p := range props {
+		prof := Profile{Property: p.Name()}
+		start := time.Now()
+		var vs []Violation
+		if ip, ok := p.(Instrumented); ok {
+			vs = ip.CheckProfiled(trace, &prof.Stats)
+		} else {
+			vs = p.Check(trace)
+		}
+		prof.Duration = time.Since(start)
+		prof.Violations = len(vs)
+		profiles = append(profiles, prof)
+		out = append(out, vs...)
+	}
+	sort.SliceStable(profiles, func(i, j int) bool { return profiles[i].Duration > profiles[j].Duration })
+	return out, profiles
+}
+
+// HotRegions returns up to n processes whose events were visited most.
+func (p Profile) HotRegions(n int) []string {
+	procs := make([]string, 0, len(p.Regions))
+	for proc := range p.Regions {
+		procs = append(procs, proc)
+	}
+	sort.Slice(procs, func(i, j int) bool {
+		if p.Regions[procs[i]] != p.Regions[procs[j]] {
+			return p.Regions[procs[i]] > p.Regions[procs[j]]
+		}
+		return procs[i] < procs[j]
+	})
+	if len(procs) > n {
+		procs = procs[:n]
+	}
+	return procs
+}
+
+func (p Profile) String() string {
+	var hot []string
+	for _, proc := range p.HotRegions(3) {
+		hot = append(hot, fmt.Sprintf("%s:%d", proc, p.Regions[proc]))
+	}
+	return fmt.Sprintf("%-16s %12s %10d triggers %10d visits  hot: %s",
+		p.Property, p.Duration, p.Triggers, p.Visits, strings.Join(hot, " "))
+}
@@ -0,0 +1,219 @@
+package property
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// Violation is a single failure of a property on a trace.
+type Violation struct {
+	Property string `json:"property"`
+	Events   []int  `json:"events"` // trace indices of the events involved
+	Message  string `json:"message"`
+	// Sources are the log lines Events were imported from, nil where an
+	// event has none, once a report has located them.
+	Sources []*t.Source `json:"sources,omitempty"`
+}
+
+func (v Violation) String() string {
+	refs := make([]string, len(v.Events))
+	for i, e := range v.Events {
+		refs[i] = fmt.Sprintf("e-%d", e)
+	}
+	return fmt.Sprintf("[%s] %s (%s)", v.Property, v.Message, strings.Join(refs, ", "))
+}
+
+// Property is a safety property checked against a whole trace.
+type Property interface {
+	Name() string
+	Check(trace t.Trace) []Violation
+}
+
+// Check runs every property on the trace and returns all violations.
+func Check(trace t.Trace, props ...Property) []Violation {
+	var out []Violation
+	for _, p := range props {
+		out = append(out, p.Check(trace)...)
+	}
+	return out
+}
+
+// Fails reports whether any of the properties is violated by the trace.
+func Fails(trace t.Trace, props ...Property) bool {
+	for _, p := range props {
+		if len(p.Check(trace)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// delivery pairs the SEND and RECV of a message by trace index.
+type delivery struct {
+	send, recv int
+}
+
+// deliveries returns every delivered message, ordered by receive index. A
+// broadcast yields one delivery per receiver, all sharing the same send.
+// Only the first delivery to each receiver counts; redeliveries are the
+// concern of DuplicateDelivery.
+func deliveries(trace t.Trace) []delivery {
+	sends := make(map[int]int)
+	for i, e := range trace {
+		if e.Type == t.EventSend {
+			sends[e.MessageID] = i
+		}
+	}
+	var out []delivery
+	delivered := make(map[recipient]bool)
+	for i, e := range trace {
+		if s, ok := sends[e.MessageID]; ok && e.Type == t.EventReceive && !delivered[recipient{e.MessageID, e.Process}] {
+			delivered[recipient{e.MessageID, e.Process}] = true
+			out = append(out, delivery{send: s, recv: i})
+		}
+	}
+	return out
+}
+
+// recipient identifies the copy of a message delivered to one process.
+type recipient struct {
+	id      int
+	process string
+}
+
+// receivedBefore reports whether recv a happens before recv b. Receives on the
+// same process are ordered by their local clock component.
+func receivedBefore(trace t.Trace, a, b int) bool {
+	return trace[a].VClock.HappensBefore(trace[b].VClock)
+}
+
+// FIFO requires messages on each sender -> receiver channel to be delivered
+// in the order they were sent. A broadcast counts as a message on the
+// channel to each of its receivers.
+type FIFO struct{}
+
+func (FIFO) Name() string { return "fifo" }
+
+func (p FIFO) Check(trace t.Trace) []Violation { return p.CheckProfiled(trace, nil) }
+
+func (FIFO) CheckProfiled(trace t.Trace, stats *Stats) []Violation {
+	var out []Violation
+	ds := deliveries(trace)
+	for i, a := range ds {
+		for _, b := range ds[i+1:] {
+			stats.trigger()
+			stats.visit(trace[a.recv])
+			stats.visit(trace[b.recv])
+			sa, sb := trace[a.send], trace[b.send]
+			if a.send == b.send || sa.Process != sb.Process || trace[a.recv].Process != trace[b.recv].Process {
+				continue
+			}
+			if sb.VClock.HappensBefore(sa.VClock) && receivedBefore(trace, a.recv, b.recv) {
+				out = append(out, Violation{
+					Property: "fifo",
+					Events:   []int{b.send, a.send, a.recv, b.recv},
+					Message: fmt.Sprintf("Msg-%d overtook Msg-%d on channel %s->%s",
+						sa.MessageID, sb.MessageID, sa.Process, trace[a.recv].Process),
+				})
+			}
+		}
+	}
+	return out
+}
+
+// CausalDelivery requires that if send(m1) happens before send(m2) and both
+// go to the same process, m1 is delivered before m2. For broadcasts this is
+// causal broadcast: every receiver of both must deliver them in that order.
+type CausalDelivery struct{}
+
+func (CausalDelivery) Name() string { return "causal" }
+
+func (p CausalDelivery) Check(trace t.Trace) []Violation { return p.CheckProfiled(trace, nil) }
+
+func (CausalDelivery) CheckProfiled(trace t.Trace, stats *Stats) []Violation {
+	var out []Violation
+	ds := deliveries(trace)
+	for i, a := range ds {
+		for _, b := range ds[i+1:] {
+			stats.trigger()
+			stats.visit(trace[a.recv])
+			stats.visit(trace[b.recv])
+			if a.send == b.send || trace[a.recv].Process != trace[b.recv].Process {
+				continue
+			}
+			sa, sb := trace[a.send], trace[b.send]
+			if sb.VClock.HappensBefore(sa.VClock) && receivedBefore(trace, a.recv, b.recv) {
+				out = append(out, Violation{
+					Property: "causal",
+					Events:   []int{b.send, a.send, a.recv, b.recv},
+					Message: fmt.Sprintf("Msg-%d delivered to %s before causally earlier Msg-%d",
+						sa.MessageID, trace[a.recv].Process, sb.MessageID),
+				})
+			}
+		}
+	}
+	return out
+}
+
+// Factory builds a property of some kind from its parameters.
+type Factory func(params map[string]string) (Property, error)
+
+// kinds maps property kinds to their factories.
+var kinds = map[string]Factory{
+	"fifo":       func(map[string]string) (Property, error) { return FIFO{}, nil },
+	"causal":     func(map[string]string) (Property, error) { return CausalDelivery{}, nil },
+	"lock-order": func(map[string]string) (Property, error) { return LockOrder{}, nil },
+	"mutex":      func(map[string]string) (Property, error) { return MutualExclusion{}, nil },
+	"quorum":     newQuorum,
+	"pre-post":   newPrePost,
+	"payload":    newPayload,
+	"read-your-writes": func(params map[string]string) (Property, error) {
+		return ReadYourWrites{Labels: sessionLabels(params)}, nil
+	},
+	"monotonic-reads": func(params map[string]string) (Property, error) {
+		return MonotonicReads{Labels: sessionLabels(params)}, nil
+	},
+	"crash-stop": func(map[string]string) (Property, error) { return CrashStop{}, nil },
+	"election-safety": func(params map[string]string) (Property, error) {
+		return ElectionSafety{RoleLabel: params["role"], Leader: params["leader"], TermLabel: params["term"]}, nil
+	},
+	"duplicates": func(map[string]string) (Property, error) { return DuplicateDelivery{}, nil },
+	"stale-reads": func(params map[string]string) (Property, error) {
+		return StaleReadsProperty{Labels: sessionLabels(params)}, nil
+	},
+	"write-skew": func(params map[string]string) (Property, error) {
+		return WriteSkew{Labels: sessionLabels(params)}, nil
+	},
+}
+
+// Register makes a property kind available to Lookup and to suite files.
+func Register(kind string, f Factory) {
+	kinds[kind] = f
+}
+
+// New builds a property of the given kind.
+func New(kind string, params map[string]string) (Property, error) {
+	f, ok := kinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown property %q (known: %s)", kind, strings.Join(Names(), ", "))
+	}
+	return f(params)
+}
+
+// Lookup returns the property of the given kind with default parameters.
+func Lookup(name string) (Property, error) {
+	return New(name, nil)
+}
+
+// Names returns the names of all registered property kinds.
+func Names() []string {
+	names := make([]string, 0, len(kinds))
+	for n := range kinds {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,53 @@
+package property
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/traces/analysis"
+	t "github.com/traces/types"
+)
+
+// Quorum is violated by every operation completion that does not causally
+// depend on responses from a quorum of distinct processes (see
+// analysis.Quorums).
+type Quorum struct {
+	Spec analysis.QuorumSpec
+}
+
+func (Quorum) Name() string { return "quorum" }
+
+func (q Quorum) Check(trace t.Trace) []Violation {
+	var out []Violation
+	for _, c := range analysis.Quorums(trace, q.Spec) {
+		if c.Reached() {
+			continue
+		}
+		events := []int{c.Event}
+		for _, p := range c.Responders() {
+			events = append(events, c.Responses[p])
+		}
+		slices.Sort(events)
+		out = append(out, Violation{
+			Property: "quorum",
+			Events:   events,
+			Message: fmt.Sprintf("operation %s completed on %s after %d of %d required responses",
+				t.QuoteName(c.Operation), trace[c.Event].Process, len(c.Responses), c.Required),
+		})
+	}
+	return out
+}
+
+// newQuorum reads the size, response and complete parameters.
+func newQuorum(params map[string]string) (Property, error) {
+	q := Quorum{Spec: analysis.QuorumSpec{Response: params["response"], Complete: params["complete"]}}
+	if s, ok := params["size"]; ok {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("quorum: size must be a positive integer, got %q", s)
+		}
+		q.Spec.Size = n
+	}
+	return q, nil
+}
@@ -0,0 +1,111 @@
+package property
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/traces/analysis"
+	"github.com/traces/dag"
+	t "github.com/traces/types"
+)
+
+// SessionLabels names the labels of key-value operations; see
+// analysis.KVLabels.
+type SessionLabels = analysis.KVLabels
+
+func sessionLabels(params map[string]string) SessionLabels {
+	return SessionLabels{Session: params["session"], Op: params["op"], Key: params["key"], Value: params["value"], Txn: params["txn"], Time: params["time"]}
+}
+
+// supersedes reports whether write b is write a or causally follows it.
+func supersedes(trace t.Trace, b, a int) bool {
+	return b == a || (b >= 0 && trace[a].VClock.HappensBefore(trace[b].VClock))
+}
+
+func describeWrite(trace t.Trace, w int) string {
+	if w < 0 {
+		return "the initial value"
+	}
+	return fmt.Sprintf("the write e-%d on %s", w, trace[w].Process)
+}
+
+func pathString(path []int) string {
+	refs := make([]string, len(path))
+	for i, e := range path {
+		refs[i] = fmt.Sprintf("e-%d", e)
+	}
+	return strings.Join(refs, " -> ")
+}
+
+// ReadYourWrites requires every read to return the session's latest earlier
+// write to the same key or a write that causally follows it. Each violation
+// names the missed write, the read and the write the read returned instead.
+type ReadYourWrites struct {
+	Labels SessionLabels
+}
+
+func (ReadYourWrites) Name() string { return "read-your-writes" }
+
+func (p ReadYourWrites) Check(trace t.Trace) []Violation {
+	var out []Violation
+	written := make(map[[2]string]int) // session, key -> latest write
+	for _, op := range p.Labels.Ops(trace) {
+		sk := [2]string{op.Session, op.Key}
+		if op.Write {
+			written[sk] = op.Index
+			continue
+		}
+		w, ok := written[sk]
+		if !ok || supersedes(trace, op.From, w) {
+			continue
+		}
+		events := []int{w, op.Index}
+		msg := fmt.Sprintf("read of %s on %s in session %s returned %s instead of the session's write e-%d on %s",
+			t.QuoteName(op.Key), trace[op.Index].Process, t.QuoteName(op.Session), describeWrite(trace, op.From),
+			w, trace[w].Process)
+		if op.From >= 0 {
+			events = append(events, op.From)
+			msg += fmt.Sprintf(", which it does not follow (%s vs %s)", trace[op.From].VClock, trace[w].VClock)
+		}
+		out = append(out, Violation{Property: "read-your-writes", Events: events, Message: msg})
+	}
+	return out
+}
+
+// MonotonicReads requires every read to return the write an earlier read of
+// the same session and key returned, or a write that causally follows it.
+// Each violation names the write that was seen, the causal path by which
+// the earlier read obtained it, and the later read that went back in time.
+type MonotonicReads struct {
+	Labels SessionLabels
+}
+
+func (MonotonicReads) Name() string { return "monotonic-reads" }
+
+func (p MonotonicReads) Check(trace t.Trace) []Violation {
+	var out []Violation
+	seen := make(map[[2]string]analysis.KVOp) // session, key -> newest read so far
+	for _, op := range p.Labels.Ops(trace) {
+		if op.Write {
+			continue
+		}
+		sk := [2]string{op.Session, op.Key}
+		prev, ok := seen[sk]
+		if !ok || prev.From < 0 || supersedes(trace, op.From, prev.From) {
+			seen[sk] = op
+			continue
+		}
+		path := dag.CausalPath(trace, prev.From, prev.Index)
+		if path == nil {
+			path = []int{prev.From, prev.Index}
+		}
+		out = append(out, Violation{
+			Property: "monotonic-reads",
+			Events:   append(path, op.Index),
+			Message: fmt.Sprintf("read of %s on %s in session %s returned %s after an earlier read saw the newer write e-%d via %s",
+				t.QuoteName(op.Key), trace[op.Index].Process, t.QuoteName(op.Session), describeWrite(trace, op.From),
+				prev.From, pathString(path)),
+		})
+	}
+	return out
+}
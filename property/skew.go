@@ -0,0 +1,138 @@
+package property
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// Transaction is the labeled reads and writes sharing a transaction label.
+type Transaction struct {
+	ID     string
+	Events []int           // trace indices, in trace order
+	Reads  map[string]bool // keys read
+	Writes map[string]bool // keys written
+}
+
+// Transactions groups the labeled operations of a trace by transaction, in
+// order of their first operation. Operations without a transaction label
+// are left out.
+func Transactions(trace t.Trace, labels SessionLabels) []Transaction {
+	var out []Transaction
+	byID := make(map[string]int)
+	for _, op := range labels.Ops(trace) {
+		if op.Txn == "" {
+			continue
+		}
+		i, ok := byID[op.Txn]
+		if !ok {
+			i = len(out)
+			byID[op.Txn] = i
+			out = append(out, Transaction{ID: op.Txn, Reads: make(map[string]bool), Writes: make(map[string]bool)})
+		}
+		out[i].Events = append(out[i].Events, op.Index)
+		if op.Write {
+			out[i].Writes[op.Key] = true
+		} else {
+			out[i].Reads[op.Key] = true
+		}
+	}
+	return out
+}
+
+// precedes reports whether some event of a happens before some event of b.
+// An event e happens before an event of b exactly when b's joined clock
+// has seen e's own entry.
+func precedes(trace t.Trace, a, b Transaction) bool {
+	join := make(t.VectorClock)
+	for _, i := range b.Events {
+		join.Merge(trace[i].VClock)
+	}
+	for _, i := range a.Events {
+		e := trace[i]
+		if e.VClock[e.Process] <= join[e.Process] {
+			return true
+		}
+	}
+	return false
+}
+
+// SkewCandidate is a pair of concurrent transactions that each read a key
+// the other wrote while writing disjoint keys. Snapshot isolation lets both
+// commit, since neither sees the other's writes and they do not conflict,
+// so the result may match no serial order.
+type SkewCandidate struct {
+	Txns [2]Transaction
+	// Keys[0] are keys the first transaction read and the second wrote,
+	// Keys[1] the other way round.
+	Keys [2][]string
+}
+
+// WriteSkews finds the write-skew candidates of a transaction-labeled trace.
+// Two transactions are concurrent when no event of either happens before
+// an event of the other.
+func WriteSkews(trace t.Trace, labels SessionLabels) []SkewCandidate {
+	txns := Transactions(trace, labels)
+	var out []SkewCandidate
+	for i, a := range txns {
+		for _, b := range txns[i+1:] {
+			if overlap(a.Writes, b.Writes) != nil {
+				continue
+			}
+			ab, ba := overlap(a.Reads, b.Writes), overlap(b.Reads, a.Writes)
+			if ab == nil || ba == nil || precedes(trace, a, b) || precedes(trace, b, a) {
+				continue
+			}
+			out = append(out, SkewCandidate{Txns: [2]Transaction{a, b}, Keys: [2][]string{ab, ba}})
+		}
+	}
+	return out
+}
+
+// overlap returns the keys in both sets, sorted, or nil if there are none.
+func overlap(a, b map[string]bool) []string {
+	var out []string
+	for _, k := range slices.Sorted(maps.Keys(a)) {
+		if b[k] {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func quoteKeys(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = t.QuoteName(k)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// WriteSkew is violated by every write-skew candidate. Candidates are
+// anomalies only if the application relies on an invariant spanning the
+// keys involved, so they are reported for review rather than as proof.
+type WriteSkew struct {
+	Labels SessionLabels
+}
+
+func (WriteSkew) Name() string { return "write-skew" }
+
+func (p WriteSkew) Check(trace t.Trace) []Violation {
+	var out []Violation
+	for _, c := range WriteSkews(trace, p.Labels) {
+		a, b := c.Txns[0], c.Txns[1]
+		events := append(slices.Clone(a.Events), b.Events...)
+		slices.Sort(events)
+		out = append(out, Violation{
+			Property: "write-skew",
+			Events:   events,
+			Message: fmt.Sprintf("concurrent transactions %s and %s: %s read %s written by %s, %s read %s written by %s, with disjoint writes",
+				t.QuoteName(a.ID), t.QuoteName(b.ID), t.QuoteName(a.ID), quoteKeys(c.Keys[0]), t.QuoteName(b.ID),
+				t.QuoteName(b.ID), quoteKeys(c.Keys[1]), t.QuoteName(a.ID)),
+		})
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package property
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/traces/analysis"
+	"github.com/traces/dag"
+	t "github.com/traces/types"
+)
+
+// StaleRead is a read that returned a value although newer writes to its key
+// happened before it.
+type StaleRead struct {
+	Read     int   // trace index of the read
+	Returned int   // write the read returned, -1 for the initial value
+	Missed   []int // newer writes that happen before the read, in trace order
+	// Hops is the length of the causal path from the returned write to the
+	// newest missed write; returning the initial value counts one hop more
+	// than the path from the oldest missed write.
+	Hops int
+	// Lag is how long the oldest missed write had been done when the read
+	// happened. Zero unless both events carry a time label.
+	Lag time.Duration
+}
+
+// StaleReads finds the stale reads of a key-value labeled trace.
+func StaleReads(trace t.Trace, labels SessionLabels) []StaleRead {
+	ops := labels.Ops(trace)
+	byIndex := make(map[int]analysis.KVOp, len(ops))
+	writes := make(map[string][]int) // key -> writes so far
+	var out []StaleRead
+	for _, op := range ops {
+		byIndex[op.Index] = op
+		if op.Write {
+			writes[op.Key] = append(writes[op.Key], op.Index)
+			continue
+		}
+		read := trace[op.Index]
+		s := StaleRead{Read: op.Index, Returned: op.From}
+		for _, w := range writes[op.Key] {
+			if w != op.From && trace[w].VClock.HappensBefore(read.VClock) && (op.From < 0 || supersedes(trace, w, op.From)) {
+				s.Missed = append(s.Missed, w)
+			}
+		}
+		if len(s.Missed) == 0 {
+			continue
+		}
+		oldest, newest := s.Missed[0], s.Missed[len(s.Missed)-1]
+		if op.From >= 0 {
+			s.Hops = len(dag.CausalPath(trace, op.From, newest)) - 1
+		} else {
+			s.Hops = len(dag.CausalPath(trace, oldest, newest))
+		}
+		if at, done := op.At, byIndex[oldest].At; !at.IsZero() && !done.IsZero() {
+			s.Lag = at.Sub(done)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// StaleReadsProperty is violated by every stale read; the message quantifies
+// how stale the returned value was.
+type StaleReadsProperty struct {
+	Labels SessionLabels
+}
+
+func (StaleReadsProperty) Name() string { return "stale-reads" }
+
+func (p StaleReadsProperty) Check(trace t.Trace) []Violation {
+	var out []Violation
+	for _, s := range StaleReads(trace, p.Labels) {
+		newest := s.Missed[len(s.Missed)-1]
+		events := []int{newest, s.Read}
+		if s.Returned >= 0 {
+			events = []int{s.Returned, newest, s.Read}
+		}
+		key := trace[s.Read].Labels[p.Labels.KeyLabel()]
+		msg := fmt.Sprintf("read of %s on %s returned %s, %d write(s) and %d causal hop(s) behind the write e-%d on %s",
+			t.QuoteName(key), trace[s.Read].Process, describeWrite(trace, s.Returned), len(s.Missed), s.Hops,
+			newest, trace[newest].Process)
+		if s.Lag != 0 {
+			msg += fmt.Sprintf(", stale for %s", s.Lag)
+		}
+		out = append(out, Violation{Property: "stale-reads", Events: events, Message: msg})
+	}
+	return out
+}
@@ -0,0 +1,374 @@
+package property
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// SuiteExt is the file extension of property suite files.
+const SuiteExt = ".props"
+
+// Spec declares one named property of a suite.
+type Spec struct {
+	Name        string
+	Kind        string
+	Description string
+	Params      map[string]string
+}
+
+// Suite is a named, versioned collection of property specs that can be
+// shared between projects.
+type Suite struct {
+	Name        string
+	Version     string
+	Description string
+	// Vars holds the default values of template variables, declared with
+	// "var <name> <value>" lines.
+	Vars  map[string]string
+	Specs []Spec
+}
+
+// named gives a property the name it was declared with in a suite.
+type named struct {
+	name string
+	Property
+}
+
+func (n named) Name() string { return n.name }
+
+func (n named) Check(trace t.Trace) []Violation {
+	vs := n.Property.Check(trace)
+	for i := range vs {
+		vs[i].Property = n.name
+	}
+	return vs
+}
+
+var templateVar = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Bind substitutes ${name} template variables in property names,
+// descriptions and parameters. Values in vars override the suite's
+// defaults; referencing a variable with neither is an error.
+func (s Suite) Bind(vars map[string]string) (Suite, error) {
+	values := make(map[string]string, len(s.Vars)+len(vars))
+	maps.Copy(values, s.Vars)
+	maps.Copy(values, vars)
+
+	var missing []string
+	expand := func(text string) string {
+		return templateVar.ReplaceAllStringFunc(text, func(ref string) string {
+			name := templateVar.FindStringSubmatch(ref)[1]
+			v, ok := values[name]
+			if !ok {
+				missing = append(missing, name)
+			}
+			return v
+		})
+	}
+
+	bound := s
+	bound.Specs = make([]Spec, len(s.Specs))
+	for i, spec := range s.Specs {
+		b := Spec{
+			Name:        expand(spec.Name),
+			Kind:        spec.Kind,
+			Description: expand(spec.Description),
+			Params:      make(map[string]string, len(spec.Params)),
+		}
+		for k, v := range spec.Params {
+			b.Params[k] = expand(v)
+		}
+		bound.Specs[i] = b
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return Suite{}, fmt.Errorf("suite %s: undefined variables: %s",
+			s.Name, strings.Join(slices.Compact(missing), ", "))
+	}
+	return bound, nil
+}
+
+func (n named) CheckProfiled(trace t.Trace, stats *Stats) []Violation {
+	ip, ok := n.Property.(Instrumented)
+	if !ok {
+		return n.Check(trace)
+	}
+	vs := ip.CheckProfiled(trace, stats)
+	for i := range vs {
+		vs[i].Property = n.name
+	}
+	return vs
+}
+
+// Properties instantiates every spec of the suite, filling template
+// variables from their defaults.
+func (s Suite) Properties() ([]Property, error) {
+	s, err := s.Bind(nil)
+	if err != nil {
+		return nil, err
+	}
+	props := make([]Property, 0, len(s.Specs))
+	for _, spec := range s.Specs {
+		p, err := New(spec.Kind, spec.Params)
+		if err != nil {
+			return nil, fmt.Errorf("suite %s, property %s: %w", s.Name, spec.Name, err)
+		}
+		props = append(props, named{name: spec.Name, Property: p})
+	}
+	return props, nil
+}
+
+// Validate checks that every spec names a known property kind and, where
+// its parameters need no variables beyond the suite's defaults, that they
+// build a property. Specs referencing variables without defaults can only
+// be built once Bind is given values, so they are not instantiated.
+func (s Suite) Validate() error {
+	for _, spec := range s.Specs {
+		if _, ok := kinds[spec.Kind]; !ok {
+			return fmt.Errorf("suite %s, property %s: unknown property %q (known: %s)",
+				s.Name, spec.Name, spec.Kind, strings.Join(Names(), ", "))
+		}
+		one := Suite{Name: s.Name, Vars: s.Vars, Specs: []Spec{spec}}
+		if _, err := one.Bind(nil); err != nil {
+			continue
+		}
+		if _, err := one.Properties(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseSuite reads a suite file. The format is line based:
+//
+//	suite messaging
+//	version 1.0
+//	description Ordering guarantees for message passing
+//
+//	property fifo-channels fifo
+//	description Messages on each channel arrive in send order
+//
+//	var lock lock1
+//	property lock-order mutex resource=${lock}
+//
+// Parameters may reference template variables as ${name}; "var" lines give
+// their defaults, which Bind overrides per run. A description line applies
+// to the suite or property declared just before it. Blank lines and lines
+// starting with '#' are ignored.
+func ParseSuite(r io.Reader) (Suite, error) {
+	var s Suite
+	var current *Spec
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keyword, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+
+		switch keyword {
+		case "suite":
+			s.Name = rest
+		case "version":
+			s.Version = rest
+		case "var":
+			name, value, _ := strings.Cut(rest, " ")
+			if name == "" {
+				return Suite{}, fmt.Errorf("line %d: expected \"var <name> <value>\"", n)
+			}
+			if s.Vars == nil {
+				s.Vars = make(map[string]string)
+			}
+			s.Vars[name] = strings.TrimSpace(value)
+		case "description":
+			if current != nil {
+				current.Description = rest
+			} else {
+				s.Description = rest
+			}
+		case "property":
+			fields := strings.Fields(rest)
+			if len(fields) < 2 {
+				return Suite{}, fmt.Errorf("line %d: expected \"property <name> <kind> [key=value ...]\"", n)
+			}
+			spec := Spec{Name: fields[0], Kind: fields[1], Params: make(map[string]string)}
+			for _, kv := range fields[2:] {
+				k, v, ok := strings.Cut(kv, "=")
+				if !ok {
+					return Suite{}, fmt.Errorf("line %d: parameter %q is not key=value", n, kv)
+				}
+				spec.Params[k] = v
+			}
+			s.Specs = append(s.Specs, spec)
+			current = &s.Specs[len(s.Specs)-1]
+		default:
+			return Suite{}, fmt.Errorf("line %d: unknown keyword %q", n, keyword)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return Suite{}, err
+	}
+	if s.Name == "" {
+		return Suite{}, fmt.Errorf("suite has no name")
+	}
+	return s, nil
+}
+
+// Write serialises the suite in the format read by ParseSuite.
+func (s Suite) Write(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "suite %s\n", s.Name)
+	if s.Version != "" {
+		fmt.Fprintf(b, "version %s\n", s.Version)
+	}
+	if s.Description != "" {
+		fmt.Fprintf(b, "description %s\n", s.Description)
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Vars)) {
+		fmt.Fprintf(b, "var %s %s\n", name, s.Vars[name])
+	}
+	for _, spec := range s.Specs {
+		fmt.Fprintf(b, "\nproperty %s %s", spec.Name, spec.Kind)
+		keys := make([]string, 0, len(spec.Params))
+		for k := range spec.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(b, " %s=%s", k, spec.Params[k])
+		}
+		fmt.Fprintln(b)
+		if spec.Description != "" {
+			fmt.Fprintf(b, "description %s\n", spec.Description)
+		}
+	}
+	return b.Flush()
+}
+
+// LoadSuites reads a single suite file, or every suite file in a directory.
+func LoadSuites(path string) ([]Suite, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*"+SuiteExt)); err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+
+	var suites []Suite
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		s, err := ParseSuite(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		suites = append(suites, s)
+	}
+	return suites, nil
+}
+
+// SuiteDir returns the directory imported suites are stored in:
+// $TRACE_SUITES if set, otherwise <user config dir>/trace/suites.
+func SuiteDir() (string, error) {
+	if dir := os.Getenv("TRACE_SUITES"); dir != "" {
+		return dir, nil
+	}
+	cfg, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cfg, "trace", "suites"), nil
+}
+
+// Install stores the suite in dir under its own name, replacing any
+// previously installed version. Names that are not plain file names are
+// rejected, so an imported suite cannot write outside dir.
+func Install(dir string, s Suite) (string, error) {
+	if err := checkSuiteName(s.Name); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, s.Name+SuiteExt)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := s.Write(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// checkSuiteName reports an error unless name can name an installed suite:
+// a plain file name that neither starts with a dot nor climbs directories.
+func checkSuiteName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid suite name %q", name)
+	}
+	return nil
+}
+
+// InstalledSuite finds a suite by name among installed suites only. Unlike
+// ResolveSuite it never reads other paths, so it is safe for names given by
+// remote users; every failure is reported as an unknown suite.
+func InstalledSuite(name string) (Suite, error) {
+	unknown := fmt.Errorf("unknown suite %q", name)
+	if checkSuiteName(name) != nil {
+		return Suite{}, unknown
+	}
+	dir, err := SuiteDir()
+	if err != nil {
+		return Suite{}, unknown
+	}
+	suites, err := LoadSuites(filepath.Join(dir, name+SuiteExt))
+	if err != nil || len(suites) != 1 {
+		return Suite{}, unknown
+	}
+	return suites[0], nil
+}
+
+// ResolveSuite finds a suite by file path, or by name among installed suites.
+func ResolveSuite(ref string) (Suite, error) {
+	if _, err := os.Stat(ref); err == nil {
+		suites, err := LoadSuites(ref)
+		if err != nil {
+			return Suite{}, err
+		}
+		if len(suites) != 1 {
+			return Suite{}, fmt.Errorf("%s contains %d suites, expected one", ref, len(suites))
+		}
+		return suites[0], nil
+	}
+
+	dir, err := SuiteDir()
+	if err != nil {
+		return Suite{}, err
+	}
+	suites, err := LoadSuites(filepath.Join(dir, ref+SuiteExt))
+	if err != nil {
+		return Suite{}, fmt.Errorf("suite %q not found: %w", ref, err)
+	}
+	return suites[0], nil
+}
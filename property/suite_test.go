@@ -0,0 +1,34 @@
+package property
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSuiteValidateUnboundVariables(tt *testing.T) {
+	s, err := ParseSuite(strings.NewReader(`suite elections
+property leader election-safety role=${role} leader=${coordinator}
+var role role
+`))
+	if err != nil {
+		tt.Fatal(err)
+	}
+	if err := s.Validate(); err != nil {
+		tt.Errorf("valid suite rejected: %v", err)
+	}
+	if _, err := s.Properties(); err == nil {
+		tt.Error("properties built without a value for coordinator")
+	}
+	b, err := s.Bind(map[string]string{"coordinator": "leader"})
+	if err != nil {
+		tt.Fatal(err)
+	}
+	if _, err := b.Properties(); err != nil {
+		tt.Errorf("bound suite: %v", err)
+	}
+
+	s.Specs = append(s.Specs, Spec{Name: "bad", Kind: "no-such-kind"})
+	if err := s.Validate(); err == nil {
+		tt.Error("unknown kind accepted")
+	}
+}
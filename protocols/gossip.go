@@ -0,0 +1,81 @@
+package protocols
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/traces/sim"
+)
+
+// Push gossip: g0 starts with a rumour, and every informed process sends it
+// to Fanout random peers once per period for Rounds periods. Rumour
+// messages carry msg=rumor and hop, the sender's distance from g0 plus one;
+// a process records when it first hears the rumour with an event labelled
+// rumor=informed, hop and time, the simulated time.
+
+const gossipPeriod = 10
+
+func setupGossip(e *sim.Engine, opts Options) error {
+	fanout := opts.Fanout
+	if fanout < 0 {
+		return fmt.Errorf("gossip fanout %d is negative", fanout)
+	} else if fanout == 0 {
+		fanout = 2
+	}
+	members := names("g", opts.Processes)
+	for i, g := range members {
+		var peers []string
+		for _, p := range members {
+			if p != g {
+				peers = append(peers, p)
+			}
+		}
+		if err := e.Add(g, &gossiper{peers: peers, fanout: min(fanout, len(peers)), rounds: opts.Rounds, origin: i == 0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type gossiper struct {
+	peers    []string
+	fanout   int
+	rounds   int
+	origin   bool
+	informed bool
+	hop      int
+	sent     int // rounds gossiped so far
+}
+
+func (g *gossiper) Init(ctx *sim.Context) {
+	if g.origin {
+		g.inform(ctx, 0)
+	}
+}
+
+func (g *gossiper) inform(ctx *sim.Context, hop int) {
+	g.informed, g.hop = true, hop
+	ctx.Step(map[string]string{"rumor": "informed", "hop": strconv.Itoa(hop), "time": strconv.Itoa(ctx.Now())})
+	g.gossip(ctx)
+}
+
+// gossip sends the rumour to fanout distinct random peers.
+func (g *gossiper) gossip(ctx *sim.Context) {
+	ctx.Rand().Shuffle(len(g.peers), func(i, j int) { g.peers[i], g.peers[j] = g.peers[j], g.peers[i] })
+	for _, p := range g.peers[:g.fanout] {
+		ctx.SendLabeled(p, nil, map[string]string{"msg": "rumor", "hop": strconv.Itoa(g.hop + 1)})
+	}
+	if g.sent++; g.sent < g.rounds {
+		ctx.SetTimer(gossipPeriod, 0)
+	}
+}
+
+func (g *gossiper) OnMessage(ctx *sim.Context, msg sim.Message) {
+	if msg.Labels["msg"] != "rumor" || g.informed {
+		return
+	}
+	hop, _ := strconv.Atoi(msg.Labels["hop"])
+	g.inform(ctx, hop)
+}
+
+func (g *gossiper) OnTimer(ctx *sim.Context, _ int) { g.gossip(ctx) }
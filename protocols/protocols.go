@@ -0,0 +1,99 @@
+// Package protocols simulates classic distributed protocols on the sim
+// engine, producing labeled traces with realistic structure for the
+// property checker and the analyses.
+package protocols
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/traces/sim"
+	t "github.com/traces/types"
+)
+
+// Options size a scenario.
+type Options struct {
+	Processes int // processes taking part, at least 2
+	// Rounds is the number of transactions, token circulations, money
+	// transfers per process, replicated log entries or gossip periods,
+	// depending on the scenario.
+	Rounds int
+	// Fanout is the number of peers a gossiper sends the rumour to each
+	// period; 0 means 2.
+	Fanout int
+	// FailureRate is the probability that a participant votes to abort in
+	// two-phase commit.
+	FailureRate float64
+}
+
+// Scenario sets up the processes of a protocol on an engine.
+type Scenario struct {
+	Name        string
+	Description string
+	Setup       func(e *sim.Engine, opts Options) error
+}
+
+var scenarios = map[string]Scenario{}
+
+// Register makes a scenario available to Lookup and Run.
+func Register(s Scenario) {
+	scenarios[s.Name] = s
+}
+
+func init() {
+	Register(Scenario{Name: "2pc", Description: "two-phase commit: a coordinator and participants", Setup: setupTwoPhaseCommit})
+	Register(Scenario{Name: "token-ring", Description: "token-ring mutual exclusion on lock \"cs\"", Setup: setupTokenRing})
+	Register(Scenario{Name: "gossip", Description: "push gossip of a rumour with a fixed fanout", Setup: setupGossip})
+	Register(Scenario{Name: "raft", Description: "simplified Raft leader election and log replication", Setup: setupRaft})
+	Register(Scenario{Name: "snapshot", Description: "Chandy-Lamport snapshot of a money-transfer application", Setup: setupSnapshot})
+}
+
+// Lookup returns the scenario registered under name.
+func Lookup(name string) (Scenario, error) {
+	s, ok := scenarios[name]
+	if !ok {
+		return Scenario{}, fmt.Errorf("unknown scenario %q (known: %s)", name, strings.Join(Names(), ", "))
+	}
+	return s, nil
+}
+
+// Names returns the names of all registered scenarios.
+func Names() []string {
+	names := make([]string, 0, len(scenarios))
+	for n := range scenarios {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run simulates the named scenario and returns its trace. maxEvents, when
+// positive, cuts the simulation short.
+func Run(name string, opts Options, seed int64, maxEvents int) (t.Trace, error) {
+	s, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Processes < 2 {
+		return nil, fmt.Errorf("%s needs at least 2 processes", name)
+	}
+	if opts.Rounds < 1 {
+		return nil, fmt.Errorf("%s needs at least one round", name)
+	}
+	e := sim.New(seed)
+	e.MaxEvents = maxEvents
+	if err := s.Setup(e, opts); err != nil {
+		return nil, err
+	}
+	return e.Run()
+}
+
+// names returns prefix0, prefix1, ...
+func names(prefix string, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return out
+}
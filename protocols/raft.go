@@ -0,0 +1,217 @@
+package protocols
+
+import (
+	"strconv"
+
+	"github.com/traces/sim"
+)
+
+// Simplified Raft: nodes elect a leader with randomised election timeouts,
+// and the leader replicates Rounds log entries with AppendEntries and
+// commits each once a majority stores it. Nodes stop once they know every
+// entry is committed. Events carry the labels msg (request-vote, vote,
+// append, append-reply), term, role (candidate, leader) on elections,
+// granted on votes and commit on commit-index changes.
+
+const (
+	electionMin, electionMax = 15, 30
+	heartbeatInterval        = 5
+)
+
+func setupRaft(e *sim.Engine, opts Options) error {
+	nodes := names("n", opts.Processes)
+	for _, n := range nodes {
+		var peers []string
+		for _, p := range nodes {
+			if p != n {
+				peers = append(peers, p)
+			}
+		}
+		if err := e.Add(n, &raftNode{peers: peers, entries: opts.Rounds}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type (
+	requestVote struct{ Term, LastIndex, LastTerm int }
+	voteReply   struct {
+		Term    int
+		Granted bool
+	}
+	appendEntries struct {
+		Term, PrevIndex, PrevTerm int
+		Entries                   []int // terms of the entries
+		LeaderCommit              int
+	}
+	appendReply struct {
+		Term    int
+		Success bool
+		Match   int
+	}
+)
+
+type raftNode struct {
+	peers   []string
+	entries int // entries the leader replicates before the cluster stops
+
+	term     int
+	votedFor string
+	leader   bool
+	votes    int
+	log      []int // term of each entry
+	commit   int
+	match    map[string]int // leader only: highest entry known stored per peer
+	epoch    int            // invalidates older election timers
+	done     bool
+}
+
+func (n *raftNode) Init(ctx *sim.Context) { n.armElection(ctx) }
+
+func (n *raftNode) armElection(ctx *sim.Context) {
+	if n.done {
+		return
+	}
+	n.epoch++
+	ctx.SetTimer(electionMin+ctx.Rand().Intn(electionMax-electionMin+1), 2*n.epoch)
+}
+
+func (n *raftNode) send(ctx *sim.Context, to, kind string, payload any, extra map[string]string) {
+	labels := map[string]string{"msg": kind, "term": strconv.Itoa(n.term)}
+	for k, v := range extra {
+		labels[k] = v
+	}
+	ctx.SendLabeled(to, payload, labels)
+}
+
+func (n *raftNode) lastTerm() int {
+	if len(n.log) == 0 {
+		return 0
+	}
+	return n.log[len(n.log)-1]
+}
+
+func (n *raftNode) OnTimer(ctx *sim.Context, timer int) {
+	if n.done {
+		return
+	}
+	if timer%2 == 1 {
+		if n.leader {
+			n.heartbeat(ctx)
+		}
+		return
+	}
+	if timer != 2*n.epoch || n.leader {
+		return
+	}
+	n.term++
+	n.votedFor, n.votes = ctx.Self(), 1
+	ctx.Step(map[string]string{"term": strconv.Itoa(n.term), "role": "candidate"})
+	for _, p := range n.peers {
+		n.send(ctx, p, "request-vote", requestVote{n.term, len(n.log), n.lastTerm()}, nil)
+	}
+	n.armElection(ctx)
+}
+
+// observe steps down to follower on seeing a newer term.
+func (n *raftNode) observe(term int) {
+	if term > n.term {
+		n.term, n.votedFor, n.leader = term, "", false
+	}
+}
+
+func (n *raftNode) OnMessage(ctx *sim.Context, msg sim.Message) {
+	switch m := msg.Payload.(type) {
+	case requestVote:
+		n.observe(m.Term)
+		upToDate := m.LastTerm > n.lastTerm() || (m.LastTerm == n.lastTerm() && m.LastIndex >= len(n.log))
+		granted := m.Term == n.term && (n.votedFor == "" || n.votedFor == msg.From) && upToDate
+		if granted {
+			n.votedFor = msg.From
+			n.armElection(ctx)
+		}
+		n.send(ctx, msg.From, "vote", voteReply{n.term, granted}, map[string]string{"granted": strconv.FormatBool(granted)})
+
+	case voteReply:
+		n.observe(m.Term)
+		if n.leader || !m.Granted || m.Term != n.term || n.votedFor != ctx.Self() {
+			return
+		}
+		if n.votes++; n.votes > (len(n.peers)+1)/2 {
+			n.leader = true
+			n.match = make(map[string]int)
+			ctx.Step(map[string]string{"term": strconv.Itoa(n.term), "role": "leader"})
+			n.heartbeat(ctx)
+		}
+
+	case appendEntries:
+		n.observe(m.Term)
+		if m.Term < n.term {
+			n.send(ctx, msg.From, "append-reply", appendReply{Term: n.term}, nil)
+			return
+		}
+		n.leader = false
+		n.armElection(ctx)
+		if m.PrevIndex > len(n.log) || (m.PrevIndex > 0 && n.log[m.PrevIndex-1] != m.PrevTerm) {
+			n.send(ctx, msg.From, "append-reply", appendReply{Term: n.term}, nil)
+			return
+		}
+		n.log = append(n.log[:m.PrevIndex], m.Entries...)
+		if c := min(m.LeaderCommit, len(n.log)); c > n.commit {
+			n.commit = c
+			ctx.Step(map[string]string{"term": strconv.Itoa(n.term), "commit": strconv.Itoa(c)})
+			n.done = n.commit >= n.entries
+		}
+		n.send(ctx, msg.From, "append-reply", appendReply{n.term, true, len(n.log)}, nil)
+
+	case appendReply:
+		n.observe(m.Term)
+		if !n.leader || m.Term != n.term {
+			return
+		}
+		if !m.Success {
+			n.match[msg.From] = max(n.match[msg.From]-1, 0)
+			return
+		}
+		n.match[msg.From] = max(n.match[msg.From], m.Match)
+		// Commit the highest entry of this term stored on a majority
+		for c := len(n.log); c > n.commit; c-- {
+			stored := 1
+			for _, p := range n.peers {
+				if n.match[p] >= c {
+					stored++
+				}
+			}
+			if stored > (len(n.peers)+1)/2 && n.log[c-1] == n.term {
+				n.commit = c
+				ctx.Step(map[string]string{"term": strconv.Itoa(n.term), "commit": strconv.Itoa(c)})
+				break
+			}
+		}
+	}
+}
+
+// heartbeat appends the next entry if any remain and sends every follower
+// the entries it lacks. Once every follower stored everything and was told
+// it is committed, the leader stops.
+func (n *raftNode) heartbeat(ctx *sim.Context) {
+	if len(n.log) < n.entries {
+		n.log = append(n.log, n.term)
+	}
+	finished := n.commit >= n.entries
+	for _, p := range n.peers {
+		finished = finished && n.match[p] >= n.entries
+		prev := n.match[p]
+		prevTerm := 0
+		if prev > 0 {
+			prevTerm = n.log[prev-1]
+		}
+		n.send(ctx, p, "append", appendEntries{n.term, prev, prevTerm, append([]int(nil), n.log[prev:]...), n.commit}, nil)
+	}
+	if finished {
+		n.done = true
+		return
+	}
+	ctx.SetTimer(heartbeatInterval, 2*n.epoch+1)
+}
@@ -0,0 +1,119 @@
+package protocols
+
+import (
+	"strconv"
+
+	"github.com/traces/sim"
+)
+
+// Chandy-Lamport snapshot over FIFO channels: processes transfer money to each other while p0
+// starts a snapshot. Each process records its balance when it first sees
+// a marker and the money in flight on each incoming channel until that
+// channel's marker arrives. Recorded balances and channel amounts add up
+// to the money in the system. Events carry the labels msg (transfer,
+// marker), amount, and for recordings record (local, channel), balance,
+// channel and snapshot.
+
+// InitialBalance is the money each process of the snapshot scenario starts
+// with.
+const InitialBalance = 100
+
+const (
+	timerTransfer = iota
+	timerSnapshot
+)
+
+func setupSnapshot(e *sim.Engine, opts Options) error {
+	// The algorithm relies on markers not overtaking transfers
+	e.FIFO = true
+	procs := names("p", opts.Processes)
+	for i, p := range procs {
+		if err := e.Add(p, &account{initiator: i == 0, transfers: opts.Rounds, balance: InitialBalance}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type account struct {
+	initiator bool
+	transfers int // transfers left to make
+	balance   int
+	recorded  bool
+	recording map[string]int // incoming channel -> money seen in flight, while recording
+}
+
+func (a *account) Init(ctx *sim.Context) {
+	ctx.SetTimer(1+ctx.Rand().Intn(5), timerTransfer)
+	if a.initiator {
+		ctx.SetTimer(5+ctx.Rand().Intn(10), timerSnapshot)
+	}
+}
+
+func (a *account) OnTimer(ctx *sim.Context, timer int) {
+	switch timer {
+	case timerTransfer:
+		if a.transfers == 0 || a.balance == 0 {
+			return
+		}
+		a.transfers--
+		var others []string
+		for _, p := range ctx.Processes() {
+			if p != ctx.Self() {
+				others = append(others, p)
+			}
+		}
+		amount := 1 + ctx.Rand().Intn(min(a.balance, 20))
+		a.balance -= amount
+		ctx.SendLabeled(others[ctx.Rand().Intn(len(others))], amount,
+			map[string]string{"msg": "transfer", "amount": strconv.Itoa(amount)})
+		ctx.SetTimer(1+ctx.Rand().Intn(5), timerTransfer)
+	case timerSnapshot:
+		a.record(ctx, "")
+	}
+}
+
+func (a *account) OnMessage(ctx *sim.Context, msg sim.Message) {
+	switch msg.Labels["msg"] {
+	case "transfer":
+		amount := msg.Payload.(int)
+		a.balance += amount
+		if _, ok := a.recording[msg.From]; ok {
+			a.recording[msg.From] += amount
+		}
+	case "marker":
+		if !a.recorded {
+			a.record(ctx, msg.From)
+		}
+		if inFlight, ok := a.recording[msg.From]; ok {
+			delete(a.recording, msg.From)
+			a.recordChannel(ctx, msg.From, inFlight)
+		}
+	}
+}
+
+// record saves the local state, sends a marker on every outgoing channel and
+// starts recording every incoming channel but the one the marker came on,
+// which is empty.
+func (a *account) record(ctx *sim.Context, from string) {
+	a.recorded = true
+	ctx.Step(map[string]string{"snapshot": "1", "record": "local", "balance": strconv.Itoa(a.balance)})
+	a.recording = make(map[string]int)
+	for _, p := range ctx.Processes() {
+		if p == ctx.Self() {
+			continue
+		}
+		ctx.SendLabeled(p, nil, map[string]string{"msg": "marker", "snapshot": "1"})
+		if p != from {
+			a.recording[p] = 0
+		}
+	}
+	if from != "" {
+		a.recordChannel(ctx, from, 0)
+	}
+}
+
+func (a *account) recordChannel(ctx *sim.Context, from string, inFlight int) {
+	ctx.Step(map[string]string{"snapshot": "1", "record": "channel", "channel": from + "->" + ctx.Self(),
+		"amount": strconv.Itoa(inFlight)})
+}
@@ -0,0 +1,58 @@
+package protocols
+
+import (
+	"github.com/traces/sim"
+)
+
+// Token-ring mutual exclusion: a token circulates p0 -> p1 -> ... -> p0 and
+// only its holder may enter the critical section, recorded as ACQUIRE and
+// RELEASE of lock "cs". Token messages carry the label msg=token.
+
+// CriticalSection is the lock the token-ring scenario protects.
+const CriticalSection = "cs"
+
+func setupTokenRing(e *sim.Engine, opts Options) error {
+	ring := names("p", opts.Processes)
+	for i, p := range ring {
+		if err := e.Add(p, &ringMember{next: ring[(i+1)%len(ring)], first: i == 0, rounds: opts.Rounds}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type ringMember struct {
+	next   string
+	first  bool // holds the token initially and counts circulations
+	rounds int
+	laps   int
+}
+
+func (m *ringMember) Init(ctx *sim.Context) {
+	if m.first {
+		m.hold(ctx)
+	}
+}
+
+func (m *ringMember) OnMessage(ctx *sim.Context, msg sim.Message) {
+	if msg.Labels["msg"] != "token" {
+		return
+	}
+	if m.first {
+		if m.laps++; m.laps >= m.rounds {
+			return
+		}
+	}
+	m.hold(ctx)
+}
+
+// hold enters the critical section half of the time, then passes the token on.
+func (m *ringMember) hold(ctx *sim.Context) {
+	if ctx.Rand().Intn(2) == 0 {
+		ctx.Acquire(CriticalSection)
+		ctx.Release(CriticalSection)
+	}
+	ctx.SendLabeled(m.next, nil, map[string]string{"msg": "token"})
+}
+
+func (m *ringMember) OnTimer(*sim.Context, int) {}
@@ -0,0 +1,103 @@
+package protocols
+
+import (
+	"strconv"
+
+	"github.com/traces/sim"
+)
+
+// Two-phase commit: the coordinator asks every participant to prepare
+// transaction "txn", collects their votes, decides and waits for their
+// acknowledgements before starting the next transaction. Events carry the
+// labels txn, msg (prepare, vote, commit, abort, ack), vote, decision and
+// state.
+
+func setupTwoPhaseCommit(e *sim.Engine, opts Options) error {
+	participants := names("p", opts.Processes-1)
+	if err := e.Add("coord", &coordinator{participants: participants, rounds: opts.Rounds}); err != nil {
+		return err
+	}
+	for _, p := range participants {
+		if err := e.Add(p, &participant{abortRate: opts.FailureRate}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type coordinator struct {
+	participants []string
+	rounds, txn  int
+	votes        map[string]bool
+	acks         int
+}
+
+func (c *coordinator) Init(ctx *sim.Context) { c.begin(ctx) }
+
+func (c *coordinator) begin(ctx *sim.Context) {
+	if c.txn++; c.txn > c.rounds {
+		return
+	}
+	c.votes, c.acks = make(map[string]bool), 0
+	txn := strconv.Itoa(c.txn)
+	ctx.Step(map[string]string{"txn": txn, "state": "preparing"})
+	for _, p := range c.participants {
+		ctx.SendLabeled(p, nil, map[string]string{"txn": txn, "msg": "prepare"})
+	}
+}
+
+func (c *coordinator) OnMessage(ctx *sim.Context, msg sim.Message) {
+	txn := msg.Labels["txn"]
+	if txn != strconv.Itoa(c.txn) {
+		return
+	}
+	switch msg.Labels["msg"] {
+	case "vote":
+		c.votes[msg.From] = msg.Labels["vote"] == "yes"
+		if len(c.votes) < len(c.participants) {
+			return
+		}
+		decision := "commit"
+		for _, yes := range c.votes {
+			if !yes {
+				decision = "abort"
+			}
+		}
+		ctx.Step(map[string]string{"txn": txn, "decision": decision})
+		for _, p := range c.participants {
+			ctx.SendLabeled(p, nil, map[string]string{"txn": txn, "msg": decision})
+		}
+	case "ack":
+		if c.acks++; c.acks == len(c.participants) {
+			ctx.Step(map[string]string{"txn": txn, "state": "done"})
+			c.begin(ctx)
+		}
+	}
+}
+
+func (c *coordinator) OnTimer(*sim.Context, int) {}
+
+type participant struct {
+	abortRate float64
+}
+
+func (p *participant) Init(*sim.Context) {}
+
+func (p *participant) OnMessage(ctx *sim.Context, msg sim.Message) {
+	txn := msg.Labels["txn"]
+	switch msg.Labels["msg"] {
+	case "prepare":
+		vote := "yes"
+		if ctx.Rand().Float64() < p.abortRate {
+			vote = "no"
+		}
+		ctx.Step(map[string]string{"txn": txn, "vote": vote})
+		ctx.SendLabeled(msg.From, nil, map[string]string{"txn": txn, "msg": "vote", "vote": vote})
+	case "commit", "abort":
+		state := map[string]string{"commit": "committed", "abort": "aborted"}[msg.Labels["msg"]]
+		ctx.Step(map[string]string{"txn": txn, "state": state})
+		ctx.SendLabeled(msg.From, nil, map[string]string{"txn": txn, "msg": "ack"})
+	}
+}
+
+func (p *participant) OnTimer(*sim.Context, int) {}
@@ -0,0 +1,204 @@
+// Package provenance records how an artifact was produced — the tool build,
+// the command line, the seed and the inputs — so a file found later can be
+// traced back to exactly the run that wrote it.
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Input is a file the run read, with the SHA-256 of its contents.
+type Input struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256,omitempty"` // empty if the file could not be read
+}
+
+// Stamp is the provenance of the artifacts of one run. It holds nothing
+// that varies between runs of the same build on the same inputs, so
+// reproducible outputs stay byte-identical.
+type Stamp struct {
+	Tool      string   `json:"tool"`
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit,omitempty"`
+	Modified  bool     `json:"modified,omitempty"` // built from a tree with uncommitted changes
+	GoVersion string   `json:"go_version,omitempty"`
+	Args      []string `json:"args"` // command line after the tool name, redacted (see Redact)
+	Seed      *int64   `json:"seed,omitempty"`
+	Inputs    []Input  `json:"inputs,omitempty"`
+}
+
+// New returns the stamp of a run of tool with the given arguments, taking
+// the version and commit from the binary's build information. The arguments
+// are recorded redacted.
+func New(tool string, args []string) *Stamp {
+	s := &Stamp{Tool: tool, Version: "unknown", Args: Redact(args)}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		s.Version = info.Main.Version
+		s.GoVersion = info.GoVersion
+		for _, kv := range info.Settings {
+			switch kv.Key {
+			case "vcs.revision":
+				s.Commit = kv.Value
+			case "vcs.modified":
+				s.Modified = kv.Value == "true"
+			}
+		}
+	}
+	for i, a := range args {
+		value, ok := "", false
+		switch {
+		case a == "-seed" || a == "--seed":
+			if i+1 < len(args) {
+				value, ok = args[i+1], true
+			}
+		case strings.HasPrefix(a, "-seed="), strings.HasPrefix(a, "--seed="):
+			_, value, ok = strings.Cut(a, "=")
+		}
+		if seed, err := strconv.ParseInt(value, 10, 64); ok && err == nil {
+			s.Seed = &seed
+		}
+	}
+	return s
+}
+
+// secretFlags are the flags whose values may hold credentials, such as the
+// webhook URLs of -alert, and are never recorded.
+var secretFlags = map[string]bool{"alert": true}
+
+// Redacted replaces secrets in recorded arguments.
+const Redacted = "REDACTED"
+
+// Redact returns a copy of command line arguments with the values of
+// secretFlags replaced by Redacted, and the user info and query of any URL,
+// where tokens are usually passed, too.
+func Redact(args []string) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		out[i] = redactURL(a)
+	}
+	for i, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		switch {
+		case !secretFlags[name]:
+		case hasValue:
+			out[i] = a[:strings.Index(a, "=")+1] + Redacted
+		case i+1 < len(args):
+			out[i+1] = Redacted
+		}
+	}
+	return out
+}
+
+// redactURL hides the user info and query of a URL within a, if any.
+func redactURL(a string) string {
+	at := strings.Index(a, "://")
+	if at < 0 {
+		return a
+	}
+	// Keep a prefix such as the "slack:" of an alert destination
+	start := strings.LastIndexAny(a[:at], ":=") + 1
+	u, err := url.Parse(a[start:])
+	if err != nil || u.Host == "" || (u.User == nil && u.RawQuery == "") {
+		return a
+	}
+	if u.User != nil {
+		u.User = url.User(Redacted)
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = Redacted
+	}
+	return a[:start] + u.String()
+}
+
+// AddInput records a file the run read. Repeated paths are recorded once.
+func (s *Stamp) AddInput(path string) {
+	if s == nil {
+		return
+	}
+	for _, in := range s.Inputs {
+		if in.Path == path {
+			return
+		}
+	}
+	in := Input{Path: path}
+	if f, err := os.Open(path); err == nil {
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err == nil {
+			in.SHA256 = hex.EncodeToString(h.Sum(nil))
+		}
+		f.Close()
+	}
+	s.Inputs = append(s.Inputs, in)
+}
+
+// Lines renders the stamp as short human-readable lines. Arguments and
+// paths that need it are quoted, so no line breaks.
+func (s *Stamp) Lines() []string {
+	build := fmt.Sprintf("%s %s", s.Tool, s.Version)
+	if s.Commit != "" {
+		build += " commit " + s.Commit
+		if s.Modified {
+			build += " (modified)"
+		}
+	}
+	if s.GoVersion != "" {
+		build += ", " + s.GoVersion
+	}
+	quoted := make([]string, len(s.Args))
+	for i, a := range s.Args {
+		quoted[i] = a
+		if a == "" || strings.ContainsAny(a, " \t\n\"'\\") {
+			quoted[i] = strconv.Quote(a)
+		}
+	}
+	lines := []string{"produced by " + build, "command: " + strings.Join(append([]string{s.Tool}, quoted...), " ")}
+	if s.Seed != nil {
+		lines = append(lines, fmt.Sprintf("seed: %d", *s.Seed))
+	}
+	for _, in := range s.Inputs {
+		if in.SHA256 == "" {
+			lines = append(lines, "input: "+strconv.Quote(in.Path))
+		} else {
+			lines = append(lines, fmt.Sprintf("input: %s sha256:%s", strconv.Quote(in.Path), in.SHA256))
+		}
+	}
+	return lines
+}
+
+// DOT renders the stamp as DOT comment lines, to precede a graph.
+func (s *Stamp) DOT() string {
+	if s == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, l := range s.Lines() {
+		b.WriteString("// " + l + "\n")
+	}
+	return b.String()
+}
+
+// HTML renders the stamp as a page footer.
+func (s *Stamp) HTML() string {
+	if s == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(`<footer style="color:#777;font-size:small;margin-top:2em">`)
+	for _, l := range s.Lines() {
+		b.WriteString(html.EscapeString(l) + "<br>\n")
+	}
+	b.WriteString("</footer>\n")
+	return b.String()
+}
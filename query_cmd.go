@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	t "github.com/traces/types"
+)
+
+// runQuery implements `trace query`: it reads only the events of a stored
+// trace that match a filter, applied while the file is decoded, and writes
+// them as a trace. A JSONL trace counted or written to a JSONL file is
+// streamed event by event, so it may be larger than memory.
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	params := map[string]*string{
+		"process": fs.String("process", "", "comma separated processes to keep"),
+		"type":    fs.String("type", "", "comma separated event types to keep, e.g. SEND,RECV"),
+		"label":   fs.String("label", "", "comma separated name=value labels the events must carry"),
+		"min":     fs.String("min", "", "keep events whose clock is at least this one in every entry, e.g. A:3,B:1"),
+		"max":     fs.String("max", "", "keep events whose clock is at most this one in every entry"),
+	}
+	count := fs.Bool("count", false, "print the number of matching events instead of the events")
+	out := fs.String("out", "", "write the matching events to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	values := make(map[string]string, len(params))
+	for name, v := range params {
+		values[name] = *v
+	}
+	q, err := t.ParseQuery(values)
+	if err != nil {
+		return err
+	}
+	if isJSONL(*in) && (*count || isJSONL(*out)) {
+		return streamQuery(*in, q, *count, *out)
+	}
+	trace, err := queryTrace(*in, q)
+	if err != nil {
+		return err
+	}
+	if *count {
+		fmt.Println(len(trace))
+		return nil
+	}
+	if *out == "" {
+		return writeTrace(os.Stdout, trace)
+	}
+	return saveTrace(*out, trace)
+}
+
+// streamQuery counts or writes the events of a JSONL trace selected by q
+// while it reads them.
+func streamQuery(in string, q t.Query, count bool, out string) error {
+	if count {
+		n := 0
+		err := streamTrace(in, q, func(t.Event) error { n++; return nil })
+		if err == nil {
+			fmt.Println(n)
+		}
+		return err
+	}
+	return writeStamped(out, func(w io.Writer) error {
+		tw := t.NewTraceWriter(w)
+		return streamTrace(in, q, tw.Write)
+	})
+}
@@ -0,0 +1,119 @@
+// Package replay drives per-process deterministic state machines with the
+// events of a trace and compares their states with the states the traced
+// implementation recorded.
+package replay
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// Machine is a deterministic state machine for one process. Step advances
+// it by one event of that process; for a RECV, sent is the state the
+// sender's machine was in right after the matching SEND, so messages can
+// carry state between machines.
+type Machine interface {
+	Step(e t.Event, sent string) error
+	State() string
+}
+
+// Factory builds the machine of a process.
+type Factory func(process string) (Machine, error)
+
+var machines = map[string]Factory{}
+
+// Register makes a machine available to New under name.
+func Register(name string, f Factory) {
+	machines[name] = f
+}
+
+// New returns the factory registered under name.
+func New(name string) (Factory, error) {
+	f, ok := machines[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown machine %q (known: %s)", name, strings.Join(Names(), ", "))
+	}
+	return f, nil
+}
+
+// Names returns the names of all registered machines.
+func Names() []string {
+	names := make([]string, 0, len(machines))
+	for n := range machines {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Divergence is the first event after which a machine's state differed from
+// the state the trace recorded, or at which the machine failed to step.
+type Divergence struct {
+	Event    int
+	Process  string
+	Recorded string // empty when the machine failed
+	Replayed string // the machine's state after the event, or before a failure
+	Err      error
+}
+
+func (d Divergence) String() string {
+	if d.Err != nil {
+		return fmt.Sprintf("e-%d on %s: machine failed in state %s: %v", d.Event, t.QuoteName(d.Process), t.QuoteName(d.Replayed), d.Err)
+	}
+	return fmt.Sprintf("e-%d on %s: recorded state %s, replayed state %s",
+		d.Event, t.QuoteName(d.Process), t.QuoteName(d.Recorded), t.QuoteName(d.Replayed))
+}
+
+// Result summarises a replay.
+type Result struct {
+	Steps      int // events replayed
+	Assertions int // recorded states compared before the divergence, if any
+	Divergence *Divergence
+}
+
+// Replay feeds every event of the trace to the machine of its process, in
+// trace order, which is causal order. Events carrying the stateLabel
+// ("state" if empty) assert the state their process was in after them;
+// replay stops at the first assertion the machine contradicts.
+func Replay(trace t.Trace, f Factory, stateLabel string) (Result, error) {
+	if stateLabel == "" {
+		stateLabel = "state"
+	}
+	var res Result
+	running := make(map[string]Machine)
+	sent := make(map[int]string) // message ID -> sender's state after the SEND
+	for i, e := range trace {
+		m, ok := running[e.Process]
+		if !ok {
+			var err error
+			if m, err = f(e.Process); err != nil {
+				return res, fmt.Errorf("machine for %s: %w", e.Process, err)
+			}
+			running[e.Process] = m
+		}
+		var payload string
+		if e.Type == t.EventReceive {
+			payload = sent[e.MessageID]
+		}
+		before := m.State()
+		res.Steps++
+		if err := m.Step(e, payload); err != nil {
+			res.Divergence = &Divergence{Event: i, Process: e.Process, Replayed: before, Err: err}
+			return res, nil
+		}
+		if e.Type == t.EventSend {
+			sent[e.MessageID] = m.State()
+		}
+		if want, ok := e.Labels[stateLabel]; ok {
+			if got := m.State(); got != want {
+				res.Divergence = &Divergence{Event: i, Process: e.Process, Recorded: want, Replayed: got}
+				return res, nil
+			}
+			res.Assertions++
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,102 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	t "github.com/traces/types"
+)
+
+// Transition moves a table machine from state From to state To on an event
+// matching its conditions. Empty conditions match anything; From "*"
+// matches every state.
+type Transition struct {
+	From   string            `json:"from"`
+	Type   string            `json:"type,omitempty"`   // event type, e.g. "RECV"
+	Labels map[string]string `json:"labels,omitempty"` // labels the event must carry
+	Sent   string            `json:"sent,omitempty"`   // state the sender of a RECV was in
+	To     string            `json:"to"`
+}
+
+func (tr Transition) matches(state string, e t.Event, sent string) bool {
+	if tr.From != "*" && tr.From != state {
+		return false
+	}
+	if tr.Type != "" && tr.Type != e.Type.String() {
+		return false
+	}
+	if tr.Sent != "" && tr.Sent != sent {
+		return false
+	}
+	for k, v := range tr.Labels {
+		if e.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Table is a state machine given as a transition table. The first matching
+// transition is taken; an event no transition matches leaves the state
+// unchanged, unless Strict is set, in which case it is an error.
+type Table struct {
+	Initial     string       `json:"initial"`
+	Transitions []Transition `json:"transitions"`
+	Strict      bool         `json:"strict,omitempty"`
+}
+
+// TableSpec assigns table machines to processes. Processes without a machine
+// of their own use the "*" entry.
+//
+//	{"machines": {"*": {"initial": "follower", "transitions": [
+//	    {"from": "follower", "type": "RECV", "labels": {"msg": "vote"}, "to": "candidate"}]}}}
+type TableSpec struct {
+	Machines map[string]Table `json:"machines"`
+}
+
+// LoadTableSpec reads a JSON encoded TableSpec.
+func LoadTableSpec(path string) (TableSpec, error) {
+	var s TableSpec
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("decoding state machine spec: %w", err)
+	}
+	return s, nil
+}
+
+// Factory builds the table machine of each process.
+func (s TableSpec) Factory() Factory {
+	return func(process string) (Machine, error) {
+		table, ok := s.Machines[process]
+		if !ok {
+			if table, ok = s.Machines["*"]; !ok {
+				return nil, fmt.Errorf("no machine for process %s and no \"*\" machine", t.QuoteName(process))
+			}
+		}
+		return &tableMachine{table: table, state: table.Initial}, nil
+	}
+}
+
+type tableMachine struct {
+	table Table
+	state string
+}
+
+func (m *tableMachine) State() string { return m.state }
+
+func (m *tableMachine) Step(e t.Event, sent string) error {
+	for _, tr := range m.table.Transitions {
+		if tr.matches(m.state, e, sent) {
+			m.state = tr.To
+			return nil
+		}
+	}
+	if m.table.Strict {
+		return fmt.Errorf("no transition for %s", e.Type)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/traces/replay"
+)
+
+// runReplay implements `trace replay`: it replays a trace through per-process
+// state machines and reports the first event whose recorded state the
+// machines contradict.
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	machine := fs.String("machine", "", "JSON state machine spec, or the name of a registered machine")
+	label := fs.String("label", "state", "label holding the state recorded after an event")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" || *machine == "" {
+		return fmt.Errorf("-in and -machine are required")
+	}
+
+	var factory replay.Factory
+	if _, err := os.Stat(*machine); err == nil {
+		spec, err := replay.LoadTableSpec(*machine)
+		if err != nil {
+			return err
+		}
+		factory = spec.Factory()
+	} else if factory, err = replay.New(*machine); err != nil {
+		return fmt.Errorf("%s is neither a spec file nor a registered machine", *machine)
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	res, err := replay.Replay(trace, factory, *label)
+	if err != nil {
+		return err
+	}
+	if res.Divergence == nil {
+		fmt.Printf("replayed %d events, all %d recorded states match\n", res.Steps, res.Assertions)
+		return nil
+	}
+	// Machines may depend on the raw names, so only the report is renamed
+	d := *res.Divergence
+	d.Process = aliases.Name(d.Process)
+	fmt.Printf("diverged after %d events and %d matching states: %s\n", res.Steps, res.Assertions, d)
+	return fmt.Errorf("replay diverged")
+}
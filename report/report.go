@@ -0,0 +1,106 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/traces/fingerprint"
+	"github.com/traces/property"
+	"github.com/traces/provenance"
+	"github.com/traces/sink"
+	t "github.com/traces/types"
+)
+
+// DefaultLimit is the number of entries printed per section on the console.
+const DefaultLimit = 20
+
+// Report is the full result of checking properties on a trace. The console
+// summary is capped; the JSON form always holds every violation.
+type Report struct {
+	Trace      string               `json:"trace"`
+	Events     int                  `json:"events"`
+	Violations []property.Violation `json:"violations"`
+	Groups     []fingerprint.Group  `json:"groups"`
+	// Provenance records how the report was produced, if known.
+	Provenance *provenance.Stamp `json:"provenance,omitempty"`
+}
+
+// New builds a report, grouping the violations by fingerprint.
+func New(name string, events int, violations []property.Violation, groups []fingerprint.Group) Report {
+	return Report{Trace: name, Events: events, Violations: violations, Groups: groups}
+}
+
+// Locate records on every violation, and on the example of every group,
+// the log lines its events were imported from, so that the report points
+// back into the logs. Violations of traces without sources are unchanged.
+func (r *Report) Locate(trace t.Trace) {
+	locate := func(v *property.Violation) {
+		v.Sources = nil
+		for k, i := range v.Events {
+			if i < 0 || i >= len(trace) || trace[i].Source == nil {
+				continue
+			}
+			if v.Sources == nil {
+				v.Sources = make([]*t.Source, len(v.Events))
+			}
+			v.Sources[k] = trace[i].Source
+		}
+	}
+	for i := range r.Violations {
+		locate(&r.Violations[i])
+	}
+	for i := range r.Groups {
+		locate(&r.Groups[i].Example)
+	}
+}
+
+// PrintSummary writes a console summary showing at most limit violations and
+// limit groups, each followed by "... and N more" when truncated. A limit of
+// zero or less prints everything.
+func (r Report) PrintSummary(w io.Writer, limit int) {
+	shown := func(n int) int {
+		if limit <= 0 || n <= limit {
+			return n
+		}
+		return limit
+	}
+
+	n := shown(len(r.Violations))
+	for _, v := range r.Violations[:n] {
+		fmt.Fprintln(w, v)
+	}
+	if more := len(r.Violations) - n; more > 0 {
+		fmt.Fprintf(w, "... and %d more violations\n", more)
+	}
+
+	fmt.Fprintf(w, "%d violations in %d distinct groups\n", len(r.Violations), len(r.Groups))
+	n = shown(len(r.Groups))
+	for _, g := range r.Groups[:n] {
+		fmt.Fprintf(w, "  %s %4dx  %s\n", g.Fingerprint, g.Count, g.Example)
+	}
+	if more := len(r.Groups) - n; more > 0 {
+		fmt.Fprintf(w, "  ... and %d more groups\n", more)
+	}
+}
+
+// WriteJSON writes the complete report as JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteJSONFile writes the complete report as JSON to a local path or an
+// object storage URL (see sink.Create).
+func (r Report) WriteJSONFile(path string) error {
+	f, err := sink.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.WriteJSON(f); err != nil {
+		sink.Abort(f)
+		return err
+	}
+	return f.Close()
+}
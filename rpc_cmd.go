@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/traces/analysis"
+)
+
+// runRPC implements `trace rpc`: it pairs requests with their responses and
+// lists the requests that were never answered.
+func runRPC(args []string) error {
+	fs := flag.NewFlagSet("rpc", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	request := fs.String("request", "request", "role of request SENDs")
+	response := fs.String("response", "response", "role of response SENDs")
+	all := fs.Bool("all", false, "list answered calls too")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	// Calls are only printed, so they can name the display processes
+	trace = aliases.Apply(trace)
+	calls := analysis.RPCCalls(trace, *request, *response)
+	counts := make(map[string]int)
+	for _, c := range calls {
+		counts[c.Status()]++
+		if *all || c.Status() != "answered" {
+			fmt.Println(c)
+		}
+	}
+	fmt.Printf("%d calls: %d answered, %d unanswered (%d requests undelivered, %d not answered, %d responses undelivered)\n",
+		len(calls), counts["answered"], len(calls)-counts["answered"],
+		counts["request undelivered"], counts["not answered"], counts["response undelivered"])
+	return nil
+}
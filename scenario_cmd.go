@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/traces/protocols"
+	"github.com/traces/script"
+)
+
+// runScenario implements `trace scenario`: it simulates a classic protocol
+// and writes its labeled trace as JSON.
+func runScenario(args []string) error {
+	fs := flag.NewFlagSet("scenario", flag.ContinueOnError)
+	name := fs.String("name", "", "protocol to simulate: "+strings.Join(protocols.Names(), ", "))
+	procs := fs.Int("processes", 3, "processes taking part")
+	rounds := fs.Int("rounds", 3, "transactions, token circulations or transfers per process, raft log entries or gossip periods")
+	failure := fs.Float64("abort-rate", 0.1, "probability that a 2pc participant votes to abort")
+	fanout := fs.Int("fanout", 2, "peers each gossiper sends the rumour to per period")
+	maxEvents := fs.Int("max-events", 0, "cut the simulation short after this many events (0: run to completion)")
+	seed := fs.Int64("seed", 1, "simulation seed")
+	out := fs.String("out", "", "write the trace to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("-name is required (one of %s)", strings.Join(protocols.Names(), ", "))
+	}
+
+	opts := protocols.Options{Processes: *procs, Rounds: *rounds, FailureRate: *failure, Fanout: *fanout}
+	trace, err := protocols.Run(*name, opts, *seed, *maxEvents)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return writeTrace(os.Stdout, trace)
+	}
+	return saveTrace(*out, trace)
+}
+
+// runScript implements `trace script`: it compiles a hand-written scenario
+// script into a trace and writes it as JSON.
+func runScript(args []string) error {
+	fs := flag.NewFlagSet("script", flag.ContinueOnError)
+	in := fs.String("in", "", "scenario script to compile")
+	out := fs.String("out", "", "write the trace to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := script.CompileFile(*in)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *in, err)
+	}
+	if *out == "" {
+		return writeTrace(os.Stdout, trace)
+	}
+	return saveTrace(*out, trace)
+}
+
+// runAbstract implements `trace abstract`: it writes a small trace as a
+// scenario script that `trace script` compiles back into it.
+func runAbstract(args []string) error {
+	fs := flag.NewFlagSet("abstract", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	out := fs.String("out", "", "write the script to this file or object storage URL instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*out, func(w io.Writer) error { return script.Abstract(w, trace) })
+}
@@ -0,0 +1,222 @@
+package script
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// maxLoopBody is the longest run of statements Abstract folds into a loop.
+const maxLoopBody = 8
+
+// Abstract writes a script that compiles to a trace with the same events and
+// happens-before order as trace, for editing and regenerating it. Message
+// IDs and labels are not kept, except msg labels of messages written in the
+// short form, and repeated runs of statements become loops.
+// Traces the script language cannot express, such as synchronous messages,
+// are rejected.
+func Abstract(w io.Writer, trace t.Trace) error {
+	var processes []string
+	for _, e := range trace {
+		if !slices.Contains(processes, e.Process) {
+			if !validName(e.Process) {
+				return fmt.Errorf("process name %s cannot be written in a script", t.QuoteName(e.Process))
+			}
+			processes = append(processes, e.Process)
+		}
+	}
+	// Idle processes appear only in clocks
+	var idle []string
+	for _, e := range trace {
+		for p := range e.VClock {
+			if !slices.Contains(processes, p) && !slices.Contains(idle, p) {
+				idle = append(idle, p)
+			}
+		}
+	}
+	slices.Sort(idle)
+	for _, p := range idle {
+		if !validName(p) {
+			return fmt.Errorf("process name %s cannot be written in a script", t.QuoteName(p))
+		}
+	}
+	processes = append(processes, idle...)
+
+	receivers := make(map[int][]int) // message ID -> its RECVs
+	for i, e := range trace {
+		if e.Type == t.EventReceive {
+			receivers[e.MessageID] = append(receivers[e.MessageID], i)
+		}
+	}
+	var lines []string
+	crashed := make(map[string]bool)
+	inline := make(map[int]bool) // RECVs written as part of their send
+	for i := 0; i < len(trace); i++ {
+		e := trace[i]
+		switch e.Type {
+		case t.EventSend:
+			recvs := receivers[e.MessageID]
+			var to []string
+			for _, r := range recvs {
+				to = append(to, trace[r].Process)
+			}
+			// Receives right after their send, one per receiver, are what
+			// an unnamed send compiles to
+			direct := len(recvs) > 0
+			for k, r := range recvs {
+				direct = direct && r == i+1+k && !slices.Contains(to[:k], to[k])
+			}
+			switch {
+			case direct && len(e.Labels) == 1 && validName(e.Labels["msg"]):
+				lines = append(lines, fmt.Sprintf("%s -> %s: %s", e.Process, strings.Join(to, ", "), e.Labels["msg"]))
+				for _, r := range recvs {
+					inline[r] = true
+				}
+			case direct:
+				lines = append(lines, fmt.Sprintf("%s sends to %s", e.Process, strings.Join(to, ", ")))
+				for _, r := range recvs {
+					inline[r] = true
+				}
+			case len(to) == 0:
+				lines = append(lines, fmt.Sprintf("%s sends m%d", e.Process, e.MessageID))
+			default:
+				slices.Sort(to)
+				lines = append(lines, fmt.Sprintf("%s sends m%d to %s", e.Process, e.MessageID, strings.Join(slices.Compact(to), ", ")))
+			}
+		case t.EventReceive:
+			if !inline[i] {
+				lines = append(lines, fmt.Sprintf("%s receives m%d", e.Process, e.MessageID))
+			}
+		case t.EventInternal:
+			lines = append(lines, e.Process+" steps")
+		case t.EventAcquire, t.EventRelease:
+			if !validName(e.Lock) {
+				return fmt.Errorf("e-%d: lock name %s cannot be written in a script", i, t.QuoteName(e.Lock))
+			}
+			verb := "acquires"
+			if e.Type == t.EventRelease {
+				verb = "releases"
+			}
+			lines = append(lines, fmt.Sprintf("%s %s %s", e.Process, verb, e.Lock))
+		case t.EventCrash:
+			crashed[e.Process] = true
+			lines = append(lines, e.Process+" crashes")
+		case t.EventRecover:
+			delete(crashed, e.Process)
+			lines = append(lines, e.Process+" recovers")
+		case t.EventBarrier:
+			// A barrier is one BARRIER per live process, in a row
+			var passed []string
+			for j := i; j < len(trace) && trace[j].Type == t.EventBarrier && trace[j].Phase == e.Phase; j++ {
+				passed = append(passed, trace[j].Process)
+			}
+			live := slices.DeleteFunc(slices.Clone(processes), func(p string) bool { return crashed[p] })
+			if len(passed) != len(live) || !containsAll(passed, live) {
+				return fmt.Errorf("e-%d: barrier %d is not passed by exactly the live processes in a row", i, e.Phase)
+			}
+			lines = append(lines, "barrier")
+			i += len(passed) - 1
+		default:
+			return fmt.Errorf("e-%d: %s events cannot be written in a script", i, e.Type)
+		}
+	}
+
+	text := "processes " + strings.Join(processes, ", ") + "\n" + strings.Join(foldLoops(lines), "\n") + "\n"
+	if err := sameOrder(trace, text); err != nil {
+		return fmt.Errorf("trace cannot be written as a script: %w", err)
+	}
+	_, err := io.WriteString(w, text)
+	return err
+}
+
+// validName reports whether a process or lock name survives parsing.
+func validName(name string) bool {
+	switch name {
+	case "", "processes", "barrier", "loop", "end":
+		return false
+	}
+	return !strings.ContainsAny(name, ",#;: \t\r\n") && !strings.Contains(name, "->")
+}
+
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		if !slices.Contains(have, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// foldLoops replaces consecutive repetitions of a run of statements with a
+// loop, taking at each point the run that saves the most lines.
+func foldLoops(lines []string) []string {
+	var out []string
+	for i := 0; i < len(lines); {
+		bestLen, bestTimes, bestSaving := 1, 1, 0
+		for n := 1; n <= maxLoopBody && i+2*n <= len(lines); n++ {
+			times := 1
+			for i+(times+1)*n <= len(lines) && slices.Equal(lines[i:i+n], lines[i+times*n:i+(times+1)*n]) {
+				times++
+			}
+			if saving := (times-1)*n - 2; saving > bestSaving {
+				bestLen, bestTimes, bestSaving = n, times, saving
+			}
+		}
+		if bestTimes == 1 {
+			out = append(out, lines[i])
+			i++
+			continue
+		}
+		out = append(out, fmt.Sprintf("loop %d times", bestTimes))
+		for _, l := range lines[i : i+bestLen] {
+			out = append(out, "  "+l)
+		}
+		out = append(out, "end")
+		i += bestLen * bestTimes
+	}
+	return out
+}
+
+// sameOrder checks that the script compiles to the events of trace, ordered
+// alike by happens-before.
+func sameOrder(trace t.Trace, text string) error {
+	compiled, err := Compile(strings.NewReader(text))
+	if err != nil {
+		return err
+	}
+	if len(compiled) != len(trace) {
+		return fmt.Errorf("the script has %d events, the trace %d", len(compiled), len(trace))
+	}
+	// Events are matched by process and position on it
+	type key struct {
+		process string
+		nth     int
+	}
+	at := make(map[key]int)
+	count := make(map[string]int)
+	for i, e := range compiled {
+		at[key{e.Process, count[e.Process]}] = i
+		count[e.Process]++
+	}
+	clear(count)
+	mapped := make([]int, len(trace))
+	for i, e := range trace {
+		j, ok := at[key{e.Process, count[e.Process]}]
+		if !ok || compiled[j].Type != e.Type {
+			return fmt.Errorf("e-%d has no counterpart in the script", i)
+		}
+		mapped[i] = j
+		count[e.Process]++
+	}
+	for i := range trace {
+		for j := range trace {
+			if trace[i].VClock.HappensBefore(trace[j].VClock) != compiled[mapped[i]].VClock.HappensBefore(compiled[mapped[j]].VClock) {
+				return fmt.Errorf("the script cannot reproduce the order of e-%d and e-%d", i, j)
+			}
+		}
+	}
+	return nil
+}
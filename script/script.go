@@ -0,0 +1,359 @@
+// Package script compiles hand-written scenario scripts into traces, so
+// textbook examples and bug reproductions need not be generated randomly.
+//
+// A script has one statement per line, or several separated by ";"; text
+// after # is a comment:
+//
+//	processes A, B, C     # optional: fixes the order and adds idle processes
+//	A sends to B          # a message delivered at once
+//	A sends m1 to B, C    # a named message, delivered by "receives"
+//	B receives m1
+//	A sends m2            # a named message nobody receives
+//	C steps               # an internal event
+//	A acquires L          # and "releases L"
+//	barrier               # every live process waits for all the others
+//	C crashes             # and "recovers"
+//	loop 10 times
+//	  A sends to B
+//	end
+//
+// Short forms suit sequence-diagram style examples:
+//
+//	A -> B, C: m1         # "A sends to B, C", labelling both ends msg=m1
+//	C: internal           # or crash, recover, acquire L, release L, receive m1
+//
+// Messages sent to a crashed process are lost, and a crashed process may do
+// nothing but recover.
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	t "github.com/traces/types"
+)
+
+// stmt is one parsed statement; loops hold their body.
+type stmt struct {
+	line    int
+	verb    string // sends, receives, steps, acquires, releases, crashes, recovers, barrier or loop
+	process string
+	name    string   // message name, or lock
+	to      []string // receivers of sends
+	label   string   // msg label of sends
+	times   int
+	body    []stmt
+}
+
+// parse reads a script and returns its processes, in declaration or first
+// appearance order, and its statements.
+func parse(r io.Reader) ([]string, []stmt, error) {
+	var declared, seen []string
+	stack := [][]stmt{nil}
+	loops := []stmt{}
+	see := func(p string) {
+		if !slices.Contains(seen, p) {
+			seen = append(seen, p)
+		}
+	}
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+		}
+		code, _, _ := strings.Cut(sc.Text(), "#")
+		for _, text := range strings.Split(code, ";") {
+			words, label, err := arrow(text)
+			if err != nil {
+				return nil, nil, fail("%v", err)
+			}
+			if len(words) == 0 {
+				continue
+			}
+
+			s := stmt{line: line}
+			switch {
+			case words[0] == "processes":
+				if len(words) == 1 {
+					return nil, nil, fail("processes needs at least one name")
+				}
+				declared = append(declared, words[1:]...)
+				continue
+			case words[0] == "barrier" && len(words) == 1:
+				s.verb = "barrier"
+			case words[0] == "loop":
+				if len(words) != 3 || words[2] != "times" {
+					return nil, nil, fail("expected \"loop N times\"")
+				}
+				n, err := strconv.Atoi(words[1])
+				if err != nil || n < 0 {
+					return nil, nil, fail("loop count %q is not a number", words[1])
+				}
+				loops = append(loops, stmt{line: line, verb: "loop", times: n})
+				stack = append(stack, nil)
+				continue
+			case words[0] == "end" && len(words) == 1:
+				if len(loops) == 0 {
+					return nil, nil, fail("end without loop")
+				}
+				s, loops = loops[len(loops)-1], loops[:len(loops)-1]
+				s.body, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			case len(words) < 2:
+				return nil, nil, fail("unknown statement %q", strings.TrimSpace(text))
+			default:
+				s.process, s.verb = words[0], words[1]
+				see(s.process)
+				args := words[2:]
+				switch s.verb {
+				case "sends":
+					if len(args) > 0 && args[0] != "to" {
+						s.name, args = args[0], args[1:]
+					}
+					if s.name != "" && len(args) == 0 {
+						break // never delivered
+					}
+					if len(args) < 2 || args[0] != "to" {
+						return nil, nil, fail("expected \"%s sends [name] to receiver, ...\"", s.process)
+					}
+					s.to = args[1:]
+					for _, p := range s.to {
+						see(p)
+					}
+					s.label = label
+				case "receives", "acquires", "releases":
+					if len(args) != 1 {
+						return nil, nil, fail("expected \"%s %s name\"", s.process, s.verb)
+					}
+					s.name = args[0]
+				case "steps", "crashes", "recovers":
+					if len(args) != 0 {
+						return nil, nil, fail("unexpected %q after %q", strings.Join(args, " "), s.verb)
+					}
+				default:
+					return nil, nil, fail("unknown statement %q", strings.TrimSpace(text))
+				}
+			}
+			stack[len(stack)-1] = append(stack[len(stack)-1], s)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(loops) > 0 {
+		return nil, nil, fmt.Errorf("line %d: loop without end", loops[len(loops)-1].line)
+	}
+
+	processes := declared
+	for _, p := range seen {
+		if !slices.Contains(processes, p) {
+			if len(declared) > 0 {
+				return nil, nil, fmt.Errorf("process %s is not declared", p)
+			}
+			processes = append(processes, p)
+		}
+	}
+	return processes, stack[0], nil
+}
+
+// arrow rewrites the short forms "A -> B, C: m1", a message delivered at
+// once and labelled msg=m1, and "C: internal" (or crash, recover,
+// acquire L, release L, receive m1) into the words of the long forms.
+func arrow(text string) (words []string, label string, err error) {
+	fields := func(s string) []string { return strings.Fields(strings.ReplaceAll(s, ",", " ")) }
+	if from, rest, ok := strings.Cut(text, "->"); ok {
+		to, name, _ := strings.Cut(rest, ":")
+		sender, receivers := fields(from), fields(to)
+		if len(sender) != 1 || len(receivers) == 0 || len(fields(name)) > 1 {
+			return nil, "", fmt.Errorf("expected \"sender -> receiver, ...: name\" in %q", strings.TrimSpace(text))
+		}
+		return append([]string{sender[0], "sends", "to"}, receivers...), strings.TrimSpace(name), nil
+	}
+	process, action, ok := strings.Cut(text, ":")
+	if !ok {
+		return fields(text), "", nil
+	}
+	who, what := fields(process), fields(action)
+	if len(who) != 1 || len(what) == 0 {
+		return nil, "", fmt.Errorf("expected \"process: action\" in %q", strings.TrimSpace(text))
+	}
+	verbs := map[string]string{"internal": "steps", "crash": "crashes", "recover": "recovers",
+		"acquire": "acquires", "release": "releases", "receive": "receives"}
+	verb, ok := verbs[what[0]]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown action %q", what[0])
+	}
+	return append([]string{who[0], verb}, what[1:]...), "", nil
+}
+
+// message is a named message awaiting its receives.
+type message struct {
+	send t.Event
+	to   []string
+}
+
+// compiler runs statements, keeping every process's clock.
+type compiler struct {
+	processes []string
+	trace     t.Trace
+	clocks    map[string]t.VectorClock
+	crashed   map[string]bool
+	inFlight  map[string]message // named message -> its latest send
+	next      int                // next message ID
+	phase     int
+}
+
+// Compile turns a script into a trace.
+func Compile(r io.Reader) (t.Trace, error) {
+	processes, stmts, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiler{
+		processes: processes,
+		clocks:    make(map[string]t.VectorClock),
+		crashed:   make(map[string]bool),
+		inFlight:  make(map[string]message),
+	}
+	for _, p := range processes {
+		c.clocks[p] = t.NewVectorClock(processes)
+	}
+	if err := c.run(stmts); err != nil {
+		return nil, err
+	}
+	return c.trace, nil
+}
+
+// CompileString compiles a script given as text, such as
+// "A -> B: m1; B -> C: m2; C: internal".
+func CompileString(text string) (t.Trace, error) {
+	return Compile(strings.NewReader(text))
+}
+
+// MustCompile is like CompileString but panics on errors, for traces
+// written into Go code.
+func MustCompile(text string) t.Trace {
+	trace, err := CompileString(text)
+	if err != nil {
+		panic("script: " + err.Error())
+	}
+	return trace
+}
+
+// CompileFile compiles the script in a file.
+func CompileFile(path string) (t.Trace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Compile(f)
+}
+
+func (c *compiler) run(stmts []stmt) error {
+	for _, s := range stmts {
+		if err := c.exec(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// local appends an event that only advances its process's clock.
+func (c *compiler) local(process string, e t.Event) {
+	c.clocks[process][process]++
+	e.Process, e.VClock = process, t.DeepCopy(c.clocks[process])
+	c.trace = append(c.trace, e)
+}
+
+// receive delivers send to process.
+func (c *compiler) receive(process string, send t.Event) {
+	clock := c.clocks[process]
+	clock[process]++
+	clock.Merge(send.VClock)
+	c.trace = append(c.trace, t.Event{Type: t.EventReceive, Process: process, VClock: t.DeepCopy(clock), MessageID: send.MessageID, Labels: maps.Clone(send.Labels)})
+}
+
+func (c *compiler) exec(s stmt) error {
+	if s.verb == "loop" {
+		for range s.times {
+			if err := c.run(s.body); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if s.verb == "barrier" {
+		c.phase++
+		// Crashed processes do not pass the barrier, so live ones learn
+		// nothing from them through it
+		merged := make(t.VectorClock, len(c.processes))
+		for _, p := range c.processes {
+			if !c.crashed[p] {
+				merged.Merge(c.clocks[p])
+			}
+		}
+		for _, p := range c.processes {
+			if c.crashed[p] {
+				continue
+			}
+			c.clocks[p] = t.DeepCopy(merged)
+			c.local(p, t.Event{Type: t.EventBarrier, MessageID: -1, Phase: c.phase})
+		}
+		return nil
+	}
+	if c.crashed[s.process] && s.verb != "recovers" {
+		return fmt.Errorf("line %d: %s %s while crashed", s.line, s.process, s.verb)
+	}
+
+	switch s.verb {
+	case "sends":
+		send := t.Event{Type: t.EventSend, MessageID: c.next}
+		if s.label != "" {
+			send.Labels = map[string]string{"msg": s.label}
+		}
+		c.local(s.process, send)
+		send = c.trace[len(c.trace)-1]
+		c.next++
+		if s.name != "" {
+			c.inFlight[s.name] = message{send, s.to}
+			return nil
+		}
+		for _, p := range s.to {
+			if !c.crashed[p] {
+				c.receive(p, send)
+			}
+		}
+	case "receives":
+		m, ok := c.inFlight[s.name]
+		if !ok {
+			return fmt.Errorf("line %d: %s receives %s, which was never sent", s.line, s.process, s.name)
+		}
+		if !slices.Contains(m.to, s.process) {
+			return fmt.Errorf("line %d: %s receives %s, which was sent to %s", s.line, s.process, s.name, strings.Join(m.to, ", "))
+		}
+		c.receive(s.process, m.send)
+	case "steps":
+		c.local(s.process, t.Event{Type: t.EventInternal, MessageID: -1})
+	case "acquires":
+		c.local(s.process, t.Event{Type: t.EventAcquire, MessageID: -1, Lock: s.name})
+	case "releases":
+		c.local(s.process, t.Event{Type: t.EventRelease, MessageID: -1, Lock: s.name})
+	case "crashes":
+		c.crashed[s.process] = true
+		c.local(s.process, t.Event{Type: t.EventCrash, MessageID: -1})
+	case "recovers":
+		if !c.crashed[s.process] {
+			return fmt.Errorf("line %d: %s recovers without having crashed", s.line, s.process)
+		}
+		delete(c.crashed, s.process)
+		c.local(s.process, t.Event{Type: t.EventRecover, MessageID: -1})
+	}
+	return nil
+}
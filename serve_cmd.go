@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/traces/alert"
+	"github.com/traces/api"
+	"github.com/traces/ingest"
+)
+
+// runServe implements `trace serve`: a gRPC ingestion service assembling a
+// global trace from remotely streamed events, and optionally the REST API
+// with live metrics of that trace, monitoring properties and alerting on
+// their violations. Stored traces given with -backfill come first, so the
+// live events continue their history. With -state it snapshots what it has
+// assembled and alerted on, and resumes from the snapshot when restarted.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", ":50051", "address of the gRPC ingestion service")
+	httpAddr := fs.String("http", "", "address of the REST API (disabled if empty)")
+	out := fs.String("out", "", "write the assembled trace to this file on shutdown")
+	store := fs.String("store", "", "keep traces uploaded to the REST API, with their summaries, in this directory")
+	window := fs.Duration("window", time.Minute, "window of the live metrics served at /live")
+	props := fs.String("property", "", "comma separated properties to monitor on the live trace")
+	every := fs.Duration("check-every", 10*time.Second, "how often to check the monitored properties")
+	state := fs.String("state", "", "snapshot the assembled trace and alerted violations to this file, and resume from it on start")
+	snapshotEvery := fs.Duration("snapshot-every", 30*time.Second, "how often to write the -state snapshot")
+	var backfill []string
+	fs.Func("backfill", "trace file placed ahead of the live events, so monitors see its history (repeatable, in order)", func(s string) error {
+		backfill = append(backfill, s)
+		return nil
+	})
+	publicURL := fs.String("public-url", "", "base URL of the REST API linked from alerts (default: http://localhost<-http>)")
+	var hooks []alert.Hook
+	fs.Func("alert", "webhook fired on each new kind of violation: an http(s) URL, or slack:<url> (repeatable)", func(s string) error {
+		h, err := alert.ParseHook(s)
+		if err == nil {
+			hooks = append(hooks, h)
+		}
+		return err
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *window <= 0 || *every <= 0 || *snapshotEvery <= 0 {
+		return fmt.Errorf("-window, -check-every and -snapshot-every must be positive")
+	}
+	if *store != "" && *httpAddr == "" {
+		return fmt.Errorf("-store needs -http")
+	}
+	if len(hooks) > 0 && *props == "" {
+		return fmt.Errorf("-alert needs -property to monitor")
+	}
+
+	var monitor *alert.Monitor
+	if *props != "" {
+		properties, err := parseProperties(*props)
+		if err != nil {
+			return err
+		}
+		monitor = &alert.Monitor{Properties: properties, Hooks: hooks}
+		if *httpAddr != "" {
+			base := *publicURL
+			if base == "" {
+				base = "http://localhost" + *httpAddr
+				if !strings.HasPrefix(*httpAddr, ":") {
+					base = "http://" + *httpAddr
+				}
+			}
+			monitor.ReportURL = strings.TrimSuffix(base, "/") + "/live/report"
+		}
+	}
+
+	collector := ingest.NewCollector()
+	if *state != "" {
+		var alerted []string
+		var err error
+		if collector, alerted, err = loadServeState(*state); err != nil {
+			return err
+		}
+		if monitor != nil {
+			monitor.Restore(alerted)
+		}
+		if n := collector.Len() + collector.Pending(); n > 0 {
+			fmt.Fprintf(os.Stderr, "resumed %d events from %s\n", n, *state)
+			if len(backfill) > 0 {
+				// The snapshot already holds the backfilled history
+				fmt.Fprintln(os.Stderr, "skipping -backfill")
+				backfill = nil
+			}
+		}
+	}
+	for _, path := range backfill {
+		trace, err := loadTrace(path)
+		if err != nil {
+			return err
+		}
+		if err := collector.Backfill(trace); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Fprintf(os.Stderr, "backfilled %d events from %s\n", len(trace), path)
+	}
+	servers := []*http.Server{ingest.NewHTTPServer(*addr, collector)}
+	fmt.Fprintf(os.Stderr, "serving traces.v1.Ingest on %s\n", *addr)
+	if *httpAddr != "" {
+		rest := api.NewServer()
+		if *store != "" {
+			if err := rest.Persist(*store); err != nil {
+				return err
+			}
+		}
+		rest.Watch(collector, *window, monitor)
+		servers = append(servers, &http.Server{Addr: *httpAddr, Handler: rest})
+		fmt.Fprintf(os.Stderr, "serving REST API on %s\n", *httpAddr)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errs := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}()
+	}
+
+	if monitor != nil {
+		go monitorLoop(ctx, collector, monitor, *every)
+	}
+	snapshots := make(chan struct{})
+	if *state != "" {
+		go func() {
+			snapshotLoop(ctx, *state, collector, monitor, *snapshotEvery)
+			close(snapshots)
+		}()
+	} else {
+		close(snapshots)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+	stop()
+	for _, srv := range servers {
+		srv.Shutdown(context.Background())
+	}
+	if err != nil {
+		return err
+	}
+	// The final snapshot must not race a periodic one for the same file
+	<-snapshots
+	if *state != "" {
+		if err := saveServeState(*state, collector, monitor); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "%d events assembled, %d pending\n", collector.Len(), collector.Pending())
+	if *out != "" {
+		return saveTrace(*out, collector.Trace())
+	}
+	return nil
+}
+
+// monitorLoop checks the live trace every interval until ctx is done,
+// reporting new kinds of violations and failed alerts on stderr.
+func monitorLoop(ctx context.Context, c *ingest.Collector, m *alert.Monitor, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		alerts, errs := m.Check(c.Trace())
+		for _, a := range alerts {
+			fmt.Fprintln(os.Stderr, "violation:", a)
+		}
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, "alert failed:", err)
+		}
+	}
+}
+
+// serveState is the snapshot written by `trace serve -state`.
+type serveState struct {
+	Collector json.RawMessage `json:"collector"`
+	Alerted   []string        `json:"alerted,omitempty"` // fingerprints the monitor fired for
+}
+
+// loadServeState restores the collector and the alerted fingerprints from a
+// snapshot, or returns an empty collector if there is none yet.
+func loadServeState(path string) (*ingest.Collector, []string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ingest.NewCollector(), nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var st serveState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c, err := ingest.RestoreCollector(bytes.NewReader(st.Collector))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, st.Alerted, nil
+}
+
+// saveServeState writes a snapshot next to path and renames it into place,
+// so a crash while writing leaves the previous snapshot intact.
+func saveServeState(path string, c *ingest.Collector, m *alert.Monitor) error {
+	var buf bytes.Buffer
+	if err := c.Snapshot(&buf); err != nil {
+		return err
+	}
+	st := serveState{Collector: buf.Bytes()}
+	if m != nil {
+		st.Alerted = m.Seen()
+	}
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// snapshotLoop writes the state every interval until ctx is done, reporting
+// failures on stderr.
+func snapshotLoop(ctx context.Context, path string, c *ingest.Collector, m *alert.Monitor, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := saveServeState(path, c, m); err != nil {
+			fmt.Fprintln(os.Stderr, "snapshot failed:", err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package sim
+
+import (
+	"bufio"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Crash stops a process once the trace has reached AtEvent events. A crashed
+// process receives no further deliveries or timers.
+type Crash struct {
+	Process string
+	AtEvent int
+}
+
+// Partition drops every message sent between different groups while the
+// trace length is in [AtEvent, AtEvent+Duration).
+type Partition struct {
+	Groups   [][]string
+	AtEvent  int
+	Duration int
+}
+
+// Drop loses a fraction of the messages on the channel From -> To.
+type Drop struct {
+	From, To string
+	Rate     float64
+}
+
+// Schedule is a declarative set of faults injected into a simulation.
+type Schedule struct {
+	Crashes    []Crash
+	Partitions []Partition
+	Drops      []Drop
+}
+
+// InjectedFault labels a fault the engine applied while producing a trace.
+type InjectedFault struct {
+	Kind      string // "crash", "partition", "drop", "crashed-receiver" or "duplicate"
+	Process   string // crashed process, or sender of a lost or duplicated message
+	To        string // receiver of a lost or duplicated message
+	Event     int    // trace index of the crash, the lost message's SEND or the duplicate's first RECV
+	MessageID int
+}
+
+func (f InjectedFault) String() string {
+	if f.Kind == "crash" {
+		return fmt.Sprintf("crash of %s at e-%d", f.Process, f.Event)
+	}
+	if f.Kind == "duplicate" {
+		return fmt.Sprintf("duplicate: Msg-%d %s->%s delivered again (first at e-%d)", f.MessageID, f.Process, f.To, f.Event)
+	}
+	return fmt.Sprintf("%s: Msg-%d %s->%s lost (sent at e-%d)", f.Kind, f.MessageID, f.Process, f.To, f.Event)
+}
+
+func (p Partition) active(events int) bool {
+	return events >= p.AtEvent && events < p.AtEvent+p.Duration
+}
+
+// separates reports whether a and b are in different groups of the partition.
+func (p Partition) separates(a, b string) bool {
+	group := func(name string) int {
+		for i, g := range p.Groups {
+			if slices.Contains(g, name) {
+				return i
+			}
+		}
+		return -1
+	}
+	ga, gb := group(a), group(b)
+	return ga >= 0 && gb >= 0 && ga != gb
+}
+
+// ParseSchedule reads a fault schedule, one fault per line:
+//
+//	crash P2 at 300
+//	partition A,B|C at 100 for 200
+//	drop A->C 0.1
+//
+// Blank lines and lines starting with '#' are ignored.
+func ParseSchedule(text string) (Schedule, error) {
+	var s Schedule
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		var err error
+		switch {
+		case f[0] == "crash" && len(f) == 4 && f[2] == "at":
+			var at int
+			at, err = strconv.Atoi(f[3])
+			s.Crashes = append(s.Crashes, Crash{Process: f[1], AtEvent: at})
+		case f[0] == "partition" && len(f) == 6 && f[2] == "at" && f[4] == "for":
+			p := Partition{}
+			for _, g := range strings.Split(f[1], "|") {
+				p.Groups = append(p.Groups, strings.Split(g, ","))
+			}
+			if p.AtEvent, err = strconv.Atoi(f[3]); err == nil {
+				p.Duration, err = strconv.Atoi(f[5])
+			}
+			s.Partitions = append(s.Partitions, p)
+		case f[0] == "drop" && len(f) == 3 && strings.Contains(f[1], "->"):
+			from, to, _ := strings.Cut(f[1], "->")
+			var rate float64
+			rate, err = strconv.ParseFloat(f[2], 64)
+			s.Drops = append(s.Drops, Drop{From: from, To: to, Rate: rate})
+		default:
+			err = fmt.Errorf("unrecognised fault")
+		}
+		if err != nil {
+			return Schedule{}, fmt.Errorf("line %d %q: %w", n, line, err)
+		}
+	}
+	return s, sc.Err()
+}
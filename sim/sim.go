@@ -0,0 +1,324 @@
+package sim
+
+import (
+	"container/heap"
+	"fmt"
+	"maps"
+	"math/rand"
+
+	t "github.com/traces/types"
+)
+
+// Message is an application message in flight between two processes.
+type Message struct {
+	ID      int
+	From    string
+	To      string
+	Payload any
+	// Labels are copied to the message's SEND and RECV events.
+	Labels map[string]string
+}
+
+// Process is a user supplied state machine. The engine calls Init once at
+// time zero, then OnMessage for every delivered message and OnTimer for every
+// expired timer. Processes react by calling methods on the Context.
+type Process interface {
+	Init(ctx *Context)
+	OnMessage(ctx *Context, msg Message)
+	OnTimer(ctx *Context, timer int)
+}
+
+// Context is the handle a process uses to interact with the simulation while
+// one of its callbacks runs.
+type Context struct {
+	engine  *Engine
+	process string
+}
+
+// Self returns the name of the process the callback runs on.
+func (c *Context) Self() string { return c.process }
+
+// Now returns the current simulated time.
+func (c *Context) Now() int { return c.engine.now }
+
+// Processes returns the names of all processes in registration order.
+func (c *Context) Processes() []string { return c.engine.order }
+
+// Rand returns the engine's random source, so process decisions stay
+// reproducible under the engine seed.
+func (c *Context) Rand() *rand.Rand { return c.engine.rand }
+
+// Send records a SEND event and schedules the delivery of payload to the
+// given process after a random network delay.
+func (c *Context) Send(to string, payload any) {
+	c.engine.send(c.process, to, payload, nil)
+}
+
+// SendLabeled is Send with labels on the SEND and RECV events.
+func (c *Context) SendLabeled(to string, payload any, labels map[string]string) {
+	c.engine.send(c.process, to, payload, labels)
+}
+
+// Step records an INTERNAL event with the given labels, such as a decision
+// or a recorded state.
+func (c *Context) Step(labels map[string]string) {
+	c.engine.local(c.process, t.Event{Type: t.EventInternal, Labels: labels})
+}
+
+// Acquire records that the process acquired lock.
+func (c *Context) Acquire(lock string) {
+	c.engine.local(c.process, t.Event{Type: t.EventAcquire, Lock: lock})
+}
+
+// Release records that the process released lock.
+func (c *Context) Release(lock string) {
+	c.engine.local(c.process, t.Event{Type: t.EventRelease, Lock: lock})
+}
+
+// SetTimer schedules OnTimer(timer) on this process after delay time units.
+func (c *Context) SetTimer(delay, timer int) {
+	c.engine.schedule(item{at: c.engine.now + max(delay, 0), process: c.process, timer: timer})
+}
+
+// item is a pending delivery or timer expiry.
+type item struct {
+	at      int
+	seq     int
+	process string
+	msg     *Message
+	send    t.VectorClock // clock of the SEND event, for deliveries
+	timer   int
+}
+
+type queue []item
+
+func (q queue) Len() int { return len(q) }
+func (q queue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	return q[i].seq < q[j].seq
+}
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x any)   { *q = append(*q, x.(item)) }
+func (q *queue) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
+
+// Engine is a discrete-event simulator producing a correctly clocked trace
+// from user supplied process state machines.
+type Engine struct {
+	// MinDelay and MaxDelay bound the random network delay of a message.
+	MinDelay, MaxDelay int
+	// MaxEvents stops the simulation once the trace has this many events.
+	// Zero means run until no deliveries or timers are pending.
+	MaxEvents int
+	// Faults is injected while the simulation runs.
+	Faults Schedule
+	// FIFO delivers the messages of each channel in the order they were
+	// sent, as protocols assuming TCP-like channels require.
+	FIFO bool
+
+	crashed  map[string]bool
+	injected []InjectedFault
+
+	rand      *rand.Rand
+	processes map[string]Process
+	order     []string
+	clocks    map[string]t.VectorClock
+	pending   queue
+	seq       int
+	now       int
+	nextMsgID int
+	trace     t.Trace
+	lastAt    map[[2]string]int // latest delivery time per channel, under FIFO
+	err       error             // stops the simulation, returned by Run
+}
+
+// New returns an engine whose random choices are derived from seed.
+func New(seed int64) *Engine {
+	return &Engine{
+		MinDelay:  1,
+		MaxDelay:  10,
+		rand:      rand.New(rand.NewSource(seed)),
+		processes: make(map[string]Process),
+	}
+}
+
+// Add registers a process under the given name.
+func (e *Engine) Add(name string, p Process) error {
+	if _, ok := e.processes[name]; ok {
+		return fmt.Errorf("process %q registered twice", name)
+	}
+	e.processes[name] = p
+	e.order = append(e.order, name)
+	return nil
+}
+
+func (e *Engine) schedule(it item) {
+	it.seq = e.seq
+	e.seq++
+	heap.Push(&e.pending, it)
+}
+
+func (e *Engine) full() bool {
+	return e.err != nil || (e.MaxEvents > 0 && len(e.trace) >= e.MaxEvents)
+}
+
+// local records an event that only advances the process's own clock.
+func (e *Engine) local(process string, ev t.Event) {
+	if e.full() {
+		return
+	}
+	clock := e.clocks[process]
+	clock[process]++
+	ev.Process, ev.VClock, ev.MessageID = process, t.DeepCopy(clock), -1
+	e.trace = append(e.trace, ev)
+}
+
+func (e *Engine) send(from, to string, payload any, labels map[string]string) {
+	if e.full() {
+		return
+	}
+	if _, ok := e.processes[to]; !ok {
+		e.err = fmt.Errorf("%s sends to unknown process %q", from, to)
+		return
+	}
+
+	clock := e.clocks[from]
+	clock[from]++
+	msg := &Message{ID: e.nextMsgID, From: from, To: to, Payload: payload, Labels: labels}
+	e.nextMsgID++
+
+	sendEvent := t.Event{
+		Type:      t.EventSend,
+		Process:   from,
+		VClock:    t.DeepCopy(clock),
+		MessageID: msg.ID,
+		Labels:    maps.Clone(labels),
+	}
+	e.trace = append(e.trace, sendEvent)
+
+	if kind := e.lose(from, to); kind != "" {
+		e.injected = append(e.injected, InjectedFault{
+			Kind: kind, Process: from, To: to, Event: len(e.trace) - 1, MessageID: msg.ID,
+		})
+		return
+	}
+
+	delay := e.MinDelay
+	if e.MaxDelay > e.MinDelay {
+		delay += e.rand.Intn(e.MaxDelay - e.MinDelay + 1)
+	}
+	at := e.now + delay
+	if e.FIFO {
+		// Deliveries due at the same time keep their scheduling order
+		if e.lastAt == nil {
+			e.lastAt = make(map[[2]string]int)
+		}
+		at = max(at, e.lastAt[[2]string{from, to}])
+		e.lastAt[[2]string{from, to}] = at
+	}
+	e.schedule(item{at: at, process: to, msg: msg, send: sendEvent.VClock})
+}
+
+// lose decides whether the fault schedule drops a message from -> to and
+// returns the responsible fault kind, or "" if the message gets through.
+func (e *Engine) lose(from, to string) string {
+	for _, p := range e.Faults.Partitions {
+		if p.active(len(e.trace)) && p.separates(from, to) {
+			return "partition"
+		}
+	}
+	for _, d := range e.Faults.Drops {
+		if d.From == from && d.To == to && e.rand.Float64() < d.Rate {
+			return "drop"
+		}
+	}
+	return ""
+}
+
+// applyCrashes marks processes whose crash point has been reached.
+func (e *Engine) applyCrashes() {
+	for _, c := range e.Faults.Crashes {
+		if !e.crashed[c.Process] && len(e.trace) >= c.AtEvent {
+			e.crashed[c.Process] = true
+			e.injected = append(e.injected, InjectedFault{Kind: "crash", Process: c.Process, Event: len(e.trace)})
+		}
+	}
+}
+
+// Injected returns the faults applied during the last Run, in order.
+func (e *Engine) Injected() []InjectedFault {
+	return e.injected
+}
+
+// Run executes the simulation and returns the produced trace. A process
+// sending to an unregistered process stops the simulation with an error.
+func (e *Engine) Run() (t.Trace, error) {
+	e.err = nil
+	e.crashed = make(map[string]bool)
+	e.lastAt = nil
+	e.clocks = make(map[string]t.VectorClock, len(e.order))
+	for _, p := range e.order {
+		e.clocks[p] = t.NewVectorClock(e.order)
+	}
+
+	e.applyCrashes()
+	for _, p := range e.order {
+		if !e.crashed[p] {
+			e.processes[p].Init(&Context{engine: e, process: p})
+		}
+	}
+
+	for e.pending.Len() > 0 && !e.full() {
+		it := heap.Pop(&e.pending).(item)
+		e.now = it.at
+		ctx := &Context{engine: e, process: it.process}
+
+		e.applyCrashes()
+		if e.crashed[it.process] {
+			if it.msg != nil {
+				e.injected = append(e.injected, InjectedFault{
+					Kind: "crashed-receiver", Process: it.msg.From, To: it.process,
+					Event: e.sendIndex(it.msg.ID), MessageID: it.msg.ID,
+				})
+			}
+			continue
+		}
+
+		if it.msg == nil {
+			e.processes[it.process].OnTimer(ctx, it.timer)
+			continue
+		}
+
+		clock := e.clocks[it.process]
+		clock[it.process]++
+		clock.Merge(it.send)
+		e.trace = append(e.trace, t.Event{
+			Type:      t.EventReceive,
+			Process:   it.process,
+			VClock:    t.DeepCopy(clock),
+			MessageID: it.msg.ID,
+			Labels:    maps.Clone(it.msg.Labels),
+		})
+		e.processes[it.process].OnMessage(ctx, *it.msg)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.trace, nil
+}
+
+// sendIndex returns the trace index of the SEND event of a message.
+func (e *Engine) sendIndex(id int) int {
+	for i, ev := range e.trace {
+		if ev.Type == t.EventSend && ev.MessageID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,76 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// metadataToken is where workloads on Google Cloud get an access token for
+// their service account.
+const metadataToken = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// openGCS writes gs://bucket/object objects with the XML API. The access
+// token is GOOGLE_OAUTH_ACCESS_TOKEN (e.g. from `gcloud auth
+// print-access-token`) or, failing that, the metadata server's, as on GKE
+// and Cloud Run. STORAGE_EMULATOR_HOST selects an emulator, which needs no
+// token.
+func openGCS(u *url.URL) (io.WriteCloser, error) {
+	bucket, object := u.Host, strings.TrimPrefix(u.Path, "/")
+	endpoint := "https://storage.googleapis.com"
+	emulator := os.Getenv("STORAGE_EMULATOR_HOST")
+	if emulator != "" {
+		endpoint = strings.TrimSuffix(emulator, "/")
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+	}
+	target := endpoint + "/" + bucket + "/" + escapePath(object)
+
+	return &upload{put: func(body []byte) error {
+		req, err := http.NewRequest(http.MethodPut, target, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", contentType(object))
+		if emulator == "" {
+			token, err := gcsToken()
+			if err != nil {
+				return fmt.Errorf("%s: %w", u, err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return send(req)
+	}}, nil
+}
+
+func gcsToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, metadataToken, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("no GOOGLE_OAUTH_ACCESS_TOKEN and no metadata server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server token: %s", resp.Status)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("metadata server token: %w", err)
+	}
+	return tok.AccessToken, nil
+}
@@ -0,0 +1,119 @@
+package sink
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// openS3 writes s3://bucket/key objects with a single PUT signed with AWS
+// Signature Version 4. Credentials and region come from the standard
+// environment variables (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN, AWS_REGION); AWS_ENDPOINT_URL selects an S3-compatible
+// service such as MinIO, addressed path-style.
+func openS3(u *url.URL) (io.WriteCloser, error) {
+	akid, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if akid == "" || secret == "" {
+		return nil, fmt.Errorf("%s: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set", u)
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	target := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, escapePath(key))
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		target = strings.TrimSuffix(endpoint, "/") + "/" + bucket + "/" + escapePath(key)
+	}
+	token := os.Getenv("AWS_SESSION_TOKEN")
+
+	return &upload{put: func(body []byte) error {
+		req, err := http.NewRequest(http.MethodPut, target, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", contentType(key))
+		if token != "" {
+			req.Header.Set("X-Amz-Security-Token", token)
+		}
+		sum := sha256.Sum256(body)
+		signV4(req, hex.EncodeToString(sum[:]), region, "s3", akid, secret, time.Now())
+		return send(req)
+	}}, nil
+}
+
+// escapePath percent-encodes an object key as S3 expects in canonical
+// requests: everything but unreserved characters and the slashes between
+// segments.
+func escapePath(key string) string {
+	var b strings.Builder
+	for _, c := range []byte(key) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// signV4 adds the date, payload hash and Authorization headers of AWS
+// Signature Version 4 to req, signing the host and every header already set.
+func signV4(req *http.Request, payloadHash, region, service, akid, secret string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	uri := req.URL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+	canonical := strings.Join([]string{req.Method, uri, req.URL.RawQuery,
+		canonicalHeaders.String(), signed, payloadHash}, "\n")
+	sum := sha256.Sum256([]byte(canonical))
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		akid, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
@@ -0,0 +1,123 @@
+// Package sink opens the destinations reports and exports are written to:
+// local paths, and object storage URLs such as s3://bucket/key and
+// gs://bucket/object, so runs in containers without persistent volumes can
+// still deliver their artifacts.
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Opener opens a writer for a destination URL of its scheme. Closing the
+// writer completes the write and reports whether it succeeded.
+type Opener func(u *url.URL) (io.WriteCloser, error)
+
+var schemes = map[string]Opener{}
+
+// Register makes destinations of a URL scheme available to Create.
+func Register(scheme string, o Opener) {
+	schemes[scheme] = o
+}
+
+func init() {
+	Register("s3", openS3)
+	Register("gs", openGCS)
+}
+
+// Schemes returns the registered URL schemes.
+func Schemes() []string {
+	names := make([]string, 0, len(schemes))
+	for s := range schemes {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Create opens dest for writing: a URL of a registered scheme, a file:// URL
+// or a local path. Remote objects are uploaded when the writer is closed,
+// so the error of Close must be checked.
+func Create(dest string) (io.WriteCloser, error) {
+	scheme, _, ok := strings.Cut(dest, "://")
+	if !ok {
+		return os.Create(dest)
+	}
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, err
+	}
+	if scheme == "file" {
+		return os.Create(u.Path)
+	}
+	open, ok := schemes[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown destination scheme %q (known: file, %s)", scheme, strings.Join(Schemes(), ", "))
+	}
+	if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("destination %q: expected %s://bucket/name", dest, scheme)
+	}
+	return open(u)
+}
+
+// WriteFile writes data to dest as Create does.
+func WriteFile(dest string, data []byte) error {
+	w, err := Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		Abort(w)
+		return err
+	}
+	return w.Close()
+}
+
+// Abort abandons a writer from Create after a failed write: a remote object
+// is not uploaded, while a local file is closed as it stands.
+func Abort(w io.WriteCloser) {
+	if _, ok := w.(*upload); !ok {
+		w.Close()
+	}
+}
+
+// upload buffers an object and hands it to put on Close.
+type upload struct {
+	bytes.Buffer
+	put func(body []byte) error
+}
+
+func (u *upload) Close() error { return u.put(u.Bytes()) }
+
+var client = &http.Client{Timeout: 5 * time.Minute}
+
+// contentType guesses the media type of an object from its name.
+func contentType(name string) string {
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// send performs an upload request and turns error responses into errors.
+func send(req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("uploading %s: %s: %s", req.URL.Redacted(), resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/traces/analysis"
+	"github.com/traces/experiment"
+	t "github.com/traces/types"
+)
+
+// runStability implements `trace stability`: it reports when each event of
+// a trace became known to every process and how long that took, and with
+// -gc how much of each process's history could be garbage collected.
+func runStability(args []string) error {
+	fs := flag.NewFlagSet("stability", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	verbose := fs.Bool("v", false, "list the stability of every event")
+	gc := fs.Bool("gc", false, "report per-process safe points up to which history can be garbage collected")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	var latencies []float64
+	unstable := 0
+	last := make(map[string]int)
+	for _, s := range analysis.CausalStability(trace) {
+		if *verbose {
+			if s.Stable {
+				fmt.Printf("e-%-4d on %s: stable after %d events, last known by %s (e-%d)\n",
+					s.Event, t.QuoteName(aliases.Name(trace[s.Event].Process)), s.Latency, t.QuoteName(aliases.Name(s.Last)), s.Frontier[s.Last])
+			} else {
+				fmt.Printf("e-%-4d on %s: never stable (known by %d processes)\n",
+					s.Event, t.QuoteName(aliases.Name(trace[s.Event].Process)), len(s.Frontier))
+			}
+		}
+		if !s.Stable {
+			unstable++
+			continue
+		}
+		latencies = append(latencies, float64(s.Latency))
+		last[s.Last]++
+	}
+
+	fmt.Printf("%d events, %d stable, %d never stable\n", len(trace), len(latencies), unstable)
+	if len(latencies) > 0 {
+		d := experiment.Summarize(latencies)
+		fmt.Printf("stability latency (events): mean %.2f, min %.0f, p50 %.0f, p90 %.0f, p99 %.0f, max %.0f\n",
+			d.Mean, d.Min, d.P50, d.P90, d.P99, d.Max)
+		for _, p := range slices.Sorted(maps.Keys(last)) {
+			fmt.Printf("  last to learn: %s for %d events\n", t.QuoteName(aliases.Name(p)), last[p])
+		}
+	}
+	if *gc {
+		fmt.Println("garbage-collection safe points:")
+		for _, sp := range analysis.GCSafePoints(trace) {
+			if sp.Event < 0 {
+				fmt.Printf("  %s: nothing collectable of %d events, held back by %s\n",
+					t.QuoteName(aliases.Name(sp.Process)), sp.Events, t.QuoteName(aliases.Name(sp.HeldBackBy)))
+				continue
+			}
+			fmt.Printf("  %s: up to e-%d (%d of %d events), held back by %s\n",
+				t.QuoteName(aliases.Name(sp.Process)), sp.Event, sp.Counter, sp.Events, t.QuoteName(aliases.Name(sp.HeldBackBy)))
+		}
+	}
+	return nil
+}
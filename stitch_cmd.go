@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	t "github.com/traces/types"
+)
+
+// runStitch implements `trace stitch`: it merges traces recorded by
+// different sinks, each holding some of the processes, into one trace whose
+// clocks are recomputed across them (see types.Stitch).
+func runStitch(args []string) error {
+	fs := flag.NewFlagSet("stitch", flag.ContinueOnError)
+	var inputs []string
+	fs.Func("in", "trace to merge (repeatable, at least two)", func(s string) error {
+		inputs = append(inputs, s)
+		return nil
+	})
+	out := fs.String("out", "", "write the merged trace to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(inputs) < 2 {
+		return fmt.Errorf("-in is required at least twice")
+	}
+
+	traces := make([]t.Trace, len(inputs))
+	for i, path := range inputs {
+		var err error
+		if traces[i], err = loadTrace(path); err != nil {
+			return err
+		}
+	}
+	merged, err := t.Stitch(traces...)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return writeTrace(os.Stdout, merged)
+	}
+	return saveTrace(*out, merged)
+}
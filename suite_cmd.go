@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/traces/property"
+)
+
+// runSuite implements `trace suite import|export|list`.
+func runSuite(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: suite import <file|dir> | export <name> <file> | list")
+	}
+	dir, err := property.SuiteDir()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "import":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: suite import <file|dir>")
+		}
+		suites, err := property.LoadSuites(args[1])
+		if err != nil {
+			return err
+		}
+		for _, s := range suites {
+			if err := s.Validate(); err != nil {
+				return err
+			}
+			path, err := property.Install(dir, s)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("imported %s %s (%d properties) to %s\n", s.Name, s.Version, len(s.Specs), path)
+		}
+	case "export":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: suite export <name> <file>")
+		}
+		s, err := property.ResolveSuite(args[1])
+		if err != nil {
+			return err
+		}
+		f, err := os.Create(args[2])
+		if err != nil {
+			return err
+		}
+		if err := s.Write(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	case "list":
+		suites, err := property.LoadSuites(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, s := range suites {
+			fmt.Printf("%-20s %-8s %d properties  %s\n", s.Name, s.Version, len(s.Specs), s.Description)
+		}
+	default:
+		return fmt.Errorf("unknown suite command %q", args[0])
+	}
+	return nil
+}
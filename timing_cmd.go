@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/traces/analysis"
+)
+
+// runTiming implements `trace timing`: it compares the physical timestamps
+// of a trace, and its hybrid logical clocks if any, with its causal order and
+// bounds the skew between clocks.
+func runTiming(args []string) error {
+	fs := flag.NewFlagSet("timing", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to read")
+	top := fs.Int("top", 10, "inversions to list, largest first (0: all)")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	stamped := 0
+	for _, e := range trace {
+		if !e.Timestamp.IsZero() {
+			stamped++
+		}
+	}
+	if stamped == 0 {
+		return fmt.Errorf("%s has no timestamps", *in)
+	}
+
+	a := analysis.ComparePhysicalOrder(trace)
+	fmt.Printf("%d of %d events timestamped\n", stamped, len(trace))
+	if a.Ordered > 0 {
+		fmt.Printf("causally ordered pairs: %d, timestamps agree on %d (%.1f%%)\n",
+			a.Ordered, a.Agreeing, 100*float64(a.Agreeing)/float64(a.Ordered))
+	}
+	fmt.Printf("concurrent pairs: %d, %d stamped with the same time\n", a.Concurrent, a.Tied)
+
+	inversions := analysis.TimeInversions(trace)
+	fmt.Printf("Inversions (%d):\n", len(inversions))
+	for i, inv := range inversions {
+		if *top > 0 && i == *top {
+			fmt.Printf("  ... %d more\n", len(inversions)-i)
+			break
+		}
+		fmt.Printf("  e-%d (%s) -> e-%d (%s) stamped %v earlier\n",
+			inv.Before, aliases.Name(trace[inv.Before].Process), inv.After, aliases.Name(trace[inv.After].Process), inv.By)
+	}
+
+	if h := analysis.CompareHLC(trace); h.Stamped > 0 {
+		fmt.Printf("HLCs on %d events: %d of %d causally ordered pairs contradicted, logical counter up to %d, wall time up to %v ahead\n",
+			h.Stamped, h.Contradictions, h.Ordered, h.MaxLogical, h.MaxDrift)
+	}
+
+	fmt.Println("Clock skew bounds:")
+	for _, b := range analysis.SkewBounds(trace) {
+		lo, hi := "-inf", "+inf"
+		if b.HasMin {
+			lo = b.Min.String()
+		}
+		if b.HasMax {
+			hi = b.Max.String()
+		}
+		fmt.Printf("  %s ahead of %s by [%s, %s] (%d messages)\n", aliases.Name(b.To), aliases.Name(b.From), lo, hi, b.Messages)
+	}
+	return nil
+}
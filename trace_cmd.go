@@ -0,0 +1,245 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/traces/analysis"
+	"github.com/traces/dag"
+	"github.com/traces/messages"
+)
+
+// runGenerate implements `trace generate`: it writes a generated trace as JSON.
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	events := fs.Int("events", 30, "events in the generated trace")
+	procs := fs.String("processes", "A,B,C", "comma separated process names")
+	topology := fs.String("topology", "complete", "who may send to whom: complete, ring, star or tree")
+	adjacency := fs.String("adjacency", "", "JSON file mapping each process to those it may send to; overrides -topology and -processes")
+	rounds := fs.Int("rounds", 0, "stop after this many scheduling rounds (0: no round budget)")
+	broadcast := fs.Float64("broadcast", 0, "probability that a send goes to every process it may send to")
+	duplicate := fs.Float64("duplicate", 0, "probability that a delivered message is delivered again later")
+	crash := fs.Float64("crash", 0, "probability that a scheduled process crashes")
+	recoverRate := fs.Float64("recover", 0, "probability that a scheduled crashed process recovers")
+	weights := fs.String("weights", "", "per-process scheduling weights, e.g. A=5,B=1 (others weigh 1)")
+	receiveRates := fs.String("receive-rates", "", "per-process probabilities of receiving rather than sending, e.g. A=0.2")
+	capacity := fs.Int("capacity", 0, "messages that may wait for each receiver before senders to it block (0: unbounded)")
+	capacities := fs.String("capacities", "", "per-receiver capacities overriding -capacity, e.g. A=1,B=4")
+	payload := fs.String("payload", "", "labels to give every message, each with the values to draw from, e.g. op=get|put,key=x|y")
+	step := fs.Duration("step", 0, "with timestamps, mean real time between the events of a process")
+	latency := fs.Duration("latency", 0, "with timestamps, mean message latency")
+	skew := fs.Duration("skew", 0, "with timestamps, largest offset of a process' clock either way")
+	hlc := fs.Bool("hlc", false, "also stamp events with hybrid logical clocks kept from their timestamps (implies timestamps)")
+	start := fs.String("start", "", "stamp events with physical times from this RFC 3339 time (implied by -step, -latency, -skew or -hlc)")
+	rpc := fs.Bool("rpc", false, "make every message a request that its receiver answers")
+	fifo := fs.Bool("fifo", false, "deliver the messages of each channel in send order")
+	causal := fs.Bool("causal", false, "deliver messages to each process in causal order of their sends")
+	sync := fs.Bool("sync", false, "make every message a synchronous rendezvous of sender and receiver")
+	barrierEvery := fs.Int("barrier-every", 0, "make all processes pass a barrier after every this many events")
+	seed := fs.Int64("seed", 1, "generator seed")
+	out := fs.String("out", "", "write the trace to this file instead of stdout")
+	decisions := fs.String("decisions", "", "write the generator's decision log to this file")
+	level := fs.Int("decision-level", 1, "decision log detail: 1 actions, 2 actions and random draws")
+	accept := fs.String("accept", "", "regenerate until the trace meets these criteria, e.g. width>=3,channels")
+	maxTries := fs.Int("max-tries", 100, "attempts before -accept gives up")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	topo, err := messages.ParseTopology(*topology)
+	if err != nil {
+		return err
+	}
+	cfg := messages.Config{
+		Processes:      strings.Split(*procs, ","),
+		NumEvents:      *events,
+		Rounds:         *rounds,
+		Topology:       topo,
+		BroadcastRate:  *broadcast,
+		DuplicateRate:  *duplicate,
+		CrashRate:      *crash,
+		RecoverRate:    *recoverRate,
+		Capacity:       *capacity,
+		Synchronous:    *sync,
+		RPC:            *rpc,
+		FIFO:           *fifo,
+		CausalDelivery: *causal,
+		BarrierEvery:   *barrierEvery,
+		DecisionLevel:  messages.DecisionLevel(*level),
+	}
+	if cfg.Weights, err = parseProcessValues(*weights); err != nil {
+		return fmt.Errorf("-weights: %w", err)
+	}
+	if cfg.ReceiveRates, err = parseProcessValues(*receiveRates); err != nil {
+		return fmt.Errorf("-receive-rates: %w", err)
+	}
+	caps, err := parseProcessValues(*capacities)
+	if err != nil {
+		return fmt.Errorf("-capacities: %w", err)
+	}
+	for p, c := range caps {
+		if c != float64(int(c)) {
+			return fmt.Errorf("-capacities: %s=%g is not a whole number", p, c)
+		}
+		if cfg.Capacities == nil {
+			cfg.Capacities = make(map[string]int)
+		}
+		cfg.Capacities[p] = int(c)
+	}
+	if cfg.Payload, err = parsePayload(*payload); err != nil {
+		return fmt.Errorf("-payload: %w", err)
+	}
+	if *start != "" || *step != 0 || *latency != 0 || *skew != 0 || *hlc {
+		cfg.Timing = &messages.Timing{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Step: *step, Latency: *latency, Skew: *skew, HLC: *hlc}
+		if *start != "" {
+			if cfg.Timing.Start, err = time.Parse(time.RFC3339Nano, *start); err != nil {
+				return fmt.Errorf("-start: %w", err)
+			}
+		}
+	}
+	if *adjacency != "" {
+		if cfg.Adjacency, err = messages.LoadAdjacency(*adjacency); err != nil {
+			return err
+		}
+		cfg.Processes = cfg.Adjacency.Processes()
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if *decisions != "" {
+		f, err := os.Create(*decisions)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		cfg.Decisions = f
+	}
+	criteria, err := analysis.ParseCriteria(*accept)
+	if err != nil {
+		return err
+	}
+	trace, tries, err := analysis.GenerateAccepted(cfg, rand.New(rand.NewSource(*seed)), *maxTries, criteria...)
+	if err != nil {
+		return err
+	}
+	if tries > 1 {
+		fmt.Fprintf(os.Stderr, "accepted attempt %d\n", tries)
+	}
+
+	if *out == "" {
+		return writeTrace(os.Stdout, trace)
+	}
+	return saveTrace(*out, trace)
+}
+
+// parseProcessValues parses "A=5,B=1" into a map, nil for an empty string.
+func parseProcessValues(s string) (map[string]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	out := make(map[string]float64)
+	for _, kv := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: expected process=value", kv)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", kv, err)
+		}
+		out[name] = v
+	}
+	return out, nil
+}
+
+// parsePayload parses "op=get|put,key=x|y" into the values of each label,
+// nil for an empty string.
+func parsePayload(s string) (map[string][]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	out := make(map[string][]string)
+	for _, kv := range strings.Split(s, ",") {
+		label, values, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q: expected label=value|value...", kv)
+		}
+		out[label] = strings.Split(values, "|")
+	}
+	return out, nil
+}
+
+// runGraph implements `trace graph`: it prints the DAG of a JSON trace as DOT,
+// optionally with the critical path highlighted.
+func runGraph(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	in := fs.String("in", "", "JSON trace to read")
+	aliasesPath := aliasesFlag(fs)
+	critical := fs.Bool("critical", false, "highlight the zero-slack events of the weighted critical path")
+	weight := fs.String("weight", "", "with -critical, label holding the cost of an event (empty: every event costs 1)")
+	edgeWeight := fs.String("edge-weight", "", "with -critical, label on a RECV holding the cost of its message")
+	labels := fs.Bool("labels", false, "draw the labels of each event next to it")
+	bucket := fs.Int("bucket", 0, "draw an overview with every this many events of a process as one node (0: draw every event)")
+	var rules ruleFlag
+	fs.Var(&rules, "rule", "extra happens-before rule as kind[:name=value;...] (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	if trace, err = dag.ApplyRules(trace, rules...); err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	if *bucket > 0 {
+		fmt.Print(stamp.DOT() + dag.Coarsen(aliases.Apply(trace), *bucket).ToGraphviz())
+		return nil
+	}
+	// Highlight is keyed by the clocks drawn, which name aliased processes
+	shown := aliases.Apply(trace)
+	g := dag.BuildDAG(shown)
+	g.ShowLabels = *labels
+	if *critical {
+		g.Highlight = make(map[string]bool)
+		for i, s := range analysis.Slack(trace, analysis.LabelWeights(*weight, *edgeWeight)) {
+			if analysis.ZeroSlack(s) {
+				g.Highlight[shown[i].VClock.Key()] = true
+			}
+		}
+	}
+	fmt.Print(stamp.DOT() + g.ToGraphviz())
+	return nil
+}
+
+// ruleFlag collects repeated -rule flags naming registered edge rules, with
+// optional parameters as in "key-order:prefix=lock:".
+type ruleFlag []dag.Rule
+
+func (r *ruleFlag) String() string { return "" }
+
+func (r *ruleFlag) Set(s string) error {
+	kind, params, err := parseKind(s)
+	if err != nil {
+		return fmt.Errorf("rule %w", err)
+	}
+	rule, err := dag.NewRule(kind, params)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, rule)
+	return nil
+}
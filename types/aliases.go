@@ -0,0 +1,68 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+)
+
+// Aliases maps raw process identifiers (pod UIDs, IP:port, ...) to
+// human-friendly display names. Processes without an alias keep their raw
+// name.
+type Aliases map[string]string
+
+// LoadAliases reads a JSON object of raw name to display name.
+func LoadAliases(path string) (Aliases, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var a Aliases
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("decoding aliases: %w", err)
+	}
+	return a, a.validate()
+}
+
+// validate rejects mappings that would merge two processes into one name.
+func (a Aliases) validate() error {
+	owner := make(map[string]string, len(a))
+	for _, raw := range slices.Sorted(maps.Keys(a)) {
+		display := a[raw]
+		if other, ok := owner[display]; ok {
+			return fmt.Errorf("processes %q and %q both map to %q", other, raw, display)
+		}
+		owner[display] = raw
+	}
+	return nil
+}
+
+// Name returns the display name of a process.
+func (a Aliases) Name(raw string) string {
+	if display, ok := a[raw]; ok {
+		return display
+	}
+	return raw
+}
+
+// Apply returns a copy of the trace with process names, including vector
+// clock keys, replaced by their display names. It is meant for human-facing
+// output only; machine-readable exports should keep the raw trace.
+func (a Aliases) Apply(trace Trace) Trace {
+	if len(a) == 0 {
+		return trace
+	}
+	out := make(Trace, len(trace))
+	for i, e := range trace {
+		e.Process = a.Name(e.Process)
+		clock := make(VectorClock, len(e.VClock))
+		for p, v := range e.VClock {
+			clock[a.Name(p)] = v
+		}
+		e.VClock = clock
+		out[i] = e
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package types
+
+import "sort"
+
+// SplitByKey partitions a trace into one sub-trace per correlation key,
+// preserving the relative order of events. Events without a key are
+// collected under the empty key.
+func SplitByKey(trace Trace) map[string]Trace {
+	parts := make(map[string]Trace)
+	for _, e := range trace {
+		parts[e.CorrelationKey] = append(parts[e.CorrelationKey], e)
+	}
+	return parts
+}
+
+// Keys returns the distinct correlation keys of a trace in sorted order.
+func (t Trace) Keys() []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, e := range t {
+		if !seen[e.CorrelationKey] {
+			seen[e.CorrelationKey] = true
+			keys = append(keys, e.CorrelationKey)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
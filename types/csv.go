@@ -0,0 +1,155 @@
+package types
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CSVMapping maps CSV columns (by header name) onto Event fields. The vector
+// clock is read either from explicit per-process columns (ClockColumns) or
+// from every column whose header starts with ClockPrefix, the remainder of
+// the header being the process name.
+type CSVMapping struct {
+	Process        string            `json:"process"`
+	Type           string            `json:"type"`
+	MessageID      string            `json:"message_id"`
+	CorrelationKey string            `json:"correlation_key,omitempty"`
+	ClockColumns   map[string]string `json:"clock_columns,omitempty"` // process -> column
+	ClockPrefix    string            `json:"clock_prefix,omitempty"`
+	// SendValue and RecvValue are the Type column values denoting SEND and
+	// RECV events; they default to "SEND" and "RECV".
+	SendValue string `json:"send_value,omitempty"`
+	RecvValue string `json:"recv_value,omitempty"`
+}
+
+// DefaultCSVMapping expects columns process, type, message_id,
+// correlation_key and one vc_<process> column per process.
+func DefaultCSVMapping() CSVMapping {
+	return CSVMapping{
+		Process:        "process",
+		Type:           "type",
+		MessageID:      "message_id",
+		CorrelationKey: "correlation_key",
+		ClockPrefix:    "vc_",
+	}
+}
+
+// LoadCSVMapping reads a JSON encoded CSVMapping, filling unset fields from
+// DefaultCSVMapping.
+func LoadCSVMapping(path string) (CSVMapping, error) {
+	m := DefaultCSVMapping()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("decoding CSV mapping: %w", err)
+	}
+	return m, nil
+}
+
+// LoadCSV reads a trace from CSV with a header row, using m to locate the
+// event fields. Rows must be in an order consistent with happens-before.
+// name is recorded as the Source file of every event.
+func LoadCSV(r io.Reader, name string, m CSVMapping) (Trace, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		col[strings.TrimSpace(h)] = i
+	}
+
+	index := func(name string) (int, error) {
+		i, ok := col[name]
+		if !ok {
+			return 0, fmt.Errorf("CSV has no column %q", name)
+		}
+		return i, nil
+	}
+	procCol, err := index(m.Process)
+	if err != nil {
+		return nil, err
+	}
+	typeCol, err := index(m.Type)
+	if err != nil {
+		return nil, err
+	}
+	msgCol, err := index(m.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	keyCol, hasKey := col[m.CorrelationKey]
+
+	clockCols := make(map[string]int)
+	for proc, name := range m.ClockColumns {
+		if clockCols[proc], err = index(name); err != nil {
+			return nil, err
+		}
+	}
+	if m.ClockPrefix != "" {
+		for h, i := range col {
+			if proc, ok := strings.CutPrefix(h, m.ClockPrefix); ok && proc != "" {
+				clockCols[proc] = i
+			}
+		}
+	}
+
+	sendValue, recvValue := m.SendValue, m.RecvValue
+	if sendValue == "" {
+		sendValue = "SEND"
+	}
+	if recvValue == "" {
+		recvValue = "RECV"
+	}
+
+	var trace Trace
+	for {
+		start := cr.InputOffset()
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return trace, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		e := Event{
+			Process: record[procCol],
+			VClock:  make(VectorClock, len(clockCols)),
+			Source:  &Source{File: name, Line: line, Offset: start},
+		}
+		switch record[typeCol] {
+		case sendValue:
+			e.Type = EventSend
+		case recvValue:
+			e.Type = EventReceive
+		default:
+			return nil, fmt.Errorf("line %d: unknown event type %q", line, record[typeCol])
+		}
+		if e.MessageID, err = strconv.Atoi(record[msgCol]); err != nil {
+			return nil, fmt.Errorf("line %d: message id: %w", line, err)
+		}
+		if hasKey {
+			e.CorrelationKey = record[keyCol]
+		}
+		for proc, i := range clockCols {
+			if record[i] == "" {
+				continue
+			}
+			if e.VClock[proc], err = strconv.Atoi(record[i]); err != nil {
+				return nil, fmt.Errorf("line %d: clock of %s: %w", line, proc, err)
+			}
+		}
+		trace = append(trace, e)
+	}
+}
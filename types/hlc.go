@@ -0,0 +1,136 @@
+package types
+
+import (
+	"fmt"
+	"maps"
+	"time"
+)
+
+// HLC is a hybrid logical clock timestamp (Kulkarni et al., 2014), as
+// logged by systems such as CockroachDB: Wall is the largest physical time,
+// in Unix nanoseconds, the process has heard of and Logical orders events
+// sharing a Wall. If a happens before b then a's HLC is smaller, while Wall
+// stays within the clock skew of physical time.
+type HLC struct {
+	Wall    int64
+	Logical int
+}
+
+// IsZero reports whether h is the zero HLC, which no event is stamped with.
+func (h HLC) IsZero() bool { return h == HLC{} }
+
+// Compare returns -1, 0 or +1 as h is before, equal to or after o.
+func (h HLC) Compare(o HLC) int {
+	switch {
+	case h.Wall < o.Wall, h.Wall == o.Wall && h.Logical < o.Logical:
+		return -1
+	case h == o:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// Before reports whether h orders before o.
+func (h HLC) Before(o HLC) bool { return h.Compare(o) < 0 }
+
+func (h HLC) String() string {
+	return fmt.Sprintf("%s,%d", time.Unix(0, h.Wall).UTC().Format(time.RFC3339Nano), h.Logical)
+}
+
+// HLCClock is the hybrid logical clock of one process.
+type HLCClock struct {
+	last HLC
+}
+
+// Now stamps a local event or a send happening at physical time pt.
+func (c *HLCClock) Now(pt int64) HLC {
+	if pt > c.last.Wall {
+		c.last = HLC{Wall: pt}
+	} else {
+		c.last.Logical++
+	}
+	return c.last
+}
+
+// Update stamps the receive, at physical time pt, of a message stamped m.
+func (c *HLCClock) Update(pt int64, m HLC) HLC {
+	prev := c.last
+	wall := max(prev.Wall, m.Wall, pt)
+	switch {
+	case wall == prev.Wall && wall == m.Wall:
+		c.last = HLC{wall, max(prev.Logical, m.Logical) + 1}
+	case wall == prev.Wall:
+		c.last = HLC{wall, prev.Logical + 1}
+	case wall == m.Wall:
+		c.last = HLC{wall, m.Logical + 1}
+	default:
+		c.last = HLC{Wall: wall}
+	}
+	return c.last
+}
+
+// HLCClocks returns the hybrid logical clocks the processes of a trace would
+// have kept, indexed like the trace, taking each event's Timestamp as the
+// physical time its process read. An event merges the clocks of the latest
+// events of other processes its vector clock knows of, which for a receive
+// is the clock of the message; the events of a rendezvous share one HLC.
+func HLCClocks(trace Trace) []HLC {
+	type slot struct {
+		process string
+		counter int
+	}
+	at := make(map[slot]int, len(trace))
+	for i, e := range trace {
+		at[slot{e.Process, e.VClock[e.Process]}] = i
+	}
+
+	clocks := make(map[string]*HLCClock)
+	out := make([]HLC, len(trace))
+	for i, e := range trace {
+		c := clocks[e.Process]
+		if c == nil {
+			c = &HLCClock{}
+			clocks[e.Process] = c
+		}
+		var heard HLC
+		partner := -1
+		for q, n := range e.VClock {
+			if q == e.Process {
+				continue
+			}
+			j, ok := at[slot{q, n}]
+			if ok && maps.Equal(trace[j].VClock, e.VClock) {
+				if j < i {
+					partner = j
+				}
+				j, ok = at[slot{q, n - 1}]
+			}
+			if ok && j < i && heard.Before(out[j]) {
+				heard = out[j]
+			}
+		}
+		pt := int64(0)
+		if !e.Timestamp.IsZero() {
+			pt = e.Timestamp.UnixNano()
+		}
+		switch {
+		case partner >= 0:
+			// Joining a rendezvous stamped by an earlier partner
+			out[i] = out[partner]
+			c.last = out[partner]
+		case heard.IsZero():
+			out[i] = c.Now(pt)
+		default:
+			out[i] = c.Update(pt, heard)
+		}
+	}
+	return out
+}
+
+// AssignHLC sets the HLC field of every event; see HLCClocks.
+func AssignHLC(trace Trace) {
+	for i, h := range HLCClocks(trace) {
+		trace[i].HLC = h
+	}
+}
@@ -0,0 +1,114 @@
+package types
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// chunkName matches the pieces of a split file: <stem>.<number><ext>[.gz],
+// e.g. trace.0001.json.gz.
+var chunkName = regexp.MustCompile(`^(.*)\.(\d+)((?:\.[^./\d][^./]*)?)(\.gz)?$`)
+
+// Ext returns the extension that identifies the format of a trace file,
+// looking through a ".gz" suffix: Ext("trace.0001.jsonl.gz") is ".jsonl".
+func Ext(path string) string {
+	return filepath.Ext(strings.TrimSuffix(path, ".gz"))
+}
+
+// Chunks returns the files that make up path. A missing trace.json stands
+// for the pieces of a split file with the same stem and extension
+// (trace.0001.json, trace.0002.json, ...), returned in numeric order. A file
+// that exists is always returned as is, even if it is named like a piece.
+func Chunks(path string) ([]string, error) {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return []string{path}, nil
+	}
+	ext := Ext(path)
+	stem := strings.TrimSuffix(strings.TrimSuffix(path, ".gz"), ext)
+
+	matches, err := filepath.Glob(globEscape(stem) + ".*" + globEscape(ext) + "*")
+	if err != nil {
+		return nil, err
+	}
+	number := make(map[string]int)
+	var chunks []string
+	for _, f := range matches {
+		m := chunkName.FindStringSubmatch(f)
+		if m == nil || m[1] != stem || m[3] != ext {
+			continue
+		}
+		number[f], _ = strconv.Atoi(m[2])
+		chunks = append(chunks, f)
+	}
+	if len(chunks) == 0 {
+		return []string{path}, nil // let the caller report the missing file
+	}
+	slices.SortFunc(chunks, func(a, b string) int { return number[a] - number[b] })
+	return chunks, nil
+}
+
+func globEscape(s string) string {
+	return strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`, `\`, `\\`).Replace(s)
+}
+
+// Open opens a trace file for reading, concatenating the pieces of a split
+// file (see Chunks) and decompressing any piece that is gzip compressed, so
+// every loader can read large production dumps as they were written.
+func Open(path string) (io.ReadCloser, error) {
+	chunks, err := Chunks(path)
+	if err != nil {
+		return nil, err
+	}
+	in := &input{}
+	var readers []io.Reader
+	for _, c := range chunks {
+		f, err := os.Open(c)
+		if err != nil {
+			in.Close()
+			return nil, err
+		}
+		in.files = append(in.files, f)
+		r, err := decompress(f)
+		if err != nil {
+			in.Close()
+			return nil, fmt.Errorf("%s: %w", c, err)
+		}
+		readers = append(readers, r)
+	}
+	in.Reader = io.MultiReader(readers...)
+	return in, nil
+}
+
+// decompress returns a reader of the file's contents, unzipping them when
+// the file starts with the gzip magic number.
+func decompress(f *os.File) (io.Reader, error) {
+	br := bufio.NewReader(f)
+	if magic, _ := br.Peek(2); len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return br, nil
+	}
+	return gzip.NewReader(br)
+}
+
+// input reads a sequence of pieces and closes all of them.
+type input struct {
+	io.Reader
+	files []*os.File
+}
+
+func (in *input) Close() error {
+	var first error
+	for _, f := range in.files {
+		if err := f.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
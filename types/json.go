@@ -0,0 +1,300 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// JSONVersion is the version of the trace JSON schema written by Save.
+const JSONVersion = 1
+
+// The JSON trace schema (version 1):
+//
+//	{
+//	  "version": 1,
+//	  "provenance": {"tool": "trace", ...}, // optional, how the file was produced
+//	  "events": [
+//	    {
+//	      "type": "SEND",                 // event type, see below
+//	      "process": "A",                 // process the event occurred on
+//	      "clock": {"A": 1, "B": 0},      // vector clock after the event
+//	      "message_id": 0,                // matches RECVs to their SEND
+//	      "correlation_key": "req-1",     // optional
+//	      "lock": "accounts",             // ACQUIRE and RELEASE only
+//	      "phase": 2,                     // BARRIER only
+//	      "role": "ack",                  // optional, role in the correlated operation
+//	      "labels": {"session": "s1"},    // optional application attributes
+//	      "timestamp": "2024-01-01T00:00:00.001Z", // optional physical time
+//	      "lamport": 3,                   // optional Lamport clock
+//	      "hlc": {"wall": 1704067200001000000, "logical": 0}, // optional hybrid logical clock
+//	      "source": {"file": "a.log", "line": 12, "offset": 345} // optional
+//	    }
+//	  ]
+//	}
+//
+// The type is one of "SEND", "RECV", "INTERNAL", "ACQUIRE", "RELEASE",
+// "BARRIER", "CRASH" or "RECOVER". Events are listed in an order consistent
+// with happens-before.
+type jsonTrace struct {
+	Version    int         `json:"version"`
+	Provenance any         `json:"provenance,omitempty"`
+	Events     []jsonEvent `json:"events"`
+}
+
+type jsonEvent struct {
+	Type           string            `json:"type"`
+	Process        string            `json:"process"`
+	Clock          VectorClock       `json:"clock"`
+	MessageID      int               `json:"message_id"`
+	CorrelationKey string            `json:"correlation_key,omitempty"`
+	Lock           string            `json:"lock,omitempty"`
+	Phase          int               `json:"phase,omitempty"`
+	Role           string            `json:"role,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Timestamp      *time.Time        `json:"timestamp,omitempty"`
+	Lamport        LamportClock      `json:"lamport,omitempty"`
+	HLC            *jsonHLC          `json:"hlc,omitempty"`
+	Source         *jsonSource       `json:"source,omitempty"`
+}
+
+type jsonHLC struct {
+	Wall    int64 `json:"wall"`
+	Logical int   `json:"logical"`
+}
+
+type jsonSource struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Offset int64  `json:"offset,omitempty"`
+}
+
+// ParseEventType is the inverse of EventType.String.
+func ParseEventType(s string) (EventType, error) {
+	switch s {
+	case "SEND":
+		return EventSend, nil
+	case "RECV":
+		return EventReceive, nil
+	case "INTERNAL":
+		return EventInternal, nil
+	case "ACQUIRE":
+		return EventAcquire, nil
+	case "RELEASE":
+		return EventRelease, nil
+	case "BARRIER":
+		return EventBarrier, nil
+	case "CRASH":
+		return EventCrash, nil
+	case "RECOVER":
+		return EventRecover, nil
+	default:
+		return 0, fmt.Errorf("unknown event type %q", s)
+	}
+}
+
+func toJSONEvent(e Event) jsonEvent {
+	je := jsonEvent{
+		Type:           e.Type.String(),
+		Process:        e.Process,
+		Clock:          e.VClock,
+		MessageID:      e.MessageID,
+		CorrelationKey: e.CorrelationKey,
+		Lock:           e.Lock,
+		Phase:          e.Phase,
+		Role:           e.Role,
+		Labels:         e.Labels,
+		Lamport:        e.Lamport,
+	}
+	if !e.Timestamp.IsZero() {
+		je.Timestamp = &e.Timestamp
+	}
+	if !e.HLC.IsZero() {
+		je.HLC = &jsonHLC{Wall: e.HLC.Wall, Logical: e.HLC.Logical}
+	}
+	if e.Source != nil {
+		je.Source = &jsonSource{File: e.Source.File, Line: e.Source.Line, Offset: e.Source.Offset}
+	}
+	return je
+}
+
+func fromJSONEvent(je jsonEvent) (Event, error) {
+	typ, err := ParseEventType(je.Type)
+	if err != nil {
+		return Event{}, err
+	}
+	if je.Process == "" {
+		return Event{}, fmt.Errorf("missing process")
+	}
+	e := Event{
+		Type:           typ,
+		Process:        je.Process,
+		VClock:         je.Clock,
+		MessageID:      je.MessageID,
+		CorrelationKey: je.CorrelationKey,
+		Lock:           je.Lock,
+		Phase:          je.Phase,
+		Role:           je.Role,
+		Labels:         je.Labels,
+		Lamport:        je.Lamport,
+	}
+	if (typ == EventAcquire || typ == EventRelease) && e.Lock == "" {
+		return Event{}, fmt.Errorf("%s without lock", typ)
+	}
+	if e.VClock == nil {
+		e.VClock = make(VectorClock)
+	}
+	if je.Timestamp != nil {
+		e.Timestamp = *je.Timestamp
+	}
+	if je.HLC != nil {
+		e.HLC = HLC{Wall: je.HLC.Wall, Logical: je.HLC.Logical}
+	}
+	if je.Source != nil {
+		e.Source = &Source{File: je.Source.File, Line: je.Source.Line, Offset: je.Source.Offset}
+	}
+	return e, nil
+}
+
+// Save writes the trace to w using the JSON schema documented above.
+func Save(w io.Writer, trace Trace) error {
+	return SaveWithProvenance(w, trace, nil)
+}
+
+// SaveWithProvenance writes a trace like Save, recording how it was produced
+// in a "provenance" field unless provenance is nil. Load ignores the field.
+func SaveWithProvenance(w io.Writer, trace Trace, provenance any) error {
+	doc := jsonTrace{Version: JSONVersion, Provenance: provenance, Events: make([]jsonEvent, len(trace))}
+	for i, e := range trace {
+		doc.Events[i] = toJSONEvent(e)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// Load reads a trace written by Save.
+func Load(r io.Reader) (Trace, error) {
+	return QueryJSON(r, Query{})
+}
+
+// QueryJSON reads the events of a trace written by Save that are selected by
+// q. Events are decoded one at a time, so only the selected ones are held in
+// memory. Anything but whitespace after the trace is an error.
+func QueryJSON(r io.Reader, q Query) (Trace, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, fmt.Errorf("decoding trace: %w", err)
+	}
+	version := 0
+	trace := Trace{}
+	n := 0
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("decoding trace: %w", err)
+		}
+		switch tok {
+		case "version":
+			if err := dec.Decode(&version); err != nil {
+				return nil, fmt.Errorf("decoding trace: %w", err)
+			}
+		case "events":
+			if err := expectDelim(dec, '['); err != nil {
+				return nil, fmt.Errorf("decoding trace: %w", err)
+			}
+			for dec.More() {
+				var je jsonEvent
+				if err := dec.Decode(&je); err != nil {
+					return nil, fmt.Errorf("decoding trace: %w", err)
+				}
+				e, err := fromJSONEvent(je)
+				if err != nil {
+					return nil, fmt.Errorf("event %d: %w", n, err)
+				}
+				n++
+				if q.Match(e) {
+					trace = append(trace, e)
+				}
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return nil, fmt.Errorf("decoding trace: %w", err)
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, fmt.Errorf("decoding trace: %w", err)
+			}
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, fmt.Errorf("decoding trace: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("decoding trace: unexpected data after the trace")
+	}
+	if version != JSONVersion {
+		return nil, fmt.Errorf("unsupported trace version %d", version)
+	}
+	return trace, nil
+}
+
+// expectDelim reads the next token of dec, which must be the delimiter d.
+func expectDelim(dec *json.Decoder, d json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != d {
+		return fmt.Errorf("expected %v, found %v", d, tok)
+	}
+	return nil
+}
+
+// SaveFile writes the trace as JSON to the named file.
+func SaveFile(path string, trace Trace) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Save(f, trace); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadFile reads a JSON trace from the named file, which may be gzip
+// compressed or split into pieces (see Open).
+func LoadFile(path string) (Trace, error) {
+	return QueryFile(path, Query{})
+}
+
+// QueryFile reads the events of a JSON trace file selected by q; see
+// QueryJSON and LoadFile. Each piece of a split file is a trace of its own,
+// and their events are concatenated in the order of the pieces.
+func QueryFile(path string, q Query) (Trace, error) {
+	chunks, err := Chunks(path)
+	if err != nil {
+		return nil, err
+	}
+	trace := Trace{}
+	for _, c := range chunks {
+		f, err := Open(c)
+		if err != nil {
+			return nil, err
+		}
+		part, err := QueryJSON(f, q)
+		f.Close()
+		if err != nil {
+			if len(chunks) > 1 {
+				return nil, fmt.Errorf("%s: %w", c, err)
+			}
+			return nil, err
+		}
+		trace = append(trace, part...)
+	}
+	return trace, nil
+}
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// hostileNames are process names and labels JSON must escape or carry as
+// they are: spaces, quotes, backslashes, newlines and non-ASCII letters.
+var hostileNames = []string{"web server", `say "hi"`, `C:\tmp`, "line\nbreak", "nœud-α"}
+
+func hostileTrace(tb testing.TB) Trace {
+	tb.Helper()
+	var trace Trace
+	for i, p := range hostileNames {
+		q := hostileNames[(i+1)%len(hostileNames)]
+		trace = append(trace,
+			Event{Type: EventSend, Process: p, MessageID: i, CorrelationKey: p, Labels: map[string]string{p: q}},
+			Event{Type: EventReceive, Process: q, MessageID: i, CorrelationKey: p, Labels: map[string]string{p: q}},
+		)
+	}
+	rebuilt, err := ReconstructClocks(trace)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return rebuilt
+}
+
+func TestJSONHostileNames(t *testing.T) {
+	trace := hostileTrace(t)
+	var buf bytes.Buffer
+	if err := Save(&buf, trace); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join("testdata", "hostile.json")
+	if *update {
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("hostile.json differs from the golden file:\n--- got\n%s\n--- want\n%s", buf.Bytes(), want)
+	}
+
+	loaded, err := Load(bytes.NewReader(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, trace) {
+		t.Errorf("loading hostile.json gives\n%v\nwant\n%v", loaded, trace)
+	}
+}
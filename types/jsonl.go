@@ -0,0 +1,111 @@
+package types
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TraceReader reads a trace incrementally from newline-delimited JSON, one
+// event object per line using the same fields as the "events" entries of the
+// JSON schema. Blank lines are skipped.
+type TraceReader struct {
+	// Name, if set, is recorded as the Source file of events that carry no
+	// provenance of their own.
+	Name string
+
+	r      *bufio.Reader
+	line   int
+	offset int64
+}
+
+// NewTraceReader returns a reader consuming events from r.
+func NewTraceReader(r io.Reader) *TraceReader {
+	return &TraceReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next event, or io.EOF once the input is exhausted.
+func (tr *TraceReader) Next() (Event, error) {
+	for {
+		raw, err := tr.r.ReadBytes('\n')
+		if len(raw) == 0 && err != nil {
+			return Event{}, err
+		}
+		tr.line++
+		start := tr.offset
+		tr.offset += int64(len(raw))
+
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			if errors.Is(err, io.EOF) {
+				return Event{}, io.EOF
+			}
+			continue
+		}
+
+		var je jsonEvent
+		if err := json.Unmarshal(raw, &je); err != nil {
+			return Event{}, fmt.Errorf("line %d: %w", tr.line, err)
+		}
+		e, err := fromJSONEvent(je)
+		if err != nil {
+			return Event{}, fmt.Errorf("line %d: %w", tr.line, err)
+		}
+		if e.Source == nil && tr.Name != "" {
+			e.Source = &Source{File: tr.Name, Line: tr.line, Offset: start}
+		}
+		return e, nil
+	}
+}
+
+// ReadAll reads the remaining events into a Trace.
+func (tr *TraceReader) ReadAll() (Trace, error) {
+	return tr.ReadQuery(Query{})
+}
+
+// ReadQuery reads the remaining events selected by q into a Trace.
+func (tr *TraceReader) ReadQuery(q Query) (Trace, error) {
+	var trace Trace
+	for {
+		e, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return trace, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if q.Match(e) {
+			trace = append(trace, e)
+		}
+	}
+}
+
+// TraceWriter writes a trace incrementally as newline-delimited JSON, in the
+// format TraceReader reads.
+type TraceWriter struct {
+	enc *json.Encoder
+}
+
+// NewTraceWriter returns a writer producing events on w.
+func NewTraceWriter(w io.Writer) *TraceWriter {
+	return &TraceWriter{enc: json.NewEncoder(w)}
+}
+
+// Write writes one event as a line.
+func (tw *TraceWriter) Write(e Event) error {
+	return tw.enc.Encode(toJSONEvent(e))
+}
+
+// SaveJSONL writes the trace as newline-delimited JSON events.
+func SaveJSONL(w io.Writer, trace Trace) error {
+	tw := NewTraceWriter(w)
+	for _, e := range trace {
+		if err := tw.Write(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package types
+
+import "maps"
+
+// LamportClock is a scalar logical clock. Every event advances it by one and
+// a receive first catches up with the clock the message was sent at, so if
+// a happens before b then a's clock is smaller than b's. Unlike vector
+// clocks the converse does not hold: concurrent events get ordered too.
+type LamportClock int
+
+// Tick advances the clock for a local event or a send and returns its new
+// value.
+func (c *LamportClock) Tick() LamportClock {
+	*c++
+	return *c
+}
+
+// Merge advances the clock for the receive of a message sent at m and
+// returns its new value.
+func (c *LamportClock) Merge(m LamportClock) LamportClock {
+	*c = max(*c, m) + 1
+	return *c
+}
+
+// LamportClocks converts the vector clocks of a trace into the Lamport
+// clocks its processes would have kept, indexed like the trace: each event
+// is one later than the latest of the events its vector clock says it knows
+// of. The events of a rendezvous, which share a vector clock, share a
+// Lamport clock too.
+func LamportClocks(trace Trace) []LamportClock {
+	type slot struct {
+		process string
+		counter int
+	}
+	at := make(map[slot]int, len(trace))
+	for i, e := range trace {
+		at[slot{e.Process, e.VClock[e.Process]}] = i
+	}
+
+	out := make([]LamportClock, len(trace))
+	for i, e := range trace {
+		var latest LamportClock
+		for q, c := range e.VClock {
+			j, ok := at[slot{q, c}]
+			if ok && (j == i || maps.Equal(trace[j].VClock, e.VClock)) {
+				// The event itself, or a partner in its rendezvous
+				j, ok = at[slot{q, c - 1}]
+			}
+			if ok && j < i {
+				latest = max(latest, out[j])
+			}
+		}
+		out[i] = latest + 1
+	}
+	return out
+}
+
+// AssignLamport sets the Lamport field of every event from its vector clock.
+func AssignLamport(trace Trace) {
+	for i, l := range LamportClocks(trace) {
+		trace[i].Lamport = l
+	}
+}
@@ -0,0 +1,26 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// QuoteName returns a process name as it appears in labels. Plain names
+// (letters, digits, '_', '-', '.', '/') are returned unchanged; anything else,
+// including spaces, separators, control characters and empty names, is
+// quoted with Go escaping so labels stay single-line and unambiguous.
+func QuoteName(name string) string {
+	if name != "" && strings.IndexFunc(name, needsQuoting) < 0 {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+func needsQuoting(r rune) bool {
+	switch r {
+	case '_', '-', '.', '/':
+		return false
+	}
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
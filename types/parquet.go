@@ -0,0 +1,722 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/traces/internal/snappy"
+	"github.com/traces/internal/thrift"
+)
+
+// Traces are stored in Parquet as one row per event with the columns type,
+// process, message_id, correlation_key, lock, role, phase, source_file,
+// source_line, source_offset, one vc_<process> column per process and one
+// label_<name> column per label, the same layout as DefaultCSVMapping.
+// SaveParquet writes a single uncompressed row group of PLAIN encoded
+// columns, required but for the labels: those are optional, null where an
+// event lacks the label, so an empty label survives. In required label
+// columns, as older files have, empty values stand for missing labels.
+// LoadParquet also reads dictionary encoding and Snappy compression, which
+// covers the defaults of pyarrow and pandas. Nested schemas and data page v2
+// are not supported.
+
+const parquetMagic = "PAR1"
+
+// Parquet physical types, encodings and page types used here.
+const (
+	pqInt32     = 1
+	pqInt64     = 2
+	pqByteArray = 6
+
+	pqPlain          = 0
+	pqPlainDict      = 2
+	pqRLE            = 3
+	pqRLEDict        = 8
+	pqDataPage       = 0
+	pqDictionaryPage = 2
+
+	pqUncompressed = 0
+	pqSnappy       = 1
+)
+
+const parquetClockPrefix = "vc_"
+
+// parquetLabelPrefix starts the name of the column holding each label.
+const parquetLabelPrefix = "label_"
+
+// pqColumn holds the values of one column; Null marks missing values of
+// optional columns.
+type pqColumn struct {
+	Name string
+	Type int32
+	Ints []int64
+	Bins [][]byte
+	Null []bool
+}
+
+func (c *pqColumn) len() int { return max(len(c.Ints), len(c.Bins)) }
+
+func (c *pqColumn) str(i int) string {
+	if i < len(c.Bins) && !c.null(i) {
+		return string(c.Bins[i])
+	}
+	return ""
+}
+
+func (c *pqColumn) int(i int) (int64, bool) {
+	if i < len(c.Ints) && !c.null(i) {
+		return c.Ints[i], true
+	}
+	return 0, false
+}
+
+func (c *pqColumn) null(i int) bool { return i < len(c.Null) && c.Null[i] }
+
+// label returns the value of a label column in row i, and whether the row
+// has the label at all: not null in an optional column, not empty in a
+// required one.
+func (c *pqColumn) label(i int) (string, bool) {
+	v := c.str(i)
+	if c.Null == nil {
+		return v, v != ""
+	}
+	return v, !c.null(i)
+}
+
+// MarshalParquet encodes the trace as a Parquet file.
+func MarshalParquet(trace Trace) []byte {
+	procSet := make(map[string]bool)
+	labelSet := make(map[string]bool)
+	for _, e := range trace {
+		procSet[e.Process] = true
+		for k := range e.Labels {
+			labelSet[k] = true
+		}
+		for p := range e.VClock {
+			procSet[p] = true
+		}
+	}
+	procs := make([]string, 0, len(procSet))
+	for p := range procSet {
+		procs = append(procs, p)
+	}
+	slices.Sort(procs)
+
+	str := func(name string, f func(Event) string) *pqColumn {
+		c := &pqColumn{Name: name, Type: pqByteArray}
+		for _, e := range trace {
+			c.Bins = append(c.Bins, []byte(f(e)))
+		}
+		return c
+	}
+	num := func(name string, f func(Event) int64) *pqColumn {
+		c := &pqColumn{Name: name, Type: pqInt64, Ints: make([]int64, 0, len(trace))}
+		for _, e := range trace {
+			c.Ints = append(c.Ints, f(e))
+		}
+		return c
+	}
+	columns := []*pqColumn{
+		str("type", func(e Event) string { return e.Type.String() }),
+		str("process", func(e Event) string { return e.Process }),
+		num("message_id", func(e Event) int64 { return int64(e.MessageID) }),
+		str("correlation_key", func(e Event) string { return e.CorrelationKey }),
+		str("lock", func(e Event) string { return e.Lock }),
+		str("role", func(e Event) string { return e.Role }),
+		str("source_file", func(e Event) string {
+			if e.Source == nil {
+				return ""
+			}
+			return e.Source.File
+		}),
+		num("phase", func(e Event) int64 { return int64(e.Phase) }),
+		num("source_line", func(e Event) int64 {
+			if e.Source == nil {
+				return 0
+			}
+			return int64(e.Source.Line)
+		}),
+		num("source_offset", func(e Event) int64 {
+			if e.Source == nil {
+				return 0
+			}
+			return e.Source.Offset
+		}),
+	}
+	if slices.ContainsFunc(trace, func(e Event) bool { return !e.Timestamp.IsZero() }) {
+		columns = append(columns, num("timestamp", func(e Event) int64 {
+			if e.Timestamp.IsZero() {
+				return 0
+			}
+			return e.Timestamp.UnixNano()
+		}))
+	}
+	if slices.ContainsFunc(trace, func(e Event) bool { return !e.HLC.IsZero() }) {
+		columns = append(columns,
+			num("hlc_wall", func(e Event) int64 { return e.HLC.Wall }),
+			num("hlc_logical", func(e Event) int64 { return int64(e.HLC.Logical) }))
+	}
+	for _, p := range procs {
+		columns = append(columns, num(parquetClockPrefix+p, func(e Event) int64 { return int64(e.VClock[p]) }))
+	}
+	for _, k := range slices.Sorted(maps.Keys(labelSet)) {
+		c := &pqColumn{Name: parquetLabelPrefix + k, Type: pqByteArray}
+		for _, e := range trace {
+			v, ok := e.Labels[k]
+			c.Bins = append(c.Bins, []byte(v))
+			c.Null = append(c.Null, !ok)
+		}
+		columns = append(columns, c)
+	}
+
+	out := []byte(parquetMagic)
+	root := &thrift.Writer{}
+	root.String(4, "schema")
+	root.I32(5, int32(len(columns)))
+	schema := []*thrift.Writer{root}
+	var chunks []*thrift.Writer
+	var rowGroupSize int64
+
+	for _, c := range columns {
+		var data []byte
+		if c.Null != nil {
+			// Definition levels as a single bit-packed run: 1 for a value,
+			// 0 for a null
+			levels := binary.AppendUvarint(nil, uint64((len(c.Null)+7)/8)<<1|1)
+			for i, null := range c.Null {
+				if i%8 == 0 {
+					levels = append(levels, 0)
+				}
+				if !null {
+					levels[len(levels)-1] |= 1 << (i % 8)
+				}
+			}
+			data = binary.LittleEndian.AppendUint32(data, uint32(len(levels)))
+			data = append(data, levels...)
+		}
+		for _, v := range c.Ints {
+			data = binary.LittleEndian.AppendUint64(data, uint64(v))
+		}
+		for i, v := range c.Bins {
+			if c.null(i) {
+				continue
+			}
+			data = binary.LittleEndian.AppendUint32(data, uint32(len(v)))
+			data = append(data, v...)
+		}
+
+		dph := &thrift.Writer{}
+		dph.I32(1, int32(len(trace)))
+		dph.I32(2, pqPlain)
+		dph.I32(3, pqRLE)
+		dph.I32(4, pqRLE)
+		ph := &thrift.Writer{}
+		ph.I32(1, pqDataPage)
+		ph.I32(2, int32(len(data)))
+		ph.I32(3, int32(len(data)))
+		ph.Struct(5, dph)
+
+		offset := int64(len(out))
+		out = append(out, ph.Bytes()...)
+		out = append(out, data...)
+		size := int64(len(out)) - offset
+		rowGroupSize += size
+
+		meta := &thrift.Writer{}
+		meta.I32(1, c.Type)
+		meta.I32List(2, []int32{pqPlain, pqRLE})
+		meta.StringList(3, []string{c.Name})
+		meta.I32(4, pqUncompressed)
+		meta.I64(5, int64(len(trace)))
+		meta.I64(6, size)
+		meta.I64(7, size)
+		meta.I64(9, offset)
+		chunk := &thrift.Writer{}
+		chunk.I64(2, offset)
+		chunk.Struct(3, meta)
+		chunks = append(chunks, chunk)
+
+		el := &thrift.Writer{}
+		el.I32(1, c.Type)
+		if c.Null != nil {
+			el.I32(3, 1) // OPTIONAL
+		} else {
+			el.I32(3, 0) // REQUIRED
+		}
+		el.String(4, c.Name)
+		if c.Type == pqByteArray {
+			el.I32(6, 0) // UTF8
+		}
+		schema = append(schema, el)
+	}
+
+	rg := &thrift.Writer{}
+	rg.StructList(1, chunks)
+	rg.I64(2, rowGroupSize)
+	rg.I64(3, int64(len(trace)))
+	fm := &thrift.Writer{}
+	fm.I32(1, 1)
+	fm.StructList(2, schema)
+	fm.I64(3, int64(len(trace)))
+	fm.StructList(4, []*thrift.Writer{rg})
+	fm.String(6, "github.com/traces")
+
+	footer := fm.Bytes()
+	out = append(out, footer...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(footer)))
+	return append(out, parquetMagic...)
+}
+
+// SaveParquet writes the trace as a Parquet file.
+func SaveParquet(w io.Writer, trace Trace) error {
+	_, err := w.Write(MarshalParquet(trace))
+	return err
+}
+
+// LoadParquet reads a Parquet file with the columns written by SaveParquet.
+// Only type, process and message_id are required; clocks missing from the
+// file leave the events' clocks empty.
+func LoadParquet(r io.Reader) (Trace, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalParquet(data)
+}
+
+// UnmarshalParquet decodes a Parquet file; see LoadParquet.
+func UnmarshalParquet(data []byte) (Trace, error) {
+	return QueryParquet(data, Query{})
+}
+
+// QueryParquetFile reads the events selected by q from a Parquet file,
+// which may be compressed or split like the files QueryFile reads. Every
+// piece of a split file is a Parquet file of its own.
+func QueryParquetFile(path string, q Query) (Trace, error) {
+	chunks, err := Chunks(path)
+	if err != nil {
+		return nil, err
+	}
+	var trace Trace
+	for _, c := range chunks {
+		f, err := Open(c)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err == nil {
+			var part Trace
+			if part, err = QueryParquet(data, q); err == nil {
+				trace = append(trace, part...)
+				continue
+			}
+		}
+		if len(chunks) > 1 {
+			return nil, fmt.Errorf("%s: %w", c, err)
+		}
+		return nil, err
+	}
+	return trace, nil
+}
+
+// QueryParquet decodes the events of a Parquet file selected by q, testing
+// each row against the columns before building its event.
+func QueryParquet(data []byte, q Query) (Trace, error) {
+	columns, rows, err := readParquet(data)
+	if err != nil {
+		return nil, fmt.Errorf("parquet: %w", err)
+	}
+	byName := make(map[string]*pqColumn, len(columns))
+	for _, c := range columns {
+		byName[c.Name] = c
+	}
+	for _, name := range []string{"type", "process", "message_id"} {
+		if byName[name] == nil {
+			return nil, fmt.Errorf("parquet: no column %q", name)
+		}
+	}
+	empty := &pqColumn{}
+	col := func(name string) *pqColumn {
+		if c := byName[name]; c != nil {
+			return c
+		}
+		return empty
+	}
+
+	var clocks []*pqColumn
+	for _, c := range columns {
+		if p, ok := strings.CutPrefix(c.Name, parquetClockPrefix); ok && p != "" {
+			clocks = append(clocks, c)
+		}
+	}
+	match := func(i int) bool {
+		if len(q.Processes) > 0 && !slices.Contains(q.Processes, col("process").str(i)) {
+			return false
+		}
+		if len(q.Types) > 0 {
+			// A row of unknown type is kept, to be reported below
+			if typ, err := ParseEventType(col("type").str(i)); err == nil && !slices.Contains(q.Types, typ) {
+				return false
+			}
+		}
+		for k, v := range q.Labels {
+			if got, ok := col(parquetLabelPrefix + k).label(i); !ok || got != v {
+				return false
+			}
+		}
+		for p, v := range q.Min {
+			if n, _ := col(parquetClockPrefix + p).int(i); int(n) < v {
+				return false
+			}
+		}
+		if q.Max != nil {
+			for _, c := range clocks {
+				if n, _ := c.int(i); int(n) > q.Max[strings.TrimPrefix(c.Name, parquetClockPrefix)] {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	var trace Trace
+	if q.IsZero() {
+		trace = make(Trace, 0, rows)
+	}
+	for i := range rows {
+		if !match(i) {
+			continue
+		}
+		e := Event{
+			Process:        col("process").str(i),
+			CorrelationKey: col("correlation_key").str(i),
+			Lock:           col("lock").str(i),
+			Role:           col("role").str(i),
+			VClock:         make(VectorClock),
+		}
+		if e.Type, err = ParseEventType(col("type").str(i)); err != nil {
+			return nil, fmt.Errorf("parquet: row %d: %w", i, err)
+		}
+		id, ok := col("message_id").int(i)
+		if !ok {
+			return nil, fmt.Errorf("parquet: row %d: missing message_id", i)
+		}
+		e.MessageID = int(id)
+		phase, _ := col("phase").int(i)
+		e.Phase = int(phase)
+		if ns, _ := col("timestamp").int(i); ns != 0 {
+			e.Timestamp = time.Unix(0, ns).UTC()
+		}
+		wall, _ := col("hlc_wall").int(i)
+		logical, _ := col("hlc_logical").int(i)
+		e.HLC = HLC{Wall: wall, Logical: int(logical)}
+		if file := col("source_file").str(i); file != "" {
+			line, _ := col("source_line").int(i)
+			offset, _ := col("source_offset").int(i)
+			e.Source = &Source{File: file, Line: int(line), Offset: offset}
+		}
+		for _, c := range columns {
+			if p, ok := strings.CutPrefix(c.Name, parquetClockPrefix); ok && p != "" {
+				if v, ok := c.int(i); ok {
+					e.VClock[p] = int(v)
+				}
+			}
+			if k, ok := strings.CutPrefix(c.Name, parquetLabelPrefix); ok && k != "" {
+				if v, ok := c.label(i); ok {
+					if e.Labels == nil {
+						e.Labels = make(map[string]string)
+					}
+					e.Labels[k] = v
+				}
+			}
+		}
+		trace = append(trace, e)
+	}
+	return trace, nil
+}
+
+// readParquet decodes every column of every row group.
+func readParquet(data []byte) ([]*pqColumn, int, error) {
+	n := len(data)
+	if n < 12 || string(data[:4]) != parquetMagic || string(data[n-4:]) != parquetMagic {
+		return nil, 0, errors.New("not a Parquet file")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[n-8:]))
+	if footerLen > n-12 {
+		return nil, 0, errors.New("footer length out of range")
+	}
+	fm, _, err := thrift.Decode(data[n-8-footerLen : n-8])
+	if err != nil {
+		return nil, 0, fmt.Errorf("footer: %w", err)
+	}
+
+	schema := fm.Fields[2].List
+	if len(schema) == 0 {
+		return nil, 0, errors.New("empty schema")
+	}
+	var columns []*pqColumn
+	var optional []bool
+	for _, el := range schema[1:] {
+		if children, _ := el.Field(5); children > 0 {
+			return nil, 0, errors.New("nested schemas are not supported")
+		}
+		typ, _ := el.Field(1)
+		rep, _ := el.Field(3)
+		if rep == 2 {
+			return nil, 0, fmt.Errorf("repeated column %q is not supported", el.Fields[4].Bin)
+		}
+		columns = append(columns, &pqColumn{Name: string(el.Fields[4].Bin), Type: int32(typ)})
+		optional = append(optional, rep == 1)
+	}
+
+	rows := 0
+	for g, rg := range fm.Fields[4].List {
+		chunks := rg.Fields[1].List
+		if len(chunks) != len(columns) {
+			return nil, 0, fmt.Errorf("row group %d has %d columns, schema has %d", g, len(chunks), len(columns))
+		}
+		numRows, _ := rg.Field(3)
+		for i, chunk := range chunks {
+			if err := readChunk(data, chunk.Fields[3], columns[i], optional[i]); err != nil {
+				return nil, 0, fmt.Errorf("row group %d, column %q: %w", g, columns[i].Name, err)
+			}
+			if columns[i].len() != rows+int(numRows) {
+				return nil, 0, fmt.Errorf("row group %d, column %q: %d values for %d rows",
+					g, columns[i].Name, columns[i].len()-rows, numRows)
+			}
+		}
+		rows += int(numRows)
+	}
+	return columns, rows, nil
+}
+
+// readChunk appends the values of one column chunk to c.
+func readChunk(data []byte, meta thrift.Value, c *pqColumn, optional bool) error {
+	codec, _ := meta.Field(4)
+	if codec != pqUncompressed && codec != pqSnappy {
+		return fmt.Errorf("unsupported compression codec %d", codec)
+	}
+	if c.Type != pqInt32 && c.Type != pqInt64 && c.Type != pqByteArray {
+		return fmt.Errorf("unsupported physical type %d", c.Type)
+	}
+	total, _ := meta.Field(5)
+	pos, _ := meta.Field(9)
+	if dict, ok := meta.Field(11); ok && dict > 0 && dict < pos {
+		pos = dict
+	}
+
+	var dictionary pqColumn
+	for read := int64(0); read < total; {
+		if pos < 0 || pos >= int64(len(data)) {
+			return errors.New("page offset out of range")
+		}
+		ph, hn, err := thrift.Decode(data[pos:])
+		if err != nil {
+			return fmt.Errorf("page header: %w", err)
+		}
+		size, _ := ph.Field(3)
+		start := pos + int64(hn)
+		if size < 0 || start+size > int64(len(data)) {
+			return errors.New("page extends past end of file")
+		}
+		page := data[start : start+size]
+		pos = start + size
+		if codec == pqSnappy {
+			if page, err = snappy.Decode(page); err != nil {
+				return err
+			}
+		}
+
+		switch typ, _ := ph.Field(1); typ {
+		case pqDictionaryPage:
+			count, _ := ph.Fields[7].Field(1)
+			dictionary = pqColumn{Type: c.Type}
+			if _, err := decodePlain(page, &dictionary, int(count)); err != nil {
+				return fmt.Errorf("dictionary page: %w", err)
+			}
+		case pqDataPage:
+			dph := ph.Fields[5]
+			count, _ := dph.Field(1)
+			encoding, _ := dph.Field(2)
+			if count < 0 || count > total-read {
+				return fmt.Errorf("data page of %d values with %d left in the chunk", count, total-read)
+			}
+			if err := decodeDataPage(page, c, &dictionary, int(count), encoding, optional); err != nil {
+				return err
+			}
+			read += count
+		default:
+			return fmt.Errorf("unsupported page type %d", typ)
+		}
+	}
+	return nil
+}
+
+func decodeDataPage(page []byte, c, dictionary *pqColumn, count int, encoding int64, optional bool) error {
+	present := count
+	var defined []int
+	if optional {
+		if len(page) < 4 {
+			return errors.New("truncated definition levels")
+		}
+		n := int(binary.LittleEndian.Uint32(page))
+		if n > len(page)-4 {
+			return errors.New("truncated definition levels")
+		}
+		var err error
+		if defined, err = decodeHybrid(page[4:4+n], 1, count); err != nil {
+			return fmt.Errorf("definition levels: %w", err)
+		}
+		page = page[4+n:]
+		present = 0
+		for _, d := range defined {
+			present += d
+		}
+	}
+
+	values := pqColumn{Type: c.Type}
+	switch encoding {
+	case pqPlain:
+		if _, err := decodePlain(page, &values, present); err != nil {
+			return err
+		}
+	case pqPlainDict, pqRLEDict:
+		if len(page) < 1 {
+			return errors.New("truncated dictionary indices")
+		}
+		indices, err := decodeHybrid(page[1:], int(page[0]), present)
+		if err != nil {
+			return fmt.Errorf("dictionary indices: %w", err)
+		}
+		for _, i := range indices {
+			if i >= dictionary.len() {
+				return fmt.Errorf("dictionary index %d out of range", i)
+			}
+			if c.Type == pqByteArray {
+				values.Bins = append(values.Bins, dictionary.Bins[i])
+			} else {
+				values.Ints = append(values.Ints, dictionary.Ints[i])
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported encoding %d", encoding)
+	}
+
+	// Spread the present values over the rows, padding nulls
+	next := 0
+	for i := range count {
+		if defined != nil && defined[i] == 0 {
+			c.Null = append(c.Null, true)
+			if c.Type == pqByteArray {
+				c.Bins = append(c.Bins, nil)
+			} else {
+				c.Ints = append(c.Ints, 0)
+			}
+			continue
+		}
+		c.Null = append(c.Null, false)
+		if c.Type == pqByteArray {
+			c.Bins = append(c.Bins, values.Bins[next])
+		} else {
+			c.Ints = append(c.Ints, values.Ints[next])
+		}
+		next++
+	}
+	return nil
+}
+
+// decodePlain appends count PLAIN encoded values to c and returns the number
+// of bytes they occupied.
+func decodePlain(data []byte, c *pqColumn, count int) (int, error) {
+	r := bytes.NewReader(data)
+	for range count {
+		switch c.Type {
+		case pqInt32:
+			var v int32
+			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+				return 0, errors.New("truncated INT32 values")
+			}
+			c.Ints = append(c.Ints, int64(v))
+		case pqInt64:
+			var v int64
+			if err := binary.Read(r, binary.LittleEndian, &v); err != nil {
+				return 0, errors.New("truncated INT64 values")
+			}
+			c.Ints = append(c.Ints, v)
+		case pqByteArray:
+			var n uint32
+			if err := binary.Read(r, binary.LittleEndian, &n); err != nil || int64(n) > int64(r.Len()) {
+				return 0, errors.New("truncated BYTE_ARRAY values")
+			}
+			v := make([]byte, n)
+			r.Read(v)
+			c.Bins = append(c.Bins, v)
+		}
+	}
+	return len(data) - r.Len(), nil
+}
+
+// decodeHybrid decodes count values of the RLE/bit-packing hybrid encoding
+// used for definition levels and dictionary indices.
+func decodeHybrid(data []byte, bitWidth, count int) ([]int, error) {
+	if bitWidth < 0 || bitWidth > 32 {
+		return nil, fmt.Errorf("bit width %d out of range", bitWidth)
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("negative value count %d", count)
+	}
+	byteWidth := (bitWidth + 7) / 8
+	// Runs may repeat a value any number of times, so the count only bounds
+	// the values decoded, not what is allocated for them up front
+	out := make([]int, 0, min(count, len(data)))
+	for len(out) < count {
+		header, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.New("truncated run header")
+		}
+		data = data[n:]
+
+		if header&1 == 0 { // RLE run
+			run := int(min(header>>1, uint64(count-len(out))))
+			if len(data) < byteWidth {
+				return nil, errors.New("truncated RLE run")
+			}
+			v := 0
+			for i := byteWidth - 1; i >= 0; i-- {
+				v = v<<8 | int(data[i])
+			}
+			data = data[byteWidth:]
+			for range run {
+				out = append(out, v)
+			}
+			continue
+		}
+
+		// Bit-packed groups of eight values, least significant bit first;
+		// each group takes bitWidth bytes
+		groups := header >> 1
+		if bitWidth > 0 && groups > uint64(len(data)/bitWidth) {
+			return nil, errors.New("truncated bit-packed run")
+		}
+		groups = min(groups, uint64(count-len(out)+7)/8)
+		size := int(groups) * bitWidth
+		for i := 0; i < int(groups)*8 && len(out) < count; i++ {
+			v := 0
+			for b := range bitWidth {
+				bit := i*bitWidth + b
+				v |= int(data[bit/8]>>(bit%8)&1) << b
+			}
+			out = append(out, v)
+		}
+		data = data[size:]
+	}
+	return out, nil
+}
@@ -0,0 +1,85 @@
+package types
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestQueryParquetLabels(tt *testing.T) {
+	trace := Trace{
+		{Type: EventInternal, Process: "A", VClock: VectorClock{"A": 1}, Labels: map[string]string{"k": ""}},
+		{Type: EventInternal, Process: "A", VClock: VectorClock{"A": 2}, Labels: map[string]string{"k": "v"}},
+		{Type: EventInternal, Process: "A", VClock: VectorClock{"A": 3}},
+	}
+	data := MarshalParquet(trace)
+	for _, labels := range []map[string]string{{"k": ""}, {"k": "v"}, {"k": "w"}, {"j": ""}} {
+		q := Query{Labels: labels}
+		var want []int
+		for _, e := range trace {
+			if q.Match(e) {
+				want = append(want, e.VClock["A"])
+			}
+		}
+		got, err := QueryParquet(data, q)
+		if err != nil {
+			tt.Fatal(err)
+		}
+		var have []int
+		for _, e := range got {
+			have = append(have, e.VClock["A"])
+		}
+		if !slices.Equal(have, want) {
+			tt.Errorf("label %v: Parquet selects events %v, Match %v", labels, have, want)
+		}
+	}
+
+	back, err := UnmarshalParquet(data)
+	if err != nil {
+		tt.Fatal(err)
+	}
+	for i := range trace {
+		if v, ok := back[i].Labels["k"]; v != trace[i].Labels["k"] || ok != (trace[i].Labels != nil) {
+			tt.Errorf("e-%d: label k read back as %q, %v", i, v, ok)
+		}
+	}
+}
+
+func TestDecodeHybridHostile(tt *testing.T) {
+	if _, err := decodeHybrid([]byte{2, 1}, 1, -1); err == nil {
+		tt.Error("negative count accepted")
+	}
+	// A bit-packed run declaring 2^62 groups, whose size overflows
+	huge := binary.AppendUvarint(nil, 1<<63|1)
+	if _, err := decodeHybrid(append(huge, 0xff), 8, 4); err == nil {
+		tt.Error("bit-packed run longer than its input accepted")
+	}
+	// A long RLE run is cut at count
+	got, err := decodeHybrid(append(binary.AppendUvarint(nil, 1<<40), 7), 3, 3)
+	if err != nil || !slices.Equal(got, []int{7, 7, 7}) {
+		tt.Errorf("RLE run decoded as %v, %v", got, err)
+	}
+}
+
+func TestQueryParquetFileSplit(tt *testing.T) {
+	dir := tt.TempDir()
+	a := Trace{{Type: EventInternal, Process: "A", VClock: VectorClock{"A": 1}}}
+	b := Trace{
+		{Type: EventInternal, Process: "A", VClock: VectorClock{"A": 2}},
+		{Type: EventInternal, Process: "B", VClock: VectorClock{"B": 1}},
+	}
+	for name, piece := range map[string]Trace{"t.0001.parquet": a, "t.0002.parquet": b} {
+		if err := os.WriteFile(filepath.Join(dir, name), MarshalParquet(piece), 0o644); err != nil {
+			tt.Fatal(err)
+		}
+	}
+	got, err := QueryParquetFile(filepath.Join(dir, "t.parquet"), Query{Processes: []string{"A"}})
+	if err != nil {
+		tt.Fatal(err)
+	}
+	if len(got) != 2 || got[0].VClock["A"] != 1 || got[1].VClock["A"] != 2 {
+		tt.Errorf("read %v from the pieces, want A's two events in order", got)
+	}
+}
@@ -0,0 +1,97 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// ProcessDict numbers process names, so that a stored trace can name each
+// process once and refer to it by a small index from every event and clock.
+type ProcessDict struct {
+	names []string
+	index map[string]int
+}
+
+// NewProcessDict returns a dictionary numbering names in the order given.
+func NewProcessDict(names ...string) *ProcessDict {
+	d := &ProcessDict{index: make(map[string]int)}
+	for _, name := range names {
+		d.Index(name)
+	}
+	return d
+}
+
+// Index returns the number of a process, adding it if it is new.
+func (d *ProcessDict) Index(name string) int {
+	i, ok := d.index[name]
+	if !ok {
+		i = len(d.names)
+		d.names = append(d.names, name)
+		d.index[name] = i
+	}
+	return i
+}
+
+// Name returns the process numbered i.
+func (d *ProcessDict) Name(i int) (string, error) {
+	if i < 0 || i >= len(d.names) {
+		return "", fmt.Errorf("process index %d out of range [0, %d)", i, len(d.names))
+	}
+	return d.names[i], nil
+}
+
+// Names returns the processes in the order of their numbers.
+func (d *ProcessDict) Names() []string { return slices.Clone(d.names) }
+
+// NewVectorClock returns a clock with 0 for every process numbered so far.
+// A dictionary that is added to as processes appear is the registry of a
+// trace whose membership is not known up front.
+func (d *ProcessDict) NewVectorClock() VectorClock { return NewVectorClock(d.names) }
+
+// AppendClock appends the compact encoding of a vector clock to b: for each
+// entry in dictionary order, the gap from the previous entry's index as a
+// varint, followed by the counter as a varint. Processes new to the
+// dictionary are added in name order.
+func (d *ProcessDict) AppendClock(b []byte, vc VectorClock) []byte {
+	indices := make([]int, 0, len(vc))
+	for _, p := range vc.Processes() {
+		indices = append(indices, d.Index(p))
+	}
+	slices.Sort(indices)
+	prev := -1
+	for _, i := range indices {
+		b = binary.AppendUvarint(b, uint64(i-prev-1))
+		b = binary.AppendUvarint(b, uint64(vc[d.names[i]]))
+		prev = i
+	}
+	return b
+}
+
+// DecodeClock decodes a clock written by AppendClock.
+func (d *ProcessDict) DecodeClock(data []byte) (VectorClock, error) {
+	vc := make(VectorClock)
+	prev := -1
+	for len(data) > 0 {
+		gap, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("truncated clock")
+		}
+		data = data[n:]
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("truncated clock")
+		}
+		data = data[n:]
+		if gap >= uint64(len(d.names)) {
+			return nil, fmt.Errorf("process index out of range [0, %d)", len(d.names))
+		}
+		prev += int(gap) + 1
+		name, err := d.Name(prev)
+		if err != nil {
+			return nil, err
+		}
+		vc[name] = int(v)
+	}
+	return vc, nil
+}
@@ -0,0 +1,245 @@
+package types
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/traces/internal/wire"
+)
+
+// ProtoVersion is the version written into the Trace message by MarshalProto.
+// Version 1 spelled out the process names in every event and clock; version
+// 2 lists them once and refers to them by index. Both are read.
+const ProtoVersion = 2
+
+func marshalEvent(e Event, d *ProcessDict) []byte {
+	var b wire.Buffer
+	b.Uvarint(1, uint64(e.Type))
+	b.Uvarint(15, uint64(d.Index(e.Process)))
+	b.Bytes(16, d.AppendClock(nil, e.VClock))
+
+	b.Uvarint(4, wire.Zigzag(int64(e.MessageID)))
+	b.String(5, e.CorrelationKey)
+	if e.Source != nil {
+		var src wire.Buffer
+		src.String(1, e.Source.File)
+		src.Uvarint(2, uint64(e.Source.Line))
+		src.Uvarint(3, uint64(e.Source.Offset))
+		b.Bytes(6, src)
+	}
+	b.String(7, e.Lock)
+	b.Uvarint(8, uint64(e.Phase))
+	b.String(9, e.Role)
+	for _, k := range slices.Sorted(maps.Keys(e.Labels)) {
+		var entry wire.Buffer
+		entry.String(1, k)
+		entry.String(2, e.Labels[k])
+		b.Bytes(10, entry)
+	}
+	if !e.Timestamp.IsZero() {
+		b.Uvarint(11, wire.Zigzag(e.Timestamp.UnixNano()))
+	}
+	b.Uvarint(12, uint64(e.Lamport))
+	b.Uvarint(13, wire.Zigzag(e.HLC.Wall))
+	b.Uvarint(14, uint64(e.HLC.Logical))
+	return b
+}
+
+// MarshalProto encodes a trace as a traces.v1.Trace protobuf message.
+func MarshalProto(trace Trace) []byte {
+	d := NewProcessDict()
+	for _, e := range trace {
+		d.Index(e.Process)
+	}
+	for _, e := range trace {
+		for _, p := range e.VClock.Processes() {
+			d.Index(p)
+		}
+	}
+
+	var b wire.Buffer
+	b.Uvarint(1, ProtoVersion)
+	for _, name := range d.Names() {
+		b.Bytes(3, []byte(name))
+	}
+	for _, e := range trace {
+		b.Bytes(2, marshalEvent(e, d))
+	}
+	return b
+}
+
+func unmarshalEvent(msg []byte, d *ProcessDict) (Event, error) {
+	fs, err := wire.Fields(msg)
+	if err != nil {
+		return Event{}, err
+	}
+	e := Event{VClock: make(VectorClock)}
+	for _, f := range fs {
+		switch f.Num {
+		case 1:
+			e.Type = EventType(f.Value)
+		case 2:
+			e.Process = string(f.Data)
+		case 15, 16:
+			// Resolved against the dictionary below, since index 0 is not written
+		case 3:
+			entry, err := wire.Fields(f.Data)
+			if err != nil {
+				return Event{}, fmt.Errorf("clock: %w", err)
+			}
+			var proc string
+			var v uint64
+			for _, ef := range entry {
+				switch ef.Num {
+				case 1:
+					proc = string(ef.Data)
+				case 2:
+					v = ef.Value
+				}
+			}
+			e.VClock[proc] = int(v)
+		case 4:
+			e.MessageID = int(wire.Unzigzag(f.Value))
+		case 5:
+			e.CorrelationKey = string(f.Data)
+		case 6:
+			src, err := wire.Fields(f.Data)
+			if err != nil {
+				return Event{}, fmt.Errorf("source: %w", err)
+			}
+			e.Source = &Source{}
+			for _, sf := range src {
+				switch sf.Num {
+				case 1:
+					e.Source.File = string(sf.Data)
+				case 2:
+					e.Source.Line = int(sf.Value)
+				case 3:
+					e.Source.Offset = int64(sf.Value)
+				}
+			}
+		case 7:
+			e.Lock = string(f.Data)
+		case 8:
+			e.Phase = int(f.Value)
+		case 9:
+			e.Role = string(f.Data)
+		case 10:
+			entry, err := wire.Fields(f.Data)
+			if err != nil {
+				return Event{}, fmt.Errorf("labels: %w", err)
+			}
+			var k, v string
+			for _, ef := range entry {
+				switch ef.Num {
+				case 1:
+					k = string(ef.Data)
+				case 2:
+					v = string(ef.Data)
+				}
+			}
+			if e.Labels == nil {
+				e.Labels = make(map[string]string)
+			}
+			e.Labels[k] = v
+		case 11:
+			e.Timestamp = time.Unix(0, wire.Unzigzag(f.Value)).UTC()
+		case 12:
+			e.Lamport = LamportClock(f.Value)
+		case 13:
+			e.HLC.Wall = wire.Unzigzag(f.Value)
+		case 14:
+			e.HLC.Logical = int(f.Value)
+		}
+	}
+	if d != nil {
+		var index uint64
+		for _, f := range fs {
+			switch f.Num {
+			case 15:
+				index = f.Value
+			case 16:
+				if e.VClock, err = d.DecodeClock(f.Data); err != nil {
+					return Event{}, fmt.Errorf("clock: %w", err)
+				}
+			}
+		}
+		if e.Process, err = d.Name(int(index)); err != nil {
+			return Event{}, err
+		}
+	}
+	return e, nil
+}
+
+// UnmarshalProto decodes a traces.v1.Trace protobuf message. Unknown fields
+// are ignored so newer writers stay readable.
+func UnmarshalProto(data []byte) (Trace, error) {
+	return QueryProto(data, Query{})
+}
+
+// QueryProto decodes the events of a traces.v1.Trace message selected by q,
+// dropping the others as soon as they are decoded.
+func QueryProto(data []byte, q Query) (Trace, error) {
+	fs, err := wire.Fields(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding trace: %w", err)
+	}
+	var version uint64
+	var d *ProcessDict
+	for _, f := range fs {
+		switch f.Num {
+		case 1:
+			version = f.Value
+		case 3:
+			if d == nil {
+				d = NewProcessDict()
+			}
+			d.Index(string(f.Data))
+		}
+	}
+	switch version {
+	case 1:
+		d = nil
+	case ProtoVersion:
+		if d == nil {
+			d = NewProcessDict()
+		}
+	default:
+		return nil, fmt.Errorf("unsupported trace version %d", version)
+	}
+
+	var trace Trace
+	n := 0
+	for _, f := range fs {
+		switch f.Num {
+		case 2:
+			e, err := unmarshalEvent(f.Data, d)
+			if err != nil {
+				return nil, fmt.Errorf("event %d: %w", n, err)
+			}
+			n++
+			if q.Match(e) {
+				trace = append(trace, e)
+			}
+		}
+	}
+	return trace, nil
+}
+
+// SaveProto writes the trace in the binary protobuf format.
+func SaveProto(w io.Writer, trace Trace) error {
+	_, err := w.Write(MarshalProto(trace))
+	return err
+}
+
+// LoadProto reads a trace written by SaveProto.
+func LoadProto(r io.Reader) (Trace, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalProto(data)
+}
@@ -0,0 +1,147 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Query selects the events of a stored trace while it is decoded, so a large
+// file never has to be held in memory whole. An event must match every
+// field that is set; the zero Query selects everything.
+type Query struct {
+	Processes []string          // events of any of these processes
+	Types     []EventType       // events of any of these types
+	Labels    map[string]string // events carrying all of these labels
+	// Min and Max bound the clocks of the events entry by entry, processes
+	// missing from a clock counting as 0: with an event's clock as Min, the
+	// event and what happens after it are selected; as Max, the event and
+	// what happens before it.
+	Min, Max VectorClock
+}
+
+// IsZero reports whether q selects every event.
+func (q Query) IsZero() bool {
+	return len(q.Processes) == 0 && len(q.Types) == 0 && len(q.Labels) == 0 && q.Min == nil && q.Max == nil
+}
+
+// Match reports whether q selects e.
+func (q Query) Match(e Event) bool {
+	if len(q.Processes) > 0 && !slices.Contains(q.Processes, e.Process) {
+		return false
+	}
+	if len(q.Types) > 0 && !slices.Contains(q.Types, e.Type) {
+		return false
+	}
+	for k, v := range q.Labels {
+		if got, ok := e.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	for p, v := range q.Min {
+		if e.VClock[p] < v {
+			return false
+		}
+	}
+	if q.Max != nil {
+		for p, v := range e.VClock {
+			if v > q.Max[p] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Filter returns the events of trace selected by q.
+func (q Query) Filter(trace Trace) Trace {
+	if q.IsZero() {
+		return trace
+	}
+	var out Trace
+	for _, e := range trace {
+		if q.Match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// ParseClock parses a clock bound written as "A:3,B:1", or as printed by
+// VectorClock.String.
+func ParseClock(s string) (VectorClock, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "<"), ">")
+	vc := make(VectorClock)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		p, v, ok := strings.Cut(entry, ":")
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if !ok || p == "" || err != nil || n < 0 {
+			return nil, fmt.Errorf("clock entry %q: expected process:count", entry)
+		}
+		vc[strings.TrimSpace(p)] = n
+	}
+	return vc, nil
+}
+
+// ParseQuery builds a query from textual parameters, as given on the command
+// line or in a URL: "process" and "type" list comma separated processes and
+// event types, "label" comma separated name=value pairs, and "min" and "max"
+// clock bounds as read by ParseClock. Unknown parameters are errors.
+func ParseQuery(params map[string]string) (Query, error) {
+	var q Query
+	for name, value := range params {
+		if value == "" {
+			continue
+		}
+		switch name {
+		case "process":
+			q.Processes = splitList(value)
+		case "type":
+			for _, s := range splitList(value) {
+				typ, err := ParseEventType(strings.ToUpper(s))
+				if err != nil {
+					return Query{}, err
+				}
+				q.Types = append(q.Types, typ)
+			}
+		case "label":
+			q.Labels = make(map[string]string)
+			for _, s := range splitList(value) {
+				k, v, ok := strings.Cut(s, "=")
+				if !ok || k == "" {
+					return Query{}, fmt.Errorf("label %q: expected name=value", s)
+				}
+				q.Labels[k] = v
+			}
+		case "min", "max":
+			vc, err := ParseClock(value)
+			if err != nil {
+				return Query{}, fmt.Errorf("%s: %w", name, err)
+			}
+			if name == "min" {
+				q.Min = vc
+			} else {
+				q.Max = vc
+			}
+		default:
+			return Query{}, fmt.Errorf("unknown query parameter %q", name)
+		}
+	}
+	return q, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
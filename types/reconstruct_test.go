@@ -0,0 +1,40 @@
+package types_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/traces/messages"
+	t "github.com/traces/types"
+)
+
+// ownClocks maps each event to its clock by process and own entry, which
+// ReconstructClocks keeps while it may reorder the trace.
+func ownClocks(trace t.Trace) map[string]string {
+	out := make(map[string]string)
+	for _, e := range trace {
+		out[e.Process+"#"+t.VectorClock{e.Process: e.VClock[e.Process]}.Key()] = e.VClock.Key()
+	}
+	return out
+}
+
+func TestReconstructClocksJoins(tt *testing.T) {
+	for name, cfg := range map[string]messages.Config{
+		"barriers":   {Processes: []string{"A", "B", "C"}, NumEvents: 40, BarrierEvery: 5},
+		"rendezvous": {Processes: []string{"A", "B", "C"}, NumEvents: 40, Synchronous: true, BroadcastRate: 0.3},
+		"both":       {Processes: []string{"A", "B", "C", "D"}, NumEvents: 60, Synchronous: true, BarrierEvery: 7},
+		"no joins":   {Processes: []string{"A", "B", "C"}, NumEvents: 40, BroadcastRate: 0.3},
+	} {
+		trace := messages.Generate(cfg, rand.New(rand.NewSource(1)))
+		got, err := t.ReconstructClocks(trace)
+		if err != nil {
+			tt.Fatalf("%s: %v", name, err)
+		}
+		want := ownClocks(trace)
+		for k, clock := range ownClocks(got) {
+			if want[k] != clock {
+				tt.Errorf("%s: event %s rebuilt with clock %s, recorded %s", name, k, clock, want[k])
+			}
+		}
+	}
+}
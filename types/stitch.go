@@ -0,0 +1,233 @@
+package types
+
+import "fmt"
+
+// messageKey identifies a message across traces. Message IDs are only
+// required to be unique per correlation key.
+type messageKey struct {
+	CorrelationKey string
+	MessageID      int
+}
+
+func keyOf(e Event) messageKey {
+	return messageKey{CorrelationKey: e.CorrelationKey, MessageID: e.MessageID}
+}
+
+// Stitch merges traces recorded by different sinks into a single trace. A
+// message sent in one trace and received in another (same MessageID and
+// CorrelationKey) becomes an inter-trace causal edge. The vector clocks of the
+// result are recomputed from scratch over the union of all processes, since
+// the clocks of separately recorded traces are not comparable.
+//
+// Every process must be recorded in exactly one of the traces, and every
+// message sent in exactly one of them: a message ID reused across traces
+// under the same CorrelationKey would stitch receives to the wrong send.
+// Only the order of each process' events is kept from the inputs, so the
+// processes of one trace may wait on messages from another independently.
+func Stitch(traces ...Trace) (Trace, error) {
+	owner := make(map[string]int)
+	sent := make(map[messageKey]bool)
+	sender := make(map[messageKey]int) // trace of each message's SEND
+
+	var all Trace
+	for i, trace := range traces {
+		all = append(all, trace...)
+		for _, e := range trace {
+			if j, ok := owner[e.Process]; !ok {
+				owner[e.Process] = i
+			} else if j != i {
+				return nil, fmt.Errorf("process %q appears in traces %d and %d", e.Process, j, i)
+			}
+			if e.Type == EventSend {
+				k := keyOf(e)
+				if j, ok := sender[k]; ok && j != i {
+					return nil, fmt.Errorf("Msg-%d of correlation key %q is sent in traces %d and %d", k.MessageID, k.CorrelationKey, j, i)
+				}
+				sender[k] = i
+				sent[k] = true
+			}
+		}
+	}
+
+	processes, sequences := splitProcesses(all)
+	return rebuild(sequences, processes, sent, owner)
+}
+
+// ReconstructClocks computes vector clocks from scratch using only the order
+// of each process' events in the trace, the matching of receives to sends by
+// MessageID (and CorrelationKey) and the joins of barriers and rendezvous.
+// Existing clocks serve only to tell a rendezvous, whose receives are
+// recorded with their send's clock. The result lists events in a linear
+// extension of happens-before, even if the input logged some receives before
+// their sends. Receives without a matching send are kept as local steps.
+func ReconstructClocks(trace Trace) (Trace, error) {
+	sent := make(map[messageKey]bool)
+	for _, e := range trace {
+		if e.Type == EventSend {
+			sent[keyOf(e)] = true
+		}
+	}
+	processes, sequences := splitProcesses(trace)
+	return rebuild(sequences, processes, sent, nil)
+}
+
+// splitProcesses returns the processes of a trace in order of appearance,
+// and the sequence of events of each.
+func splitProcesses(trace Trace) ([]string, []Trace) {
+	var processes []string
+	perProcess := make(map[string]Trace)
+	for _, e := range trace {
+		if _, ok := perProcess[e.Process]; !ok {
+			processes = append(processes, e.Process)
+		}
+		perProcess[e.Process] = append(perProcess[e.Process], e)
+	}
+	sequences := make([]Trace, len(processes))
+	for i, p := range processes {
+		sequences[i] = perProcess[p]
+	}
+	return processes, sequences
+}
+
+// rebuild interleaves the sequences of events of each process, which keep
+// their order, into a single trace where every receive follows its send,
+// assigning fresh vector clocks over the given processes. The participants
+// of a barrier phase, and the SEND and RECVs of a rendezvous (a receive
+// recorded with its send's clock), are placed together once all of them are
+// next on their processes: they leave a barrier knowing what all the others
+// did before it, and a rendezvous sharing one merged clock, as generated.
+// Barrier phases are numbered per recorded trace, given by owner for each
+// process when the sequences come from several.
+func rebuild(sequences []Trace, processes []string, sent map[messageKey]bool, owner map[string]int) (Trace, error) {
+	type slot struct{ seq, pos int }
+	total := 0
+	sends := make(map[messageKey]slot)
+	type phase struct{ trace, phase int }
+	barriers := make(map[phase][]slot)
+	for i, seq := range sequences {
+		total += len(seq)
+		for j, e := range seq {
+			switch e.Type {
+			case EventSend:
+				if _, ok := sends[keyOf(e)]; !ok {
+					sends[keyOf(e)] = slot{i, j}
+				}
+			case EventBarrier:
+				ph := phase{owner[e.Process], e.Phase}
+				barriers[ph] = append(barriers[ph], slot{i, j})
+			}
+		}
+	}
+	// Events placed together, each group with its SEND first; a process
+	// takes part in a group once at most, or nothing could place it
+	group := make(map[slot][]slot)
+	join := func(members []slot) {
+		seen := make(map[int]bool)
+		var g []slot
+		for _, m := range members {
+			if !seen[m.seq] {
+				seen[m.seq] = true
+				g = append(g, m)
+			}
+		}
+		if len(g) > 1 {
+			for _, m := range g {
+				group[m] = g
+			}
+		}
+	}
+	for _, members := range barriers {
+		join(members)
+	}
+	rendezvous := make(map[slot][]slot) // by SEND
+	for i, seq := range sequences {
+		for j, e := range seq {
+			s, ok := sends[keyOf(e)]
+			if ok && e.Type == EventReceive && len(e.VClock) > 0 &&
+				e.VClock.Key() == sequences[s.seq][s.pos].VClock.Key() {
+				if rendezvous[s] == nil {
+					rendezvous[s] = []slot{s}
+				}
+				rendezvous[s] = append(rendezvous[s], slot{i, j})
+			}
+		}
+	}
+	for _, members := range rendezvous {
+		join(members)
+	}
+
+	clocks := make(map[string]VectorClock, len(processes))
+	for _, p := range processes {
+		clocks[p] = NewVectorClock(processes)
+	}
+	// Clocks of SEND events already emitted, keyed by message
+	emitted := make(map[messageKey]VectorClock)
+	cursors := make([]int, len(sequences))
+	out := make(Trace, 0, total)
+	emit := func(e Event) {
+		if e.Type == EventSend {
+			emitted[keyOf(e)] = e.VClock
+		}
+		out = append(out, e)
+	}
+	// ready reports whether the event next on sequence i can be placed
+	ready := func(i int) bool {
+		here := slot{i, cursors[i]}
+		for _, m := range group[here] {
+			if cursors[m.seq] != m.pos {
+				return false
+			}
+		}
+		e := sequences[i][cursors[i]]
+		_, delivered := emitted[keyOf(e)]
+		// A receive waits for its send to be emitted from its own process
+		return group[here] != nil || e.Type != EventReceive || delivered || !sent[keyOf(e)]
+	}
+
+	for len(out) < total {
+		progressed := false
+		for i, seq := range sequences {
+			for cursors[i] < len(seq) && ready(i) {
+				progressed = true
+				if g := group[slot{i, cursors[i]}]; g != nil {
+					merged := make(VectorClock)
+					for _, m := range g {
+						merged.Merge(clocks[sequences[m.seq][m.pos].Process])
+					}
+					barrier := sequences[g[0].seq][g[0].pos].Type == EventBarrier
+					if !barrier {
+						for _, m := range g {
+							merged[sequences[m.seq][m.pos].Process]++
+						}
+					}
+					for _, m := range g {
+						e := sequences[m.seq][m.pos]
+						clock := DeepCopy(merged)
+						if barrier {
+							clock[e.Process]++
+						}
+						clocks[e.Process] = clock
+						e.VClock = DeepCopy(clock)
+						emit(e)
+						cursors[m.seq]++
+					}
+					continue
+				}
+
+				e := seq[cursors[i]]
+				clock := clocks[e.Process]
+				clock[e.Process]++
+				if sendClock, delivered := emitted[keyOf(e)]; e.Type == EventReceive && delivered {
+					clock.Merge(sendClock)
+				}
+				e.VClock = DeepCopy(clock)
+				emit(e)
+				cursors[i]++
+			}
+		}
+		if !progressed {
+			return nil, fmt.Errorf("events cannot be ordered: receives wait on each other's sends")
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,33 @@
+package types
+
+import "testing"
+
+func TestStitchCrossedExchange(tt *testing.T) {
+	// P waits on S's message and R on Q's: each trace blocks on the other,
+	// but P -> Q and R -> S are independent processes
+	a := Trace{
+		{Type: EventReceive, Process: "P", MessageID: 1},
+		{Type: EventSend, Process: "Q", MessageID: 2},
+	}
+	b := Trace{
+		{Type: EventReceive, Process: "R", MessageID: 2},
+		{Type: EventSend, Process: "S", MessageID: 1},
+	}
+	got, err := Stitch(a, b)
+	if err != nil {
+		tt.Fatal(err)
+	}
+	if len(got) != 4 {
+		tt.Fatalf("stitched %d events, want 4", len(got))
+	}
+	at := make(map[string]Event)
+	for _, e := range got {
+		at[e.Process] = e
+	}
+	if !at["S"].VClock.HappensBefore(at["P"].VClock) || !at["Q"].VClock.HappensBefore(at["R"].VClock) {
+		tt.Errorf("sends do not happen before their receives: %v", got)
+	}
+	if !at["P"].VClock.ConcurrentWith(at["Q"].VClock) {
+		tt.Errorf("P %s and Q %s are not concurrent", at["P"].VClock, at["Q"].VClock)
+	}
+}
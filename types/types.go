@@ -2,6 +2,9 @@ package types
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 )
 
 type EventType int
@@ -9,13 +12,69 @@ type EventType int
 const (
 	EventSend EventType = iota
 	EventReceive
+	EventInternal // a local step that neither sends nor receives
+	EventAcquire  // the process acquired Event.Lock
+	EventRelease  // the process released Event.Lock
+	EventBarrier  // the process passed the barrier of Event.Phase
+	EventCrash    // the process crashed, losing its volatile state
+	EventRecover  // the process restarted after a crash
 )
 
+// IsMessage reports whether events of this type send or receive a message,
+// i.e. whether their MessageID is meaningful.
+func (et EventType) IsMessage() bool {
+	return et == EventSend || et == EventReceive
+}
+
 type Event struct {
 	Type      EventType
 	Process   string
 	VClock    VectorClock
-	MessageID int
+	MessageID int // shared by a SEND and its RECVs; a broadcast has one RECV per receiver
+	// CorrelationKey groups events belonging to the same request or
+	// transaction. Empty when the event is not correlated.
+	CorrelationKey string
+	Lock           string // lock taken or released by ACQUIRE and RELEASE events
+	Phase          int    // barrier phase passed by BARRIER events
+	Role           string // part played in the CorrelationKey's operation, e.g. "ack" or "commit"
+	// Labels are application attributes of the event, such as the session
+	// or the key a read or write touched. The RECVs of a message carry the
+	// payload labels of its SEND. Nil when the event has none.
+	Labels map[string]string
+	// Timestamp is the physical time the process recorded for the event by
+	// its own, possibly skewed, clock. Zero when unknown.
+	Timestamp time.Time
+	// Lamport is the event's Lamport clock as recorded or assigned by
+	// AssignLamport. Zero when unknown.
+	Lamport LamportClock
+	// HLC is the event's hybrid logical clock as recorded or assigned by
+	// AssignHLC. Zero when unknown.
+	HLC    HLC
+	Source *Source // where the event was imported from, nil for generated events
+}
+
+// Source is the provenance of an imported event: the log file and the
+// position of the line the event was parsed from.
+type Source struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`   // 1-based line number
+	Offset int64  `json:"offset"` // byte offset of the start of the line
+}
+
+func (s *Source) String() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// URL returns a link to the original log line, usable from viewers that
+// open local files.
+func (s *Source) URL() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("file://%s#L%d", s.File, s.Line)
 }
 
 func (et EventType) String() string {
@@ -24,6 +83,18 @@ func (et EventType) String() string {
 		return "SEND"
 	case EventReceive:
 		return "RECV"
+	case EventInternal:
+		return "INTERNAL"
+	case EventAcquire:
+		return "ACQUIRE"
+	case EventRelease:
+		return "RELEASE"
+	case EventBarrier:
+		return "BARRIER"
+	case EventCrash:
+		return "CRASH"
+	case EventRecover:
+		return "RECOVER"
 	default:
 		return "UNKNOWN"
 	}
@@ -34,8 +105,27 @@ type Trace []Event
 func (t Trace) String() string {
 	var result string
 	for i, e := range t {
-		result += fmt.Sprintf("e-%-2d: Msg-%d %-4s on %s, VClock: %s\n",
-			i, e.MessageID, e.Type.String(), e.Process, e.VClock.String())
+		result += fmt.Sprintf("e-%-2d: Msg-%d %-4s on %s, VClock: %s",
+			i, e.MessageID, e.Type.String(), QuoteName(e.Process), e.VClock.String())
+		if e.CorrelationKey != "" {
+			result += ", Key: " + QuoteName(e.CorrelationKey)
+		}
+		if e.Lock != "" {
+			result += ", Lock: " + QuoteName(e.Lock)
+		}
+		if e.Role != "" {
+			result += ", Role: " + QuoteName(e.Role)
+		}
+		for _, k := range slices.Sorted(maps.Keys(e.Labels)) {
+			result += ", " + QuoteName(k) + ": " + QuoteName(e.Labels[k])
+		}
+		if e.Type == EventBarrier {
+			result += fmt.Sprintf(", Phase: %d", e.Phase)
+		}
+		if e.Source != nil {
+			result += " (" + e.Source.String() + ")"
+		}
+		result += "\n"
 	}
 	return result
 
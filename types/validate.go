@@ -0,0 +1,171 @@
+package types
+
+import "fmt"
+
+// ClockError is an event whose recorded vector clock no execution could have
+// produced, as found by ValidateTrace.
+type ClockError struct {
+	Event  int // trace index
+	Reason string
+}
+
+func (e ClockError) Error() string {
+	return fmt.Sprintf("e-%d: %s", e.Event, e.Reason)
+}
+
+// ValidateTrace checks the recorded clocks of a trace against its send and
+// receive structure, as imported logs often carry instrumentation bugs. It
+// recomputes what every event can know of the other processes: what the
+// previous event of its process knew, merged with the knowledge of the SEND
+// a RECV matches by MessageID and CorrelationKey, of the other participants
+// of a rendezvous (a SEND whose RECVs share its clock) or of the processes
+// passing the same barrier before they reached it. An event's own entry is
+// taken as recorded but must advance past the previous event of its process;
+// every other entry must equal the recomputed one, so entries that claim to
+// know more than the event could, or forget what it knew, are reported.
+// Receives without a send are not errors.
+func ValidateTrace(trace Trace) []ClockError {
+	known := knownClocks(trace)
+
+	var errs []ClockError
+	report := func(i int, format string, args ...any) {
+		errs = append(errs, ClockError{Event: i, Reason: fmt.Sprintf(format, args...)})
+	}
+	last := make(map[string]int) // previous event of each process
+	for i, e := range trace {
+		p := e.Process
+		if prev, ok := last[p]; ok && e.VClock[p] <= trace[prev].VClock[p] {
+			report(i, "entry %s:%d does not advance past %d of the previous event e-%d", QuoteName(p), e.VClock[p], trace[prev].VClock[p], prev)
+		} else if !ok && e.VClock[p] <= 0 {
+			report(i, "entry %s:%d of the first event of its process is not positive", QuoteName(p), e.VClock[p])
+		}
+		last[p] = i
+
+		entries := DeepCopy(known[i])
+		entries.Merge(e.VClock)
+		for _, q := range entries.Processes() {
+			switch {
+			case q == p:
+			case e.VClock[q] > known[i][q]:
+				report(i, "entry %s:%d is above %d, the most the event can know of %s", QuoteName(q), e.VClock[q], known[i][q], QuoteName(q))
+			case e.VClock[q] < known[i][q]:
+				report(i, "entry %s:%d is below %d, which the event already knows of %s", QuoteName(q), e.VClock[q], known[i][q], QuoteName(q))
+			}
+		}
+	}
+	return errs
+}
+
+// knownClocks recomputes, for every event, the clock it can have from the
+// recorded own entries of the events that causally precede it; see
+// ValidateTrace. Events are placed process by process, a RECV once its SEND
+// is placed and a rendezvous or barrier once all its participants are next
+// on their processes. If nothing is ready, as in a trace whose receives and
+// sends wait on each other, the first pending event is placed on its own.
+func knownClocks(trace Trace) []VectorClock {
+	sends := make(map[messageKey]int)
+	for i, e := range trace {
+		if e.Type == EventSend {
+			if _, ok := sends[keyOf(e)]; !ok {
+				sends[keyOf(e)] = i
+			}
+		}
+	}
+	// Events placed together: the participants of each rendezvous and
+	// barrier
+	rendezvous := make(map[int][]int) // by SEND
+	barriers := make(map[int][]int)   // by phase
+	for i, e := range trace {
+		switch e.Type {
+		case EventReceive:
+			if s, ok := sends[keyOf(e)]; ok && e.VClock.Key() == trace[s].VClock.Key() {
+				if rendezvous[s] == nil {
+					rendezvous[s] = []int{s}
+				}
+				rendezvous[s] = append(rendezvous[s], i)
+			}
+		case EventBarrier:
+			barriers[e.Phase] = append(barriers[e.Phase], i)
+		}
+	}
+	group := make(map[int][]int)
+	for _, groups := range []map[int][]int{rendezvous, barriers} {
+		for _, g := range groups {
+			for _, i := range g {
+				group[i] = g
+			}
+		}
+	}
+
+	var processes []string
+	queue := make(map[string][]int)
+	for i, e := range trace {
+		if queue[e.Process] == nil {
+			processes = append(processes, e.Process)
+		}
+		queue[e.Process] = append(queue[e.Process], i)
+	}
+	known := make([]VectorClock, len(trace))
+	placed := make([]bool, len(trace))
+	before := make(map[string]VectorClock) // knowledge of each process so far
+	next := func(i int) bool { q := queue[trace[i].Process]; return len(q) > 0 && q[0] == i }
+	place := func(members []int) {
+		merged := make(VectorClock)
+		for _, j := range members {
+			e := trace[j]
+			state := DeepCopy(before[e.Process])
+			if e.Type != EventBarrier {
+				// Rendezvous partners learn each other's step
+				state[e.Process] = e.VClock[e.Process]
+			}
+			merged.Merge(state)
+			if s, ok := sends[keyOf(e)]; ok && e.Type == EventReceive && placed[s] {
+				merged.Merge(known[s])
+			}
+		}
+		for _, j := range members {
+			e := trace[j]
+			clock := DeepCopy(merged)
+			clock[e.Process] = e.VClock[e.Process]
+			known[j], placed[j] = clock, true
+			before[e.Process] = clock
+			queue[e.Process] = queue[e.Process][1:]
+		}
+	}
+	ready := func(i int) bool {
+		for _, j := range group[i] {
+			if !next(j) {
+				return false
+			}
+		}
+		e := trace[i]
+		s, ok := sends[keyOf(e)]
+		return e.Type != EventReceive || !ok || placed[s] || group[i] != nil
+	}
+
+	for remaining := len(trace); remaining > 0; {
+		progressed := false
+		for _, p := range processes {
+			for len(queue[p]) > 0 && ready(queue[p][0]) {
+				members := group[queue[p][0]]
+				if members == nil {
+					members = queue[p][:1:1]
+				}
+				remaining -= len(members)
+				place(members)
+				progressed = true
+			}
+		}
+		if !progressed {
+			first := -1
+			for _, p := range processes {
+				if len(queue[p]) > 0 && (first < 0 || queue[p][0] < first) {
+					first = queue[p][0]
+				}
+			}
+			place([]int{first})
+			remaining--
+		}
+	}
+	return known
+}
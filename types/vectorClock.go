@@ -3,13 +3,16 @@ package types
 import (
 	"fmt"
 	"maps"
+	"slices"
 	"sort"
 	"strings"
 )
 
 type VectorClock map[string]int
 
-// NewVectorClock initializes a VectorClock with 0 for each process
+// NewVectorClock initializes a VectorClock with 0 for each process. Processes
+// that join later are added to the clock by Merge, or may be listed from a
+// growing registry with ProcessDict.NewVectorClock.
 func NewVectorClock(processes []string) VectorClock {
 	vc := make(VectorClock)
 	for _, key := range processes {
@@ -25,17 +28,79 @@ func DeepCopy(vc VectorClock) VectorClock {
 	return newVC
 }
 
-// String returns a string representation of the VectorClock
-func (vc VectorClock) String() string {
+// Merge raises each entry of vc to the matching entry of other, as a receive
+// does with the clock of its send. Processes vc has not heard of yet are
+// added to it.
+func (vc VectorClock) Merge(other VectorClock) {
+	for p, v := range other {
+		vc[p] = max(vc[p], v)
+	}
+}
+
+// Key returns the clock as String does, leaving out its zero entries, so
+// that clocks which only differ in the processes they have heard of share a
+// key.
+func (vc VectorClock) Key() string {
+	var parts []string
+	for _, k := range vc.Processes() {
+		if vc[k] != 0 {
+			parts = append(parts, fmt.Sprintf("%s:%d", QuoteName(k), vc[k]))
+		}
+	}
+	return "<" + strings.Join(parts, ", ") + ">"
+}
+
+// PadClocks returns the trace with every clock naming every process of the
+// trace, entries missing from a clock counting as 0. Events of processes
+// that joined mid-trace then compare and print alike with the others. The
+// trace itself is returned if no clock misses a process.
+func PadClocks(trace Trace) Trace {
+	processes := make(map[string]bool)
+	for _, e := range trace {
+		processes[e.Process] = true
+		for p := range e.VClock {
+			processes[p] = true
+		}
+	}
+	var out Trace
+	for i, e := range trace {
+		if len(e.VClock) == len(processes) {
+			continue
+		}
+		if out == nil {
+			out = slices.Clone(trace)
+		}
+		clock := DeepCopy(e.VClock)
+		for p := range processes {
+			if _, ok := clock[p]; !ok {
+				clock[p] = 0
+			}
+		}
+		out[i].VClock = clock
+	}
+	if out == nil {
+		return trace
+	}
+	return out
+}
+
+// Processes returns the processes of the clock sorted by name, for callers
+// whose output must not depend on map iteration order.
+func (vc VectorClock) Processes() []string {
 	keys := make([]string, 0, len(vc))
 	for k := range vc {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
+	return keys
+}
 
+// String returns a string representation of the VectorClock, with entries
+// sorted by process name and names quoted as by QuoteName
+func (vc VectorClock) String() string {
 	var parts []string
-	for _, k := range keys {
-		parts = append(parts, fmt.Sprintf("%s:%d", k, vc[k]))
+	for _, k := range vc.Processes() {
+		parts = append(parts, fmt.Sprintf("%s:%d", QuoteName(k), vc[k]))
 	}
 	return "<" + strings.Join(parts, ", ") + ">"
 }
@@ -43,6 +108,9 @@ func (vc VectorClock) String() string {
 // Returns true if vc happens-before other.
 // 1. Checks that vc <= other and that
 // 2. At least one entry is strictly less.
+// Entries missing from either clock count as zero, so clocks over different
+// sets of processes, as imported or sparse clocks are, compare as if every
+// process were listed.
 func (vc VectorClock) HappensBefore(other VectorClock) bool {
 	lessOrEqual := true
 	strictlyLess := false
@@ -56,6 +124,17 @@ func (vc VectorClock) HappensBefore(other VectorClock) bool {
 			strictlyLess = true
 		}
 	}
+	// Entries missing from vc count as zero
+	for p, v := range other {
+		if _, ok := vc[p]; !ok && v > 0 {
+			strictlyLess = true
+		}
+	}
 
 	return lessOrEqual && strictlyLess
 }
+
+// ConcurrentWith reports whether neither clock happens before the other.
+func (vc VectorClock) ConcurrentWith(other VectorClock) bool {
+	return !vc.HappensBefore(other) && !other.HappensBefore(vc)
+}
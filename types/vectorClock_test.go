@@ -0,0 +1,30 @@
+package types
+
+import "testing"
+
+// TestHappensBeforeMissingEntries checks that entries a clock does not list
+// count as zero on either side.
+func TestHappensBeforeMissingEntries(t *testing.T) {
+	tests := []struct {
+		a, b VectorClock
+		want bool
+	}{
+		{VectorClock{"A": 1}, VectorClock{"A": 1, "B": 1}, true},         // only b knows of B
+		{VectorClock{"A": 1, "B": 0}, VectorClock{"A": 1}, false},        // equal once B counts as zero
+		{VectorClock{"A": 1}, VectorClock{"A": 1}, false},                // equal
+		{VectorClock{"A": 1, "B": 1}, VectorClock{"A": 2}, false},        // b lacks B, so is not above a
+		{VectorClock{}, VectorClock{"A": 1}, true},                       // the empty clock precedes any other
+		{VectorClock{"A": 1}, VectorClock{"B": 1}, false},                // concurrent
+		{VectorClock{"A": 1, "B": 2}, VectorClock{"A": 3, "B": 2}, true}, // same processes
+		{VectorClock{"A": 0}, VectorClock{"B": 0}, false},                // both all zero
+		{VectorClock{"A": 2}, VectorClock{"A": 1, "B": 5}, false},        // a ahead on A
+	}
+	for _, tt := range tests {
+		if got := tt.a.HappensBefore(tt.b); got != tt.want {
+			t.Errorf("%s.HappensBefore(%s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+	if !(VectorClock{"A": 1}).ConcurrentWith(VectorClock{"B": 1}) {
+		t.Errorf("<A:1> and <B:1> are not concurrent")
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	t "github.com/traces/types"
+)
+
+// runValidate implements `trace validate`: it reports the events of a trace
+// whose recorded vector clocks are impossible given its sends and receives.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	in := fs.String("in", "", "trace to validate")
+	aliasesPath := aliasesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	trace, err := loadTrace(*in)
+	if err != nil {
+		return err
+	}
+	aliases, err := loadAliases(*aliasesPath)
+	if err != nil {
+		return err
+	}
+	errs := t.ValidateTrace(aliases.Apply(trace))
+	for _, e := range errs {
+		fmt.Println(e)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d clock errors in %d events", len(errs), len(trace))
+	}
+	fmt.Printf("clocks of %d events are consistent\n", len(trace))
+	return nil
+}